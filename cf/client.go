@@ -1,16 +1,24 @@
 package cf
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"path"
+	"sync"
 
 	"github.com/pkg/errors"
 )
 
 const (
+	RootPath          = "/"
+	InfoPath          = "/v2/info"
+	OrganizationsPath = "/v3/organizations"
+	// ResultsPerPage is the page size asked of the API when listing.
+	ResultsPerPage = "100"
+
 	CfApiURLParsingError                     = "error parsing CF API URL: %s"
 	CreateCfApiHTTPRequestError              = "error creating HTTP request for CF API endpoint: %s"
 	CfApiRequestError                        = "error accessing CF API endpoint: %s"
@@ -20,9 +28,18 @@ const (
 	UAAEndpointEmptyError                    = "UAA url is empty"
 )
 
+// Organization is a CF org.
+type Organization struct {
+	GUID string
+	Name string
+}
+
 type Client struct {
 	cfApiURL   string
 	httpClient httpClient
+
+	uaaURLMutex sync.Mutex
+	uaaURL      string
 }
 
 //go:generate counterfeiter . httpClient
@@ -34,39 +51,66 @@ func NewClient(cfApiURL string, httpClient httpClient) *Client {
 	return &Client{cfApiURL: cfApiURL, httpClient: httpClient}
 }
 
-func (cl *Client) GetUAAURL() (string, error) {
+// GetUAAURL discovers UAA from the links of the v3 root endpoint, falling
+// back to the v2 info endpoint on deployments without it. The URL found is
+// kept for later calls.
+func (cl *Client) GetUAAURL(ctx context.Context) (string, error) {
+	cl.uaaURLMutex.Lock()
+	defer cl.uaaURLMutex.Unlock()
+	if cl.uaaURL != "" {
+		return cl.uaaURL, nil
+	}
+
 	cfApiURL, err := url.Parse(cl.cfApiURL)
 	if err != nil {
 		return "", errors.Wrapf(err, CfApiURLParsingError, cl.cfApiURL)
 	}
 
-	cfApiURL.Path = path.Join(cfApiURL.Path, "/v2/info")
-	req, err := http.NewRequest(http.MethodGet, cfApiURL.String(), nil)
-	if err != nil {
-		return "", errors.Wrap(err, CreateCfApiHTTPRequestError)
+	uaaURL := cl.rootUAAURL(ctx, *cfApiURL)
+	if uaaURL == "" {
+		uaaURL, err = cl.infoUAAURL(ctx, *cfApiURL)
+		if err != nil {
+			return "", err
+		}
 	}
 
-	resp, err := cl.httpClient.Do(req)
-	if err != nil {
-		return "", errors.Wrapf(err, CfApiRequestError, cfApiURL.String())
-	}
-	defer resp.Body.Close()
+	cl.uaaURL = uaaURL
+	return uaaURL, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", errors.Errorf(CFApiUnexpectedResponseStatusErrorFormat, resp.StatusCode)
+// rootUAAURL is the uaa, or else login, link of the v3 root endpoint, or
+// empty when the endpoint cannot say.
+func (cl *Client) rootUAAURL(ctx context.Context, rootURL url.URL) string {
+	rootURL.Path = path.Join(rootURL.Path, RootPath)
+
+	var rootResponse struct {
+		Links struct {
+			UAA struct {
+				Href string `json:"href"`
+			} `json:"uaa"`
+			Login struct {
+				Href string `json:"href"`
+			} `json:"login"`
+		} `json:"links"`
+	}
+	if err := cl.get(ctx, &rootURL, &rootResponse); err != nil {
+		return ""
 	}
 
-	respBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", errors.Wrapf(err, CFApiReadResponseError, cfApiURL.String())
+	if rootResponse.Links.UAA.Href != "" {
+		return rootResponse.Links.UAA.Href
 	}
+	return rootResponse.Links.Login.Href
+}
+
+func (cl *Client) infoUAAURL(ctx context.Context, infoURL url.URL) (string, error) {
+	infoURL.Path = path.Join(infoURL.Path, InfoPath)
 
 	var cfResponse struct {
 		TokenEndpoint string `json:"token_endpoint"`
 	}
-	err = json.Unmarshal(respBody, &cfResponse)
-	if err != nil {
-		return "", errors.Wrapf(err, CFApiUnmarshalError, cfApiURL.String())
+	if err := cl.get(ctx, &infoURL, &cfResponse); err != nil {
+		return "", err
 	}
 
 	if cfResponse.TokenEndpoint == "" {
@@ -75,3 +119,90 @@ func (cl *Client) GetUAAURL() (string, error) {
 
 	return cfResponse.TokenEndpoint, nil
 }
+
+// Organizations lists every org the client can see.
+func (cl *Client) Organizations(ctx context.Context) ([]Organization, error) {
+	resources, err := cl.ListV3Resources(ctx, OrganizationsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var organizations []Organization
+	for _, resource := range resources {
+		var organization struct {
+			GUID string `json:"guid"`
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(resource, &organization); err != nil {
+			return nil, errors.Wrapf(err, CFApiUnmarshalError, OrganizationsPath)
+		}
+		organizations = append(organizations, Organization{GUID: organization.GUID, Name: organization.Name})
+	}
+	return organizations, nil
+}
+
+// ListV3Resources lists every resource the client can see at a v3 API path
+// such as OrganizationsPath, following the pages until there are no more.
+func (cl *Client) ListV3Resources(ctx context.Context, resourcePath string) ([]json.RawMessage, error) {
+	cfApiURL, err := url.Parse(cl.cfApiURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, CfApiURLParsingError, cl.cfApiURL)
+	}
+	pageURL := *cfApiURL
+	pageURL.Path = path.Join(pageURL.Path, resourcePath)
+	pageURL.RawQuery = url.Values{"per_page": {ResultsPerPage}}.Encode()
+
+	var resources []json.RawMessage
+	for {
+		var page struct {
+			Pagination struct {
+				Next *struct {
+					Href string `json:"href"`
+				} `json:"next"`
+			} `json:"pagination"`
+			Resources []json.RawMessage `json:"resources"`
+		}
+		if err := cl.get(ctx, &pageURL, &page); err != nil {
+			return nil, err
+		}
+		resources = append(resources, page.Resources...)
+
+		if page.Pagination.Next == nil || page.Pagination.Next.Href == "" {
+			return resources, nil
+		}
+		nextURL, err := cfApiURL.Parse(page.Pagination.Next.Href)
+		if err != nil {
+			return nil, errors.Wrapf(err, CfApiURLParsingError, page.Pagination.Next.Href)
+		}
+		pageURL = *nextURL
+	}
+}
+
+func (cl *Client) get(ctx context.Context, targetURL *url.URL, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, targetURL.String(), nil)
+	if err != nil {
+		return errors.Wrap(err, CreateCfApiHTTPRequestError)
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := cl.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, CfApiRequestError, targetURL.String())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf(CFApiUnexpectedResponseStatusErrorFormat, resp.StatusCode)
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, CFApiReadResponseError, targetURL.String())
+	}
+
+	err = json.Unmarshal(respBody, v)
+	if err != nil {
+		return errors.Wrapf(err, CFApiUnmarshalError, targetURL.String())
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package cf_test
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -35,16 +36,74 @@ var _ = Describe("Client", func() {
 	})
 
 	Describe("GetUAAURL", func() {
+		BeforeEach(func() {
+			fakeHTTPClient.DoReturnsOnCall(0, &http.Response{StatusCode: http.StatusNotFound, Body: &readerCloser{reader: bytes.NewReader(nil)}}, nil)
+		})
+
 		It("makes a request to UAA and retrieves the UAA url", func() {
-			uaaURL, err := client.GetUAAURL()
+			uaaURL, err := client.GetUAAURL(context.Background())
 			Expect(err).NotTo(HaveOccurred())
 			Expect(uaaURL).To(Equal("http://api.funstuff.com/uaa"))
 			Expect(responseReader.isClosed).To(BeTrue())
 		})
 
+		It("discovers UAA from the uaa link of the v3 root endpoint", func() {
+			fakeHTTPClient.DoReturnsOnCall(0, &http.Response{StatusCode: http.StatusOK, Body: &readerCloser{reader: bytes.NewReader([]byte(`{
+				"links": {
+					"uaa": {"href": "https://uaa.funstuff.com"},
+					"login": {"href": "https://login.funstuff.com"}
+				}
+			}`))}}, nil)
+
+			uaaURL, err := client.GetUAAURL(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(uaaURL).To(Equal("https://uaa.funstuff.com"))
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(1))
+			Expect(fakeHTTPClient.DoArgsForCall(0).URL.String()).To(Equal(cfURL))
+		})
+
+		It("uses the login link of the v3 root endpoint when there is no uaa link", func() {
+			fakeHTTPClient.DoReturnsOnCall(0, &http.Response{StatusCode: http.StatusOK, Body: &readerCloser{reader: bytes.NewReader([]byte(`{
+				"links": {"login": {"href": "https://login.funstuff.com"}}
+			}`))}}, nil)
+
+			uaaURL, err := client.GetUAAURL(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(uaaURL).To(Equal("https://login.funstuff.com"))
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(1))
+		})
+
+		It("falls back to the v2 info endpoint when the v3 root endpoint fails", func() {
+			uaaURL, err := client.GetUAAURL(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(uaaURL).To(Equal("http://api.funstuff.com/uaa"))
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(2))
+			Expect(fakeHTTPClient.DoArgsForCall(1).URL.String()).To(Equal(cfURL + InfoPath))
+		})
+
+		It("keeps the UAA url for later calls", func() {
+			_, err := client.GetUAAURL(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			callCount := fakeHTTPClient.DoCallCount()
+
+			uaaURL, err := client.GetUAAURL(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(uaaURL).To(Equal("http://api.funstuff.com/uaa"))
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(callCount))
+		})
+
+		It("sends the request with the given context", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			_, err := client.GetUAAURL(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeHTTPClient.DoArgsForCall(0).Context()).To(Equal(ctx))
+		})
+
 		It("returns an error when the CF API URL is invalid", func() {
 			client = NewClient(" bad://url", nil)
-			_, err := client.GetUAAURL()
+			_, err := client.GetUAAURL(context.Background())
 			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(CfApiURLParsingError, " bad://url"))))
 			Expect(err).To(MatchError(ContainSubstring("first path segment in URL cannot contain colon")))
 		})
@@ -52,7 +111,7 @@ var _ = Describe("Client", func() {
 		It("returns an error when the request to the CF API endpoint fails", func() {
 			fakeHTTPClient.DoReturns(nil, errors.New("Requesting stuff is hard"))
 
-			_, err := client.GetUAAURL()
+			_, err := client.GetUAAURL(context.Background())
 
 			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(CfApiRequestError, cfURL+"/v2/info"))))
 			Expect(err).To(MatchError(ContainSubstring("Requesting stuff is hard")))
@@ -62,7 +121,7 @@ var _ = Describe("Client", func() {
 			responseReader := &readerCloser{reader: &badReader{}}
 			fakeHTTPClient.DoReturns(&http.Response{StatusCode: http.StatusOK, Body: responseReader}, nil)
 
-			_, err := client.GetUAAURL()
+			_, err := client.GetUAAURL(context.Background())
 			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(CFApiReadResponseError, cfURL+"/v2/info"))))
 			Expect(err).To(MatchError(ContainSubstring("Reading is hard")))
 			Expect(responseReader.isClosed).To(BeTrue())
@@ -72,7 +131,7 @@ var _ = Describe("Client", func() {
 			responseReader = &readerCloser{reader: bytes.NewReader([]byte(`{"messed-up,"}`))}
 			fakeHTTPClient.DoReturns(&http.Response{Body: responseReader, StatusCode: http.StatusOK}, nil)
 
-			_, err := client.GetUAAURL()
+			_, err := client.GetUAAURL(context.Background())
 			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(CFApiUnmarshalError, cfURL+"/v2/info"))))
 			Expect(err).To(MatchError(ContainSubstring("invalid character '}' after object key")))
 			Expect(responseReader.isClosed).To(BeTrue())
@@ -80,7 +139,7 @@ var _ = Describe("Client", func() {
 
 		It("returns an error when the response is not 200", func() {
 			fakeHTTPClient.DoReturns(&http.Response{Body: responseReader, StatusCode: http.StatusInternalServerError}, nil)
-			_, err := client.GetUAAURL()
+			_, err := client.GetUAAURL(context.Background())
 			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(CFApiUnexpectedResponseStatusErrorFormat, 500))))
 			Expect(responseReader.isClosed).To(BeTrue())
 		})
@@ -89,12 +148,90 @@ var _ = Describe("Client", func() {
 			responseReader = &readerCloser{reader: bytes.NewReader([]byte(`{"token_endpoint":"", "other_non_string_prop": true}`))}
 			fakeHTTPClient.DoReturns(&http.Response{Body: responseReader, StatusCode: http.StatusOK}, nil)
 
-			_, err := client.GetUAAURL()
+			_, err := client.GetUAAURL(context.Background())
 			Expect(err).To(MatchError(ContainSubstring(UAAEndpointEmptyError)))
 			Expect(responseReader.isClosed).To(BeTrue())
 		})
 
 	})
+
+	Describe("ListV3Resources", func() {
+		It("lists the resources on every page", func() {
+			fakeHTTPClient.DoReturnsOnCall(0, &http.Response{StatusCode: http.StatusOK, Body: &readerCloser{reader: bytes.NewReader([]byte(`{
+				"pagination": {"next": {"href": "https://example.com/v3/stacks?page=2&per_page=100"}},
+				"resources": [{"guid": "stack-1-guid", "name": "cflinuxfs3"}]
+			}`))}}, nil)
+			fakeHTTPClient.DoReturnsOnCall(1, &http.Response{StatusCode: http.StatusOK, Body: &readerCloser{reader: bytes.NewReader([]byte(`{
+				"pagination": {"next": null},
+				"resources": [{"guid": "stack-2-guid", "name": "cflinuxfs4"}]
+			}`))}}, nil)
+
+			resources, err := client.ListV3Resources(context.Background(), "/v3/stacks")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resources).To(HaveLen(2))
+			Expect(resources[0]).To(MatchJSON(`{"guid": "stack-1-guid", "name": "cflinuxfs3"}`))
+			Expect(resources[1]).To(MatchJSON(`{"guid": "stack-2-guid", "name": "cflinuxfs4"}`))
+
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(2))
+			Expect(fakeHTTPClient.DoArgsForCall(0).URL.String()).To(Equal(cfURL + "/v3/stacks?per_page=" + ResultsPerPage))
+			Expect(fakeHTTPClient.DoArgsForCall(1).URL.String()).To(Equal("https://example.com/v3/stacks?page=2&per_page=100"))
+		})
+	})
+
+	Describe("Organizations", func() {
+		It("lists the orgs on every page", func() {
+			fakeHTTPClient.DoReturnsOnCall(0, &http.Response{StatusCode: http.StatusOK, Body: &readerCloser{reader: bytes.NewReader([]byte(`{
+				"pagination": {"next": {"href": "https://example.com/v3/organizations?page=2&per_page=100"}},
+				"resources": [{"guid": "org-1-guid", "name": "org-1"}]
+			}`))}}, nil)
+			lastPage := &readerCloser{reader: bytes.NewReader([]byte(`{
+				"pagination": {"next": null},
+				"resources": [{"guid": "org-2-guid", "name": "org-2"}]
+			}`))}
+			fakeHTTPClient.DoReturnsOnCall(1, &http.Response{StatusCode: http.StatusOK, Body: lastPage}, nil)
+
+			organizations, err := client.Organizations(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(organizations).To(Equal([]Organization{
+				{GUID: "org-1-guid", Name: "org-1"},
+				{GUID: "org-2-guid", Name: "org-2"},
+			}))
+
+			Expect(fakeHTTPClient.DoCallCount()).To(Equal(2))
+			Expect(fakeHTTPClient.DoArgsForCall(0).URL.String()).To(Equal(cfURL + OrganizationsPath + "?per_page=" + ResultsPerPage))
+			Expect(fakeHTTPClient.DoArgsForCall(1).URL.String()).To(Equal("https://example.com/v3/organizations?page=2&per_page=100"))
+			Expect(lastPage.isClosed).To(BeTrue())
+		})
+
+		It("sends the requests with the given context", func() {
+			fakeHTTPClient.DoReturns(&http.Response{StatusCode: http.StatusOK, Body: &readerCloser{reader: bytes.NewReader([]byte(`{"resources": []}`))}}, nil)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			organizations, err := client.Organizations(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(organizations).To(BeEmpty())
+			Expect(fakeHTTPClient.DoArgsForCall(0).Context()).To(Equal(ctx))
+		})
+
+		It("returns an error when a page cannot be retrieved", func() {
+			fakeHTTPClient.DoReturnsOnCall(0, &http.Response{StatusCode: http.StatusOK, Body: &readerCloser{reader: bytes.NewReader([]byte(`{
+				"pagination": {"next": {"href": "https://example.com/v3/organizations?page=2"}},
+				"resources": [{"guid": "org-1-guid", "name": "org-1"}]
+			}`))}}, nil)
+			fakeHTTPClient.DoReturnsOnCall(1, &http.Response{StatusCode: http.StatusForbidden, Body: &readerCloser{reader: bytes.NewReader(nil)}}, nil)
+
+			_, err := client.Organizations(context.Background())
+			Expect(err).To(MatchError(fmt.Sprintf(CFApiUnexpectedResponseStatusErrorFormat, http.StatusForbidden)))
+		})
+
+		It("returns an error when the response cannot be unmarshaled", func() {
+			fakeHTTPClient.DoReturns(&http.Response{StatusCode: http.StatusOK, Body: &readerCloser{reader: bytes.NewReader([]byte(`{"resources": {}}`))}}, nil)
+
+			_, err := client.Organizations(context.Background())
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(CFApiUnmarshalError, cfURL+OrganizationsPath+"?per_page="+ResultsPerPage))))
+		})
+	})
 })
 
 type badReader struct{}
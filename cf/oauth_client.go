@@ -5,18 +5,24 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"path"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
+const TokenPath = "/oauth/token"
+
+// OAuthClient authenticates requests with a client credentials token from
+// UAA. The token is shared by copies of the client and only fetched again
+// when it is about to expire, or once when a request is unauthorized.
 type OAuthClient struct {
-	oauthConfig   *oauth2.Config
-	oauthConfigCC *clientcredentials.Config
-	context       context.Context
-	target        string
-	timeout       time.Duration
+	tokens  *tokenCache
+	client  client
+	timeout time.Duration
 }
 
 type client interface {
@@ -29,38 +35,116 @@ func NewOAuthClient(target, clientID, clientSecret string, requestTimeout time.D
 		ClientSecret: clientSecret,
 	}
 
-	return funcName(confCC, client, target, requestTimeout)
-}
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		err = fmt.Errorf("could not parse target url: %s", err)
+	} else {
+		targetURL.Path = path.Join(targetURL.Path, TokenPath)
+		confCC.TokenURL = targetURL.String()
+	}
 
-func funcName(confCC *clientcredentials.Config, client client, target string, requestTimeout time.Duration) OAuthClient {
 	return OAuthClient{
-		oauthConfig:   &oauth2.Config{},
-		oauthConfigCC: confCC,
-		context:       context.WithValue(context.TODO(), oauth2.HTTPClient, client),
-		target:        target,
-		timeout:       requestTimeout,
+		tokens:  &tokenCache{config: confCC, targetErr: err},
+		client:  client,
+		timeout: requestTimeout,
 	}
 }
 
 func (oc OAuthClient) Do(request *http.Request) (*http.Response, error) {
-	var client *http.Client
+	if oc.tokens.targetErr != nil {
+		return nil, oc.tokens.targetErr
+	}
 
-	targetURL, err := url.Parse(oc.target)
+	tokens := &requestTokens{cache: oc.tokens, ctx: request.Context(), client: oc.client}
+	resp, err := oc.do(request, tokens)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse target url: %s", err)
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized || !replayable(request) {
+		return resp, nil
 	}
 
-	targetURL.Path = "/oauth/token"
-	oc.oauthConfigCC.TokenURL = targetURL.String()
-	oc.oauthConfig.Endpoint.TokenURL = targetURL.String()
+	// The token may have been revoked or UAA's keys rotated before it
+	// expired, so it is replaced and the request made once more.
+	resp.Body.Close()
+	oc.tokens.invalidate(tokens.used)
+	retry := request.Clone(request.Context())
+	if request.GetBody != nil {
+		retry.Body, err = request.GetBody()
+		if err != nil {
+			return nil, errors.Wrap(err, "error performing request")
+		}
+	}
+	return oc.do(retry, &requestTokens{cache: oc.tokens, ctx: request.Context(), client: oc.client})
+}
 
-	client = oc.oauthConfigCC.Client(oc.context)
+func (oc OAuthClient) do(request *http.Request, tokens *requestTokens) (*http.Response, error) {
+	client := oauth2.NewClient(context.WithValue(request.Context(), oauth2.HTTPClient, oc.client), tokens)
 	client.Timeout = oc.timeout
 
 	resp, err := client.Do(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "error performing request")
+	}
+	return resp, nil
+}
+
+func replayable(request *http.Request) bool {
+	return request.Body == nil || request.Body == http.NoBody || request.GetBody != nil
+}
+
+// tokenCache holds the token every request is made with.
+type tokenCache struct {
+	config    *clientcredentials.Config
+	targetErr error
+
+	mutex sync.Mutex
+	token *oauth2.Token
+}
+
+// get returns the cached token, fetching a new one with ctx when there is
+// none or it is about to expire. Concurrent requests wait for the one fetch.
+func (tc *tokenCache) get(ctx context.Context) (*oauth2.Token, error) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	if tc.token.Valid() {
+		return tc.token, nil
+	}
+	token, err := tc.config.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tc.token = token
+	return token, nil
+}
+
+// invalidate drops token from the cache, unless it has already been
+// replaced.
+func (tc *tokenCache) invalidate(token *oauth2.Token) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	if tc.token == token {
+		tc.token = nil
+	}
+}
+
+// requestTokens is the token source for a single request. The token is
+// fetched with the request's context so that cancelling the request also
+// cancels fetching the token.
+type requestTokens struct {
+	cache  *tokenCache
+	ctx    context.Context
+	client client
+	used   *oauth2.Token
+}
 
+func (rt *requestTokens) Token() (*oauth2.Token, error) {
+	token, err := rt.cache.get(context.WithValue(rt.ctx, oauth2.HTTPClient, rt.client))
 	if err != nil {
-		return nil, fmt.Errorf("error performing request %s", err)
+		return nil, err
 	}
-	return resp, err
+	rt.used = token
+	return token, nil
 }
@@ -3,12 +3,14 @@ package cf_test
 import (
 	"bufio"
 	"bytes"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/http/httputil"
 	"net/url"
 	"strings"
-
+	"sync/atomic"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -20,38 +22,70 @@ var _ = Describe("OAuthClient", func() {
 	var (
 		receivedRequest []byte
 		authHeader      string
+		tokenCount      int32
+		expiresIn       int
+		unauthorized    func(authHeader string) bool
 		oauthServer     *httptest.Server
 		server          *httptest.Server
 		accessURL       string
 	)
 
 	BeforeEach(func() {
+		atomic.StoreInt32(&tokenCount, 0)
+		expiresIn = 3600
+		unauthorized = func(string) bool { return false }
 		oauthServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-			if req.URL.Path == "/oauth/token" {
+			if req.URL.Path == "/oauth/token" || req.URL.Path == "/uaa/oauth/token" {
 				var err error
 				receivedRequest, err = httputil.DumpRequest(req, true)
 				Expect(err).NotTo(HaveOccurred())
 
+				accessToken := "some-token"
+				if count := atomic.AddInt32(&tokenCount, 1); count > 1 {
+					accessToken = fmt.Sprintf("some-token-%d", count)
+				}
 				w.Header().Set("Content-Type", "application/json")
 
-				_, err = w.Write([]byte(`{
-					"access_token": "some-token",
+				_, err = w.Write([]byte(fmt.Sprintf(`{
+					"access_token": "%s",
 					"token_type": "bearer",
-					"expires_in": 3600
-					}`))
+					"expires_in": %d
+					}`, accessToken, expiresIn)))
 				Expect(err).ToNot(HaveOccurred())
 			}
 		}))
 		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 			if req.URL.Path == "/some/path" {
 				authHeader = req.Header.Get("Authorization")
+				body, err := ioutil.ReadAll(req.Body)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(body)).To(Equal("request-body"))
 
+				if unauthorized(authHeader) {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
 				w.WriteHeader(http.StatusNoContent)
 			}
 		}))
 		accessURL = server.URL + "/some/path"
 	})
 
+	AfterEach(func() {
+		oauthServer.Close()
+		server.Close()
+	})
+
+	doRequest := func(client OAuthClient) *http.Response {
+		req, err := http.NewRequest("GET", accessURL, strings.NewReader("request-body"))
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := client.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		return resp
+	}
+
 	Describe("Do", func() {
 		It("makes a request with client credentials", func() {
 			client := NewOAuthClient(oauthServer.URL, "client_id", "client_secret", time.Duration(30)*time.Second, http.DefaultClient)
@@ -83,6 +117,55 @@ var _ = Describe("OAuthClient", func() {
 			}))
 		})
 
+		It("reuses the token for later requests", func() {
+			client := NewOAuthClient(oauthServer.URL, "client_id", "client_secret", time.Duration(30)*time.Second, http.DefaultClient)
+
+			for i := 0; i < 3; i++ {
+				Expect(doRequest(client).StatusCode).To(Equal(http.StatusNoContent))
+				Expect(authHeader).To(Equal("Bearer some-token"))
+			}
+			Expect(atomic.LoadInt32(&tokenCount)).To(BeEquivalentTo(1))
+		})
+
+		It("fetches a new token when the cached one is about to expire", func() {
+			expiresIn = 5
+			client := NewOAuthClient(oauthServer.URL, "client_id", "client_secret", time.Duration(30)*time.Second, http.DefaultClient)
+
+			doRequest(client)
+			doRequest(client)
+			Expect(authHeader).To(Equal("Bearer some-token-2"))
+			Expect(atomic.LoadInt32(&tokenCount)).To(BeEquivalentTo(2))
+		})
+
+		It("keeps the path of the target url in the token url", func() {
+			client := NewOAuthClient(oauthServer.URL+"/uaa/", "client_id", "client_secret", time.Duration(30)*time.Second, http.DefaultClient)
+
+			Expect(doRequest(client).StatusCode).To(Equal(http.StatusNoContent))
+
+			req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(receivedRequest)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(req.URL.Path).To(Equal("/uaa/oauth/token"))
+		})
+
+		It("fetches a new token and retries once when the request is unauthorized", func() {
+			unauthorized = func(authHeader string) bool { return authHeader == "Bearer some-token" }
+			client := NewOAuthClient(oauthServer.URL, "client_id", "client_secret", time.Duration(30)*time.Second, http.DefaultClient)
+
+			Expect(doRequest(client).StatusCode).To(Equal(http.StatusNoContent))
+			Expect(authHeader).To(Equal("Bearer some-token-2"))
+
+			Expect(doRequest(client).StatusCode).To(Equal(http.StatusNoContent))
+			Expect(atomic.LoadInt32(&tokenCount)).To(BeEquivalentTo(2))
+		})
+
+		It("returns the unauthorized response when the new token is unauthorized too", func() {
+			unauthorized = func(string) bool { return true }
+			client := NewOAuthClient(oauthServer.URL, "client_id", "client_secret", time.Duration(30)*time.Second, http.DefaultClient)
+
+			Expect(doRequest(client).StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(atomic.LoadInt32(&tokenCount)).To(BeEquivalentTo(2))
+		})
+
 		Context("when the target url is empty", func() {
 			It("returns an error", func() {
 				client := NewOAuthClient("", "", "", time.Duration(30)*time.Second, http.DefaultClient)
@@ -0,0 +1,13 @@
+package cfapi_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCfApi(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CF API Suite")
+}
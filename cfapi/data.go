@@ -0,0 +1,44 @@
+package cfapi
+
+import (
+	"io"
+)
+
+type Data struct {
+	reader   io.Reader
+	dataType string
+	err      error
+}
+
+func NewData(reader io.Reader, dataType string) Data {
+	return Data{reader: reader, dataType: dataType}
+}
+
+// NewFailedData records a retrieval that failed during a partial collection.
+func NewFailedData(dataType string, err error) Data {
+	return Data{dataType: dataType, err: err}
+}
+
+func (d Data) Name() string {
+	return d.dataType
+}
+
+func (d Data) Content() io.Reader {
+	return d.reader
+}
+
+func (d Data) MimeType() string {
+	return "application/json"
+}
+
+func (d Data) Type() string {
+	return ""
+}
+
+func (d Data) DataType() string {
+	return d.dataType
+}
+
+func (d Data) Err() error {
+	return d.err
+}
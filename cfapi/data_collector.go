@@ -0,0 +1,49 @@
+package cfapi
+
+import (
+	"context"
+	"io"
+	"log"
+)
+
+//go:generate counterfeiter . cfApiService
+type cfApiService interface {
+	List(ctx context.Context, dataType string) (io.Reader, error)
+}
+
+type DataCollector struct {
+	logger       *log.Logger
+	cfApiService cfApiService
+	cfApiURL     string
+	allowPartial bool
+}
+
+// NewDataCollector returns a collector that stops at the first data type
+// that cannot be listed, or with allowPartial records it as failed data and
+// carries on.
+func NewDataCollector(logger *log.Logger, cs cfApiService, cfApiURL string, allowPartial bool) *DataCollector {
+	return &DataCollector{
+		logger:       logger,
+		cfApiService: cs,
+		cfApiURL:     cfApiURL,
+		allowPartial: allowPartial,
+	}
+}
+
+func (dc *DataCollector) Collect(ctx context.Context) ([]Data, error) {
+	dc.logger.Printf("Collecting data from CF API at %s", dc.cfApiURL)
+
+	var inventory []Data
+	for _, dataType := range DataTypes() {
+		reader, err := dc.cfApiService.List(ctx, dataType)
+		if err != nil {
+			if !dc.allowPartial || ctx.Err() != nil {
+				return []Data{}, err
+			}
+			inventory = append(inventory, NewFailedData(dataType, err))
+			continue
+		}
+		inventory = append(inventory, NewData(reader, dataType))
+	}
+	return inventory, nil
+}
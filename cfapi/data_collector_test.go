@@ -0,0 +1,84 @@
+package cfapi_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/aqueduct-courier/cfapi"
+	"github.com/pivotal-cf/aqueduct-courier/cfapi/cfapifakes"
+)
+
+var _ = Describe("DataCollector", func() {
+	var (
+		logger         *log.Logger
+		bufferedOutput *gbytes.Buffer
+		cfApiService   *cfapifakes.FakeCfApiService
+	)
+
+	BeforeEach(func() {
+		bufferedOutput = gbytes.NewBuffer()
+		logger = log.New(bufferedOutput, "", 0)
+		cfApiService = new(cfapifakes.FakeCfApiService)
+		cfApiService.ListStub = func(_ context.Context, dataType string) (io.Reader, error) {
+			return strings.NewReader(dataType + " data"), nil
+		}
+	})
+
+	It("lists each data type of the inventory", func() {
+		dataCollector := NewDataCollector(logger, cfApiService, "some-cf-api-url", false)
+
+		collectedData, err := dataCollector.Collect(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bufferedOutput).To(gbytes.Say("Collecting data from CF API at some-cf-api-url"))
+
+		var dataTypes []string
+		for _, data := range collectedData {
+			dataTypes = append(dataTypes, data.DataType())
+		}
+		Expect(dataTypes).To(Equal(DataTypes()))
+		Expect(dataTypes).To(ContainElement(ServiceBrokersDataType))
+		Expect(cfApiService.ListCallCount()).To(Equal(len(DataTypes())))
+	})
+
+	It("returns an error when a data type cannot be listed", func() {
+		cfApiService.ListReturnsOnCall(1, nil, errors.New("listing is hard"))
+		dataCollector := NewDataCollector(logger, cfApiService, "some-cf-api-url", false)
+
+		collectedData, err := dataCollector.Collect(context.Background())
+		Expect(collectedData).To(BeEmpty())
+		Expect(err).To(MatchError("listing is hard"))
+		Expect(cfApiService.ListCallCount()).To(Equal(2))
+	})
+
+	Context("when partial collection is allowed", func() {
+		It("returns failed data for the data types that cannot be listed", func() {
+			cfApiService.ListReturnsOnCall(1, nil, errors.New("listing is hard"))
+			dataCollector := NewDataCollector(logger, cfApiService, "some-cf-api-url", true)
+
+			collectedData, err := dataCollector.Collect(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(collectedData).To(HaveLen(len(DataTypes())))
+			Expect(collectedData[1].DataType()).To(Equal(SpacesDataType))
+			Expect(collectedData[1].Err()).To(MatchError("listing is hard"))
+			Expect(collectedData[2].Err()).NotTo(HaveOccurred())
+		})
+
+		It("returns an error when the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			cfApiService.ListReturns(nil, ctx.Err())
+			dataCollector := NewDataCollector(logger, cfApiService, "some-cf-api-url", true)
+
+			collectedData, err := dataCollector.Collect(ctx)
+			Expect(collectedData).To(BeEmpty())
+			Expect(err).To(MatchError(context.Canceled))
+		})
+	})
+})
@@ -0,0 +1,29 @@
+package cfapi_test
+
+import (
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/aqueduct-courier/cfapi"
+)
+
+var _ = Describe("Data", func() {
+	It("is named for its data type", func() {
+		dataReader := strings.NewReader("best-data")
+		d := NewData(dataReader, AppsDataType)
+		Expect(d.Name()).To(Equal(AppsDataType))
+		Expect(d.DataType()).To(Equal(AppsDataType))
+		Expect(d.Content()).To(Equal(dataReader))
+		Expect(d.MimeType()).To(Equal("application/json"))
+		Expect(d.Type()).To(Equal(""))
+		Expect(d.Err()).NotTo(HaveOccurred())
+	})
+
+	It("records a failed retrieval", func() {
+		d := NewFailedData(StacksDataType, errors.New("listing is hard"))
+		Expect(d.DataType()).To(Equal(StacksDataType))
+		Expect(d.Err()).To(MatchError("listing is hard"))
+	})
+})
@@ -0,0 +1,117 @@
+package cfapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/pivotal-cf/aqueduct-courier/redaction"
+	"github.com/pkg/errors"
+)
+
+const (
+	OrganizationsDataType      = "organizations"
+	SpacesDataType             = "spaces"
+	AppsDataType               = "apps"
+	ProcessesDataType          = "processes"
+	ServiceInstancesDataType   = "service_instances"
+	ServiceBrokersDataType     = "service_brokers"
+	OrganizationQuotasDataType = "organization_quotas"
+	SpaceQuotasDataType        = "space_quotas"
+	StacksDataType             = "stacks"
+
+	UnknownDataTypeFormat      = "Unknown CF API data type %s"
+	ListResourcesFailureFormat = "Failed listing %s from the CF API"
+	ParseResourceFailureFormat = "Failed parsing %s from the CF API"
+	RedactionFailureFormat     = "Failed redacting %s"
+)
+
+// Inventory is how each data type is listed from the v3 API, and the fields
+// kept of each resource besides its GUID and creation and update times.
+// An app's instances, memory and disk are those of its processes.
+// Relationships only hold the GUIDs of related resources. Anything else,
+// such as app environment variables or broker credentials, is never
+// collected.
+var Inventory = []struct {
+	DataType string
+	Path     string
+	Fields   []string
+}{
+	{OrganizationsDataType, "/v3/organizations", []string{"name", "suspended", "relationships"}},
+	{SpacesDataType, "/v3/spaces", []string{"name", "relationships"}},
+	{AppsDataType, "/v3/apps", []string{"name", "state", "lifecycle", "relationships"}},
+	{ProcessesDataType, "/v3/processes", []string{"type", "instances", "memory_in_mb", "disk_in_mb", "relationships"}},
+	{ServiceInstancesDataType, "/v3/service_instances", []string{"name", "type", "upgrade_available", "relationships"}},
+	{ServiceBrokersDataType, "/v3/service_brokers", []string{"name", "relationships"}},
+	{OrganizationQuotasDataType, "/v3/organization_quotas", []string{"name", "apps", "services", "routes", "domains", "relationships"}},
+	{SpaceQuotasDataType, "/v3/space_quotas", []string{"name", "apps", "services", "routes", "relationships"}},
+	{StacksDataType, "/v3/stacks", []string{"name", "description"}},
+}
+
+// DataTypes are the data types of the inventory, in the order collected.
+func DataTypes() []string {
+	var dataTypes []string
+	for _, inventory := range Inventory {
+		dataTypes = append(dataTypes, inventory.DataType)
+	}
+	return dataTypes
+}
+
+//go:generate counterfeiter . resourceLister
+type resourceLister interface {
+	ListV3Resources(ctx context.Context, resourcePath string) ([]json.RawMessage, error)
+}
+
+type Service struct {
+	lister          resourceLister
+	redactionPolicy redaction.Policy
+}
+
+// NewService returns a service that applies redactionPolicy to the data it
+// returns.
+func NewService(lister resourceLister, redactionPolicy redaction.Policy) *Service {
+	return &Service{lister: lister, redactionPolicy: redactionPolicy}
+}
+
+// List returns the count of the resources of the data type and, for each,
+// its GUID, creation and update times and the fields the Inventory keeps,
+// as {"count": n, "<data type>": [...]}.
+func (s *Service) List(ctx context.Context, dataType string) (io.Reader, error) {
+	for _, inventory := range Inventory {
+		if inventory.DataType != dataType {
+			continue
+		}
+
+		resources, err := s.lister.ListV3Resources(ctx, inventory.Path)
+		if err != nil {
+			return nil, errors.Wrapf(err, ListResourcesFailureFormat, dataType)
+		}
+
+		records := []map[string]json.RawMessage{}
+		for _, resource := range resources {
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(resource, &fields); err != nil {
+				return nil, errors.Wrapf(err, ParseResourceFailureFormat, dataType)
+			}
+			record := map[string]json.RawMessage{}
+			for _, field := range append([]string{"guid", "created_at", "updated_at"}, inventory.Fields...) {
+				if value, ok := fields[field]; ok {
+					record[field] = value
+				}
+			}
+			records = append(records, record)
+		}
+
+		contents, err := json.Marshal(map[string]interface{}{"count": len(records), dataType: records})
+		if err != nil {
+			return nil, err
+		}
+		redacted, err := s.redactionPolicy.Apply(dataType, contents)
+		if err != nil {
+			return nil, errors.Wrapf(err, RedactionFailureFormat, dataType)
+		}
+		return bytes.NewReader(redacted), nil
+	}
+	return nil, errors.Errorf(UnknownDataTypeFormat, dataType)
+}
@@ -0,0 +1,181 @@
+package cfapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/aqueduct-courier/cfapi"
+	"github.com/pivotal-cf/aqueduct-courier/cfapi/cfapifakes"
+	"github.com/pivotal-cf/aqueduct-courier/redaction"
+)
+
+var _ = Describe("Service", func() {
+	var (
+		lister  *cfapifakes.FakeResourceLister
+		service *Service
+	)
+
+	BeforeEach(func() {
+		lister = new(cfapifakes.FakeResourceLister)
+		service = NewService(lister, redaction.Policy{})
+	})
+
+	It("lists the count and kept fields of the resources of the data type", func() {
+		lister.ListV3ResourcesReturns([]json.RawMessage{
+			json.RawMessage(`{
+				"guid": "app-1-guid",
+				"created_at": "2018-01-01T00:00:00Z",
+				"updated_at": "2018-02-01T00:00:00Z",
+				"name": "app-1",
+				"state": "STARTED",
+				"lifecycle": {"type": "buildpack", "data": {"buildpacks": ["java_buildpack"], "stack": "cflinuxfs3"}},
+				"relationships": {"space": {"data": {"guid": "space-guid"}}},
+				"metadata": {"labels": {"team": "secret-team"}},
+				"links": {"self": {"href": "https://api.example.com/v3/apps/app-1-guid"}}
+			}`),
+			json.RawMessage(`{"guid": "app-2-guid", "created_at": "2018-01-01T00:00:00Z", "updated_at": "2018-02-01T00:00:00Z", "name": "app-2", "state": "STOPPED"}`),
+		}, nil)
+
+		reader, err := service.List(context.Background(), AppsDataType)
+		Expect(err).NotTo(HaveOccurred())
+		contents, err := ioutil.ReadAll(reader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contents).To(MatchJSON(`{
+			"count": 2,
+			"apps": [
+				{
+					"guid": "app-1-guid",
+					"created_at": "2018-01-01T00:00:00Z",
+					"updated_at": "2018-02-01T00:00:00Z",
+					"name": "app-1",
+					"state": "STARTED",
+					"lifecycle": {"type": "buildpack", "data": {"buildpacks": ["java_buildpack"], "stack": "cflinuxfs3"}},
+					"relationships": {"space": {"data": {"guid": "space-guid"}}}
+				},
+				{"guid": "app-2-guid", "created_at": "2018-01-01T00:00:00Z", "updated_at": "2018-02-01T00:00:00Z", "name": "app-2", "state": "STOPPED"}
+			]
+		}`))
+
+		_, resourcePath := lister.ListV3ResourcesArgsForCall(0)
+		Expect(resourcePath).To(Equal("/v3/apps"))
+	})
+
+	It("lists the instances, memory and disk of each process", func() {
+		lister.ListV3ResourcesReturns([]json.RawMessage{
+			json.RawMessage(`{
+				"guid": "process-1-guid",
+				"created_at": "2018-01-01T00:00:00Z",
+				"updated_at": "2018-02-01T00:00:00Z",
+				"type": "web",
+				"command": "secret-command",
+				"instances": 2,
+				"memory_in_mb": 1024,
+				"disk_in_mb": 512,
+				"health_check": {"type": "port"},
+				"relationships": {"revision": {"data": {"guid": "revision-guid"}}},
+				"links": {"app": {"href": "https://api.example.com/v3/apps/app-1-guid"}}
+			}`),
+		}, nil)
+
+		reader, err := service.List(context.Background(), ProcessesDataType)
+		Expect(err).NotTo(HaveOccurred())
+		contents, err := ioutil.ReadAll(reader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contents).To(MatchJSON(`{
+			"count": 1,
+			"processes": [
+				{
+					"guid": "process-1-guid",
+					"created_at": "2018-01-01T00:00:00Z",
+					"updated_at": "2018-02-01T00:00:00Z",
+					"type": "web",
+					"instances": 2,
+					"memory_in_mb": 1024,
+					"disk_in_mb": 512,
+					"relationships": {"revision": {"data": {"guid": "revision-guid"}}}
+				}
+			]
+		}`))
+
+		_, resourcePath := lister.ListV3ResourcesArgsForCall(0)
+		Expect(resourcePath).To(Equal("/v3/processes"))
+	})
+
+	It("lists every data type from the v3 API", func() {
+		for _, dataType := range DataTypes() {
+			_, err := service.List(context.Background(), dataType)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		var resourcePaths []string
+		for i := 0; i < lister.ListV3ResourcesCallCount(); i++ {
+			_, resourcePath := lister.ListV3ResourcesArgsForCall(i)
+			resourcePaths = append(resourcePaths, resourcePath)
+		}
+		Expect(resourcePaths).To(Equal([]string{
+			"/v3/organizations",
+			"/v3/spaces",
+			"/v3/apps",
+			"/v3/processes",
+			"/v3/service_instances",
+			"/v3/service_brokers",
+			"/v3/organization_quotas",
+			"/v3/space_quotas",
+			"/v3/stacks",
+		}))
+	})
+
+	It("lists an empty inventory when there are no resources", func() {
+		reader, err := service.List(context.Background(), StacksDataType)
+		Expect(err).NotTo(HaveOccurred())
+		contents, err := ioutil.ReadAll(reader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contents).To(MatchJSON(`{"count": 0, "stacks": []}`))
+	})
+
+	It("applies the redaction policy to the data type", func() {
+		service = NewService(lister, redaction.Policy{Rules: []redaction.Rule{
+			{DataType: OrganizationsDataType, Path: "$.organizations[*].name", Action: redaction.Mask},
+			{DataType: SpacesDataType, Path: "$.spaces[*].name", Action: redaction.Drop},
+		}})
+		lister.ListV3ResourcesReturns([]json.RawMessage{
+			json.RawMessage(`{"guid": "org-guid", "created_at": "2018-01-01T00:00:00Z", "updated_at": "2018-02-01T00:00:00Z", "name": "some-org", "suspended": false}`),
+		}, nil)
+
+		reader, err := service.List(context.Background(), OrganizationsDataType)
+		Expect(err).NotTo(HaveOccurred())
+		contents, err := ioutil.ReadAll(reader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contents).To(MatchJSON(`{
+			"count": 1,
+			"organizations": [{"guid": "org-guid", "created_at": "2018-01-01T00:00:00Z", "updated_at": "2018-02-01T00:00:00Z", "name": "REDACTED", "suspended": false}]
+		}`))
+	})
+
+	It("returns an error when the resources cannot be listed", func() {
+		lister.ListV3ResourcesReturns(nil, errors.New("listing is hard"))
+
+		_, err := service.List(context.Background(), SpacesDataType)
+		Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(ListResourcesFailureFormat, SpacesDataType))))
+		Expect(err).To(MatchError(ContainSubstring("listing is hard")))
+	})
+
+	It("returns an error when a resource is not an object", func() {
+		lister.ListV3ResourcesReturns([]json.RawMessage{json.RawMessage(`[]`)}, nil)
+
+		_, err := service.List(context.Background(), StacksDataType)
+		Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(ParseResourceFailureFormat, StacksDataType))))
+	})
+
+	It("returns an error for an unknown data type", func() {
+		_, err := service.List(context.Background(), "routes")
+		Expect(err).To(MatchError(fmt.Sprintf(UnknownDataTypeFormat, "routes")))
+		Expect(lister.ListV3ResourcesCallCount()).To(Equal(0))
+	})
+})
@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pivotal-cf/aqueduct-courier/encryption"
+	"github.com/pivotal-cf/aqueduct-courier/network"
+	"github.com/pivotal-cf/aqueduct-courier/opsmanager"
+	"github.com/pivotal-cf/aqueduct-courier/report"
+	"github.com/pivotal-cf/telemetry-utils/tar"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	CertsWithinFlag = "within"
+	CertsWithinKey  = "CERTS_WITHIN"
+	CertsFormatFlag = "format"
+	CertsFormatKey  = "CERTS_FORMAT"
+
+	CertsFormatTable = "table"
+	CertsFormatJSON  = "json"
+
+	CertsFailureMessage          = "Unable to check certificate expiry"
+	InvalidCertsFormatFormat     = "Invalid format %s, must be table or json"
+	CertificatesExpiringFormat   = "%d certificates expire within %s"
+	UnknownExpiriesFormat        = "The expiry of %d certificates could not be read"
+	NoCertificatesExpiringFormat = "No certificates expire within %s"
+	UnknownExpiryFormat          = "Could not read the expiry of %s from %s"
+	CertificatesExpiringExitCode = 4
+)
+
+var certsCmd = &cobra.Command{
+	Use:   "certs",
+	Short: "Lists certificates that expire soon",
+	Long:  "Lists the Ops Manager and CredHub certificates that expire within a window, from a PCF foundation or a file from the 'collect' command",
+	RunE:  certs,
+}
+
+func init() {
+	bindFlagAndEnvVar(certsCmd, DataTarFilePathFlag, "", fmt.Sprintf("``The path to a file from the 'collect' command to read the certificates from, instead of a foundation [$%s]\n", DataTarFilePathKey), DataTarFilePathKey)
+	bindFlagAndEnvVar(certsCmd, OpsManagerURLFlag, "", fmt.Sprintf("``Ops Manager URL [$%s]", OpsManagerURLKey), OpsManagerURLKey)
+	bindFlagAndEnvVar(certsCmd, OpsManagerUsernameFlag, "", fmt.Sprintf("``Ops Manager username [$%s]", OpsManagerUsernameKey), OpsManagerUsernameKey)
+	bindFlagAndEnvVar(certsCmd, OpsManagerPasswordFlag, "", fmt.Sprintf("``Ops Manager password [$%s]", OpsManagerPasswordKey), OpsManagerPasswordKey)
+	bindFlagAndEnvVar(certsCmd, OpsManagerClientIdFlag, "", fmt.Sprintf("``Ops Manager client id [$%s]", OpsManagerClientIdKey), OpsManagerClientIdKey)
+	bindFlagAndEnvVar(certsCmd, OpsManagerClientSecretFlag, "", fmt.Sprintf("``Ops Manager client secret [$%s]", OpsManagerClientSecretKey), OpsManagerClientSecretKey)
+	bindFlagAndEnvVar(certsCmd, OpsManagerTimeoutFlag, 30, fmt.Sprintf("``Ops Manager http request timeout in seconds [$%s]", OpsManagerTimeoutKey), OpsManagerTimeoutKey)
+	bindFlagAndEnvVar(certsCmd, SkipTlsVerifyFlag, false, fmt.Sprintf("``Skip TLS validation on http requests to Ops Manager [$%s]", SkipTlsVerifyKey), SkipTlsVerifyKey)
+	bindFlagAndEnvVar(certsCmd, CollectFromCredhubFlag, false, fmt.Sprintf("Include CredHub certificates [$%s]", WithCredhubInfoKey), WithCredhubInfoKey)
+	bindCredhubFlags(certsCmd)
+	bindFlagAndEnvVar(certsCmd, CertsWithinFlag, "30d", fmt.Sprintf("``List certificates expiring within this long, such as 30d or 72h [$%s]", CertsWithinKey), CertsWithinKey)
+	bindFlagAndEnvVar(certsCmd, CertsFormatFlag, CertsFormatTable, fmt.Sprintf("``Output format (table, json) [$%s]\n", CertsFormatKey), CertsFormatKey)
+	bindPrivateKeyFlag(certsCmd)
+	bindRetryFlags(certsCmd)
+	bindTimeoutFlag(certsCmd)
+
+	certsCmd.Flags().BoolP("help", "h", false, "Help for the certs command\n")
+	certsCmd.Flags().SortFlags = false
+
+	certsCmd.Example = `
+      List certificates expiring within 30 days on a foundation:
+      telemetry-collector certs --url --username --password [or --client-id and
+      --client-secret] --with-credhub-info
+
+      List certificates expiring within 90 days in collected data, as JSON:
+      telemetry-collector certs --path --within 90d --format json`
+
+	customUsageTextTemplate := `
+USAGE EXAMPLES
+{{.Example}}
+
+FLAGS
+
+{{.LocalFlags.FlagUsages}}`
+
+	customHelpTextTemplate := fmt.Sprintf(`
+Lists the certificates and certificate authorities that expire within the
+window, soonest first, then those whose expiry could not be read, and exits
+with status %d when there are any.
+%s`, CertificatesExpiringExitCode, customUsageTextTemplate)
+
+	certsCmd.SetHelpTemplate(customHelpTextTemplate)
+	certsCmd.SetUsageTemplate(customUsageTextTemplate)
+	rootCmd.AddCommand(certsCmd)
+}
+
+// CertificatesExpiringError is returned when any certificate expires within
+// the window, or its expiry could not be read, so that the command exits with
+// CertificatesExpiringExitCode.
+type CertificatesExpiringError struct {
+	Count   int
+	Unknown int
+	Within  string
+}
+
+func (e CertificatesExpiringError) Error() string {
+	var messages []string
+	if e.Count > 0 || e.Unknown == 0 {
+		messages = append(messages, fmt.Sprintf(CertificatesExpiringFormat, e.Count, e.Within))
+	}
+	if e.Unknown > 0 {
+		messages = append(messages, fmt.Sprintf(UnknownExpiriesFormat, e.Unknown))
+	}
+	return strings.Join(messages, "; ")
+}
+
+func certs(c *cobra.Command, _ []string) error {
+	tarFilePath := viper.GetString(DataTarFilePathFlag)
+	if tarFilePath == "" {
+		if err := verifyRequiredConfig(OpsManagerURLFlag); err != nil {
+			return err
+		}
+		if err := validateCredConfig(); err != nil {
+			return err
+		}
+	}
+	within, err := parseWithin()
+	if err != nil {
+		return err
+	}
+	format := viper.GetString(CertsFormatFlag)
+	if format != CertsFormatTable && format != CertsFormatJSON {
+		return errors.Errorf(InvalidCertsFormatFormat, format)
+	}
+	policy, err := retryPolicy()
+	if err != nil {
+		return err
+	}
+	ctx, stop, err := commandContext()
+	if err != nil {
+		return err
+	}
+	defer stop()
+	c.SilenceUsage = true
+
+	now := time.Now().UTC()
+	var certificates []report.Certificate
+	if tarFilePath != "" {
+		certificates, err = collectedCertificates(c, tarFilePath, now)
+	} else {
+		certificates, err = foundationCertificates(ctx, now, policy, log.New(c.ErrOrStderr(), "", 0))
+	}
+	if err != nil {
+		return commandError(ctx, errors.Wrap(err, CertsFailureMessage))
+	}
+
+	var listed []report.Certificate
+	var expiring, unknown int
+	for _, certificate := range certificates {
+		certificate.Status = certificate.StatusWithin(now, within)
+		switch certificate.Status {
+		case report.StatusUnknown:
+			fmt.Fprintf(c.ErrOrStderr(), UnknownExpiryFormat+"\n", certificate.Name, certificate.Source)
+			unknown++
+		case report.StatusExpired, report.StatusExpiring:
+			expiring++
+		default:
+			continue
+		}
+		listed = append(listed, certificate)
+	}
+	report.SortByExpiry(listed)
+
+	if format == CertsFormatJSON {
+		err = printCertificatesJSON(listed)
+	} else {
+		printCertificatesTable(listed)
+	}
+	if err != nil {
+		return err
+	}
+	if len(listed) > 0 {
+		return CertificatesExpiringError{Count: expiring, Unknown: unknown, Within: viper.GetString(CertsWithinFlag)}
+	}
+	return nil
+}
+
+// parseWithin accepts a number of days, such as 30d, as well as any
+// duration time.ParseDuration does.
+func parseWithin() (time.Duration, error) {
+	value := viper.GetString(CertsWithinFlag)
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf(InvalidDurationErrorFormat, value, CertsWithinFlag)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return parseDurationFlag(CertsWithinFlag)
+}
+
+// collectedCertificates reads the certificates in a file from the 'collect'
+// command, noting any it cannot read on stderr.
+func collectedCertificates(c *cobra.Command, tarFilePath string, now time.Time) ([]report.Certificate, error) {
+	tarFile, err := openArchive(tarFilePath)
+	if os.IsNotExist(errors.Cause(err)) {
+		return nil, errors.New(fmt.Sprintf(FileNotFoundErrorFormat, tarFilePath))
+	}
+	if encryptedErr, ok := err.(encryption.EncryptedError); ok {
+		return nil, errors.Errorf(EncryptedArchiveFormat, tarFilePath, encryptedErr.Recipient, PrivateKeyFlag)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer tarFile.Close()
+
+	collection, err := report.Build(tar.NewTarReader(tarFile), now)
+	if err != nil {
+		return nil, err
+	}
+	for _, problem := range collection.Problems {
+		fmt.Fprintln(c.ErrOrStderr(), problem)
+	}
+	return append(collection.Certificates, collection.CertificateAuthorities...), nil
+}
+
+type certificateSource struct {
+	retrieve func(context.Context) (io.Reader, error)
+	parse    func([]byte, time.Time) ([]report.Certificate, error)
+}
+
+// foundationCertificates reads the certificates from Ops Manager, and from
+// CredHub when --with-credhub-info is set, logging CredHub progress to
+// progressLogger.
+func foundationCertificates(ctx context.Context, now time.Time, policy network.RetryPolicy, progressLogger *log.Logger) ([]report.Certificate, error) {
+	omService := &opsmanager.Service{Requestor: makeOpsManagerAPI(ctx, policy)}
+	sources := []certificateSource{
+		{omService.Certificates, report.ParseOpsManagerCertificates},
+		{omService.CertificateAuthorities, report.ParseCertificateAuthorities},
+	}
+	if viper.GetBool(CollectFromCredhubFlag) {
+		credhubService, _, err := makeCredhubService(ctx, omService, policy, progressLogger)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, certificateSource{credhubService.Certificates, report.ParseCredHubCertificates})
+	}
+
+	var certificates []report.Certificate
+	for _, source := range sources {
+		reader, err := source.retrieve(ctx)
+		if err != nil {
+			return nil, err
+		}
+		contents, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := source.parse(contents, now)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, parsed...)
+	}
+	return certificates, nil
+}
+
+func printCertificatesTable(certificates []report.Certificate) {
+	if len(certificates) == 0 {
+		logger.Printf(NoCertificatesExpiringFormat+"\n", viper.GetString(CertsWithinFlag))
+		return
+	}
+
+	var output bytes.Buffer
+	w := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EXPIRES\tDAYS LEFT\tSTATUS\tSOURCE\tNAME\tISSUER")
+	for _, certificate := range certificates {
+		if certificate.ExpiresAt.IsZero() {
+			fmt.Fprintf(w, "-\t-\t%s\t%s\t%s\t%s\n", certificate.Status, certificate.Source, certificate.Name, certificate.Issuer)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", certificate.ExpiresAt.Format(time.RFC3339), certificate.DaysLeft, certificate.Status, certificate.Source, certificate.Name, certificate.Issuer)
+	}
+	w.Flush()
+	logger.Print(output.String())
+}
+
+func printCertificatesJSON(certificates []report.Certificate) error {
+	if certificates == nil {
+		certificates = []report.Certificate{}
+	}
+	contents, err := json.MarshalIndent(certificates, "", "  ")
+	if err != nil {
+		return err
+	}
+	logger.Println(string(contents))
+	return nil
+}
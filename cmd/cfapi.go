@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pivotal-cf/aqueduct-courier/cf"
+	"github.com/pivotal-cf/aqueduct-courier/cfapi"
+	"github.com/pivotal-cf/aqueduct-courier/network"
+	"github.com/pivotal-cf/aqueduct-courier/redaction"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	WithCfInventoryKey  = "WITH_CF_INVENTORY"
+	CfClientIDKey       = "CF_CLIENT_ID"
+	CfClientSecretKey   = "CF_CLIENT_SECRET"
+	CfSkipTlsVerifyKey  = "CF_INSECURE_SKIP_TLS_VERIFY"
+	WithCfInventoryFlag = "with-cf-inventory"
+	CfClientIDFlag      = "cf-client-id"
+	CfClientSecretFlag  = "cf-client-secret"
+	CfSkipTlsVerifyFlag = "cf-insecure-skip-tls-verify"
+
+	InvalidCfInventoryConfigurationMessage = "--with-cf-inventory requires --cf-api-url, --cf-client-id and --cf-client-secret"
+)
+
+func bindCfApiFlags(cmd *cobra.Command) {
+	bindFlagAndEnvVar(cmd, WithCfInventoryFlag, false, fmt.Sprintf("Include an inventory of orgs, spaces, apps and their processes, services, quotas and stacks from the CF API [$%s]", WithCfInventoryKey), WithCfInventoryKey)
+	bindFlagAndEnvVar(cmd, CfClientIDFlag, "", fmt.Sprintf("``UAA client id with the cloud_controller.admin_read_only scope, for the CF inventory and listing orgs for --usage-by-org [$%s]", CfClientIDKey), CfClientIDKey)
+	bindFlagAndEnvVar(cmd, CfClientSecretFlag, "", fmt.Sprintf("``UAA client secret with read access to the CF API [$%s]", CfClientSecretKey), CfClientSecretKey)
+	bindFlagAndEnvVar(cmd, CfSkipTlsVerifyFlag, false, fmt.Sprintf("Skip TLS validation on http requests to the CF API and its UAA [$%s]\n", CfSkipTlsVerifyKey), CfSkipTlsVerifyKey)
+}
+
+func validateCfInventoryConfig() error {
+	if viper.GetString(CfApiURLFlag) == "" ||
+		viper.GetString(CfClientIDFlag) == "" ||
+		viper.GetString(CfClientSecretFlag) == "" {
+
+		return errors.New(InvalidCfInventoryConfigurationMessage)
+	}
+	return nil
+}
+
+type cfApiDataCollector interface {
+	Collect(ctx context.Context) ([]cfapi.Data, error)
+}
+
+// makeCfApiCollector returns a collector for the CF API inventory,
+// authenticated with the CF client credentials, or nil when the inventory is
+// not wanted.
+func makeCfApiCollector(ctx context.Context, policy network.RetryPolicy, redactionPolicy redaction.Policy) (cfApiDataCollector, error) {
+	if !viper.GetBool(WithCfInventoryFlag) {
+		return nil, nil
+	}
+	if err := validateCfInventoryConfig(); err != nil {
+		return nil, err
+	}
+
+	cfClient, err := makeCfApiClient(ctx, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfapi.NewDataCollector(
+		logger,
+		cfapi.NewService(cfClient, redactionPolicy),
+		viper.GetString(CfApiURLFlag),
+		viper.GetBool(AllowPartialFlag),
+	), nil
+}
+
+// cfClientCredentialsProvided is whether the CF client credentials are set,
+// which --usage-by-org then prefers over the Usage Service's for listing orgs.
+func cfClientCredentialsProvided() bool {
+	return viper.GetString(CfClientIDFlag) != "" && viper.GetString(CfClientSecretFlag) != ""
+}
+
+// makeCfApiClient returns a CF API client authenticated with the CF client
+// credentials.
+func makeCfApiClient(ctx context.Context, policy network.RetryPolicy) (*cf.Client, error) {
+	client := network.NewClient(viper.GetBool(CfSkipTlsVerifyFlag))
+	uaaURL, err := cf.NewClient(viper.GetString(CfApiURLFlag), network.NewRetryingClient(client, policy)).GetUAAURL(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, GetUAAURLError)
+	}
+
+	authedClient := cf.NewOAuthClient(
+		uaaURL,
+		viper.GetString(CfClientIDFlag),
+		viper.GetString(CfClientSecretFlag),
+		30*time.Second,
+		client,
+	)
+	return cf.NewClient(viper.GetString(CfApiURLFlag), network.NewRetryingClient(authedClient, policy)), nil
+}
@@ -1,7 +1,11 @@
 package cmd
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -12,17 +16,21 @@ import (
 
 	"github.com/gofrs/uuid"
 
+	"github.com/pivotal-cf/aqueduct-courier/compression"
+	"github.com/pivotal-cf/aqueduct-courier/encryption"
 	"github.com/pivotal-cf/aqueduct-courier/network"
 
 	"github.com/pivotal-cf/aqueduct-courier/consumption"
 
-	ogCredhub "code.cloudfoundry.org/credhub-cli/credhub"
-	"code.cloudfoundry.org/credhub-cli/credhub/auth"
 	"github.com/pivotal-cf/aqueduct-courier/cf"
+	"github.com/pivotal-cf/aqueduct-courier/cfapi"
 	"github.com/pivotal-cf/aqueduct-courier/credhub"
 
 	"github.com/pivotal-cf/aqueduct-courier/operations"
 	"github.com/pivotal-cf/aqueduct-courier/opsmanager"
+	"github.com/pivotal-cf/aqueduct-courier/pseudonym"
+	"github.com/pivotal-cf/aqueduct-courier/redaction"
+	"github.com/pivotal-cf/aqueduct-courier/signing"
 	omNetwork "github.com/pivotal-cf/om/network"
 	"github.com/pivotal-cf/telemetry-utils/tar"
 	"github.com/pkg/errors"
@@ -37,6 +45,7 @@ const (
 	OpsManagerClientIdKey        = "OPS_MANAGER_CLIENT_ID"
 	OpsManagerClientSecretKey    = "OPS_MANAGER_CLIENT_SECRET"
 	OpsManagerTimeoutKey         = "OPS_MANAGER_TIMEOUT"
+	OpsManagerMaxConcurrencyKey  = "OPS_MANAGER_MAX_CONCURRENCY"
 	EnvTypeKey                   = "ENV_TYPE"
 	OutputPathKey                = "OUTPUT_DIR"
 	SkipTlsVerifyKey             = "INSECURE_SKIP_TLS_VERIFY"
@@ -46,6 +55,14 @@ const (
 	UsageServiceClientSecretKey  = "USAGE_SERVICE_CLIENT_SECRET"
 	CfApiURLKey                  = "CF_API_URL"
 	UsageServiceSkipTlsVerifyKey = "USAGE_SERVICE_INSECURE_SKIP_TLS_VERIFY"
+	AllowPartialKey              = "ALLOW_PARTIAL"
+	CompressionKey               = "COMPRESSION"
+	EncryptToKey                 = "ENCRYPT_TO"
+	SigningKeyKey                = "SIGNING_KEY_PATH"
+	RedactionPolicyKey           = "REDACTION_POLICY_PATH"
+	PseudonymizeKey              = "PSEUDONYMIZE"
+	PseudonymizeSaltKey          = "PSEUDONYMIZE_SALT"
+	PseudonymMappingDirKey       = "PSEUDONYM_MAPPING_DIR"
 
 	OpsManagerURLFlag             = "url"
 	OpsManagerUsernameFlag        = "username"
@@ -53,6 +70,7 @@ const (
 	OpsManagerClientIdFlag        = "client-id"
 	OpsManagerClientSecretFlag    = "client-secret"
 	OpsManagerTimeoutFlag         = "ops-manager-timeout"
+	OpsManagerMaxConcurrencyFlag  = "ops-manager-max-concurrency"
 	CollectFromCredhubFlag        = "with-credhub-info"
 	EnvTypeFlag                   = "env-type"
 	OutputPathFlag                = "output-dir"
@@ -62,6 +80,14 @@ const (
 	UsageServiceClientSecretFlag  = "usage-service-client-secret"
 	CfApiURLFlag                  = "cf-api-url"
 	UsageServiceSkipTlsVerifyFlag = "usage-service-insecure-skip-tls-verify"
+	AllowPartialFlag              = "allow-partial"
+	CompressionFlag               = "compression"
+	EncryptToFlag                 = "encrypt-to"
+	SigningKeyFlag                = "signing-key"
+	RedactionPolicyFlag           = "redaction-policy"
+	PseudonymizeFlag              = "pseudonymize"
+	PseudonymizeSaltFlag          = "pseudonymize-salt"
+	PseudonymMappingDirFlag       = "pseudonym-mapping-dir"
 
 	EnvTypeSandbox       = "sandbox"
 	EnvTypeDevelopment   = "development"
@@ -75,14 +101,20 @@ const (
 	InvalidAuthConfigurationMessage  = "Invalid auth configuration. Requires username/password or client/secret to be set."
 	InvalidUsageConfigurationMessage = "Not all usage service configurations provided."
 	CreateTarFileFailureFormat       = "Could not create tar file %s"
+	WriteTarFileFailureFormat        = "Could not write tar file %s"
 	UsageServiceURLParsingError      = "error parsing Usage Service URL"
 	GetUAAURLError                   = "error getting UAA URL"
+	InvalidMaxConcurrencyMessage     = "--ops-manager-max-concurrency must be at least 1"
+	PartialCollectionFailureFormat   = "Could not collect %s: %s"
+	PseudonymMappingInOutputMessage  = "--pseudonym-mapping-dir must not be the output directory or inside it, the mapping is only for local use"
+	ResolvePathFailureFormat         = "Could not resolve the path %s"
+	PseudonymMappingFileSuffix       = ".pseudonyms.json"
 )
 
 var collectCmd = &cobra.Command{
 	Use:   "collect",
 	Short: "Collects information from a PCF foundation",
-	Long:  "Collects information from Operations Manager and outputs the content to the configured directory.\nOptionally collects information from Usage Service and/or Credhub.",
+	Long:  "Collects information from Operations Manager and outputs the content to the configured directory.\nOptionally collects information from Usage Service, Credhub and/or the CF API.",
 	RunE:  collect,
 }
 
@@ -94,16 +126,32 @@ func init() {
 	bindFlagAndEnvVar(collectCmd, OpsManagerClientSecretFlag, "", fmt.Sprintf("``Ops Manager client secret [$%s]", OpsManagerClientSecretKey), OpsManagerClientSecretKey)
 	bindFlagAndEnvVar(collectCmd, EnvTypeFlag, "", fmt.Sprintf("``Specify environment type (sandbox, development, qa, pre-production, production) [$%s]", EnvTypeKey), EnvTypeKey)
 	bindFlagAndEnvVar(collectCmd, OpsManagerTimeoutFlag, 30, fmt.Sprintf("``Ops Manager http request timeout in seconds [$%s]", OpsManagerTimeoutKey), OpsManagerTimeoutKey)
+	bindFlagAndEnvVar(collectCmd, OpsManagerMaxConcurrencyFlag, 4, fmt.Sprintf("``Maximum number of concurrent requests to Ops Manager [$%s]", OpsManagerMaxConcurrencyKey), OpsManagerMaxConcurrencyKey)
 	bindFlagAndEnvVar(collectCmd, SkipTlsVerifyFlag, false, fmt.Sprintf("``Skip TLS validation on http requests to Ops Manager [$%s]\n", SkipTlsVerifyKey), SkipTlsVerifyKey)
 
 	bindFlagAndEnvVar(collectCmd, CfApiURLFlag, "", fmt.Sprintf("``CF API URL for UAA authentication to access Usage Service [$%s]", CfApiURLKey), CfApiURLKey)
 	bindFlagAndEnvVar(collectCmd, UsageServiceURLFlag, "", fmt.Sprintf("``Usage Service URL [$%s]", UsageServiceURLKey), UsageServiceURLKey)
 	bindFlagAndEnvVar(collectCmd, UsageServiceClientIDFlag, "", fmt.Sprintf("``Usage Service client id [$%s]", UsageServiceClientIDKey), UsageServiceClientIDKey)
 	bindFlagAndEnvVar(collectCmd, UsageServiceClientSecretFlag, "", fmt.Sprintf("``Usage Service client secret [$%s]", UsageServiceClientSecretKey), UsageServiceClientSecretKey)
-	bindFlagAndEnvVar(collectCmd, UsageServiceSkipTlsVerifyFlag, false, fmt.Sprintf("``Skip TLS validation for Usage Service components [$%s]\n", UsageServiceSkipTlsVerifyKey), UsageServiceSkipTlsVerifyKey)
-
-	bindFlagAndEnvVar(collectCmd, CollectFromCredhubFlag, false, fmt.Sprintf("Include CredHub certificate expiry information [$%s]\n", WithCredhubInfoKey), WithCredhubInfoKey)
+	bindFlagAndEnvVar(collectCmd, UsageServiceSkipTlsVerifyFlag, false, fmt.Sprintf("``Skip TLS validation for Usage Service components [$%s]", UsageServiceSkipTlsVerifyKey), UsageServiceSkipTlsVerifyKey)
+	bindUsageFlags(collectCmd)
+
+	bindFlagAndEnvVar(collectCmd, CollectFromCredhubFlag, false, fmt.Sprintf("Include CredHub certificate expiry information [$%s]", WithCredhubInfoKey), WithCredhubInfoKey)
+	bindCredhubFlags(collectCmd)
+	bindCfApiFlags(collectCmd)
+	bindFlagAndEnvVar(collectCmd, RedactionPolicyFlag, "", fmt.Sprintf("``YAML file of rules to drop, mask or hash Ops Manager and CF API data with, in addition to the default rules [$%s]\n", RedactionPolicyKey), RedactionPolicyKey)
+	bindFlagAndEnvVar(collectCmd, PseudonymizeFlag, false, fmt.Sprintf("Replace GUIDs, host names and IP addresses in the collected data with consistent pseudonyms [$%s]", PseudonymizeKey), PseudonymizeKey)
+	bindFlagAndEnvVar(collectCmd, PseudonymizeSaltFlag, "", fmt.Sprintf("``Secret salt of at least %d characters to derive pseudonyms with, kept the same to keep them consistent across collections [$%s]", pseudonym.MinimumSaltLength, PseudonymizeSaltKey), PseudonymizeSaltKey)
+	bindFlagAndEnvVar(collectCmd, PseudonymMappingDirFlag, "", fmt.Sprintf("``Local directory, outside the output directory, to write a file mapping the pseudonyms back to what they replaced [$%s]\n", PseudonymMappingDirKey), PseudonymMappingDirKey)
+	bindFlagAndEnvVar(collectCmd, AllowPartialFlag, false, fmt.Sprintf("Write the data that can be collected when some of it cannot, recording the failures in the metadata and exiting with status %d [$%s]\n", PartialExitCode, AllowPartialKey), AllowPartialKey)
 	bindFlagAndEnvVar(collectCmd, OutputPathFlag, "", fmt.Sprintf("``Local directory to write data [$%s]\n", OutputPathKey), OutputPathKey)
+	bindFlagAndEnvVar(collectCmd, CompressionFlag, compression.None, fmt.Sprintf("``Compression of the written tar file (none, gzip, zstd) [$%s]", CompressionKey), CompressionKey)
+	bindFlagAndEnvVar(collectCmd, EncryptToFlag, "", fmt.Sprintf("``PEM file with an RSA public key to encrypt the written file for, adding a %s extension [$%s]", encryption.FileExtension, EncryptToKey), EncryptToKey)
+	bindFlagAndEnvVar(collectCmd, SigningKeyFlag, "", fmt.Sprintf("``PEM file with an Ed25519 private key to sign the written file with, writing the signature next to it with a %s extension [$%s]\n", signing.SignatureFileExtension, SigningKeyKey), SigningKeyKey)
+	bindDryRunFlag(collectCmd, "List the requests collection would make and the files it would write, without making or writing them")
+	bindFlagAndEnvVar(collectCmd, FoundationsConfigFlag, "", fmt.Sprintf("``YAML file of foundations to collect from, writing one file each. Its settings override the matching flags [$%s]\n", FoundationsConfigKey), FoundationsConfigKey)
+	bindRetryFlags(collectCmd)
+	bindTimeoutFlag(collectCmd)
 
 	collectCmd.Flags().BoolP("help", "h", false, "Help for the collect command\n")
 	collectCmd.Flags().SortFlags = false
@@ -116,7 +164,28 @@ func init() {
       Collect data from Ops Manager and Usage Service:
       telemetry-collector collect --url --username --password [or --client-id and
       --client-secret] --usage-service-url --usage-service-client-id
-      --usage-service-client-secret --cf-api-url --env-type --output-dir`
+      --usage-service-client-secret --cf-api-url --env-type --output-dir
+
+      Collect data from Ops Manager and the last three months of Usage Service reports:
+      telemetry-collector collect --url --username --password [or --client-id and
+      --client-secret] --usage-service-url --usage-service-client-id
+      --usage-service-client-secret --cf-api-url --usage-months 3 --env-type --output-dir
+
+      Collect data from each foundation listed in a config file:
+      telemetry-collector collect --config --output-dir
+
+      Collect data from Ops Manager into a compressed file encrypted for a recipient:
+      telemetry-collector collect --url --username --password [or --client-id and
+      --client-secret] --env-type --output-dir --compression gzip --encrypt-to
+
+      Collect data from Ops Manager and sign it:
+      telemetry-collector collect --url --username --password [or --client-id and
+      --client-secret] --env-type --output-dir --signing-key
+
+      List what would be collected from Ops Manager and Usage Service:
+      telemetry-collector collect --url --username --password [or --client-id and
+      --client-secret] --usage-service-url --usage-service-client-id
+      --usage-service-client-secret --cf-api-url --env-type --output-dir --dry-run`
 
 	customUsageTextTemplate := `
 USAGE EXAMPLES
@@ -129,6 +198,7 @@ FLAGS
 	customHelpTextTemplate := fmt.Sprintf(`
 Collects information from a single Ops Manager (and optionally from
 Usage Service and/or Credhub) and outputs the content to the configured directory.
+With --config, collects from each foundation listed in the file in turn.
 %s`, customUsageTextTemplate)
 
 	collectCmd.SetHelpTemplate(customHelpTextTemplate)
@@ -137,6 +207,10 @@ Usage Service and/or Credhub) and outputs the content to the configured director
 }
 
 func collect(c *cobra.Command, _ []string) error {
+	if configPath := viper.GetString(FoundationsConfigFlag); configPath != "" {
+		return collectFoundations(c, configPath)
+	}
+
 	if err := verifyRequiredConfig(OpsManagerURLFlag, EnvTypeFlag, OutputPathFlag); err != nil {
 		return err
 	}
@@ -147,42 +221,216 @@ func collect(c *cobra.Command, _ []string) error {
 	if err != nil {
 		return err
 	}
+	if err := compression.Validate(viper.GetString(CompressionFlag)); err != nil {
+		return err
+	}
+	keys, err := readArchiveKeys()
+	if err != nil {
+		return err
+	}
+	policy, err := retryPolicy()
+	if err != nil {
+		return err
+	}
+	ctx, stop, err := commandContext()
+	if err != nil {
+		return err
+	}
+	defer stop()
 
 	c.SilenceUsage = true
 
-	tarFilePath := filepath.Join(
-		viper.GetString(OutputPathFlag),
-		fmt.Sprintf("%s%d.tar", OutputFilePrefix, time.Now().UTC().Unix()),
-	)
-	tarFile, err := os.Create(tarFilePath)
+	if viper.GetBool(DryRunFlag) {
+		return printCollectionPlan(OutputFilePrefix, keys)
+	}
+
+	tarFilePath, err := writeCollection(ctx, OutputFilePrefix, envType, keys, policy)
+	if partialErr, ok := err.(operations.PartialCollectionError); ok {
+		logPartialCollection(partialErr)
+		logOutput(tarFilePath, keys)
+		return err
+	}
 	if err != nil {
-		return errors.Wrapf(err, CreateTarFileFailureFormat, tarFilePath)
+		return commandError(ctx, err)
 	}
-	defer tarFile.Close()
 
-	tarWriter := tar.NewTarWriter(tarFile)
+	logOutput(tarFilePath, keys)
+	logger.Println("Success!")
+	return nil
+}
 
-	collectExecutor, err := makeCollector(tarWriter)
+func logOutput(tarFilePath string, keys archiveKeys) {
+	logger.Printf("Wrote output to %s\n", tarFilePath)
+	if keys.signingKey != nil {
+		logger.Printf("Wrote signature to %s\n", tarFilePath+signing.SignatureFileExtension)
+	}
+}
+
+// archiveKeys holds the keys configured for written files, each of which is
+// nil when not configured.
+type archiveKeys struct {
+	recipient  *rsa.PublicKey
+	signingKey ed25519.PrivateKey
+}
+
+func readArchiveKeys() (archiveKeys, error) {
+	var keys archiveKeys
+	var err error
+	if keyPath := viper.GetString(EncryptToFlag); keyPath != "" {
+		keys.recipient, err = encryption.ReadPublicKey(keyPath)
+		if err != nil {
+			return archiveKeys{}, err
+		}
+	}
+	if keyPath := viper.GetString(SigningKeyFlag); keyPath != "" {
+		keys.signingKey, err = signing.ReadPrivateKey(keyPath)
+		if err != nil {
+			return archiveKeys{}, err
+		}
+	}
+	return keys, nil
+}
+
+// writeCollection removes the partially written file when collection fails
+// or is stopped through ctx. A partial collection is kept and its path is
+// returned along with the operations.PartialCollectionError. The file is
+// encrypted and signed with the keys that are configured.
+func writeCollection(ctx context.Context, filePrefix, envType string, keys archiveKeys, policy network.RetryPolicy) (string, error) {
+	pseudonymizer, err := makePseudonymizer()
 	if err != nil {
-		tarFile.Close()
-		os.Remove(tarFilePath)
-		return err
+		return "", err
 	}
 
-	err = collectExecutor.Collect(envType, version)
+	format := viper.GetString(CompressionFlag)
+	tarFilePath := collectionFilePath(filePrefix, keys)
+	tarFile, err := os.Create(tarFilePath)
 	if err != nil {
+		return "", errors.Wrapf(err, CreateTarFileFailureFormat, tarFilePath)
+	}
+	defer tarFile.Close()
+
+	removeTarFile := func() {
 		tarFile.Close()
 		os.Remove(tarFilePath)
-		return err
 	}
 
-	logger.Printf("Wrote output to %s\n", tarFilePath)
-	logger.Println("Success!")
-	return nil
+	var output io.Writer = tarFile
+	var encryptor io.WriteCloser
+	if keys.recipient != nil {
+		encryptor, err = encryption.NewWriter(tarFile, keys.recipient)
+		if err != nil {
+			removeTarFile()
+			return "", err
+		}
+		output = encryptor
+	}
+
+	compressor, err := compression.NewWriter(output, format)
+	if err != nil {
+		removeTarFile()
+		return "", err
+	}
+	tarWriter := signing.NewMetadataRecorder(tar.NewTarWriter(compressor))
+
+	collectExecutor, err := makeCollector(ctx, tarWriter, policy, pseudonymizer)
+	if err != nil {
+		removeTarFile()
+		return "", err
+	}
+
+	err = collectExecutor.Collect(ctx, envType, version)
+	_, partial := err.(operations.PartialCollectionError)
+	if err != nil && !partial {
+		removeTarFile()
+		return "", err
+	}
+
+	if closeErr := compressor.Close(); closeErr != nil {
+		removeTarFile()
+		return "", errors.Wrapf(closeErr, WriteTarFileFailureFormat, tarFilePath)
+	}
+	if encryptor != nil {
+		if closeErr := encryptor.Close(); closeErr != nil {
+			removeTarFile()
+			return "", errors.Wrapf(closeErr, WriteTarFileFailureFormat, tarFilePath)
+		}
+	}
+	if keys.signingKey != nil {
+		signature := signing.Sign(keys.signingKey, tarWriter.MetadataFiles)
+		if signErr := signature.WriteFile(tarFilePath + signing.SignatureFileExtension); signErr != nil {
+			removeTarFile()
+			os.Remove(tarFilePath + signing.SignatureFileExtension)
+			return "", signErr
+		}
+	}
+	if mappingDir := viper.GetString(PseudonymMappingDirFlag); pseudonymizer != nil && mappingDir != "" {
+		mappingPath := filepath.Join(mappingDir, filepath.Base(tarFilePath)+PseudonymMappingFileSuffix)
+		if mappingErr := pseudonymizer.WriteMapping(mappingPath); mappingErr != nil {
+			removeTarFile()
+			os.Remove(tarFilePath + signing.SignatureFileExtension)
+			return "", mappingErr
+		}
+		logger.Printf("Wrote pseudonym mapping to %s\n", mappingPath)
+	}
+	return tarFilePath, err
+}
+
+// collectionFilePath names the file a collection is written to, with the
+// extensions for its compression and encryption.
+func collectionFilePath(filePrefix string, keys archiveKeys) string {
+	extension := ".tar" + compression.Extension(viper.GetString(CompressionFlag))
+	if keys.recipient != nil {
+		extension += encryption.FileExtension
+	}
+	return filepath.Join(
+		viper.GetString(OutputPathFlag),
+		fmt.Sprintf("%s%d%s", filePrefix, time.Now().UTC().Unix(), extension),
+	)
+}
+
+type pseudonymizer interface {
+	Value(value string) string
+	Text(text string) string
+	JSON(contents []byte) ([]byte, error)
+	WriteMapping(mappingPath string) error
+}
+
+// makePseudonymizer returns nil unless --pseudonymize is set.
+func makePseudonymizer() (pseudonymizer, error) {
+	if !viper.GetBool(PseudonymizeFlag) {
+		return nil, nil
+	}
+	if err := verifyRequiredConfig(PseudonymizeSaltFlag); err != nil {
+		return nil, err
+	}
+	if mappingDir := viper.GetString(PseudonymMappingDirFlag); mappingDir != "" {
+		absMappingDir, err := filepath.Abs(mappingDir)
+		if err != nil {
+			return nil, errors.Wrapf(err, ResolvePathFailureFormat, mappingDir)
+		}
+		absOutputDir, err := filepath.Abs(viper.GetString(OutputPathFlag))
+		if err != nil {
+			return nil, errors.Wrapf(err, ResolvePathFailureFormat, viper.GetString(OutputPathFlag))
+		}
+		rel, err := filepath.Rel(absOutputDir, absMappingDir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, errors.New(PseudonymMappingInOutputMessage)
+		}
+	}
+	return pseudonym.NewPseudonymizer(viper.GetString(PseudonymizeSaltFlag))
 }
 
+func logPartialCollection(partialErr operations.PartialCollectionError) {
+	for _, failure := range partialErr.Failures {
+		logger.Printf(PartialCollectionFailureFormat+"\n", strings.TrimSpace(failure.ProductType+" "+failure.DataType), failure.Error)
+	}
+}
+
+// anyUsageServiceConfigsProvided is whether Usage Service collection is
+// wanted. --cf-api-url alone does not count when it is there for the CF
+// inventory.
 func anyUsageServiceConfigsProvided() bool {
-	return viper.GetString(CfApiURLFlag) != "" ||
+	return (viper.GetString(CfApiURLFlag) != "" && !viper.GetBool(WithCfInventoryFlag)) ||
 		viper.GetString(UsageServiceURLFlag) != "" ||
 		viper.GetString(UsageServiceClientIDFlag) != "" ||
 		viper.GetString(UsageServiceClientSecretFlag) != ""
@@ -221,25 +469,32 @@ func validateAndNormalizeEnvType() (string, error) {
 }
 
 type consumptionDataCollector interface {
-	Collect() ([]consumption.Data, error)
+	Collect(ctx context.Context) ([]consumption.Data, error)
 }
 
-func makeConsumptionCollector() (consumptionDataCollector, error) {
+func makeConsumptionCollector(ctx context.Context, policy network.RetryPolicy) (consumptionDataCollector, error) {
 	if anyUsageServiceConfigsProvided() {
 		err := validateUsageServiceConfig()
 		if err != nil {
 			return nil, err
 		}
+		reportRanges, err := usageReportRanges(time.Now().UTC())
+		if err != nil {
+			return nil, err
+		}
+		if err := validateUsageByOrgConfig(); err != nil {
+			return nil, err
+		}
 
 		client := network.NewClient(viper.GetBool(UsageServiceSkipTlsVerifyFlag))
-		cfApiClient := cf.NewClient(viper.GetString(CfApiURLFlag), client)
+		cfApiClient := cf.NewClient(viper.GetString(CfApiURLFlag), network.NewRetryingClient(client, policy))
 
 		usageURL, err := url.Parse(viper.GetString(UsageServiceURLFlag))
 		if err != nil {
 			return nil, errors.New(UsageServiceURLParsingError)
 		}
 
-		uaaURL, err := cfApiClient.GetUAAURL()
+		uaaURL, err := cfApiClient.GetUAAURL(ctx)
 		if err != nil {
 			return nil, errors.Wrap(err, GetUAAURLError)
 		}
@@ -254,13 +509,32 @@ func makeConsumptionCollector() (consumptionDataCollector, error) {
 
 		consumptionService := &consumption.Service{
 			BaseURL: usageURL,
-			Client:  authedClient,
+			Client:  network.NewRetryingClient(authedClient, policy),
+		}
+
+		var orgLister interface {
+			Organizations(ctx context.Context) ([]cf.Organization, error)
+		}
+		if viper.GetBool(UsageByOrgFlag) {
+			if cfClientCredentialsProvided() {
+				cfClient, err := makeCfApiClient(ctx, policy)
+				if err != nil {
+					return nil, err
+				}
+				orgLister = cfClient
+			} else {
+				orgLister = cf.NewClient(viper.GetString(CfApiURLFlag), network.NewRetryingClient(authedClient, policy))
+			}
 		}
 
 		consumptionCollector := consumption.NewDataCollector(
 			*logger,
 			consumptionService,
 			viper.GetString(UsageServiceURLFlag),
+			reportRanges,
+			orgLister,
+			viper.GetInt(UsageServiceMaxConcurrencyFlag),
+			viper.GetBool(AllowPartialFlag),
 		)
 
 		return consumptionCollector, nil
@@ -269,47 +543,36 @@ func makeConsumptionCollector() (consumptionDataCollector, error) {
 }
 
 type credhubDataCollector interface {
-	Collect() (credhub.Data, error)
+	Collect(ctx context.Context) ([]credhub.Data, error)
 }
 
-func makeCredhubCollector(omService *opsmanager.Service, credhubCollectionEnabled bool) (credhubDataCollector, error) {
+func makeCredhubCollector(ctx context.Context, omService *opsmanager.Service, credhubCollectionEnabled bool, policy network.RetryPolicy) (credhubDataCollector, error) {
 	if credhubCollectionEnabled {
-		chCreds, err := omService.BoshCredentials()
+		credhubService, credHubURL, err := makeCredhubService(ctx, omService, policy, logger)
 		if err != nil {
 			return nil, err
 		}
-		credHubURL := "https://" + chCreds.Host + ":8844"
-		requestor, err := ogCredhub.New(
-			credHubURL,
-			ogCredhub.SkipTLSValidation(true),
-			ogCredhub.Auth(auth.UaaClientCredentials(chCreds.ClientID, chCreds.ClientSecret)),
-		)
-		if err != nil {
-			return nil, errors.Wrap(err, CredhubClientError)
-		}
-		credhubService := credhub.NewCredhubService(requestor)
-		return credhub.NewDataCollector(*logger, credhubService, credHubURL), nil
+		return credhub.NewDataCollector(*logger, credhubService, credHubURL, viper.GetBool(AllowPartialFlag)), nil
 	} else {
 		return nil, nil
 	}
 }
 
-func makeCollector(tarWriter *tar.TarWriter) (*operations.CollectExecutor, error) {
-	authedClient, _ := omNetwork.NewOAuthClient(
-		viper.GetString(OpsManagerURLFlag),
-		viper.GetString(OpsManagerUsernameFlag),
-		viper.GetString(OpsManagerPasswordFlag),
-		viper.GetString(OpsManagerClientIdFlag),
-		viper.GetString(OpsManagerClientSecretFlag),
-		viper.GetBool(SkipTlsVerifyFlag),
-		false,
-		time.Duration(viper.GetInt(OpsManagerTimeoutFlag))*time.Second,
-		5*time.Second,
-	)
+func makeCollector(ctx context.Context, tarWriter *signing.MetadataRecorder, policy network.RetryPolicy, pseudonymizer pseudonymizer) (*operations.CollectExecutor, error) {
+	maxConcurrency := viper.GetInt(OpsManagerMaxConcurrencyFlag)
+	if maxConcurrency < 1 {
+		return nil, errors.New(InvalidMaxConcurrencyMessage)
+	}
 
-	apiService := api.New(api.ApiInput{Client: authedClient})
+	redactionPolicy, err := readRedactionPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	apiService := makeOpsManagerAPI(ctx, policy)
 	omService := &opsmanager.Service{
-		Requestor: apiService,
+		Requestor:       apiService,
+		RedactionPolicy: &redactionPolicy,
 	}
 
 	omCollector := opsmanager.NewDataCollector(
@@ -318,17 +581,54 @@ func makeCollector(tarWriter *tar.TarWriter) (*operations.CollectExecutor, error
 		viper.GetString(OpsManagerURLFlag),
 		apiService,
 		apiService,
+		maxConcurrency,
+		viper.GetBool(AllowPartialFlag),
 	)
 
-	consumptionCollector, err := makeConsumptionCollector()
+	consumptionCollector, err := makeConsumptionCollector(ctx, policy)
 	if err != nil {
 		return nil, err
 	}
 
-	credhubCollector, err := makeCredhubCollector(omService, viper.GetBool(CollectFromCredhubFlag))
+	credhubCollector, err := makeCredhubCollector(ctx, omService, viper.GetBool(CollectFromCredhubFlag), policy)
 	if err != nil {
 		return nil, err
 	}
 
-	return operations.NewCollector(omCollector, credhubCollector, consumptionCollector, tarWriter, uuid.DefaultGenerator), nil
+	cfApiCollector, err := makeCfApiCollector(ctx, policy, redactionPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	return operations.NewCollector(omCollector, credhubCollector, consumptionCollector, cfApiCollector, tarWriter, uuid.DefaultGenerator, redactionPolicy.Hash(), pseudonymizer), nil
+}
+
+// makeOpsManagerAPI returns a client for the Ops Manager API authenticated
+// with the configured credentials.
+func makeOpsManagerAPI(ctx context.Context, policy network.RetryPolicy) api.Api {
+	authedClient, _ := omNetwork.NewOAuthClient(
+		viper.GetString(OpsManagerURLFlag),
+		viper.GetString(OpsManagerUsernameFlag),
+		viper.GetString(OpsManagerPasswordFlag),
+		viper.GetString(OpsManagerClientIdFlag),
+		viper.GetString(OpsManagerClientSecretFlag),
+		viper.GetBool(SkipTlsVerifyFlag),
+		false,
+		time.Duration(viper.GetInt(OpsManagerTimeoutFlag))*time.Second,
+		5*time.Second,
+	)
+
+	return api.New(api.ApiInput{
+		Client: network.NewContextClient(ctx, network.NewRetryingClient(authedClient, policy)),
+	})
+}
+
+// readRedactionPolicy returns the default policy when no --redaction-policy
+// is configured. The policy may also redact the CF API inventory.
+func readRedactionPolicy() (redaction.Policy, error) {
+	policyPath := viper.GetString(RedactionPolicyFlag)
+	if policyPath == "" {
+		return opsmanager.DefaultRedactionPolicy(), nil
+	}
+	return opsmanager.ReadRedactionPolicy(policyPath, cfapi.DataTypes()...)
 }
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	ogCredhub "code.cloudfoundry.org/credhub-cli/credhub"
+	"code.cloudfoundry.org/credhub-cli/credhub/auth"
+	"github.com/pivotal-cf/aqueduct-courier/credhub"
+	"github.com/pivotal-cf/aqueduct-courier/network"
+	"github.com/pivotal-cf/aqueduct-courier/opsmanager"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	CredhubMaxConcurrencyKey   = "CREDHUB_MAX_CONCURRENCY"
+	CredhubURLKey              = "CREDHUB_URL"
+	CredhubCACertKey           = "CREDHUB_CA_CERT"
+	CredhubUseOpsManagerCAKey  = "CREDHUB_USE_OPS_MANAGER_CA"
+	CredhubSkipTlsVerifyKey    = "CREDHUB_INSECURE_SKIP_TLS_VERIFY"
+	CredhubMaxConcurrencyFlag  = "credhub-max-concurrency"
+	CredhubURLFlag             = "credhub-url"
+	CredhubCACertFlag          = "credhub-ca-cert"
+	CredhubUseOpsManagerCAFlag = "credhub-use-ops-manager-ca"
+	CredhubSkipTlsVerifyFlag   = "credhub-insecure-skip-tls-verify"
+
+	// credhubPort is where the BOSH Director's CredHub listens, unless
+	// --credhub-url says otherwise.
+	credhubPort = "8844"
+
+	InvalidCredhubConcurrencyMessage = "--credhub-max-concurrency must be at least 1"
+	CredhubTLSConflictMessage        = "--credhub-insecure-skip-tls-verify cannot be used with --credhub-ca-cert or --credhub-use-ops-manager-ca"
+	ReadCredhubCACertFailureFormat   = "Could not read CredHub CA certificates %s"
+	InvalidCredhubCACertFormat       = "No PEM certificates found in %s"
+	OpsManagerRootCAFailureMessage   = "Could not get the Ops Manager root CA to verify CredHub with"
+)
+
+func bindCredhubFlags(cmd *cobra.Command) {
+	bindFlagAndEnvVar(cmd, CredhubURLFlag, "", fmt.Sprintf("``CredHub URL, when it is not on port %s of the BOSH Director [$%s]", credhubPort, CredhubURLKey), CredhubURLKey)
+	bindFlagAndEnvVar(cmd, CredhubCACertFlag, "", fmt.Sprintf("``PEM file of CA certificates to verify CredHub and its UAA with, in addition to the system's [$%s]", CredhubCACertKey), CredhubCACertKey)
+	bindFlagAndEnvVar(cmd, CredhubUseOpsManagerCAFlag, false, fmt.Sprintf("Verify CredHub and its UAA with the Ops Manager root CA, which signs the BOSH Director's certificates [$%s]", CredhubUseOpsManagerCAKey), CredhubUseOpsManagerCAKey)
+	bindFlagAndEnvVar(cmd, CredhubSkipTlsVerifyFlag, false, fmt.Sprintf("Skip TLS validation on http requests to CredHub and its UAA [$%s]", CredhubSkipTlsVerifyKey), CredhubSkipTlsVerifyKey)
+	bindFlagAndEnvVar(cmd, CredhubMaxConcurrencyFlag, 8, fmt.Sprintf("``Maximum number of concurrent requests to CredHub [$%s]\n", CredhubMaxConcurrencyKey), CredhubMaxConcurrencyKey)
+}
+
+func validateCredhubConfig() error {
+	if viper.GetInt(CredhubMaxConcurrencyFlag) < 1 {
+		return errors.New(InvalidCredhubConcurrencyMessage)
+	}
+	if viper.GetBool(CredhubSkipTlsVerifyFlag) && (viper.GetString(CredhubCACertFlag) != "" || viper.GetBool(CredhubUseOpsManagerCAFlag)) {
+		return errors.New(CredhubTLSConflictMessage)
+	}
+	return nil
+}
+
+// credhubURL is --credhub-url, or CredHub's port on the given BOSH Director.
+func credhubURL(directorHost string) string {
+	if configured := viper.GetString(CredhubURLFlag); configured != "" {
+		return strings.TrimSuffix(configured, "/")
+	}
+	return "https://" + directorHost + ":" + credhubPort
+}
+
+// makeCredhubService returns a service for the BOSH Director's CredHub,
+// authenticated with the director credentials from Ops Manager, and its URL.
+// The service logs its progress to progressLogger.
+func makeCredhubService(ctx context.Context, omService *opsmanager.Service, policy network.RetryPolicy, progressLogger *log.Logger) (*credhub.Service, string, error) {
+	if err := validateCredhubConfig(); err != nil {
+		return nil, "", err
+	}
+	chCreds, err := omService.BoshCredentials(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	tlsOption, err := credhubTLSOption(ctx, omService)
+	if err != nil {
+		return nil, "", err
+	}
+	credHubURL := credhubURL(chCreds.Host)
+	requestor, err := ogCredhub.New(
+		credHubURL,
+		tlsOption,
+		ogCredhub.Auth(auth.UaaClientCredentials(chCreds.ClientID, chCreds.ClientSecret)),
+	)
+	if err != nil {
+		return nil, "", errors.Wrap(err, CredhubClientError)
+	}
+	return credhub.NewCredhubService(credhub.NewRetryingRequestor(ctx, requestor, policy), viper.GetInt(CredhubMaxConcurrencyFlag), progressLogger), credHubURL, nil
+}
+
+// credhubTLSOption verifies CredHub against the system's CAs and any from
+// --credhub-ca-cert and --credhub-use-ops-manager-ca, unless verification is
+// skipped.
+func credhubTLSOption(ctx context.Context, omService *opsmanager.Service) (ogCredhub.Option, error) {
+	if viper.GetBool(CredhubSkipTlsVerifyFlag) {
+		return ogCredhub.SkipTLSValidation(true), nil
+	}
+
+	var caCerts []string
+	if caCertPath := viper.GetString(CredhubCACertFlag); caCertPath != "" {
+		contents, err := ioutil.ReadFile(caCertPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, ReadCredhubCACertFailureFormat, caCertPath)
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(contents) {
+			return nil, errors.Errorf(InvalidCredhubCACertFormat, caCertPath)
+		}
+		caCerts = append(caCerts, string(contents))
+	}
+	if viper.GetBool(CredhubUseOpsManagerCAFlag) {
+		rootCA, err := omService.RootCACertificate(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, OpsManagerRootCAFailureMessage)
+		}
+		caCerts = append(caCerts, rootCA)
+	}
+	if len(caCerts) == 0 {
+		return ogCredhub.SkipTLSValidation(false), nil
+	}
+	return ogCredhub.CaCerts(caCerts...), nil
+}
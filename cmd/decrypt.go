@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pivotal-cf/aqueduct-courier/compression"
+	"github.com/pivotal-cf/aqueduct-courier/encryption"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	PrivateKeyFlag        = "private-key"
+	PrivateKeyKey         = "PRIVATE_KEY_PATH"
+	DecryptOutputPathFlag = "output"
+	DecryptOutputPathKey  = "DECRYPT_OUTPUT_PATH"
+
+	DecryptFailureFormat         = "Unable to decrypt %s"
+	DecryptOutputRequiredFormat  = "%s does not end in %s, so --output is required"
+	CreateDecryptedFailureFormat = "Could not create decrypted file %s"
+)
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "Decrypts collected information",
+	Long:  "Decrypts a file written by the 'collect' command with --encrypt-to, using the matching private key",
+	RunE:  decrypt,
+}
+
+func init() {
+	bindFlagAndEnvVar(decryptCmd, DataTarFilePathFlag, "", fmt.Sprintf("``The path to the encrypted file from the 'collect' command [$%s]", DataTarFilePathKey), DataTarFilePathKey)
+	bindFlagAndEnvVar(decryptCmd, PrivateKeyFlag, "", fmt.Sprintf("``PEM file with the RSA private key the file was encrypted for [$%s]", PrivateKeyKey), PrivateKeyKey)
+	bindFlagAndEnvVar(decryptCmd, DecryptOutputPathFlag, "", fmt.Sprintf("``Path to write the decrypted file to, which must not exist. Defaults to the path without its %s extension [$%s]\n", encryption.FileExtension, DecryptOutputPathKey), DecryptOutputPathKey)
+
+	decryptCmd.Flags().BoolP("help", "h", false, "Help for the decrypt command\n")
+	decryptCmd.Flags().SortFlags = false
+
+	decryptCmd.Example = `
+      Decrypt collected data:
+      telemetry-collector decrypt --path --private-key
+
+      Decrypt collected data to a chosen file:
+      telemetry-collector decrypt --path --private-key --output`
+
+	customUsageTextTemplate := `
+USAGE EXAMPLES
+{{.Example}}
+
+FLAGS
+
+{{.LocalFlags.FlagUsages}}`
+
+	customHelpTextTemplate := fmt.Sprintf(`
+Decrypts a file written by the 'collect' command with --encrypt-to. The
+decrypted file can be validated, inspected and sent like any other.
+%s`, customUsageTextTemplate)
+
+	decryptCmd.SetHelpTemplate(customHelpTextTemplate)
+	decryptCmd.SetUsageTemplate(customUsageTextTemplate)
+	rootCmd.AddCommand(decryptCmd)
+}
+
+func decrypt(c *cobra.Command, _ []string) error {
+	err := verifyRequiredConfig(DataTarFilePathFlag, PrivateKeyFlag)
+	if err != nil {
+		return err
+	}
+	encryptedPath := viper.GetString(DataTarFilePathFlag)
+	outputPath := viper.GetString(DecryptOutputPathFlag)
+	if outputPath == "" {
+		if !strings.HasSuffix(encryptedPath, encryption.FileExtension) {
+			return errors.Errorf(DecryptOutputRequiredFormat, encryptedPath, encryption.FileExtension)
+		}
+		outputPath = strings.TrimSuffix(encryptedPath, encryption.FileExtension)
+	}
+	privateKey, err := readPrivateKey()
+	if err != nil {
+		return err
+	}
+	c.SilenceUsage = true
+
+	encryptedFile, err := os.Open(encryptedPath)
+	if err != nil {
+		return errors.New(fmt.Sprintf(FileNotFoundErrorFormat, encryptedPath))
+	}
+	defer encryptedFile.Close()
+
+	logger.Printf("Decrypting %s\n", encryptedPath)
+	plaintext, err := encryption.NewReader(encryptedFile, privateKey)
+	if err != nil {
+		return errors.Wrapf(err, DecryptFailureFormat, encryptedPath)
+	}
+
+	outputFile, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return errors.Wrapf(err, CreateDecryptedFailureFormat, outputPath)
+	}
+	defer outputFile.Close()
+
+	if _, err := io.Copy(outputFile, plaintext); err != nil {
+		outputFile.Close()
+		os.Remove(outputPath)
+		return errors.Wrapf(err, DecryptFailureFormat, encryptedPath)
+	}
+
+	logger.Printf("Wrote decrypted output to %s\n", outputPath)
+	logger.Println("Success!")
+	return nil
+}
+
+// readPrivateKey returns nil when no --private-key is configured.
+func readPrivateKey() (*rsa.PrivateKey, error) {
+	keyPath := viper.GetString(PrivateKeyFlag)
+	if keyPath == "" {
+		return nil, nil
+	}
+	return encryption.ReadPrivateKey(keyPath)
+}
+
+func bindPrivateKeyFlag(cmd *cobra.Command) {
+	bindFlagAndEnvVar(cmd, PrivateKeyFlag, "", fmt.Sprintf("``PEM file with the RSA private key to read a file encrypted with 'collect --encrypt-to' [$%s]\n", PrivateKeyKey), PrivateKeyKey)
+}
+
+// archive is a data file opened as an uncompressed tar.
+type archive struct {
+	*compression.File
+	decrypted *encryption.File
+}
+
+// openArchive decrypts the data file at path with the --private-key when it
+// is encrypted and decompresses it when it is compressed.
+func openArchive(path string) (*archive, error) {
+	privateKey, err := readPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := encryption.Open(path, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	decompressed, err := compression.Open(decrypted.Name())
+	if err != nil {
+		decrypted.Close()
+		return nil, err
+	}
+	return &archive{File: decompressed, decrypted: decrypted}, nil
+}
+
+func (a *archive) Close() error {
+	err := a.File.Close()
+	a.decrypted.Close()
+	return err
+}
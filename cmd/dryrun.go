@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pivotal-cf/aqueduct-courier/cf"
+	"github.com/pivotal-cf/aqueduct-courier/cfapi"
+	"github.com/pivotal-cf/aqueduct-courier/consumption"
+	"github.com/pivotal-cf/aqueduct-courier/credhub"
+	"github.com/pivotal-cf/aqueduct-courier/encryption"
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
+	"github.com/pivotal-cf/aqueduct-courier/operations"
+	"github.com/pivotal-cf/aqueduct-courier/opsmanager"
+	"github.com/pivotal-cf/aqueduct-courier/signing"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+	"github.com/pivotal-cf/telemetry-utils/tar"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	DryRunFlag = "dry-run"
+	DryRunKey  = "DRY_RUN"
+
+	DryRunCollectMessage = "Dry run: no requests will be made and nothing will be written"
+	DryRunSendMessage    = "Dry run: nothing will be sent"
+
+	// These stand in for what is only known once collection starts.
+	productGUIDPlaceholder  = "{product guid}"
+	productTypePlaceholder  = "{product type}"
+	directorHostPlaceholder = "{bosh director}"
+	credhubAuthPlaceholder  = "{credhub auth server}"
+	uaaPlaceholder          = "{uaa from the CF API}"
+	certNamePlaceholder     = "{certificate name}"
+	orgGUIDPlaceholder      = "{org guid}"
+)
+
+func bindDryRunFlag(cmd *cobra.Command, usage string) {
+	bindFlagAndEnvVar(cmd, DryRunFlag, false, fmt.Sprintf("%s [$%s]\n", usage, DryRunKey), DryRunKey)
+}
+
+// plannedRequest is a request collection would make, and the file in the
+// archive its response is written to, if any.
+type plannedRequest struct {
+	method string
+	url    string
+	writes string
+}
+
+// collectionPlan lists the requests collection makes, in order, from the
+// configuration alone. Requests made once per deployed product or per
+// certificate appear once, with placeholders for what is only known from
+// earlier responses.
+func collectionPlan() []plannedRequest {
+	omURL := strings.TrimSuffix(viper.GetString(OpsManagerURLFlag), "/")
+	omData := func(productType, dataType string) string {
+		return path.Join(collector_tar.OpsManagerCollectorDataSetId, productType+"_"+dataType)
+	}
+
+	plan := []plannedRequest{
+		{http.MethodPost, omURL + opsmanager.TokenPath, ""},
+		{http.MethodGet, omURL + opsmanager.PendingChangesPath, ""},
+		{http.MethodGet, omURL + opsmanager.DeployedProductsPath, ""},
+		{http.MethodGet, omURL + opsmanager.DeployedProductsPath, omData(collector_tar.OpsManagerProductType, collector_tar.DeployedProductsDataType)},
+		{http.MethodGet, omURL + fmt.Sprintf(opsmanager.ProductResourcesPathFormat, productGUIDPlaceholder), omData(productTypePlaceholder, collector_tar.ResourcesDataType) + " (per deployed product)"},
+		{http.MethodGet, omURL + fmt.Sprintf(opsmanager.ProductPropertiesPathFormat, productGUIDPlaceholder), omData(productTypePlaceholder, collector_tar.PropertiesDataType) + " (per deployed product)"},
+		{http.MethodGet, omURL + opsmanager.VmTypesPath, omData(collector_tar.OpsManagerProductType, collector_tar.VmTypesDataType)},
+		{http.MethodGet, omURL + opsmanager.DiagnosticReportPath, omData(collector_tar.OpsManagerProductType, collector_tar.DiagnosticReportDataType)},
+		{http.MethodGet, omURL + opsmanager.InstallationsPath, omData(collector_tar.OpsManagerProductType, collector_tar.InstallationsDataType)},
+		{http.MethodGet, omURL + opsmanager.CertificatesPath, omData(collector_tar.OpsManagerProductType, collector_tar.CertificatesDataType)},
+		{http.MethodGet, omURL + opsmanager.CertificateAuthoritiesPath, omData(collector_tar.OpsManagerProductType, collector_tar.CertificateAuthoritiesDataType)},
+	}
+
+	if viper.GetBool(CollectFromCredhubFlag) {
+		credhubURL := credhubURL(directorHostPlaceholder)
+		plan = append(plan, plannedRequest{http.MethodGet, omURL + opsmanager.BoshCredentialsPath, ""})
+		if viper.GetBool(CredhubUseOpsManagerCAFlag) && !viper.GetBool(CredhubSkipTlsVerifyFlag) {
+			plan = append(plan, plannedRequest{http.MethodGet, omURL + opsmanager.RootCACertificatePath, ""})
+		}
+		plan = append(plan,
+			plannedRequest{http.MethodGet, credhubURL + credhub.InfoPath, ""},
+			plannedRequest{http.MethodPost, credhubAuthPlaceholder + cf.TokenPath, ""},
+			plannedRequest{http.MethodGet, credhubURL + credhub.CertificatesPath, ""},
+			plannedRequest{http.MethodGet, credhubURL + credhub.DataPath + "?name=" + certNamePlaceholder, omData(collector_tar.DirectorProductType, collector_tar.CertificatesDataType) + ", " + omData(collector_tar.DirectorProductType, credhub.CertificateDetailsDataType) + " (per certificate)"},
+		)
+	}
+
+	if anyUsageServiceConfigsProvided() {
+		usageURL, _ := url.Parse(viper.GetString(UsageServiceURLFlag))
+		reportRanges, _ := usageReportRanges(time.Now().UTC())
+		if len(reportRanges) == 0 {
+			reportRanges = []consumption.ReportRange{{}}
+		}
+		plan = append(plan,
+			plannedRequest{http.MethodGet, strings.TrimSuffix(viper.GetString(CfApiURLFlag), "/") + cf.RootPath, ""},
+			plannedRequest{http.MethodPost, uaaPlaceholder + cf.TokenPath, ""},
+		)
+		for _, report := range consumption.SystemReports {
+			for _, reportRange := range reportRanges {
+				targetURL := *usageURL
+				targetURL.Path = path.Join(targetURL.Path, consumption.SystemReportPathPrefix, report.ReportName)
+				targetURL.RawQuery = reportRange.Query().Encode()
+				data := consumption.NewReportData(nil, report.DataType, reportRange)
+				plan = append(plan, plannedRequest{http.MethodGet, targetURL.String(), path.Join(collector_tar.UsageServiceCollectorDataSetId, data.Name())})
+			}
+		}
+
+		if viper.GetBool(UsageByOrgFlag) {
+			orgRanges := reportRanges
+			if len(orgRanges) == 1 && orgRanges[0].IsZero() {
+				orgRanges = consumption.RecentMonths(1, time.Now().UTC())
+			}
+			if cfClientCredentialsProvided() {
+				plan = append(plan,
+					plannedRequest{http.MethodGet, strings.TrimSuffix(viper.GetString(CfApiURLFlag), "/") + cf.RootPath, ""},
+					plannedRequest{http.MethodPost, uaaPlaceholder + cf.TokenPath, ""},
+				)
+			}
+			plan = append(plan, plannedRequest{http.MethodGet, strings.TrimSuffix(viper.GetString(CfApiURLFlag), "/") + cf.OrganizationsPath, ""})
+			for _, report := range consumption.OrgReports {
+				for _, reportRange := range orgRanges {
+					targetURL := *usageURL
+					targetURL.Path = path.Join(targetURL.Path, consumption.OrganizationsPathPrefix)
+					reportURL := targetURL.String() + "/" + orgGUIDPlaceholder + "/" + report.ReportName + "?" + reportRange.Query().Encode()
+					data := consumption.NewReportData(nil, report.DataType, reportRange)
+					plan = append(plan, plannedRequest{http.MethodGet, reportURL, path.Join(collector_tar.UsageServiceCollectorDataSetId, data.Name()) + " (per org)"})
+				}
+			}
+		}
+	}
+
+	if viper.GetBool(WithCfInventoryFlag) {
+		cfApiURL := strings.TrimSuffix(viper.GetString(CfApiURLFlag), "/")
+		plan = append(plan,
+			plannedRequest{http.MethodGet, cfApiURL + cf.RootPath, ""},
+			plannedRequest{http.MethodPost, uaaPlaceholder + cf.TokenPath, ""},
+		)
+		for _, resource := range cfapi.Inventory {
+			plan = append(plan, plannedRequest{http.MethodGet, cfApiURL + resource.Path, path.Join(manifest.CfApiDataSetId, resource.DataType) + " (per page)"})
+		}
+	}
+	return plan
+}
+
+// printCollectionPlan validates the rest of the collection configuration
+// and prints what collection would request and write, without doing either.
+func printCollectionPlan(filePrefix string, keys archiveKeys) error {
+	if viper.GetInt(OpsManagerMaxConcurrencyFlag) < 1 {
+		return errors.New(InvalidMaxConcurrencyMessage)
+	}
+	if viper.GetBool(CollectFromCredhubFlag) {
+		if err := validateCredhubConfig(); err != nil {
+			return err
+		}
+	}
+	if anyUsageServiceConfigsProvided() {
+		if err := validateUsageServiceConfig(); err != nil {
+			return err
+		}
+		if _, err := url.Parse(viper.GetString(UsageServiceURLFlag)); err != nil {
+			return errors.New(UsageServiceURLParsingError)
+		}
+		if _, err := usageReportRanges(time.Now().UTC()); err != nil {
+			return err
+		}
+		if err := validateUsageByOrgConfig(); err != nil {
+			return err
+		}
+	}
+	if viper.GetBool(WithCfInventoryFlag) {
+		if err := validateCfInventoryConfig(); err != nil {
+			return err
+		}
+	}
+	redactionPolicy, err := readRedactionPolicy()
+	if err != nil {
+		return err
+	}
+	pseudonymizer, err := makePseudonymizer()
+	if err != nil {
+		return err
+	}
+
+	var output bytes.Buffer
+	w := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(&output, DryRunCollectMessage)
+	fmt.Fprintln(&output)
+	fmt.Fprintln(w, "METHOD\tURL\tWRITES")
+	for _, request := range collectionPlan() {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", request.method, request.url, request.writes)
+	}
+	w.Flush()
+
+	fmt.Fprintln(&output)
+	fmt.Fprintf(w, "Redaction policy:\t%s\n", redactionPolicy.Hash())
+	w.Flush()
+	fmt.Fprintln(w, "DATA TYPE\tPATH\tACTION\tKEEP IF")
+	for _, rule := range redactionPolicy.Rules {
+		keepIf := ""
+		if rule.KeepIf != nil {
+			keepIf = fmt.Sprintf("%s in %s", rule.KeepIf.Field, strings.Join(rule.KeepIf.In, ", "))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", rule.DataType, rule.Path, rule.Action, keepIf)
+	}
+	w.Flush()
+
+	fmt.Fprintln(&output)
+	tarFilePath := collectionFilePath(filePrefix, keys)
+	fmt.Fprintf(w, "Would write:\t%s\n", tarFilePath)
+	fmt.Fprintf(w, "Compression:\t%s\n", viper.GetString(CompressionFlag))
+	if keys.recipient != nil {
+		fmt.Fprintf(w, "Encrypted for:\t%s\n", encryption.Fingerprint(keys.recipient))
+	}
+	if keys.signingKey != nil {
+		fmt.Fprintf(w, "Signed with:\t%s\n", signing.KeyID(keys.signingKey.Public().(ed25519.PublicKey)))
+		fmt.Fprintf(w, "Signature:\t%s\n", tarFilePath+signing.SignatureFileExtension)
+	}
+	fmt.Fprintf(w, "Pseudonymized:\t%t\n", pseudonymizer != nil)
+	if mappingDir := viper.GetString(PseudonymMappingDirFlag); pseudonymizer != nil && mappingDir != "" {
+		fmt.Fprintf(w, "Pseudonym mapping:\t%s\n", filepath.Join(mappingDir, filepath.Base(tarFilePath)+PseudonymMappingFileSuffix))
+	}
+	w.Flush()
+
+	logger.Print(output.String())
+	return nil
+}
+
+// printSendPlan prints the request send would start with and a summary of
+// the archive it would send, without sending it.
+func printSendPlan(plan operations.SendPlan, tarFilePath string) error {
+	var output bytes.Buffer
+	w := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(&output, DryRunSendMessage)
+	fmt.Fprintln(&output)
+	fmt.Fprintf(w, "Request:\t%s %s\n", plan.Method, plan.URL)
+	var headerNames []string
+	for name := range plan.Header {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	for _, name := range headerNames {
+		fmt.Fprintf(w, "%s:\t%s\n", name, strings.Join(plan.Header[name], ", "))
+	}
+	fmt.Fprintf(w, "Size:\t%d bytes\n", plan.Size)
+	if plan.Chunks > 0 {
+		fmt.Fprintf(w, "Chunks:\t%d\n", plan.Chunks)
+	}
+	w.Flush()
+	logger.Print(output.String())
+
+	tarFile, err := openArchive(tarFilePath)
+	if err != nil {
+		return err
+	}
+	defer tarFile.Close()
+
+	dataSets, err := operations.NewInspector(tar.NewTarReader(tarFile)).Inspect()
+	if err != nil {
+		return errors.Wrapf(err, InspectFailureFormat, tarFilePath)
+	}
+	for _, dataSet := range dataSets {
+		logger.Println()
+		printDataSetSummary(dataSet)
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"text/tabwriter"
+
+	"github.com/pivotal-cf/aqueduct-courier/compression"
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
+	"github.com/pivotal-cf/aqueduct-courier/network"
+	"github.com/pivotal-cf/aqueduct-courier/operations"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	FoundationsConfigFlag = "config"
+	FoundationsConfigKey  = "FOUNDATIONS_CONFIG"
+
+	ReadFoundationsConfigFailureFormat = "Could not read foundations config %s"
+	NoFoundationsConfiguredFormat      = "No foundations configured in %s"
+	InvalidFoundationNameFormat        = "Invalid foundation name %q. Names may only contain letters, digits, '.', '_' and '-'."
+	DuplicateFoundationNameFormat      = "Foundation %s is configured more than once"
+	UnknownFoundationSettingFormat     = "Unknown setting %s for foundation %s"
+	FoundationsFailedFormat            = "Failed to collect from %d of %d foundations"
+	FoundationPlanFailureFormat        = "Invalid configuration for foundation %s"
+)
+
+var (
+	foundationNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	foundationSettings    = []string{
+		OpsManagerURLFlag,
+		OpsManagerUsernameFlag,
+		OpsManagerPasswordFlag,
+		OpsManagerClientIdFlag,
+		OpsManagerClientSecretFlag,
+		EnvTypeFlag,
+		OpsManagerTimeoutFlag,
+		OpsManagerMaxConcurrencyFlag,
+		SkipTlsVerifyFlag,
+		CfApiURLFlag,
+		UsageServiceURLFlag,
+		UsageServiceClientIDFlag,
+		UsageServiceClientSecretFlag,
+		UsageServiceSkipTlsVerifyFlag,
+		UsageStartFlag,
+		UsageEndFlag,
+		UsageMonthsFlag,
+		UsageByOrgFlag,
+		UsageServiceMaxConcurrencyFlag,
+		CollectFromCredhubFlag,
+		CredhubURLFlag,
+		CredhubCACertFlag,
+		CredhubUseOpsManagerCAFlag,
+		CredhubSkipTlsVerifyFlag,
+		CredhubMaxConcurrencyFlag,
+		WithCfInventoryFlag,
+		CfClientIDFlag,
+		CfClientSecretFlag,
+		CfSkipTlsVerifyFlag,
+		AllowPartialFlag,
+		RedactionPolicyFlag,
+		PseudonymizeFlag,
+		PseudonymizeSaltFlag,
+		PseudonymMappingDirFlag,
+	}
+)
+
+// foundationConfig holds the settings for one foundation, keyed by the
+// collect flag they replace. Flags and env vars still apply to any setting a
+// foundation leaves out.
+type foundationConfig struct {
+	Name     string                 `yaml:"name"`
+	Settings map[string]interface{} `yaml:",inline"`
+}
+
+type foundationResult struct {
+	name        string
+	tarFilePath string
+	err         error
+}
+
+func (r foundationResult) partial() bool {
+	_, ok := r.err.(operations.PartialCollectionError)
+	return ok
+}
+
+func readFoundationsConfig(configPath string) ([]foundationConfig, error) {
+	contents, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, ReadFoundationsConfigFailureFormat, configPath)
+	}
+
+	var config struct {
+		Foundations []foundationConfig `yaml:"foundations"`
+	}
+	if err := yaml.UnmarshalStrict(contents, &config); err != nil {
+		return nil, errors.Wrapf(err, ReadFoundationsConfigFailureFormat, configPath)
+	}
+	if len(config.Foundations) == 0 {
+		return nil, errors.Errorf(NoFoundationsConfiguredFormat, configPath)
+	}
+
+	names := map[string]bool{}
+	for _, foundation := range config.Foundations {
+		if !foundationNamePattern.MatchString(foundation.Name) {
+			return nil, errors.Errorf(InvalidFoundationNameFormat, foundation.Name)
+		}
+		if names[foundation.Name] {
+			return nil, errors.Errorf(DuplicateFoundationNameFormat, foundation.Name)
+		}
+		names[foundation.Name] = true
+
+		for setting := range foundation.Settings {
+			if !isFoundationSetting(setting) {
+				return nil, errors.Errorf(UnknownFoundationSettingFormat, setting, foundation.Name)
+			}
+		}
+	}
+
+	return config.Foundations, nil
+}
+
+func isFoundationSetting(setting string) bool {
+	for _, foundationSetting := range foundationSettings {
+		if setting == foundationSetting {
+			return true
+		}
+	}
+	return false
+}
+
+func collectFoundations(c *cobra.Command, configPath string) error {
+	if err := verifyRequiredConfig(OutputPathFlag); err != nil {
+		return err
+	}
+	foundations, err := readFoundationsConfig(configPath)
+	if err != nil {
+		return err
+	}
+	if err := compression.Validate(viper.GetString(CompressionFlag)); err != nil {
+		return err
+	}
+	keys, err := readArchiveKeys()
+	if err != nil {
+		return err
+	}
+	policy, err := retryPolicy()
+	if err != nil {
+		return err
+	}
+	ctx, stop, err := commandContext()
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	c.SilenceUsage = true
+
+	if viper.GetBool(DryRunFlag) {
+		return printFoundationPlans(foundations, keys)
+	}
+
+	var results []foundationResult
+	failures := 0
+	for _, foundation := range foundations {
+		if ctx.Err() != nil {
+			failures++
+			results = append(results, foundationResult{name: foundation.Name, err: commandError(ctx, ctx.Err())})
+			continue
+		}
+
+		logger.Printf("Collecting from foundation %s\n", foundation.Name)
+		tarFilePath, err := collectFoundation(ctx, foundation, keys, policy)
+		result := foundationResult{name: foundation.Name, tarFilePath: tarFilePath, err: err}
+		if result.partial() {
+			logPartialCollection(err.(operations.PartialCollectionError))
+		} else if err != nil {
+			result.err = commandError(ctx, err)
+			logger.Printf("Failed to collect from foundation %s: %s\n", foundation.Name, result.err)
+			failures++
+		}
+		results = append(results, result)
+	}
+
+	printFoundationsSummary(results)
+	if failures > 0 {
+		return errors.Errorf(FoundationsFailedFormat, failures, len(foundations))
+	}
+	var partialFailures []manifest.Failure
+	for _, result := range results {
+		if result.partial() {
+			partialFailures = append(partialFailures, result.err.(operations.PartialCollectionError).Failures...)
+		}
+	}
+	if len(partialFailures) > 0 {
+		return operations.PartialCollectionError{Failures: partialFailures}
+	}
+	logger.Println("Success!")
+	return nil
+}
+
+func collectFoundation(ctx context.Context, foundation foundationConfig, keys archiveKeys, policy network.RetryPolicy) (string, error) {
+	for setting, value := range foundation.Settings {
+		viper.Set(setting, value)
+	}
+	defer func() {
+		for setting := range foundation.Settings {
+			viper.Set(setting, nil)
+		}
+	}()
+
+	if err := verifyRequiredConfig(OpsManagerURLFlag, EnvTypeFlag); err != nil {
+		return "", err
+	}
+	if err := validateCredConfig(); err != nil {
+		return "", err
+	}
+	envType, err := validateAndNormalizeEnvType()
+	if err != nil {
+		return "", err
+	}
+
+	return writeCollection(ctx, OutputFilePrefix+foundation.Name+"_", envType, keys, policy)
+}
+
+func printFoundationPlans(foundations []foundationConfig, keys archiveKeys) error {
+	for _, foundation := range foundations {
+		logger.Printf("Foundation %s\n", foundation.Name)
+		if err := printFoundationPlan(foundation, keys); err != nil {
+			return errors.Wrapf(err, FoundationPlanFailureFormat, foundation.Name)
+		}
+	}
+	return nil
+}
+
+func printFoundationPlan(foundation foundationConfig, keys archiveKeys) error {
+	for setting, value := range foundation.Settings {
+		viper.Set(setting, value)
+	}
+	defer func() {
+		for setting := range foundation.Settings {
+			viper.Set(setting, nil)
+		}
+	}()
+
+	if err := verifyRequiredConfig(OpsManagerURLFlag, EnvTypeFlag); err != nil {
+		return err
+	}
+	if err := validateCredConfig(); err != nil {
+		return err
+	}
+	if _, err := validateAndNormalizeEnvType(); err != nil {
+		return err
+	}
+	return printCollectionPlan(OutputFilePrefix+foundation.Name+"_", keys)
+}
+
+func printFoundationsSummary(results []foundationResult) {
+	var output bytes.Buffer
+	w := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "FOUNDATION\tRESULT")
+	for _, result := range results {
+		if result.partial() {
+			fmt.Fprintf(w, "%s\tPartial: wrote %s (%s)\n", result.name, result.tarFilePath, result.err)
+		} else if result.err != nil {
+			fmt.Fprintf(w, "%s\tFailed: %s\n", result.name, result.err)
+		} else {
+			fmt.Fprintf(w, "%s\tWrote %s\n", result.name, result.tarFilePath)
+		}
+	}
+	w.Flush()
+
+	logger.Print(output.String())
+}
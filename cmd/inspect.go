@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/pivotal-cf/aqueduct-courier/encryption"
+	"github.com/pivotal-cf/aqueduct-courier/operations"
+	"github.com/pivotal-cf/telemetry-utils/tar"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	ShowFileFlag = "show"
+	ShowFileKey  = "INSPECT_SHOW_FILE"
+
+	InspectFailureFormat   = "Unable to inspect %s"
+	EncryptedArchiveFormat = "%s is encrypted for key %s, use --%s to inspect its contents"
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Lists collected information",
+	Long:  "Lists the data sets and files in a file from the 'collect' command without sending it",
+	RunE:  inspect,
+}
+
+func init() {
+	bindFlagAndEnvVar(inspectCmd, DataTarFilePathFlag, "", fmt.Sprintf("``The path to the file with data from the 'collect' command [$%s]", DataTarFilePathKey), DataTarFilePathKey)
+	bindFlagAndEnvVar(inspectCmd, ShowFileFlag, "", fmt.Sprintf("``Pretty-print a single file from the data, e.g. opsmanager/ops_manager_vm_types [$%s]\n", ShowFileKey), ShowFileKey)
+	bindPrivateKeyFlag(inspectCmd)
+
+	inspectCmd.Flags().BoolP("help", "h", false, "Help for the inspect command\n")
+	inspectCmd.Flags().SortFlags = false
+
+	inspectCmd.Example = `
+      List collected data:
+      telemetry-collector inspect --path
+
+      Show a single collected file:
+      telemetry-collector inspect --path --show
+
+      List encrypted collected data:
+      telemetry-collector inspect --path --private-key`
+
+	customUsageTextTemplate := `
+USAGE EXAMPLES
+{{.Example}}
+
+FLAGS
+
+{{.LocalFlags.FlagUsages}}`
+
+	customHelpTextTemplate := fmt.Sprintf(`
+Lists the metadata and files of each data set in the specified file, or
+pretty-prints a single file.
+%s`, customUsageTextTemplate)
+
+	inspectCmd.SetHelpTemplate(customHelpTextTemplate)
+	inspectCmd.SetUsageTemplate(customUsageTextTemplate)
+	rootCmd.AddCommand(inspectCmd)
+}
+
+func inspect(c *cobra.Command, _ []string) error {
+	err := verifyRequiredConfig(DataTarFilePathFlag)
+	if err != nil {
+		return err
+	}
+	c.SilenceUsage = true
+
+	tarFilePath := viper.GetString(DataTarFilePathFlag)
+	tarFile, err := openArchive(tarFilePath)
+	if os.IsNotExist(errors.Cause(err)) {
+		return errors.New(fmt.Sprintf(FileNotFoundErrorFormat, tarFilePath))
+	}
+	if encryptedErr, ok := err.(encryption.EncryptedError); ok {
+		return errors.Errorf(EncryptedArchiveFormat, tarFilePath, encryptedErr.Recipient, PrivateKeyFlag)
+	}
+	if err != nil {
+		return errors.Wrapf(err, InspectFailureFormat, tarFilePath)
+	}
+	defer tarFile.Close()
+
+	inspector := operations.NewInspector(tar.NewTarReader(tarFile))
+
+	if fileName := viper.GetString(ShowFileFlag); fileName != "" {
+		contents, err := inspector.Show(fileName)
+		if err != nil {
+			return errors.Wrapf(err, InspectFailureFormat, tarFilePath)
+		}
+		logger.Println(string(contents))
+		return nil
+	}
+
+	dataSets, err := inspector.Inspect()
+	if err != nil {
+		return errors.Wrapf(err, InspectFailureFormat, tarFilePath)
+	}
+
+	for _, dataSet := range dataSets {
+		printDataSetSummary(dataSet)
+	}
+	return nil
+}
+
+func printDataSetSummary(dataSet operations.DataSetSummary) {
+	var output bytes.Buffer
+	w := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Data set:\t%s\n", dataSet.Name)
+	fmt.Fprintf(w, "EnvType:\t%s\n", dataSet.Metadata.EnvType)
+	fmt.Fprintf(w, "CollectionId:\t%s\n", dataSet.Metadata.CollectionId)
+	fmt.Fprintf(w, "FoundationId:\t%s\n", dataSet.Metadata.FoundationId)
+	fmt.Fprintf(w, "CollectedAt:\t%s\n", dataSet.Metadata.CollectedAt)
+	fmt.Fprintf(w, "CollectorVersion:\t%s\n", dataSet.Metadata.CollectorVersion)
+	w.Flush()
+
+	fmt.Fprintln(&output)
+	fmt.Fprintln(w, "NAME\tPRODUCT TYPE\tDATA TYPE\tMIME TYPE\tSIZE\tMD5")
+	for _, file := range dataSet.Files {
+		size := "missing"
+		if file.Size != operations.UnknownFileSize {
+			size = strconv.Itoa(file.Size)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", file.Name, file.ProductType, file.DataType, file.MimeType, size, file.MD5Checksum)
+	}
+	w.Flush()
+
+	if len(dataSet.Metadata.Failures) > 0 {
+		fmt.Fprintln(&output)
+		fmt.Fprintln(&output, "Not collected:")
+		fmt.Fprintln(w, "PRODUCT TYPE\tDATA TYPE\tERROR")
+		for _, failure := range dataSet.Metadata.Failures {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", failure.ProductType, failure.DataType, failure.Error)
+		}
+		w.Flush()
+	}
+
+	logger.Println(output.String())
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/pivotal-cf/aqueduct-courier/encryption"
+	"github.com/pivotal-cf/aqueduct-courier/report"
+	"github.com/pivotal-cf/telemetry-utils/tar"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	ReportOutputPathFlag = "out"
+	ReportOutputPathKey  = "REPORT_OUTPUT_PATH"
+
+	ReportFailureFormat      = "Unable to report on %s"
+	WriteReportFailureFormat = "Could not write report %s"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Renders collected information as HTML",
+	Long:  "Renders the data in a file from the 'collect' command as an HTML page, without sending it",
+	RunE:  generateReport,
+}
+
+func init() {
+	bindFlagAndEnvVar(reportCmd, DataTarFilePathFlag, "", fmt.Sprintf("``The path to the file with data from the 'collect' command [$%s]", DataTarFilePathKey), DataTarFilePathKey)
+	bindFlagAndEnvVar(reportCmd, ReportOutputPathFlag, "", fmt.Sprintf("``Path to write the HTML report to [$%s]\n", ReportOutputPathKey), ReportOutputPathKey)
+	bindPrivateKeyFlag(reportCmd)
+
+	reportCmd.Flags().BoolP("help", "h", false, "Help for the report command\n")
+	reportCmd.Flags().SortFlags = false
+
+	reportCmd.Example = `
+      Render collected data as HTML:
+      telemetry-collector report --path --out report.html
+
+      Render encrypted collected data as HTML:
+      telemetry-collector report --path --out report.html --private-key`
+
+	customUsageTextTemplate := `
+USAGE EXAMPLES
+{{.Example}}
+
+FLAGS
+
+{{.LocalFlags.FlagUsages}}`
+
+	customHelpTextTemplate := fmt.Sprintf(`
+Renders the deployed products, VM types, installation history, certificate
+and CA expiry and monthly usage in the specified file as a single HTML page.
+%s`, customUsageTextTemplate)
+
+	reportCmd.SetHelpTemplate(customHelpTextTemplate)
+	reportCmd.SetUsageTemplate(customUsageTextTemplate)
+	rootCmd.AddCommand(reportCmd)
+}
+
+func generateReport(c *cobra.Command, _ []string) error {
+	err := verifyRequiredConfig(DataTarFilePathFlag, ReportOutputPathFlag)
+	if err != nil {
+		return err
+	}
+	c.SilenceUsage = true
+
+	tarFilePath := viper.GetString(DataTarFilePathFlag)
+	outputPath := viper.GetString(ReportOutputPathFlag)
+	tarFile, err := openArchive(tarFilePath)
+	if os.IsNotExist(errors.Cause(err)) {
+		return errors.New(fmt.Sprintf(FileNotFoundErrorFormat, tarFilePath))
+	}
+	if encryptedErr, ok := err.(encryption.EncryptedError); ok {
+		return errors.Errorf(EncryptedArchiveFormat, tarFilePath, encryptedErr.Recipient, PrivateKeyFlag)
+	}
+	if err != nil {
+		return errors.Wrapf(err, ReportFailureFormat, tarFilePath)
+	}
+	defer tarFile.Close()
+
+	collectionReport, err := report.Build(tar.NewTarReader(tarFile), time.Now().UTC())
+	if err != nil {
+		return errors.Wrapf(err, ReportFailureFormat, tarFilePath)
+	}
+
+	var output bytes.Buffer
+	if err := collectionReport.WriteHTML(&output); err != nil {
+		return errors.Wrapf(err, ReportFailureFormat, tarFilePath)
+	}
+	if err := ioutil.WriteFile(outputPath, output.Bytes(), 0644); err != nil {
+		return errors.Wrapf(err, WriteReportFailureFormat, outputPath)
+	}
+
+	for _, problem := range collectionReport.Problems {
+		logger.Println(problem)
+	}
+	logger.Printf("Wrote report to %s\n", outputPath)
+	return nil
+}
@@ -1,27 +1,51 @@
 package cmd
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/pivotal-cf/aqueduct-courier/network"
+	"github.com/pivotal-cf/aqueduct-courier/operations"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 const (
-	RequiredConfigErrorFormat = "Missing required flags: %s"
-	toolName                  = "telemetry-collector"
+	RetryMaxAttemptsFlag = "retry-max-attempts"
+	RetryMaxAttemptsKey  = "RETRY_MAX_ATTEMPTS"
+	RetryBaseDelayFlag   = "retry-base-delay"
+	RetryBaseDelayKey    = "RETRY_BASE_DELAY"
+	RetryMaxDelayFlag    = "retry-max-delay"
+	RetryMaxDelayKey     = "RETRY_MAX_DELAY"
+	RetryMaxJitterFlag   = "retry-max-jitter"
+	RetryMaxJitterKey    = "RETRY_MAX_JITTER"
+	TimeoutFlag          = "timeout"
+	TimeoutKey           = "TIMEOUT"
+
+	RequiredConfigErrorFormat      = "Missing required flags: %s"
+	InvalidDurationErrorFormat     = "Invalid duration %q for --%s"
+	InvalidRetryMaxAttemptsMessage = "--retry-max-attempts must be at least 1"
+	TimeoutExceededFormat          = "Timed out after %s"
+	InterruptedFormat              = "Interrupted by %s"
+	PartialExitCode                = 3
+	toolName                       = "telemetry-collector"
 )
 
 var (
 	version = "dev"
 	logger  *log.Logger
 	rootCmd = &cobra.Command{
-		Use:   toolName,
-		Short: "Utility for collecting information about a PCF Foundation",
+		Use:              toolName,
+		Short:            "Utility for collecting information about a PCF Foundation",
+		PersistentPreRun: bindCommandFlags,
 	}
 )
 
@@ -46,6 +70,11 @@ COMMANDS
 
   collect     Collects information from a PCF foundation
   send        Sends information to Pivotal
+  validate    Validates collected information
+  inspect     Lists collected information
+  report      Renders collected information as HTML
+  certs       Lists certificates that expire soon
+  decrypt     Decrypts collected information
   help        Shows help about any command
 
 FLAGS
@@ -54,6 +83,12 @@ FLAGS
 	rootCmd.SetHelpTemplate(customHelpTextTemplate)
 
 	if err := rootCmd.Execute(); err != nil {
+		if _, ok := err.(operations.PartialCollectionError); ok {
+			os.Exit(PartialExitCode)
+		}
+		if _, ok := err.(CertificatesExpiringError); ok {
+			os.Exit(CertificatesExpiringExitCode)
+		}
 		os.Exit(1)
 	}
 }
@@ -74,6 +109,109 @@ func verifyRequiredConfig(keys ...string) error {
 	return nil
 }
 
+// Commands may share flag names, so the running command's flags are bound
+// last to take precedence over any bindings made when other commands were initialized.
+func bindCommandFlags(cmd *cobra.Command, _ []string) {
+	viper.BindPFlags(cmd.Flags())
+}
+
+func bindRetryFlags(cmd *cobra.Command) {
+	bindFlagAndEnvVar(cmd, RetryMaxAttemptsFlag, 3, fmt.Sprintf("``Number of attempts for requests that fail with a connection error or 5xx response [$%s]", RetryMaxAttemptsKey), RetryMaxAttemptsKey)
+	bindFlagAndEnvVar(cmd, RetryBaseDelayFlag, "1s", fmt.Sprintf("``Delay before the first retry, doubled for each later retry [$%s]", RetryBaseDelayKey), RetryBaseDelayKey)
+	bindFlagAndEnvVar(cmd, RetryMaxDelayFlag, network.DefaultMaxDelay.String(), fmt.Sprintf("``Longest delay before a retry, including one asked for with Retry-After [$%s]", RetryMaxDelayKey), RetryMaxDelayKey)
+	bindFlagAndEnvVar(cmd, RetryMaxJitterFlag, "1s", fmt.Sprintf("``Maximum random delay added to each retry [$%s]", RetryMaxJitterKey), RetryMaxJitterKey)
+}
+
+func bindTimeoutFlag(cmd *cobra.Command) {
+	bindFlagAndEnvVar(cmd, TimeoutFlag, "0s", fmt.Sprintf("``Overall time limit for the command, such as 10m. 0s means no limit [$%s]\n", TimeoutKey), TimeoutKey)
+}
+
+// interruptSignal holds the signal that cancelled the command, if any.
+var interruptSignal atomic.Value
+
+// commandContext returns a context that is cancelled once the --timeout
+// elapses or the process receives SIGINT or SIGTERM. Use commandError to
+// report which of these stopped the command.
+func commandContext() (context.Context, context.CancelFunc, error) {
+	timeout, err := parseDurationFlag(TimeoutFlag)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopTimeout := func() {}
+	if timeout > 0 {
+		ctx, stopTimeout = context.WithTimeout(ctx, timeout)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			interruptSignal.Store(sig)
+			logger.Printf("Received %s, stopping\n", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		stopTimeout()
+		cancel()
+	}, nil
+}
+
+// commandError explains why err occurred when the command was stopped by
+// its timeout or a signal.
+func commandError(ctx context.Context, err error) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return errors.Wrapf(err, TimeoutExceededFormat, viper.GetString(TimeoutFlag))
+	case context.Canceled:
+		if sig, ok := interruptSignal.Load().(os.Signal); ok {
+			return errors.Wrapf(err, InterruptedFormat, sig)
+		}
+	}
+	return err
+}
+
+func retryPolicy() (network.RetryPolicy, error) {
+	maxAttempts := viper.GetInt(RetryMaxAttemptsFlag)
+	if maxAttempts < 1 {
+		return network.RetryPolicy{}, errors.New(InvalidRetryMaxAttemptsMessage)
+	}
+	baseDelay, err := parseDurationFlag(RetryBaseDelayFlag)
+	if err != nil {
+		return network.RetryPolicy{}, err
+	}
+	maxDelay, err := parseDurationFlag(RetryMaxDelayFlag)
+	if err != nil {
+		return network.RetryPolicy{}, err
+	}
+	maxJitter, err := parseDurationFlag(RetryMaxJitterFlag)
+	if err != nil {
+		return network.RetryPolicy{}, err
+	}
+
+	return network.RetryPolicy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   baseDelay,
+		MaxDelay:    maxDelay,
+		MaxJitter:   maxJitter,
+		Logger:      logger,
+	}, nil
+}
+
+func parseDurationFlag(flagName string) (time.Duration, error) {
+	duration, err := time.ParseDuration(viper.GetString(flagName))
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf(InvalidDurationErrorFormat, viper.GetString(flagName), flagName)
+	}
+	return duration, nil
+}
+
 func bindFlagAndEnvVar(cmd *cobra.Command, flagName string, defaultValue interface{}, usageText, flagKey string) {
 	switch val := defaultValue.(type) {
 	case string:
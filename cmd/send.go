@@ -16,6 +16,10 @@ const (
 	DataTarFilePathKey  = "DATA_TAR_FILE_PATH"
 	ApiKeyFlag          = "api-key"
 	ApiKeyKey           = "API_KEY"
+	SkipValidationFlag  = "skip-validation"
+	SkipValidationKey   = "SKIP_VALIDATION"
+	ChunkSizeFlag       = "chunk-size"
+	ChunkSizeKey        = "CHUNK_SIZE_KB"
 
 	SendFailureMessage      = "Failed to send data"
 	FileNotFoundErrorFormat = "File not found at: %s"
@@ -33,13 +37,35 @@ var sendCmd = &cobra.Command{
 func init() {
 	bindFlagAndEnvVar(sendCmd, ApiKeyFlag, "", fmt.Sprintf("``Telemetry Collector API Key used to authenticate with Pivotal [$%s]", ApiKeyKey), ApiKeyKey)
 	bindFlagAndEnvVar(sendCmd, DataTarFilePathFlag, "", fmt.Sprintf("``The path to the file with data from the 'collect' command [$%s]\n", DataTarFilePathKey), DataTarFilePathKey)
+	bindFlagAndEnvVar(sendCmd, ChunkSizeFlag, 0, fmt.Sprintf("``Upload the file in chunks of this many kilobytes, resuming any interrupted upload of the same file [$%s]", ChunkSizeKey), ChunkSizeKey)
+	bindFlagAndEnvVar(sendCmd, SkipValidationFlag, false, fmt.Sprintf("Send the file without first validating its contents against its metadata [$%s]\n", SkipValidationKey), SkipValidationKey)
+	bindDryRunFlag(sendCmd, "Print the request that would be made and a summary of the file, without sending it")
+	bindPrivateKeyFlag(sendCmd)
+	bindVerificationKeyFlag(sendCmd)
+	bindRetryFlags(sendCmd)
+	bindTimeoutFlag(sendCmd)
 
 	sendCmd.Flags().BoolP("help", "h", false, "Help for the send command\n")
 	sendCmd.Flags().SortFlags = false
 
 	sendCmd.Example = `
       Send data to Pivotal:
-      telemetry-collector send --api-key --path`
+      telemetry-collector send --api-key --path
+
+      Send data to Pivotal in resumable 5MB chunks:
+      telemetry-collector send --api-key --path --chunk-size 5120
+
+      Send data to Pivotal without validating it first:
+      telemetry-collector send --api-key --path --skip-validation
+
+      Send encrypted data to Pivotal:
+      telemetry-collector send --api-key --path --private-key
+
+      Send signed data to Pivotal after verifying its signature:
+      telemetry-collector send --api-key --path --verification-key
+
+      Show what would be sent to Pivotal:
+      telemetry-collector send --api-key --path --dry-run`
 
 	customUsageTextTemplate := `
 USAGE EXAMPLES
@@ -63,20 +89,48 @@ func send(c *cobra.Command, _ []string) error {
 	if err != nil {
 		return err
 	}
+	policy, err := retryPolicy()
+	if err != nil {
+		return err
+	}
+	privateKey, err := readPrivateKey()
+	if err != nil {
+		return err
+	}
+	verificationKey, err := readVerificationKey()
+	if err != nil {
+		return err
+	}
+	ctx, stop, err := commandContext()
+	if err != nil {
+		return err
+	}
+	defer stop()
 	c.SilenceUsage = true
 
-	sender := operations.SendExecutor{}
-	tarFile, err := os.Open(viper.GetString(DataTarFilePathFlag))
-	if err != nil {
-		return errors.New(fmt.Sprintf(FileNotFoundErrorFormat, viper.GetString(DataTarFilePathFlag)))
+	sender := operations.NewSender(viper.GetBool(SkipValidationFlag), int64(viper.GetInt(ChunkSizeFlag))*1024, privateKey, verificationKey)
+	tarFilePath := viper.GetString(DataTarFilePathFlag)
+	if _, err := os.Stat(tarFilePath); err != nil {
+		return errors.New(fmt.Sprintf(FileNotFoundErrorFormat, tarFilePath))
 	}
 
-	client := network.NewClient(false)
+	if viper.GetBool(DryRunFlag) {
+		plan, err := sender.Plan(tarFilePath, dataLoaderURL, viper.GetString(ApiKeyFlag), version)
+		if err != nil {
+			return errors.Wrap(err, SendFailureMessage)
+		}
+		return printSendPlan(plan, tarFilePath)
+	}
 
-	logger.Printf("Sending %s to Pivotal at %s\n", viper.GetString(DataTarFilePathFlag), dataLoaderURL)
-	err = sender.Send(client, tarFile.Name(), dataLoaderURL, viper.GetString(ApiKeyFlag), version)
+	client := network.NewRetryingClient(network.NewClient(false), policy)
+
+	if viper.GetBool(SkipValidationFlag) {
+		logger.Printf("Skipping validation of %s\n", tarFilePath)
+	}
+	logger.Printf("Sending %s to Pivotal at %s\n", tarFilePath, dataLoaderURL)
+	err = sender.Send(ctx, client, tarFilePath, dataLoaderURL, viper.GetString(ApiKeyFlag), version)
 	if err != nil {
-		return errors.Wrap(err, SendFailureMessage)
+		return commandError(ctx, errors.Wrap(err, SendFailureMessage))
 	}
 
 	logger.Println("Success!")
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/pivotal-cf/aqueduct-courier/signing"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	VerificationKeyFlag = "verification-key"
+	VerificationKeyKey  = "VERIFICATION_KEY_PATH"
+
+	SignatureVerificationFailureFormat = "Signature verification failed for %s"
+)
+
+func bindVerificationKeyFlag(cmd *cobra.Command) {
+	bindFlagAndEnvVar(cmd, VerificationKeyFlag, "", fmt.Sprintf("``PEM file with the Ed25519 public key the file must be signed with by 'collect --signing-key'. The signature is read from next to the file [$%s]\n", VerificationKeyKey), VerificationKeyKey)
+}
+
+// readVerificationKey returns nil when no --verification-key is configured.
+func readVerificationKey() (ed25519.PublicKey, error) {
+	keyPath := viper.GetString(VerificationKeyFlag)
+	if keyPath == "" {
+		return nil, nil
+	}
+	return signing.ReadPublicKey(keyPath)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pivotal-cf/aqueduct-courier/consumption"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	UsageStartKey                  = "USAGE_START"
+	UsageEndKey                    = "USAGE_END"
+	UsageMonthsKey                 = "USAGE_MONTHS"
+	UsageByOrgKey                  = "USAGE_BY_ORG"
+	UsageServiceMaxConcurrencyKey  = "USAGE_SERVICE_MAX_CONCURRENCY"
+	UsageStartFlag                 = "usage-start"
+	UsageEndFlag                   = "usage-end"
+	UsageMonthsFlag                = "usage-months"
+	UsageByOrgFlag                 = "usage-by-org"
+	UsageServiceMaxConcurrencyFlag = "usage-service-max-concurrency"
+
+	InvalidUsageDateFormat         = "--%s must be a date as YYYY-MM-DD"
+	UsageEndWithoutStartMessage    = "--usage-end requires --usage-start"
+	UsageRangeOrderMessage         = "--usage-start must not be after --usage-end"
+	UsageRangeConflictMessage      = "--usage-months cannot be used with --usage-start or --usage-end"
+	InvalidUsageMonthsMessage      = "--usage-months must not be negative"
+	InvalidUsageConcurrencyMessage = "--usage-service-max-concurrency must be at least 1"
+)
+
+func bindUsageFlags(cmd *cobra.Command) {
+	bindFlagAndEnvVar(cmd, UsageStartFlag, "", fmt.Sprintf("``First day, as YYYY-MM-DD, to collect Usage Service reports for, one report per month [$%s]", UsageStartKey), UsageStartKey)
+	bindFlagAndEnvVar(cmd, UsageEndFlag, "", fmt.Sprintf("``Last day, as YYYY-MM-DD, to collect Usage Service reports for, defaulting to today [$%s]", UsageEndKey), UsageEndKey)
+	bindFlagAndEnvVar(cmd, UsageMonthsFlag, 0, fmt.Sprintf("``Number of calendar months up to today to collect Usage Service reports for, one report per month [$%s]", UsageMonthsKey), UsageMonthsKey)
+	bindFlagAndEnvVar(cmd, UsageByOrgFlag, false, fmt.Sprintf("Also collect each org's Usage Service reports, broken down by space, for the current month unless a range is given. Orgs are listed with the --cf-client-id credentials when given, otherwise with the Usage Service's, which then need the cloud_controller.admin_read_only scope [$%s]", UsageByOrgKey), UsageByOrgKey)
+	bindFlagAndEnvVar(cmd, UsageServiceMaxConcurrencyFlag, 4, fmt.Sprintf("``Maximum number of concurrent requests to Usage Service for org reports [$%s]\n", UsageServiceMaxConcurrencyKey), UsageServiceMaxConcurrencyKey)
+}
+
+func validateUsageByOrgConfig() error {
+	if viper.GetBool(UsageByOrgFlag) && viper.GetInt(UsageServiceMaxConcurrencyFlag) < 1 {
+		return errors.New(InvalidUsageConcurrencyMessage)
+	}
+	return nil
+}
+
+// usageReportRanges is the monthly ranges to collect Usage Service reports
+// for, or none to collect the Usage Service's default reports.
+func usageReportRanges(now time.Time) ([]consumption.ReportRange, error) {
+	startFlag := viper.GetString(UsageStartFlag)
+	endFlag := viper.GetString(UsageEndFlag)
+	months := viper.GetInt(UsageMonthsFlag)
+
+	if months < 0 {
+		return nil, errors.New(InvalidUsageMonthsMessage)
+	}
+	if months > 0 {
+		if startFlag != "" || endFlag != "" {
+			return nil, errors.New(UsageRangeConflictMessage)
+		}
+		return consumption.RecentMonths(months, now), nil
+	}
+	if startFlag == "" {
+		if endFlag != "" {
+			return nil, errors.New(UsageEndWithoutStartMessage)
+		}
+		return nil, nil
+	}
+
+	start, err := time.Parse(consumption.DateFormat, startFlag)
+	if err != nil {
+		return nil, errors.Errorf(InvalidUsageDateFormat, UsageStartFlag)
+	}
+	end := now
+	if endFlag != "" {
+		end, err = time.Parse(consumption.DateFormat, endFlag)
+		if err != nil {
+			return nil, errors.Errorf(InvalidUsageDateFormat, UsageEndFlag)
+		}
+	}
+	ranges := consumption.MonthlyRanges(start, end)
+	if len(ranges) == 0 {
+		return nil, errors.New(UsageRangeOrderMessage)
+	}
+	return ranges, nil
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pivotal-cf/aqueduct-courier/operations"
+	"github.com/pivotal-cf/aqueduct-courier/signing"
+	"github.com/pivotal-cf/telemetry-utils/tar"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	ValidationFailureFormat = "Validation failed for %s"
+	ValidationErrorFormat   = "Unable to validate %s"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validates collected information",
+	Long:  "Verifies the contents of a file from the 'collect' command against its metadata without sending it",
+	RunE:  validate,
+}
+
+func init() {
+	bindFlagAndEnvVar(validateCmd, DataTarFilePathFlag, "", fmt.Sprintf("``The path to the file with data from the 'collect' command [$%s]\n", DataTarFilePathKey), DataTarFilePathKey)
+	bindPrivateKeyFlag(validateCmd)
+	bindVerificationKeyFlag(validateCmd)
+
+	validateCmd.Flags().BoolP("help", "h", false, "Help for the validate command\n")
+	validateCmd.Flags().SortFlags = false
+
+	validateCmd.Example = `
+      Validate collected data:
+      telemetry-collector validate --path
+
+      Verify the signature of collected data and validate it:
+      telemetry-collector validate --path --verification-key`
+
+	customUsageTextTemplate := `
+USAGE EXAMPLES
+{{.Example}}
+
+FLAGS
+
+{{.LocalFlags.FlagUsages}}`
+
+	customHelpTextTemplate := fmt.Sprintf(`
+Verifies each data set in the specified file against its metadata, reporting
+missing, unexpected, modified and invalidly named files.
+%s`, customUsageTextTemplate)
+
+	validateCmd.SetHelpTemplate(customHelpTextTemplate)
+	validateCmd.SetUsageTemplate(customUsageTextTemplate)
+	rootCmd.AddCommand(validateCmd)
+}
+
+func validate(c *cobra.Command, _ []string) error {
+	err := verifyRequiredConfig(DataTarFilePathFlag)
+	if err != nil {
+		return err
+	}
+	verificationKey, err := readVerificationKey()
+	if err != nil {
+		return err
+	}
+	c.SilenceUsage = true
+
+	tarFilePath := viper.GetString(DataTarFilePathFlag)
+	tarFile, err := openArchive(tarFilePath)
+	if os.IsNotExist(errors.Cause(err)) {
+		return errors.New(fmt.Sprintf(FileNotFoundErrorFormat, tarFilePath))
+	}
+	if err != nil {
+		return errors.Wrapf(err, ValidationErrorFormat, tarFilePath)
+	}
+	defer tarFile.Close()
+
+	tarReader := tar.NewTarReader(tarFile)
+	if verificationKey != nil {
+		err = signing.VerifyFile(tarFilePath+signing.SignatureFileExtension, verificationKey, tarReader)
+		if err != nil {
+			return errors.Wrapf(err, SignatureVerificationFailureFormat, tarFilePath)
+		}
+		logger.Printf("Verified signature of %s by key %s\n", tarFilePath, signing.KeyID(verificationKey))
+	}
+
+	logger.Printf("Validating %s\n", tarFilePath)
+	report, err := operations.NewValidator(tarReader).Validate()
+	if err != nil {
+		return errors.Wrapf(err, ValidationErrorFormat, tarFilePath)
+	}
+
+	printValidationReport(report)
+	if !report.Valid() {
+		return errors.Errorf(ValidationFailureFormat, tarFilePath)
+	}
+
+	logger.Println("Success!")
+	return nil
+}
+
+func printValidationReport(report operations.ValidationReport) {
+	for _, dataSet := range report.DataSets {
+		if dataSet.Name != "" {
+			logger.Printf("%s:\n", dataSet.Name)
+		}
+		for _, file := range dataSet.Files {
+			logger.Printf("  %s: %s\n", file.Name, file.Status)
+		}
+		for _, failure := range dataSet.Failures {
+			logger.Printf("  %s: not collected: %s\n", strings.TrimSpace(failure.ProductType+" "+failure.DataType), failure.Error)
+		}
+		if !dataSet.Valid() {
+			logger.Printf("  error: %s\n", dataSet.Err)
+		}
+	}
+}
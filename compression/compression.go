@@ -0,0 +1,176 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+const (
+	None = "none"
+	Gzip = "gzip"
+	Zstd = "zstd"
+
+	InvalidFormatFormat      = "Invalid compression %s, must be one of none, gzip or zstd"
+	DecompressFailureMessage = "Failed to decompress archive"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+func Validate(format string) error {
+	switch format {
+	case None, Gzip, Zstd:
+		return nil
+	default:
+		return errors.Errorf(InvalidFormatFormat, format)
+	}
+}
+
+// Extension returns the suffix added to a .tar file name for the format.
+func Extension(format string) string {
+	switch format {
+	case Gzip:
+		return ".gz"
+	case Zstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// ContentEncoding returns the HTTP Content-Encoding of the format, which is
+// empty when it is not compressed.
+func ContentEncoding(format string) string {
+	if format == None {
+		return ""
+	}
+	return format
+}
+
+// NewWriter compresses what is written to it into w. Closing it flushes the
+// compressed data but does not close w.
+func NewWriter(w io.Writer, format string) (io.WriteCloser, error) {
+	switch format {
+	case None:
+		return nopWriteCloser{w}, nil
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, errors.Errorf(InvalidFormatFormat, format)
+	}
+}
+
+// Detect returns the format of the file from its leading bytes, leaving the
+// file positioned at its start.
+func Detect(file io.ReadSeeker) (string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	header := make([]byte, len(zstdMagic))
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	header = header[:n]
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return Gzip, nil
+	case bytes.HasPrefix(header, zstdMagic):
+		return Zstd, nil
+	default:
+		return None, nil
+	}
+}
+
+// File is an archive opened through Open.
+type File struct {
+	*os.File
+	temporary bool
+}
+
+// Open opens the archive at path for reading as an uncompressed tar. A
+// compressed archive is decompressed into a temporary file, which is removed
+// when the File is closed.
+func Open(path string) (*File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := Detect(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if format == None {
+		return &File{File: file}, nil
+	}
+	defer file.Close()
+
+	decompressed, err := ioutil.TempFile("", "decompressed-archive")
+	if err != nil {
+		return nil, errors.Wrap(err, DecompressFailureMessage)
+	}
+	result := &File{File: decompressed, temporary: true}
+	if err := decompress(decompressed, file, format); err != nil {
+		result.Close()
+		return nil, errors.Wrap(err, DecompressFailureMessage)
+	}
+	if _, err := decompressed.Seek(0, io.SeekStart); err != nil {
+		result.Close()
+		return nil, errors.Wrap(err, DecompressFailureMessage)
+	}
+	return result, nil
+}
+
+func (f *File) Close() error {
+	err := f.File.Close()
+	if f.temporary {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func decompress(dst io.Writer, src io.Reader, format string) error {
+	switch format {
+	case Gzip:
+		reader, err := gzip.NewReader(src)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		_, err = io.Copy(dst, reader)
+		return err
+	case Zstd:
+		reader, err := zstd.NewReader(src)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		_, err = io.Copy(dst, reader)
+		return err
+	default:
+		_, err := io.Copy(dst, src)
+		return err
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
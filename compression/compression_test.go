@@ -0,0 +1,115 @@
+package compression_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/pivotal-cf/aqueduct-courier/compression"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Compression", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "compression")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	writeArchive := func(format string, contents []byte) string {
+		var buf bytes.Buffer
+		writer, err := NewWriter(&buf, format)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = writer.Write(contents)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+
+		path := filepath.Join(tempDir, "archive.tar"+Extension(format))
+		Expect(ioutil.WriteFile(path, buf.Bytes(), 0644)).To(Succeed())
+		return path
+	}
+
+	table.DescribeTable("round trips the contents through an archive",
+		func(format, extension string) {
+			path := writeArchive(format, []byte("some-tar-contents"))
+			Expect(path).To(HaveSuffix(".tar" + extension))
+
+			file, err := os.Open(path)
+			Expect(err).NotTo(HaveOccurred())
+			detected, err := Detect(file)
+			file.Close()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(detected).To(Equal(format))
+
+			archive, err := Open(path)
+			Expect(err).NotTo(HaveOccurred())
+			defer archive.Close()
+			contents, err := ioutil.ReadAll(archive)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("some-tar-contents"))
+		},
+		table.Entry("none", None, ""),
+		table.Entry("gzip", Gzip, ".gz"),
+		table.Entry("zstd", Zstd, ".zst"),
+	)
+
+	It("detects an empty file as uncompressed", func() {
+		Expect(Detect(bytes.NewReader(nil))).To(Equal(None))
+	})
+
+	It("removes the decompressed copy when the archive is closed", func() {
+		archive, err := Open(writeArchive(Gzip, []byte("some-tar-contents")))
+		Expect(err).NotTo(HaveOccurred())
+		decompressedPath := archive.Name()
+		Expect(decompressedPath).To(BeAnExistingFile())
+
+		Expect(archive.Close()).To(Succeed())
+		Expect(decompressedPath).NotTo(BeAnExistingFile())
+	})
+
+	It("keeps an uncompressed archive when it is closed", func() {
+		path := writeArchive(None, []byte("some-tar-contents"))
+		archive, err := Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(archive.Name()).To(Equal(path))
+
+		Expect(archive.Close()).To(Succeed())
+		Expect(path).To(BeAnExistingFile())
+	})
+
+	It("errors when a compressed archive is corrupt", func() {
+		path := filepath.Join(tempDir, "archive.tar.gz")
+		Expect(ioutil.WriteFile(path, []byte{0x1f, 0x8b, 0x00}, 0644)).To(Succeed())
+
+		_, err := Open(path)
+		Expect(err).To(MatchError(ContainSubstring(DecompressFailureMessage)))
+	})
+
+	It("errors when the archive does not exist", func() {
+		_, err := Open(filepath.Join(tempDir, "missing.tar"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects unknown formats", func() {
+		Expect(Validate("bzip2")).To(MatchError("Invalid compression bzip2, must be one of none, gzip or zstd"))
+		_, err := NewWriter(&bytes.Buffer{}, "bzip2")
+		Expect(err).To(MatchError("Invalid compression bzip2, must be one of none, gzip or zstd"))
+		Expect(Validate(Zstd)).To(Succeed())
+	})
+
+	It("maps formats to content encodings", func() {
+		Expect(ContentEncoding(None)).To(BeEmpty())
+		Expect(ContentEncoding(Gzip)).To(Equal("gzip"))
+		Expect(ContentEncoding(Zstd)).To(Equal("zstd"))
+	})
+})
@@ -2,15 +2,21 @@
 package consumptionfakes
 
 import (
+	"context"
 	"io"
 	"sync"
+
+	"github.com/pivotal-cf/aqueduct-courier/consumption"
 )
 
 type FakeConsumptionService struct {
-	AppUsagesStub        func() (io.Reader, error)
+	AppUsagesStub        func(context.Context, consumption.ReportRange) (io.Reader, error)
 	appUsagesMutex       sync.RWMutex
-	appUsagesArgsForCall []struct{}
-	appUsagesReturns     struct {
+	appUsagesArgsForCall []struct {
+		arg1 context.Context
+		arg2 consumption.ReportRange
+	}
+	appUsagesReturns struct {
 		result1 io.Reader
 		result2 error
 	}
@@ -18,10 +24,13 @@ type FakeConsumptionService struct {
 		result1 io.Reader
 		result2 error
 	}
-	ServiceUsagesStub        func() (io.Reader, error)
+	ServiceUsagesStub        func(context.Context, consumption.ReportRange) (io.Reader, error)
 	serviceUsagesMutex       sync.RWMutex
-	serviceUsagesArgsForCall []struct{}
-	serviceUsagesReturns     struct {
+	serviceUsagesArgsForCall []struct {
+		arg1 context.Context
+		arg2 consumption.ReportRange
+	}
+	serviceUsagesReturns struct {
 		result1 io.Reader
 		result2 error
 	}
@@ -29,10 +38,13 @@ type FakeConsumptionService struct {
 		result1 io.Reader
 		result2 error
 	}
-	TaskUsagesStub        func() (io.Reader, error)
+	TaskUsagesStub        func(context.Context, consumption.ReportRange) (io.Reader, error)
 	taskUsagesMutex       sync.RWMutex
-	taskUsagesArgsForCall []struct{}
-	taskUsagesReturns     struct {
+	taskUsagesArgsForCall []struct {
+		arg1 context.Context
+		arg2 consumption.ReportRange
+	}
+	taskUsagesReturns struct {
 		result1 io.Reader
 		result2 error
 	}
@@ -40,23 +52,43 @@ type FakeConsumptionService struct {
 		result1 io.Reader
 		result2 error
 	}
+	OrgUsagesStub        func(context.Context, string, string, consumption.ReportRange) ([]byte, error)
+	orgUsagesMutex       sync.RWMutex
+	orgUsagesArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 consumption.ReportRange
+	}
+	orgUsagesReturns struct {
+		result1 []byte
+		result2 error
+	}
+	orgUsagesReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeConsumptionService) AppUsages() (io.Reader, error) {
+func (fake *FakeConsumptionService) AppUsages(arg1 context.Context, arg2 consumption.ReportRange) (io.Reader, error) {
 	fake.appUsagesMutex.Lock()
 	ret, specificReturn := fake.appUsagesReturnsOnCall[len(fake.appUsagesArgsForCall)]
-	fake.appUsagesArgsForCall = append(fake.appUsagesArgsForCall, struct{}{})
-	fake.recordInvocation("AppUsages", []interface{}{})
+	fake.appUsagesArgsForCall = append(fake.appUsagesArgsForCall, struct {
+		arg1 context.Context
+		arg2 consumption.ReportRange
+	}{arg1, arg2})
+	fake.recordInvocation("AppUsages", []interface{}{arg1, arg2})
 	fake.appUsagesMutex.Unlock()
 	if fake.AppUsagesStub != nil {
-		return fake.AppUsagesStub()
+		return fake.AppUsagesStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	return fake.appUsagesReturns.result1, fake.appUsagesReturns.result2
+	fakeReturns := fake.appUsagesReturns
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeConsumptionService) AppUsagesCallCount() int {
@@ -65,7 +97,22 @@ func (fake *FakeConsumptionService) AppUsagesCallCount() int {
 	return len(fake.appUsagesArgsForCall)
 }
 
+func (fake *FakeConsumptionService) AppUsagesCalls(stub func(context.Context, consumption.ReportRange) (io.Reader, error)) {
+	fake.appUsagesMutex.Lock()
+	defer fake.appUsagesMutex.Unlock()
+	fake.AppUsagesStub = stub
+}
+
+func (fake *FakeConsumptionService) AppUsagesArgsForCall(i int) (context.Context, consumption.ReportRange) {
+	fake.appUsagesMutex.RLock()
+	defer fake.appUsagesMutex.RUnlock()
+	argsForCall := fake.appUsagesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
 func (fake *FakeConsumptionService) AppUsagesReturns(result1 io.Reader, result2 error) {
+	fake.appUsagesMutex.Lock()
+	defer fake.appUsagesMutex.Unlock()
 	fake.AppUsagesStub = nil
 	fake.appUsagesReturns = struct {
 		result1 io.Reader
@@ -74,6 +121,8 @@ func (fake *FakeConsumptionService) AppUsagesReturns(result1 io.Reader, result2
 }
 
 func (fake *FakeConsumptionService) AppUsagesReturnsOnCall(i int, result1 io.Reader, result2 error) {
+	fake.appUsagesMutex.Lock()
+	defer fake.appUsagesMutex.Unlock()
 	fake.AppUsagesStub = nil
 	if fake.appUsagesReturnsOnCall == nil {
 		fake.appUsagesReturnsOnCall = make(map[int]struct {
@@ -87,19 +136,23 @@ func (fake *FakeConsumptionService) AppUsagesReturnsOnCall(i int, result1 io.Rea
 	}{result1, result2}
 }
 
-func (fake *FakeConsumptionService) ServiceUsages() (io.Reader, error) {
+func (fake *FakeConsumptionService) ServiceUsages(arg1 context.Context, arg2 consumption.ReportRange) (io.Reader, error) {
 	fake.serviceUsagesMutex.Lock()
 	ret, specificReturn := fake.serviceUsagesReturnsOnCall[len(fake.serviceUsagesArgsForCall)]
-	fake.serviceUsagesArgsForCall = append(fake.serviceUsagesArgsForCall, struct{}{})
-	fake.recordInvocation("ServiceUsages", []interface{}{})
+	fake.serviceUsagesArgsForCall = append(fake.serviceUsagesArgsForCall, struct {
+		arg1 context.Context
+		arg2 consumption.ReportRange
+	}{arg1, arg2})
+	fake.recordInvocation("ServiceUsages", []interface{}{arg1, arg2})
 	fake.serviceUsagesMutex.Unlock()
 	if fake.ServiceUsagesStub != nil {
-		return fake.ServiceUsagesStub()
+		return fake.ServiceUsagesStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	return fake.serviceUsagesReturns.result1, fake.serviceUsagesReturns.result2
+	fakeReturns := fake.serviceUsagesReturns
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeConsumptionService) ServiceUsagesCallCount() int {
@@ -108,7 +161,22 @@ func (fake *FakeConsumptionService) ServiceUsagesCallCount() int {
 	return len(fake.serviceUsagesArgsForCall)
 }
 
+func (fake *FakeConsumptionService) ServiceUsagesCalls(stub func(context.Context, consumption.ReportRange) (io.Reader, error)) {
+	fake.serviceUsagesMutex.Lock()
+	defer fake.serviceUsagesMutex.Unlock()
+	fake.ServiceUsagesStub = stub
+}
+
+func (fake *FakeConsumptionService) ServiceUsagesArgsForCall(i int) (context.Context, consumption.ReportRange) {
+	fake.serviceUsagesMutex.RLock()
+	defer fake.serviceUsagesMutex.RUnlock()
+	argsForCall := fake.serviceUsagesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
 func (fake *FakeConsumptionService) ServiceUsagesReturns(result1 io.Reader, result2 error) {
+	fake.serviceUsagesMutex.Lock()
+	defer fake.serviceUsagesMutex.Unlock()
 	fake.ServiceUsagesStub = nil
 	fake.serviceUsagesReturns = struct {
 		result1 io.Reader
@@ -117,6 +185,8 @@ func (fake *FakeConsumptionService) ServiceUsagesReturns(result1 io.Reader, resu
 }
 
 func (fake *FakeConsumptionService) ServiceUsagesReturnsOnCall(i int, result1 io.Reader, result2 error) {
+	fake.serviceUsagesMutex.Lock()
+	defer fake.serviceUsagesMutex.Unlock()
 	fake.ServiceUsagesStub = nil
 	if fake.serviceUsagesReturnsOnCall == nil {
 		fake.serviceUsagesReturnsOnCall = make(map[int]struct {
@@ -130,19 +200,23 @@ func (fake *FakeConsumptionService) ServiceUsagesReturnsOnCall(i int, result1 io
 	}{result1, result2}
 }
 
-func (fake *FakeConsumptionService) TaskUsages() (io.Reader, error) {
+func (fake *FakeConsumptionService) TaskUsages(arg1 context.Context, arg2 consumption.ReportRange) (io.Reader, error) {
 	fake.taskUsagesMutex.Lock()
 	ret, specificReturn := fake.taskUsagesReturnsOnCall[len(fake.taskUsagesArgsForCall)]
-	fake.taskUsagesArgsForCall = append(fake.taskUsagesArgsForCall, struct{}{})
-	fake.recordInvocation("TaskUsages", []interface{}{})
+	fake.taskUsagesArgsForCall = append(fake.taskUsagesArgsForCall, struct {
+		arg1 context.Context
+		arg2 consumption.ReportRange
+	}{arg1, arg2})
+	fake.recordInvocation("TaskUsages", []interface{}{arg1, arg2})
 	fake.taskUsagesMutex.Unlock()
 	if fake.TaskUsagesStub != nil {
-		return fake.TaskUsagesStub()
+		return fake.TaskUsagesStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	return fake.taskUsagesReturns.result1, fake.taskUsagesReturns.result2
+	fakeReturns := fake.taskUsagesReturns
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeConsumptionService) TaskUsagesCallCount() int {
@@ -151,7 +225,22 @@ func (fake *FakeConsumptionService) TaskUsagesCallCount() int {
 	return len(fake.taskUsagesArgsForCall)
 }
 
+func (fake *FakeConsumptionService) TaskUsagesCalls(stub func(context.Context, consumption.ReportRange) (io.Reader, error)) {
+	fake.taskUsagesMutex.Lock()
+	defer fake.taskUsagesMutex.Unlock()
+	fake.TaskUsagesStub = stub
+}
+
+func (fake *FakeConsumptionService) TaskUsagesArgsForCall(i int) (context.Context, consumption.ReportRange) {
+	fake.taskUsagesMutex.RLock()
+	defer fake.taskUsagesMutex.RUnlock()
+	argsForCall := fake.taskUsagesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
 func (fake *FakeConsumptionService) TaskUsagesReturns(result1 io.Reader, result2 error) {
+	fake.taskUsagesMutex.Lock()
+	defer fake.taskUsagesMutex.Unlock()
 	fake.TaskUsagesStub = nil
 	fake.taskUsagesReturns = struct {
 		result1 io.Reader
@@ -160,6 +249,8 @@ func (fake *FakeConsumptionService) TaskUsagesReturns(result1 io.Reader, result2
 }
 
 func (fake *FakeConsumptionService) TaskUsagesReturnsOnCall(i int, result1 io.Reader, result2 error) {
+	fake.taskUsagesMutex.Lock()
+	defer fake.taskUsagesMutex.Unlock()
 	fake.TaskUsagesStub = nil
 	if fake.taskUsagesReturnsOnCall == nil {
 		fake.taskUsagesReturnsOnCall = make(map[int]struct {
@@ -173,6 +264,72 @@ func (fake *FakeConsumptionService) TaskUsagesReturnsOnCall(i int, result1 io.Re
 	}{result1, result2}
 }
 
+func (fake *FakeConsumptionService) OrgUsages(arg1 context.Context, arg2 string, arg3 string, arg4 consumption.ReportRange) ([]byte, error) {
+	fake.orgUsagesMutex.Lock()
+	ret, specificReturn := fake.orgUsagesReturnsOnCall[len(fake.orgUsagesArgsForCall)]
+	fake.orgUsagesArgsForCall = append(fake.orgUsagesArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 consumption.ReportRange
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("OrgUsages", []interface{}{arg1, arg2, arg3, arg4})
+	fake.orgUsagesMutex.Unlock()
+	if fake.OrgUsagesStub != nil {
+		return fake.OrgUsagesStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.orgUsagesReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeConsumptionService) OrgUsagesCallCount() int {
+	fake.orgUsagesMutex.RLock()
+	defer fake.orgUsagesMutex.RUnlock()
+	return len(fake.orgUsagesArgsForCall)
+}
+
+func (fake *FakeConsumptionService) OrgUsagesCalls(stub func(context.Context, string, string, consumption.ReportRange) ([]byte, error)) {
+	fake.orgUsagesMutex.Lock()
+	defer fake.orgUsagesMutex.Unlock()
+	fake.OrgUsagesStub = stub
+}
+
+func (fake *FakeConsumptionService) OrgUsagesArgsForCall(i int) (context.Context, string, string, consumption.ReportRange) {
+	fake.orgUsagesMutex.RLock()
+	defer fake.orgUsagesMutex.RUnlock()
+	argsForCall := fake.orgUsagesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeConsumptionService) OrgUsagesReturns(result1 []byte, result2 error) {
+	fake.orgUsagesMutex.Lock()
+	defer fake.orgUsagesMutex.Unlock()
+	fake.OrgUsagesStub = nil
+	fake.orgUsagesReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeConsumptionService) OrgUsagesReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.orgUsagesMutex.Lock()
+	defer fake.orgUsagesMutex.Unlock()
+	fake.OrgUsagesStub = nil
+	if fake.orgUsagesReturnsOnCall == nil {
+		fake.orgUsagesReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.orgUsagesReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeConsumptionService) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -182,6 +339,8 @@ func (fake *FakeConsumptionService) Invocations() map[string][][]interface{} {
 	defer fake.serviceUsagesMutex.RUnlock()
 	fake.taskUsagesMutex.RLock()
 	defer fake.taskUsagesMutex.RUnlock()
+	fake.orgUsagesMutex.RLock()
+	defer fake.orgUsagesMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
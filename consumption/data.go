@@ -5,16 +5,37 @@ import (
 )
 
 type Data struct {
-	reader   io.Reader
-	dataType string
+	reader      io.Reader
+	dataType    string
+	reportRange ReportRange
+	err         error
 }
 
 func NewData(reader io.Reader, dataType string) Data {
 	return Data{reader: reader, dataType: dataType}
 }
 
+// NewFailedData records a retrieval that failed during a partial collection.
+func NewFailedData(dataType string, err error) Data {
+	return Data{dataType: dataType, err: err}
+}
+
+// NewReportData is a report for a range of days, named for its month.
+func NewReportData(reader io.Reader, dataType string, reportRange ReportRange) Data {
+	return Data{reader: reader, dataType: dataType, reportRange: reportRange}
+}
+
+// NewFailedReportData records a report for a range of days that failed
+// during a partial collection.
+func NewFailedReportData(dataType string, reportRange ReportRange, err error) Data {
+	return Data{dataType: dataType, reportRange: reportRange, err: err}
+}
+
 func (d Data) Name() string {
-	return d.dataType
+	if d.reportRange.IsZero() {
+		return d.dataType
+	}
+	return d.dataType + "_" + d.reportRange.Month()
 }
 
 func (d Data) Content() io.Reader {
@@ -32,3 +53,12 @@ func (d Data) Type() string {
 func (d Data) DataType() string {
 	return d.dataType
 }
+
+// Range is zero unless the report is for a range of days.
+func (d Data) Range() ReportRange {
+	return d.reportRange
+}
+
+func (d Data) Err() error {
+	return d.err
+}
@@ -1,6 +1,7 @@
 package consumption
 
 import (
+	"context"
 	"io"
 	"log"
 
@@ -12,51 +13,99 @@ const (
 	AppUsageRequestError     = "Failed retrieving app usage data"
 	ServiceUsageRequestError = "Failed retrieving service usage data"
 	TaskUsageRequestError    = "Failed retrieving task usage data"
+	RangeRequestErrorFormat  = "for %s"
 	SystemReportPathPrefix   = "system_report"
 )
 
+// SystemReports are the Usage Service reports on the whole foundation, and
+// the data type each is collected as.
+var SystemReports = []struct {
+	ReportName string
+	DataType   string
+}{
+	{AppUsagesReportName, collector_tar.AppUsageDataType},
+	{ServiceUsagesReportName, collector_tar.ServiceUsageDataType},
+	{TaskUsagesReportName, collector_tar.TaskUsageDataType},
+}
+
 //go:generate counterfeiter . consumptionService
 type consumptionService interface {
-	AppUsages() (io.Reader, error)
-	ServiceUsages() (io.Reader, error)
-	TaskUsages() (io.Reader, error)
+	AppUsages(ctx context.Context, reportRange ReportRange) (io.Reader, error)
+	ServiceUsages(ctx context.Context, reportRange ReportRange) (io.Reader, error)
+	TaskUsages(ctx context.Context, reportRange ReportRange) (io.Reader, error)
+	OrgUsages(ctx context.Context, orgGUID, reportName string, reportRange ReportRange) ([]byte, error)
 }
 
 type DataCollector struct {
 	logger             log.Logger
 	consumptionService consumptionService
 	usageServiceURL    string
+	reportRanges       []ReportRange
+	orgLister          orgLister
+	maxConcurrency     int
+	allowPartial       bool
 }
 
-func NewDataCollector(logger log.Logger, cs consumptionService, usageServiceURL string) *DataCollector {
+// NewDataCollector returns a collector that stops at the first failed
+// retrieval, or with allowPartial records failed retrievals as failed data
+// and carries on. Each report is retrieved once for each of reportRanges, or
+// once for the Usage Service's default range when there are none. Unless ol
+// is nil, each org's reports are retrieved too, up to maxConcurrency at once.
+func NewDataCollector(logger log.Logger, cs consumptionService, usageServiceURL string, reportRanges []ReportRange, ol orgLister, maxConcurrency int, allowPartial bool) *DataCollector {
+	if len(reportRanges) == 0 {
+		reportRanges = []ReportRange{{}}
+	}
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
 	return &DataCollector{
 		logger:             logger,
 		consumptionService: cs,
 		usageServiceURL:    usageServiceURL,
+		reportRanges:       reportRanges,
+		orgLister:          ol,
+		maxConcurrency:     maxConcurrency,
+		allowPartial:       allowPartial,
 	}
 }
 
-func (dc *DataCollector) Collect() ([]Data, error) {
+func (dc *DataCollector) Collect(ctx context.Context) ([]Data, error) {
 	dc.logger.Printf("Collecting data from Usage Service at %s", dc.usageServiceURL)
 
-	appUsagesDataReader, err := dc.consumptionService.AppUsages()
-	if err != nil {
-		return []Data{}, errors.Wrap(err, AppUsageRequestError)
-	}
-
-	serviceUsagesDataReader, err := dc.consumptionService.ServiceUsages()
-	if err != nil {
-		return []Data{}, errors.Wrap(err, ServiceUsageRequestError)
+	var usages []Data
+	for _, retrieval := range []struct {
+		retriever    func(context.Context, ReportRange) (io.Reader, error)
+		dataType     string
+		errorMessage string
+	}{
+		{dc.consumptionService.AppUsages, collector_tar.AppUsageDataType, AppUsageRequestError},
+		{dc.consumptionService.ServiceUsages, collector_tar.ServiceUsageDataType, ServiceUsageRequestError},
+		{dc.consumptionService.TaskUsages, collector_tar.TaskUsageDataType, TaskUsageRequestError},
+	} {
+		for _, reportRange := range dc.reportRanges {
+			reader, err := retrieval.retriever(ctx, reportRange)
+			if err != nil {
+				err = errors.Wrap(err, retrieval.errorMessage)
+				if !reportRange.IsZero() {
+					err = errors.Wrapf(err, RangeRequestErrorFormat, reportRange.Month())
+				}
+				if !dc.allowPartial || ctx.Err() != nil {
+					return []Data{}, err
+				}
+				usages = append(usages, NewFailedReportData(retrieval.dataType, reportRange, err))
+				continue
+			}
+			usages = append(usages, NewReportData(reader, retrieval.dataType, reportRange))
+		}
 	}
 
-	taskUsagesDataReader, err := dc.consumptionService.TaskUsages()
-	if err != nil {
-		return []Data{}, errors.Wrap(err, TaskUsageRequestError)
+	if dc.orgLister != nil {
+		orgUsages, err := dc.collectOrgUsages(ctx)
+		if err != nil {
+			return []Data{}, err
+		}
+		usages = append(usages, orgUsages...)
 	}
 
-	return []Data{
-		NewData(appUsagesDataReader, collector_tar.AppUsageDataType),
-		NewData(serviceUsagesDataReader, collector_tar.ServiceUsageDataType),
-		NewData(taskUsagesDataReader, collector_tar.TaskUsageDataType),
-	}, nil
+	return usages, nil
 }
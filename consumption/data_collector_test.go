@@ -1,9 +1,11 @@
 package consumption_test
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/onsi/gomega/gbytes"
 
@@ -29,7 +31,7 @@ var _ = Describe("DataCollector", func() {
 		bufferedOutput = gbytes.NewBuffer()
 		logger = log.New(bufferedOutput, "", 0)
 		consumptionService = new(consumptionfakes.FakeConsumptionService)
-		dataCollector = NewDataCollector(*logger, consumptionService, "some-usage-url")
+		dataCollector = NewDataCollector(*logger, consumptionService, "some-usage-url", nil, nil, 1, false)
 	})
 
 	Describe("collect", func() {
@@ -42,7 +44,7 @@ var _ = Describe("DataCollector", func() {
 			consumptionService.ServiceUsagesReturns(serviceUsagesReader, nil)
 			consumptionService.TaskUsagesReturns(taskUsagesReader, nil)
 
-			collectedUsageData, err := dataCollector.Collect()
+			collectedUsageData, err := dataCollector.Collect(context.Background())
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(bufferedOutput).To(gbytes.Say("Collecting data from Usage Service at some-usage-url"))
@@ -55,7 +57,7 @@ var _ = Describe("DataCollector", func() {
 
 		It("returns an error when consumptionService.AppUsages errors", func() {
 			consumptionService.AppUsagesReturns(nil, errors.New("Requesting things is hard"))
-			collectedData, err := dataCollector.Collect()
+			collectedData, err := dataCollector.Collect(context.Background())
 
 			Expect(collectedData).To(BeEmpty())
 			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(AppUsageRequestError))))
@@ -64,7 +66,7 @@ var _ = Describe("DataCollector", func() {
 
 		It("returns an error when consumptionService.ServiceUsages errors", func() {
 			consumptionService.ServiceUsagesReturns(nil, errors.New("Requesting things is hard"))
-			collectedData, err := dataCollector.Collect()
+			collectedData, err := dataCollector.Collect(context.Background())
 
 			Expect(collectedData).To(BeEmpty())
 			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(ServiceUsageRequestError))))
@@ -73,11 +75,96 @@ var _ = Describe("DataCollector", func() {
 
 		It("returns an error when consumptionService.TaskUsages errors", func() {
 			consumptionService.TaskUsagesReturns(nil, errors.New("Requesting things is hard"))
-			collectedData, err := dataCollector.Collect()
+			collectedData, err := dataCollector.Collect(context.Background())
 
 			Expect(collectedData).To(BeEmpty())
 			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(TaskUsageRequestError))))
 			Expect(err).To(MatchError(ContainSubstring("Requesting things is hard")))
 		})
+
+		Context("when report ranges are given", func() {
+			var january, february ReportRange
+
+			BeforeEach(func() {
+				january = ReportRange{
+					Start: time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC),
+					End:   time.Date(2018, time.January, 31, 0, 0, 0, 0, time.UTC),
+				}
+				february = ReportRange{
+					Start: time.Date(2018, time.February, 1, 0, 0, 0, 0, time.UTC),
+					End:   time.Date(2018, time.February, 28, 0, 0, 0, 0, time.UTC),
+				}
+				dataCollector = NewDataCollector(*logger, consumptionService, "some-usage-url", []ReportRange{january, february}, nil, 1, false)
+			})
+
+			It("retrieves each report for each range", func() {
+				collectedUsageData, err := dataCollector.Collect(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(consumptionService.AppUsagesCallCount()).To(Equal(2))
+				_, firstRange := consumptionService.AppUsagesArgsForCall(0)
+				Expect(firstRange).To(Equal(january))
+				_, secondRange := consumptionService.AppUsagesArgsForCall(1)
+				Expect(secondRange).To(Equal(february))
+				Expect(consumptionService.ServiceUsagesCallCount()).To(Equal(2))
+				Expect(consumptionService.TaskUsagesCallCount()).To(Equal(2))
+
+				var names []string
+				for _, usageData := range collectedUsageData {
+					names = append(names, usageData.Name())
+				}
+				Expect(names).To(Equal([]string{
+					collector_tar.AppUsageDataType + "_2018-01",
+					collector_tar.AppUsageDataType + "_2018-02",
+					collector_tar.ServiceUsageDataType + "_2018-01",
+					collector_tar.ServiceUsageDataType + "_2018-02",
+					collector_tar.TaskUsageDataType + "_2018-01",
+					collector_tar.TaskUsageDataType + "_2018-02",
+				}))
+				Expect(collectedUsageData[1].Range()).To(Equal(february))
+			})
+
+			It("returns an error naming the month that could not be retrieved", func() {
+				consumptionService.TaskUsagesReturnsOnCall(1, nil, errors.New("Requesting things is hard"))
+
+				collectedData, err := dataCollector.Collect(context.Background())
+				Expect(collectedData).To(BeEmpty())
+				Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(RangeRequestErrorFormat, "2018-02"))))
+				Expect(err).To(MatchError(ContainSubstring(TaskUsageRequestError)))
+			})
+		})
+
+		Context("when partial collection is allowed", func() {
+			BeforeEach(func() {
+				dataCollector = NewDataCollector(*logger, consumptionService, "some-usage-url", nil, nil, 1, true)
+			})
+
+			It("returns failed data for the usages that cannot be retrieved", func() {
+				appUsagesReader := strings.NewReader("app instance data")
+				taskUsagesReader := strings.NewReader("task instance data")
+				consumptionService.AppUsagesReturns(appUsagesReader, nil)
+				consumptionService.ServiceUsagesReturns(nil, errors.New("Requesting things is hard"))
+				consumptionService.TaskUsagesReturns(taskUsagesReader, nil)
+
+				collectedUsageData, err := dataCollector.Collect(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(collectedUsageData).To(HaveLen(3))
+				Expect(collectedUsageData[0]).To(Equal(NewData(appUsagesReader, collector_tar.AppUsageDataType)))
+				Expect(collectedUsageData[1].DataType()).To(Equal(collector_tar.ServiceUsageDataType))
+				Expect(collectedUsageData[1].Err()).To(MatchError(ContainSubstring(ServiceUsageRequestError)))
+				Expect(collectedUsageData[1].Err()).To(MatchError(ContainSubstring("Requesting things is hard")))
+				Expect(collectedUsageData[2]).To(Equal(NewData(taskUsagesReader, collector_tar.TaskUsageDataType)))
+			})
+
+			It("returns an error when the context is done", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				consumptionService.AppUsagesReturns(nil, ctx.Err())
+
+				collectedData, err := dataCollector.Collect(ctx)
+				Expect(collectedData).To(BeEmpty())
+				Expect(err).To(MatchError(ContainSubstring(AppUsageRequestError)))
+			})
+		})
 	})
 })
@@ -1,7 +1,9 @@
 package consumption_test
 
 import (
+	"errors"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -16,6 +18,18 @@ var _ = Describe("Data", func() {
 		Expect(d.Name()).To(Equal(collector_tar.AppUsageDataType))
 	})
 
+	It("names a report for a range of days for its month", func() {
+		reportRange := ReportRange{
+			Start: time.Date(2018, time.March, 1, 0, 0, 0, 0, time.UTC),
+			End:   time.Date(2018, time.March, 31, 0, 0, 0, 0, time.UTC),
+		}
+		d := NewReportData(strings.NewReader(""), collector_tar.AppUsageDataType, reportRange)
+		Expect(d.Name()).To(Equal(collector_tar.AppUsageDataType + "_2018-03"))
+		Expect(d.DataType()).To(Equal(collector_tar.AppUsageDataType))
+		Expect(d.Range()).To(Equal(reportRange))
+		Expect(NewData(nil, collector_tar.AppUsageDataType).Range().IsZero()).To(BeTrue())
+	})
+
 	It("returns content for the data", func() {
 		dataReader := strings.NewReader("best-data")
 		d := NewData(dataReader, collector_tar.AppUsageDataType)
@@ -37,4 +51,12 @@ var _ = Describe("Data", func() {
 		Expect(d.DataType()).To(Equal(collector_tar.AppUsageDataType))
 	})
 
+	It("has no error unless the retrieval failed", func() {
+		Expect(NewData(nil, collector_tar.AppUsageDataType).Err()).NotTo(HaveOccurred())
+
+		d := NewFailedData(collector_tar.TaskUsageDataType, errors.New("collecting is hard"))
+		Expect(d.Err()).To(MatchError("collecting is hard"))
+		Expect(d.DataType()).To(Equal(collector_tar.TaskUsageDataType))
+	})
+
 })
@@ -0,0 +1,144 @@
+package consumption
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pivotal-cf/aqueduct-courier/cf"
+	"github.com/pkg/errors"
+)
+
+const (
+	OrgAppUsageDataType     = "org_app_usage"
+	OrgServiceUsageDataType = "org_service_usage"
+	OrgTaskUsageDataType    = "org_task_usage"
+
+	ListOrganizationsError     = "Failed listing orgs from the CF API, which needs a client with the cloud_controller.admin_read_only scope"
+	NoOrganizationsError       = "The CF API listed no orgs, so its client likely lacks the cloud_controller.admin_read_only scope"
+	OrgUsageRequestErrorFormat = "Failed retrieving %s data by org"
+	InvalidOrgReportFormat     = "Usage Service returned invalid JSON for %s of org %s"
+)
+
+//go:generate counterfeiter . orgLister
+type orgLister interface {
+	Organizations(ctx context.Context) ([]cf.Organization, error)
+}
+
+// OrgUsage is an org's Usage Service report, which breaks its usage down by
+// space. Error is set instead of Report when the report could not be
+// retrieved.
+type OrgUsage struct {
+	OrganizationGUID string          `json:"organization_guid"`
+	OrganizationName string          `json:"organization_name"`
+	Report           json.RawMessage `json:"report,omitempty"`
+	Error            string          `json:"error,omitempty"`
+}
+
+// OrgReports are the Usage Service reports retrieved for each org, and the
+// data type each is collected as.
+var OrgReports = []struct {
+	ReportName string
+	DataType   string
+}{
+	{AppUsagesReportName, OrgAppUsageDataType},
+	{ServiceUsagesReportName, OrgServiceUsageDataType},
+	{TaskUsagesReportName, OrgTaskUsageDataType},
+}
+
+// collectOrgUsages retrieves each org's reports, with at most maxConcurrency
+// requests in flight, as one data per report and range listing every org.
+// An org's report that cannot be retrieved is recorded with its error
+// rather than failing the others, unless ctx is done. The Usage Service
+// only reports on orgs over a range, so without one the current month is
+// used.
+func (dc *DataCollector) collectOrgUsages(ctx context.Context) ([]Data, error) {
+	reportRanges := dc.reportRanges
+	if len(reportRanges) == 1 && reportRanges[0].IsZero() {
+		reportRanges = RecentMonths(1, time.Now().UTC())
+	}
+
+	orgs, err := dc.orgLister.Organizations(ctx)
+	if err != nil {
+		err = errors.Wrap(err, ListOrganizationsError)
+	} else if len(orgs) == 0 {
+		err = errors.New(NoOrganizationsError)
+	}
+	if err != nil {
+		if !dc.allowPartial || ctx.Err() != nil {
+			return nil, err
+		}
+		var failed []Data
+		for _, report := range OrgReports {
+			for _, reportRange := range reportRanges {
+				failed = append(failed, NewFailedReportData(report.DataType, reportRange, err))
+			}
+		}
+		return failed, nil
+	}
+	dc.logger.Printf("Collecting usage of %d orgs from Usage Service", len(orgs))
+
+	type request struct {
+		reportName  string
+		reportRange ReportRange
+		org         cf.Organization
+	}
+	var requests []request
+	for _, report := range OrgReports {
+		for _, reportRange := range reportRanges {
+			for _, org := range orgs {
+				requests = append(requests, request{report.ReportName, reportRange, org})
+			}
+		}
+	}
+
+	usages := make([]OrgUsage, len(requests))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < dc.maxConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				r := requests[index]
+				usages[index] = OrgUsage{OrganizationGUID: r.org.GUID, OrganizationName: r.org.Name}
+				if ctx.Err() != nil {
+					continue
+				}
+				report, err := dc.consumptionService.OrgUsages(ctx, r.org.GUID, r.reportName, r.reportRange)
+				if err != nil {
+					usages[index].Error = err.Error()
+					continue
+				}
+				if !json.Valid(report) {
+					usages[index].Error = errors.Errorf(InvalidOrgReportFormat, r.reportName, r.org.GUID).Error()
+					continue
+				}
+				usages[index].Report = report
+			}
+		}()
+	}
+	for index := range requests {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	var orgData []Data
+	for reportIndex, report := range OrgReports {
+		for rangeIndex, reportRange := range reportRanges {
+			if err := ctx.Err(); err != nil {
+				return nil, errors.Wrapf(err, OrgUsageRequestErrorFormat, report.DataType)
+			}
+			first := (reportIndex*len(reportRanges) + rangeIndex) * len(orgs)
+			contents, err := json.Marshal(map[string][]OrgUsage{"organization_usages": append([]OrgUsage{}, usages[first:first+len(orgs)]...)})
+			if err != nil {
+				return nil, errors.Wrapf(err, OrgUsageRequestErrorFormat, report.DataType)
+			}
+			orgData = append(orgData, NewReportData(bytes.NewReader(contents), report.DataType, reportRange))
+		}
+	}
+	return orgData, nil
+}
@@ -0,0 +1,205 @@
+package consumption_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/aqueduct-courier/cf"
+	. "github.com/pivotal-cf/aqueduct-courier/consumption"
+	"github.com/pivotal-cf/aqueduct-courier/consumption/consumptionfakes"
+)
+
+var _ = Describe("DataCollector org usage", func() {
+	var (
+		logger             *log.Logger
+		bufferedOutput     *gbytes.Buffer
+		consumptionService *consumptionfakes.FakeConsumptionService
+		orgLister          *consumptionfakes.FakeOrgLister
+		january, february  ReportRange
+	)
+
+	orgUsages := func(data Data) []OrgUsage {
+		contents, err := ioutil.ReadAll(data.Content())
+		Expect(err).NotTo(HaveOccurred())
+		var report struct {
+			OrganizationUsages []OrgUsage `json:"organization_usages"`
+		}
+		Expect(json.Unmarshal(contents, &report)).To(Succeed())
+		return report.OrganizationUsages
+	}
+
+	BeforeEach(func() {
+		bufferedOutput = gbytes.NewBuffer()
+		logger = log.New(bufferedOutput, "", 0)
+		consumptionService = new(consumptionfakes.FakeConsumptionService)
+		consumptionService.OrgUsagesStub = func(_ context.Context, orgGUID, reportName string, reportRange ReportRange) ([]byte, error) {
+			return []byte(fmt.Sprintf(`{"org": %q, "report": %q, "month": %q}`, orgGUID, reportName, reportRange.Month())), nil
+		}
+		orgLister = new(consumptionfakes.FakeOrgLister)
+		orgLister.OrganizationsReturns([]cf.Organization{{GUID: "org-1-guid", Name: "org-1"}, {GUID: "org-2-guid", Name: "org-2"}}, nil)
+		january = ReportRange{
+			Start: time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC),
+			End:   time.Date(2018, time.January, 31, 0, 0, 0, 0, time.UTC),
+		}
+		february = ReportRange{
+			Start: time.Date(2018, time.February, 1, 0, 0, 0, 0, time.UTC),
+			End:   time.Date(2018, time.February, 28, 0, 0, 0, 0, time.UTC),
+		}
+	})
+
+	It("collects each org's reports for each range after the system reports", func() {
+		dataCollector := NewDataCollector(*logger, consumptionService, "some-usage-url", []ReportRange{january, february}, orgLister, 3, false)
+
+		collectedData, err := dataCollector.Collect(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bufferedOutput).To(gbytes.Say("Collecting usage of 2 orgs from Usage Service"))
+		Expect(consumptionService.OrgUsagesCallCount()).To(Equal(12))
+
+		Expect(collectedData).To(HaveLen(12))
+		var names []string
+		for _, data := range collectedData[6:] {
+			names = append(names, data.Name())
+		}
+		Expect(names).To(Equal([]string{
+			OrgAppUsageDataType + "_2018-01",
+			OrgAppUsageDataType + "_2018-02",
+			OrgServiceUsageDataType + "_2018-01",
+			OrgServiceUsageDataType + "_2018-02",
+			OrgTaskUsageDataType + "_2018-01",
+			OrgTaskUsageDataType + "_2018-02",
+		}))
+		Expect(collectedData[7].Range()).To(Equal(february))
+
+		Expect(orgUsages(collectedData[9])).To(Equal([]OrgUsage{
+			{OrganizationGUID: "org-1-guid", OrganizationName: "org-1", Report: json.RawMessage(`{"org":"org-1-guid","report":"service_usages","month":"2018-02"}`)},
+			{OrganizationGUID: "org-2-guid", OrganizationName: "org-2", Report: json.RawMessage(`{"org":"org-2-guid","report":"service_usages","month":"2018-02"}`)},
+		}))
+	})
+
+	It("asks for the current month when no range is given", func() {
+		dataCollector := NewDataCollector(*logger, consumptionService, "some-usage-url", nil, orgLister, 1, false)
+
+		collectedData, err := dataCollector.Collect(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(collectedData).To(HaveLen(6))
+		Expect(collectedData[0].Range().IsZero()).To(BeTrue())
+		Expect(collectedData[3].Range().Month()).To(Equal(time.Now().UTC().Format(MonthFormat)))
+	})
+
+	It("makes no more than the maximum number of concurrent requests", func() {
+		var mutex sync.Mutex
+		inFlight, maxInFlight := 0, 0
+		consumptionService.OrgUsagesStub = func(context.Context, string, string, ReportRange) ([]byte, error) {
+			mutex.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mutex.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mutex.Lock()
+			inFlight--
+			mutex.Unlock()
+			return []byte(`{}`), nil
+		}
+		dataCollector := NewDataCollector(*logger, consumptionService, "some-usage-url", []ReportRange{january, february}, orgLister, 2, false)
+
+		_, err := dataCollector.Collect(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(maxInFlight).To(Equal(2))
+	})
+
+	It("records the error of an org's report that cannot be retrieved", func() {
+		consumptionService.OrgUsagesStub = func(_ context.Context, orgGUID, reportName string, _ ReportRange) ([]byte, error) {
+			if orgGUID == "org-2-guid" && reportName == TaskUsagesReportName {
+				return nil, errors.New("requesting things is hard")
+			}
+			if orgGUID == "org-2-guid" {
+				return []byte(`not json`), nil
+			}
+			return []byte(`{}`), nil
+		}
+		dataCollector := NewDataCollector(*logger, consumptionService, "some-usage-url", []ReportRange{january}, orgLister, 1, false)
+
+		collectedData, err := dataCollector.Collect(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+
+		appUsages := orgUsages(collectedData[3])
+		Expect(appUsages[0].Error).To(BeEmpty())
+		Expect(appUsages[1].Error).To(Equal(fmt.Sprintf(InvalidOrgReportFormat, AppUsagesReportName, "org-2-guid")))
+		Expect(appUsages[1].Report).To(BeNil())
+
+		taskUsages := orgUsages(collectedData[5])
+		Expect(taskUsages[1]).To(Equal(OrgUsage{OrganizationGUID: "org-2-guid", OrganizationName: "org-2", Error: "requesting things is hard"}))
+	})
+
+	It("returns an error when the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		consumptionService.OrgUsagesStub = func(context.Context, string, string, ReportRange) ([]byte, error) {
+			cancel()
+			return nil, ctx.Err()
+		}
+		dataCollector := NewDataCollector(*logger, consumptionService, "some-usage-url", []ReportRange{january}, orgLister, 1, true)
+
+		collectedData, err := dataCollector.Collect(ctx)
+		Expect(collectedData).To(BeEmpty())
+		Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(OrgUsageRequestErrorFormat, OrgAppUsageDataType))))
+		Expect(consumptionService.OrgUsagesCallCount()).To(Equal(1))
+	})
+
+	It("returns an error when the orgs cannot be listed", func() {
+		orgLister.OrganizationsReturns(nil, errors.New("listing is hard"))
+		dataCollector := NewDataCollector(*logger, consumptionService, "some-usage-url", []ReportRange{january}, orgLister, 1, false)
+
+		collectedData, err := dataCollector.Collect(context.Background())
+		Expect(collectedData).To(BeEmpty())
+		Expect(err).To(MatchError(ContainSubstring(ListOrganizationsError)))
+		Expect(err).To(MatchError(ContainSubstring("listing is hard")))
+	})
+
+	It("returns an error when the CF API lists no orgs", func() {
+		orgLister.OrganizationsReturns(nil, nil)
+		dataCollector := NewDataCollector(*logger, consumptionService, "some-usage-url", []ReportRange{january}, orgLister, 1, false)
+
+		collectedData, err := dataCollector.Collect(context.Background())
+		Expect(collectedData).To(BeEmpty())
+		Expect(err).To(MatchError(NoOrganizationsError))
+		Expect(consumptionService.OrgUsagesCallCount()).To(Equal(0))
+	})
+
+	It("returns failed data for the org reports when the CF API lists no orgs and partial collection is allowed", func() {
+		orgLister.OrganizationsReturns(nil, nil)
+		dataCollector := NewDataCollector(*logger, consumptionService, "some-usage-url", []ReportRange{january}, orgLister, 1, true)
+
+		collectedData, err := dataCollector.Collect(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(collectedData).To(HaveLen(6))
+		for _, data := range collectedData[3:] {
+			Expect(data.Err()).To(MatchError(NoOrganizationsError))
+		}
+	})
+
+	It("returns failed data for the org reports when the orgs cannot be listed and partial collection is allowed", func() {
+		orgLister.OrganizationsReturns(nil, errors.New("listing is hard"))
+		dataCollector := NewDataCollector(*logger, consumptionService, "some-usage-url", []ReportRange{january}, orgLister, 1, true)
+
+		collectedData, err := dataCollector.Collect(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(collectedData).To(HaveLen(6))
+		for _, data := range collectedData[3:] {
+			Expect(data.Err()).To(MatchError(ContainSubstring(ListOrganizationsError)))
+			Expect(data.Range()).To(Equal(january))
+		}
+		Expect(collectedData[5].DataType()).To(Equal(OrgTaskUsageDataType))
+	})
+})
@@ -0,0 +1,70 @@
+package consumption
+
+import (
+	"net/url"
+	"time"
+)
+
+const (
+	// DateFormat is how report range dates are given and sent to the Usage
+	// Service.
+	DateFormat = "2006-01-02"
+	// MonthFormat names the month a report covers.
+	MonthFormat = "2006-01"
+)
+
+// ReportRange is the days a report covers, from Start to End inclusive. The
+// zero ReportRange leaves the range to the Usage Service.
+type ReportRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (r ReportRange) IsZero() bool {
+	return r.Start.IsZero() && r.End.IsZero()
+}
+
+// Month is the month the range starts in.
+func (r ReportRange) Month() string {
+	return r.Start.Format(MonthFormat)
+}
+
+// Query is the query string that asks the Usage Service for the range.
+func (r ReportRange) Query() url.Values {
+	query := url.Values{}
+	if !r.IsZero() {
+		query.Set("start", r.Start.Format(DateFormat))
+		query.Set("end", r.End.Format(DateFormat))
+	}
+	return query
+}
+
+// MonthlyRanges splits the days from start to end into one range per
+// calendar month, oldest first.
+func MonthlyRanges(start, end time.Time) []ReportRange {
+	start = day(start)
+	end = day(end)
+
+	var ranges []ReportRange
+	for !start.After(end) {
+		monthEnd := start.AddDate(0, 1, -start.Day())
+		if monthEnd.After(end) {
+			monthEnd = end
+		}
+		ranges = append(ranges, ReportRange{Start: start, End: monthEnd})
+		start = monthEnd.AddDate(0, 0, 1)
+	}
+	return ranges
+}
+
+// RecentMonths is the ranges of the last months calendar months, the last
+// of them running from the start of the month to now.
+func RecentMonths(months int, now time.Time) []ReportRange {
+	now = day(now)
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1-months, 0)
+	return MonthlyRanges(start, now)
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,65 @@
+package consumption_test
+
+import (
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/aqueduct-courier/consumption"
+)
+
+var _ = Describe("ReportRange", func() {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	It("asks for nothing when it is zero", func() {
+		Expect(ReportRange{}.IsZero()).To(BeTrue())
+		Expect(ReportRange{}.Query()).To(BeEmpty())
+	})
+
+	It("asks for its start and end days", func() {
+		r := ReportRange{Start: date(2018, time.March, 4), End: date(2018, time.March, 20)}
+		Expect(r.IsZero()).To(BeFalse())
+		Expect(r.Month()).To(Equal("2018-03"))
+		Expect(r.Query()).To(Equal(url.Values{"start": {"2018-03-04"}, "end": {"2018-03-20"}}))
+	})
+
+	Describe("MonthlyRanges", func() {
+		It("splits the days into calendar months", func() {
+			Expect(MonthlyRanges(date(2017, time.December, 15), date(2018, time.February, 10))).To(Equal([]ReportRange{
+				{Start: date(2017, time.December, 15), End: date(2017, time.December, 31)},
+				{Start: date(2018, time.January, 1), End: date(2018, time.January, 31)},
+				{Start: date(2018, time.February, 1), End: date(2018, time.February, 10)},
+			}))
+		})
+
+		It("returns one range for days within a month", func() {
+			Expect(MonthlyRanges(date(2018, time.January, 31), date(2018, time.January, 31))).To(Equal([]ReportRange{
+				{Start: date(2018, time.January, 31), End: date(2018, time.January, 31)},
+			}))
+		})
+
+		It("ignores the time of day", func() {
+			start := time.Date(2018, time.January, 2, 13, 4, 5, 0, time.UTC)
+			Expect(MonthlyRanges(start, start)).To(Equal([]ReportRange{
+				{Start: date(2018, time.January, 2), End: date(2018, time.January, 2)},
+			}))
+		})
+
+		It("returns nothing when the end is before the start", func() {
+			Expect(MonthlyRanges(date(2018, time.February, 1), date(2018, time.January, 1))).To(BeEmpty())
+		})
+	})
+
+	Describe("RecentMonths", func() {
+		It("returns the months up to now", func() {
+			Expect(RecentMonths(3, time.Date(2018, time.March, 5, 10, 0, 0, 0, time.UTC))).To(Equal([]ReportRange{
+				{Start: date(2018, time.January, 1), End: date(2018, time.January, 31)},
+				{Start: date(2018, time.February, 1), End: date(2018, time.February, 28)},
+				{Start: date(2018, time.March, 1), End: date(2018, time.March, 5)},
+			}))
+		})
+	})
+})
@@ -2,6 +2,7 @@ package consumption
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"io/ioutil"
@@ -16,6 +17,7 @@ const (
 	AppUsagesReportName     = "app_usages"
 	ServiceUsagesReportName = "service_usages"
 	TaskUsagesReportName    = "task_usages"
+	OrganizationsPathPrefix = "organizations"
 
 	CreateUsageServiceHTTPRequestError              = "error creating HTTP request to usage service endpoint"
 	UsageServiceRequestError                        = "error accessing usage service"
@@ -24,6 +26,7 @@ const (
 	AppUsagesRequestError     = "error retrieving app usages data"
 	ServiceUsagesRequestError = "error retrieving service usages data"
 	TaskUsagesRequestError    = "error retrieving task usages data"
+	OrgUsagesRequestFormat    = "error retrieving %s data for org %s"
 	UnmarshalResponseError    = "error unmarshalling response"
 	ReadResponseError         = "error reading response"
 )
@@ -74,16 +77,16 @@ type serviceReport struct {
 	} `json:"yearly_service_report"`
 }
 
-func (s *Service) AppUsages() (io.Reader, error) {
-	contents, err := s.makeRequest(AppUsagesReportName)
+func (s *Service) AppUsages(ctx context.Context, reportRange ReportRange) (io.Reader, error) {
+	contents, err := s.makeRequest(ctx, SystemReportPathPrefix, AppUsagesReportName, reportRange)
 	if err != nil {
 		return nil, errors.Wrap(err, AppUsagesRequestError)
 	}
 	return bytes.NewReader(contents), nil
 }
 
-func (s *Service) ServiceUsages() (io.Reader, error) {
-	contents, err := s.makeRequest(ServiceUsagesReportName)
+func (s *Service) ServiceUsages(ctx context.Context, reportRange ReportRange) (io.Reader, error) {
+	contents, err := s.makeRequest(ctx, SystemReportPathPrefix, ServiceUsagesReportName, reportRange)
 	if err != nil {
 		return nil, errors.Wrap(err, ServiceUsagesRequestError)
 	}
@@ -100,21 +103,35 @@ func (s *Service) ServiceUsages() (io.Reader, error) {
 	return bytes.NewReader(redactedContent), nil
 }
 
-func (s *Service) TaskUsages() (io.Reader, error) {
-	respBody, err := s.makeRequest(TaskUsagesReportName)
+func (s *Service) TaskUsages(ctx context.Context, reportRange ReportRange) (io.Reader, error) {
+	respBody, err := s.makeRequest(ctx, SystemReportPathPrefix, TaskUsagesReportName, reportRange)
 	if err != nil {
 		return nil, errors.Wrap(err, TaskUsagesRequestError)
 	}
 	return bytes.NewReader(respBody), nil
 }
 
-func (s *Service) makeRequest(reportName string) ([]byte, error) {
+// OrgUsages returns the named report, such as AppUsagesReportName, for an
+// org over reportRange, broken down by space.
+func (s *Service) OrgUsages(ctx context.Context, orgGUID, reportName string, reportRange ReportRange) ([]byte, error) {
+	contents, err := s.makeRequest(ctx, path.Join(OrganizationsPathPrefix, orgGUID), reportName, reportRange)
+	if err != nil {
+		return nil, errors.Wrapf(err, OrgUsagesRequestFormat, reportName, orgGUID)
+	}
+	return contents, nil
+}
+
+// makeRequest asks for the report under pathPrefix over reportRange, or the
+// Usage Service's default range when it is zero.
+func (s *Service) makeRequest(ctx context.Context, pathPrefix, reportName string, reportRange ReportRange) ([]byte, error) {
 	targetURL, _ := url.Parse(s.BaseURL.String())
-	targetURL.Path = path.Join(targetURL.Path, SystemReportPathPrefix, reportName)
+	targetURL.Path = path.Join(targetURL.Path, pathPrefix, reportName)
+	targetURL.RawQuery = reportRange.Query().Encode()
 	req, err := http.NewRequest(http.MethodGet, targetURL.String(), nil)
 	if err != nil {
 		return nil, errors.Wrap(err, CreateUsageServiceHTTPRequestError)
 	}
+	req = req.WithContext(ctx)
 
 	resp, err := s.Client.Do(req)
 	if err != nil {
@@ -2,6 +2,7 @@ package consumption_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -47,7 +49,7 @@ var _ = Describe("Service", func() {
 			fakeClient.DoReturns(appUsagesResponse, nil)
 
 			expectedBody := []byte(`successful app usage content`)
-			respBody, err := service.AppUsages()
+			respBody, err := service.AppUsages(context.Background(), ReportRange{})
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fakeClient.DoCallCount()).To(Equal(1))
@@ -62,9 +64,33 @@ var _ = Describe("Service", func() {
 			Expect(content).To(Equal([]byte(expectedBody)))
 		})
 
+		It("asks for the given range of days", func() {
+			fakeClient.DoReturns(&http.Response{Body: &readerCloser{reader: bytes.NewReader(nil)}, StatusCode: http.StatusOK}, nil)
+
+			_, err := service.AppUsages(context.Background(), ReportRange{
+				Start: time.Date(2018, time.February, 1, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2018, time.February, 28, 0, 0, 0, 0, time.UTC),
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			req := fakeClient.DoArgsForCall(0)
+			Expect(req.URL.Path).To(Equal(path.Join(usageURL.Path, SystemReportPathPrefix, AppUsagesReportName)))
+			Expect(req.URL.Query()).To(Equal(url.Values{"start": {"2018-02-01"}, "end": {"2018-02-28"}}))
+		})
+
+		It("sends the request with the given context", func() {
+			fakeClient.DoReturns(&http.Response{Body: &readerCloser{reader: bytes.NewReader(nil)}, StatusCode: http.StatusOK}, nil)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			_, err := service.AppUsages(ctx, ReportRange{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeClient.DoArgsForCall(0).Context()).To(Equal(ctx))
+		})
+
 		It("errors when the request to the usage service fails", func() {
 			fakeClient.DoReturns(nil, errors.New("requesting things is hard"))
-			_, err := service.AppUsages()
+			_, err := service.AppUsages(context.Background(), ReportRange{})
 
 			Expect(err).To(MatchError(ContainSubstring("requesting things is hard")))
 			Expect(err).To(MatchError(ContainSubstring(UsageServiceRequestError)))
@@ -74,7 +100,7 @@ var _ = Describe("Service", func() {
 			body := &readerCloser{}
 			badStatusResponse := &http.Response{Body: body, StatusCode: http.StatusInternalServerError}
 			fakeClient.DoReturns(badStatusResponse, nil)
-			_, err := service.AppUsages()
+			_, err := service.AppUsages(context.Background(), ReportRange{})
 
 			Expect(body.isClosed).To(BeTrue())
 			Expect(err).To(MatchError(ContainSubstring(AppUsagesRequestError)))
@@ -139,7 +165,7 @@ var _ = Describe("Service", func() {
 			serviceUsagesResponse := &http.Response{Body: body, StatusCode: http.StatusOK}
 			fakeClient.DoReturns(serviceUsagesResponse, nil)
 
-			respBody, err := service.ServiceUsages()
+			respBody, err := service.ServiceUsages(context.Background(), ReportRange{})
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fakeClient.DoCallCount()).To(Equal(1))
@@ -212,7 +238,7 @@ var _ = Describe("Service", func() {
 
 		It("errors when the request to the usage service fails", func() {
 			fakeClient.DoReturns(nil, errors.New("requesting things is hard"))
-			_, err := service.ServiceUsages()
+			_, err := service.ServiceUsages(context.Background(), ReportRange{})
 
 			Expect(err).To(MatchError(ContainSubstring("requesting things is hard")))
 			Expect(err).To(MatchError(ContainSubstring(UsageServiceRequestError)))
@@ -222,7 +248,7 @@ var _ = Describe("Service", func() {
 			body := &readerCloser{}
 			badStatusResponse := &http.Response{Body: body, StatusCode: http.StatusInternalServerError}
 			fakeClient.DoReturns(badStatusResponse, nil)
-			_, err := service.ServiceUsages()
+			_, err := service.ServiceUsages(context.Background(), ReportRange{})
 
 			Expect(body.isClosed).To(BeTrue())
 			Expect(err).To(MatchError(ContainSubstring(ServiceUsagesRequestError)))
@@ -234,7 +260,7 @@ var _ = Describe("Service", func() {
 			badReaderResponse := &http.Response{Body: body, StatusCode: http.StatusOK}
 			fakeClient.DoReturns(badReaderResponse, nil)
 
-			_, err := service.ServiceUsages()
+			_, err := service.ServiceUsages(context.Background(), ReportRange{})
 			Expect(body.isClosed).To(BeTrue())
 			Expect(err).To(MatchError(ContainSubstring(ReadResponseError)))
 			Expect(err).To(MatchError(ContainSubstring("bad-reader")))
@@ -245,7 +271,7 @@ var _ = Describe("Service", func() {
 			badJSONResponse := &http.Response{Body: body, StatusCode: http.StatusOK}
 			fakeClient.DoReturns(badJSONResponse, nil)
 
-			_, err := service.ServiceUsages()
+			_, err := service.ServiceUsages(context.Background(), ReportRange{})
 			Expect(err).To(MatchError(ContainSubstring(UnmarshalResponseError)))
 		})
 	})
@@ -258,7 +284,7 @@ var _ = Describe("Service", func() {
 
 			expectedBody := []byte(`successful task usage content`)
 
-			respBody, err := service.TaskUsages()
+			respBody, err := service.TaskUsages(context.Background(), ReportRange{})
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fakeClient.DoCallCount()).To(Equal(1))
@@ -275,7 +301,7 @@ var _ = Describe("Service", func() {
 
 		It("errors when the request to the usage service fails", func() {
 			fakeClient.DoReturns(nil, errors.New("requesting things is hard"))
-			_, err := service.TaskUsages()
+			_, err := service.TaskUsages(context.Background(), ReportRange{})
 
 			Expect(err).To(MatchError(ContainSubstring("requesting things is hard")))
 			Expect(err).To(MatchError(ContainSubstring(UsageServiceRequestError)))
@@ -285,13 +311,40 @@ var _ = Describe("Service", func() {
 			body := &readerCloser{}
 			badStatusResponse := &http.Response{Body: body, StatusCode: http.StatusInternalServerError}
 			fakeClient.DoReturns(badStatusResponse, nil)
-			_, err := service.TaskUsages()
+			_, err := service.TaskUsages(context.Background(), ReportRange{})
 
 			Expect(body.isClosed).To(BeTrue())
 			Expect(err).To(MatchError(ContainSubstring(TaskUsagesRequestError)))
 			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(UsageServiceUnexpectedResponseStatusErrorFormat, http.StatusInternalServerError, TaskUsagesReportName))))
 		})
 	})
+
+	Describe("Org Usages", func() {
+		It("returns the org's report over the range", func() {
+			body := &readerCloser{reader: bytes.NewReader([]byte(`{"app_usages": []}`))}
+			fakeClient.DoReturns(&http.Response{Body: body, StatusCode: http.StatusOK}, nil)
+
+			content, err := service.OrgUsages(context.Background(), "some-org-guid", AppUsagesReportName, ReportRange{
+				Start: time.Date(2018, time.February, 1, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2018, time.February, 28, 0, 0, 0, 0, time.UTC),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal(`{"app_usages": []}`))
+			Expect(body.isClosed).To(BeTrue())
+
+			req := fakeClient.DoArgsForCall(0)
+			Expect(req.URL.Path).To(Equal(path.Join(usageURL.Path, OrganizationsPathPrefix, "some-org-guid", AppUsagesReportName)))
+			Expect(req.URL.Query()).To(Equal(url.Values{"start": {"2018-02-01"}, "end": {"2018-02-28"}}))
+		})
+
+		It("errors when the usage service returns an unexpected response", func() {
+			fakeClient.DoReturns(&http.Response{Body: &readerCloser{}, StatusCode: http.StatusNotFound}, nil)
+			_, err := service.OrgUsages(context.Background(), "some-org-guid", TaskUsagesReportName, ReportRange{})
+
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(OrgUsagesRequestFormat, TaskUsagesReportName, "some-org-guid"))))
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(UsageServiceUnexpectedResponseStatusErrorFormat, http.StatusNotFound, TaskUsagesReportName))))
+		})
+	})
 })
 
 type badReader struct{}
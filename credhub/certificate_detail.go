@@ -0,0 +1,157 @@
+package credhub
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"time"
+)
+
+const CertificateDetailsDataType = "certificate_details"
+
+// CertificateDetail is a CredHub certificate with every version CredHub
+// holds, latest first. CAName and Signs are the names of the certificates
+// that signed it and that it signed, from which the CA chain can be rebuilt.
+// Error is set instead of Versions when the certificate could not be read.
+type CertificateDetail struct {
+	Name     string                     `json:"name"`
+	CAName   string                     `json:"ca_name"`
+	Signs    []string                   `json:"signs"`
+	Versions []CertificateVersionDetail `json:"versions"`
+	Error    string                     `json:"error,omitempty"`
+}
+
+// CertificateVersionDetail is the public part of a version of a certificate.
+// Active is set on the version CredHub hands out, the latest one that is not
+// transitional.
+type CertificateVersionDetail struct {
+	ID                 string   `json:"id"`
+	CreatedAt          string   `json:"created_at"`
+	Active             bool     `json:"active"`
+	Transitional       bool     `json:"transitional"`
+	Subject            string   `json:"subject"`
+	Issuer             string   `json:"issuer"`
+	SerialNumber       string   `json:"serial_number"`
+	NotBefore          string   `json:"not_before"`
+	NotAfter           string   `json:"not_after"`
+	DNSNames           []string `json:"dns_names"`
+	IPAddresses        []string `json:"ip_addresses"`
+	EmailAddresses     []string `json:"email_addresses"`
+	URIs               []string `json:"uris"`
+	KeyAlgorithm       string   `json:"key_algorithm"`
+	KeySize            int      `json:"key_size"`
+	SignatureAlgorithm string   `json:"signature_algorithm"`
+	IsCA               bool     `json:"is_ca"`
+	SelfSigned         bool     `json:"self_signed"`
+	KeyUsage           []string `json:"key_usage"`
+	ExtKeyUsage        []string `json:"ext_key_usage"`
+	SubjectKeyID       string   `json:"subject_key_id"`
+	AuthorityKeyID     string   `json:"authority_key_id"`
+	SHA256Fingerprint  string   `json:"sha256_fingerprint"`
+}
+
+var keyUsageNames = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "digital_signature"},
+	{x509.KeyUsageContentCommitment, "content_commitment"},
+	{x509.KeyUsageKeyEncipherment, "key_encipherment"},
+	{x509.KeyUsageDataEncipherment, "data_encipherment"},
+	{x509.KeyUsageKeyAgreement, "key_agreement"},
+	{x509.KeyUsageCertSign, "cert_sign"},
+	{x509.KeyUsageCRLSign, "crl_sign"},
+	{x509.KeyUsageEncipherOnly, "encipher_only"},
+	{x509.KeyUsageDecipherOnly, "decipher_only"},
+}
+
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "any",
+	x509.ExtKeyUsageServerAuth:      "server_auth",
+	x509.ExtKeyUsageClientAuth:      "client_auth",
+	x509.ExtKeyUsageCodeSigning:     "code_signing",
+	x509.ExtKeyUsageEmailProtection: "email_protection",
+	x509.ExtKeyUsageTimeStamping:    "time_stamping",
+	x509.ExtKeyUsageOCSPSigning:     "ocsp_signing",
+}
+
+func newCertificateDetail(listed listedCertificate, versions []certificateVersion) CertificateDetail {
+	transitional := map[string]bool{}
+	for _, version := range listed.Versions {
+		transitional[version.ID] = version.Transitional
+	}
+
+	detail := CertificateDetail{Name: listed.Name, CAName: listed.SignedBy, Signs: listed.Signs}
+	activeFound := false
+	for _, version := range versions {
+		versionDetail := newCertificateVersionDetail(version)
+		versionDetail.Transitional = transitional[version.id]
+		if !activeFound && !versionDetail.Transitional {
+			versionDetail.Active = true
+			activeFound = true
+		}
+		detail.Versions = append(detail.Versions, versionDetail)
+	}
+	return detail
+}
+
+func newCertificateVersionDetail(version certificateVersion) CertificateVersionDetail {
+	cert := version.cert
+	detail := CertificateVersionDetail{
+		ID:                 version.id,
+		CreatedAt:          version.createdAt,
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SerialNumber:       cert.SerialNumber.String(),
+		NotBefore:          cert.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:           cert.NotAfter.UTC().Format(time.RFC3339),
+		DNSNames:           cert.DNSNames,
+		EmailAddresses:     cert.EmailAddresses,
+		KeyAlgorithm:       cert.PublicKeyAlgorithm.String(),
+		KeySize:            keySize(cert.PublicKey),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		IsCA:               cert.IsCA,
+		SelfSigned:         bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil,
+		SubjectKeyID:       hex.EncodeToString(cert.SubjectKeyId),
+		AuthorityKeyID:     hex.EncodeToString(cert.AuthorityKeyId),
+	}
+	for _, ip := range cert.IPAddresses {
+		detail.IPAddresses = append(detail.IPAddresses, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		detail.URIs = append(detail.URIs, uri.String())
+	}
+	for _, keyUsage := range keyUsageNames {
+		if cert.KeyUsage&keyUsage.usage != 0 {
+			detail.KeyUsage = append(detail.KeyUsage, keyUsage.name)
+		}
+	}
+	for _, extKeyUsage := range cert.ExtKeyUsage {
+		name, ok := extKeyUsageNames[extKeyUsage]
+		if !ok {
+			name = "other"
+		}
+		detail.ExtKeyUsage = append(detail.ExtKeyUsage, name)
+	}
+	fingerprint := sha256.Sum256(cert.Raw)
+	detail.SHA256Fingerprint = hex.EncodeToString(fingerprint[:])
+	return detail
+}
+
+// keySize is the size in bits of an RSA modulus or elliptic curve, and zero
+// for keys of other types.
+func keySize(publicKey interface{}) int {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	}
+	return 0
+}
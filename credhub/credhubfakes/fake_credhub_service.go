@@ -2,6 +2,7 @@
 package credhubfakes
 
 import (
+	"context"
 	"io"
 	"sync"
 
@@ -9,69 +10,96 @@ import (
 )
 
 type FakeCredhubService struct {
-	CertificatesStub        func() (io.Reader, error)
-	certificatesMutex       sync.RWMutex
-	certificatesArgsForCall []struct{}
-	certificatesReturns     struct {
+	CertificateDataStub        func(context.Context) (io.Reader, io.Reader, error)
+	certificateDataMutex       sync.RWMutex
+	certificateDataArgsForCall []struct {
+		arg1 context.Context
+	}
+	certificateDataReturns struct {
 		result1 io.Reader
-		result2 error
+		result2 io.Reader
+		result3 error
 	}
-	certificatesReturnsOnCall map[int]struct {
+	certificateDataReturnsOnCall map[int]struct {
 		result1 io.Reader
-		result2 error
+		result2 io.Reader
+		result3 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeCredhubService) Certificates() (io.Reader, error) {
-	fake.certificatesMutex.Lock()
-	ret, specificReturn := fake.certificatesReturnsOnCall[len(fake.certificatesArgsForCall)]
-	fake.certificatesArgsForCall = append(fake.certificatesArgsForCall, struct{}{})
-	fake.recordInvocation("Certificates", []interface{}{})
-	fake.certificatesMutex.Unlock()
-	if fake.CertificatesStub != nil {
-		return fake.CertificatesStub()
+func (fake *FakeCredhubService) CertificateData(arg1 context.Context) (io.Reader, io.Reader, error) {
+	fake.certificateDataMutex.Lock()
+	ret, specificReturn := fake.certificateDataReturnsOnCall[len(fake.certificateDataArgsForCall)]
+	fake.certificateDataArgsForCall = append(fake.certificateDataArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	fake.recordInvocation("CertificateData", []interface{}{arg1})
+	fake.certificateDataMutex.Unlock()
+	if fake.CertificateDataStub != nil {
+		return fake.CertificateDataStub(arg1)
 	}
 	if specificReturn {
-		return ret.result1, ret.result2
+		return ret.result1, ret.result2, ret.result3
 	}
-	return fake.certificatesReturns.result1, fake.certificatesReturns.result2
+	fakeReturns := fake.certificateDataReturns
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
+}
+
+func (fake *FakeCredhubService) CertificateDataCallCount() int {
+	fake.certificateDataMutex.RLock()
+	defer fake.certificateDataMutex.RUnlock()
+	return len(fake.certificateDataArgsForCall)
+}
+
+func (fake *FakeCredhubService) CertificateDataCalls(stub func(context.Context) (io.Reader, io.Reader, error)) {
+	fake.certificateDataMutex.Lock()
+	defer fake.certificateDataMutex.Unlock()
+	fake.CertificateDataStub = stub
 }
 
-func (fake *FakeCredhubService) CertificatesCallCount() int {
-	fake.certificatesMutex.RLock()
-	defer fake.certificatesMutex.RUnlock()
-	return len(fake.certificatesArgsForCall)
+func (fake *FakeCredhubService) CertificateDataArgsForCall(i int) context.Context {
+	fake.certificateDataMutex.RLock()
+	defer fake.certificateDataMutex.RUnlock()
+	argsForCall := fake.certificateDataArgsForCall[i]
+	return argsForCall.arg1
 }
 
-func (fake *FakeCredhubService) CertificatesReturns(result1 io.Reader, result2 error) {
-	fake.CertificatesStub = nil
-	fake.certificatesReturns = struct {
+func (fake *FakeCredhubService) CertificateDataReturns(result1 io.Reader, result2 io.Reader, result3 error) {
+	fake.certificateDataMutex.Lock()
+	defer fake.certificateDataMutex.Unlock()
+	fake.CertificateDataStub = nil
+	fake.certificateDataReturns = struct {
 		result1 io.Reader
-		result2 error
-	}{result1, result2}
+		result2 io.Reader
+		result3 error
+	}{result1, result2, result3}
 }
 
-func (fake *FakeCredhubService) CertificatesReturnsOnCall(i int, result1 io.Reader, result2 error) {
-	fake.CertificatesStub = nil
-	if fake.certificatesReturnsOnCall == nil {
-		fake.certificatesReturnsOnCall = make(map[int]struct {
+func (fake *FakeCredhubService) CertificateDataReturnsOnCall(i int, result1 io.Reader, result2 io.Reader, result3 error) {
+	fake.certificateDataMutex.Lock()
+	defer fake.certificateDataMutex.Unlock()
+	fake.CertificateDataStub = nil
+	if fake.certificateDataReturnsOnCall == nil {
+		fake.certificateDataReturnsOnCall = make(map[int]struct {
 			result1 io.Reader
-			result2 error
+			result2 io.Reader
+			result3 error
 		})
 	}
-	fake.certificatesReturnsOnCall[i] = struct {
+	fake.certificateDataReturnsOnCall[i] = struct {
 		result1 io.Reader
-		result2 error
-	}{result1, result2}
+		result2 io.Reader
+		result3 error
+	}{result1, result2, result3}
 }
 
 func (fake *FakeCredhubService) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
-	fake.certificatesMutex.RLock()
-	defer fake.certificatesMutex.RUnlock()
+	fake.certificateDataMutex.RLock()
+	defer fake.certificateDataMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -8,11 +8,18 @@ import (
 )
 
 type Data struct {
-	reader io.Reader
+	reader   io.Reader
+	dataType string
+	err      error
 }
 
-func NewData(reader io.Reader) Data {
-	return Data{reader: reader}
+func NewData(reader io.Reader, dataType string) Data {
+	return Data{reader: reader, dataType: dataType}
+}
+
+// NewFailedData records a retrieval that failed during a partial collection.
+func NewFailedData(dataType string, err error) Data {
+	return Data{dataType: dataType, err: err}
 }
 
 func (d Data) Name() string {
@@ -32,5 +39,9 @@ func (d Data) Type() string {
 }
 
 func (d Data) DataType() string {
-	return collector_tar.CertificatesDataType
+	return d.dataType
+}
+
+func (d Data) Err() error {
+	return d.err
 }
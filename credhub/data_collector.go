@@ -1,35 +1,52 @@
 package credhub
 
 import (
+	"context"
 	"io"
 	"log"
+
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
 )
 
 //go:generate counterfeiter . CredhubService
 type CredhubService interface {
-	Certificates() (io.Reader, error)
+	CertificateData(ctx context.Context) (io.Reader, io.Reader, error)
 }
 
 type DataCollector struct {
 	logger         log.Logger
 	credhubService CredhubService
 	credHubURL     string
+	allowPartial   bool
 }
 
-func NewDataCollector(logger log.Logger, cs CredhubService, credHubURL string) *DataCollector {
+// NewDataCollector returns a collector that fails when the certificates
+// cannot be retrieved, or with allowPartial returns both the certificates
+// and their details as failed data.
+func NewDataCollector(logger log.Logger, cs CredhubService, credHubURL string, allowPartial bool) *DataCollector {
 	return &DataCollector{
 		logger:         logger,
 		credhubService: cs,
 		credHubURL:     credHubURL,
+		allowPartial:   allowPartial,
 	}
 }
 
-func (dc *DataCollector) Collect() (Data, error) {
+func (dc *DataCollector) Collect(ctx context.Context) ([]Data, error) {
 	dc.logger.Printf("Collecting data from CredHub at %s", dc.credHubURL)
-	certReader, err := dc.credhubService.Certificates()
+	certReader, detailsReader, err := dc.credhubService.CertificateData(ctx)
 	if err != nil {
-		return Data{}, err
+		if dc.allowPartial && ctx.Err() == nil {
+			return []Data{
+				NewFailedData(collector_tar.CertificatesDataType, err),
+				NewFailedData(CertificateDetailsDataType, err),
+			}, nil
+		}
+		return []Data{}, err
 	}
 
-	return NewData(certReader), nil
+	return []Data{
+		NewData(certReader, collector_tar.CertificatesDataType),
+		NewData(detailsReader, CertificateDetailsDataType),
+	}, nil
 }
@@ -1,6 +1,7 @@
 package credhub_test
 
 import (
+	"context"
 	"log"
 	"strings"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/onsi/gomega/gbytes"
 	. "github.com/pivotal-cf/aqueduct-courier/credhub"
 	"github.com/pivotal-cf/aqueduct-courier/credhub/credhubfakes"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
 	"github.com/pkg/errors"
 )
 
@@ -28,23 +30,56 @@ var _ = Describe("DataCollector", func() {
 
 	It("returns data using the credhub service", func() {
 		certificatesReader := strings.NewReader("certificates data reader")
+		detailsReader := strings.NewReader("certificate details data reader")
 		credHubService := new(credhubfakes.FakeCredhubService)
-		credHubService.CertificatesReturns(certificatesReader, nil)
-		collector := NewDataCollector(*logger, credHubService, credHubURL)
+		credHubService.CertificateDataReturns(certificatesReader, detailsReader, nil)
+		collector := NewDataCollector(*logger, credHubService, credHubURL, false)
 
-		data, err := collector.Collect()
+		data, err := collector.Collect(context.Background())
 		Expect(err).NotTo(HaveOccurred())
 		Expect(bufferedOutput).To(gbytes.Say("Collecting data from CredHub at some-credhub-url"))
-		Expect(data).To(Equal(NewData(certificatesReader)))
+		Expect(data).To(Equal([]Data{
+			NewData(certificatesReader, collector_tar.CertificatesDataType),
+			NewData(detailsReader, CertificateDetailsDataType),
+		}))
+		Expect(data[0].Name()).To(Equal("p-bosh_certificates"))
+		Expect(data[1].Name()).To(Equal("p-bosh_certificate_details"))
 	})
 
 	It("returns an error when collecting certificates fails", func() {
 		credHubService := new(credhubfakes.FakeCredhubService)
-		credHubService.CertificatesReturns(nil, errors.New("collecting certificates is hard"))
-		collector := NewDataCollector(*logger, credHubService, credHubURL)
+		credHubService.CertificateDataReturns(nil, nil, errors.New("collecting certificates is hard"))
+		collector := NewDataCollector(*logger, credHubService, credHubURL, false)
 
-		_, err := collector.Collect()
+		_, err := collector.Collect(context.Background())
 		Expect(err).To(HaveOccurred())
 		Expect(err).To(MatchError("collecting certificates is hard"))
 	})
+
+	Context("when partial collection is allowed", func() {
+		It("returns failed data when collecting certificates fails", func() {
+			credHubService := new(credhubfakes.FakeCredhubService)
+			credHubService.CertificateDataReturns(nil, nil, errors.New("collecting certificates is hard"))
+			collector := NewDataCollector(*logger, credHubService, credHubURL, true)
+
+			data, err := collector.Collect(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(HaveLen(2))
+			Expect(data[0].DataType()).To(Equal(collector_tar.CertificatesDataType))
+			Expect(data[0].Err()).To(MatchError("collecting certificates is hard"))
+			Expect(data[1].DataType()).To(Equal(CertificateDetailsDataType))
+			Expect(data[1].Err()).To(MatchError("collecting certificates is hard"))
+		})
+
+		It("returns an error when the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			credHubService := new(credhubfakes.FakeCredhubService)
+			credHubService.CertificateDataReturns(nil, nil, ctx.Err())
+			collector := NewDataCollector(*logger, credHubService, credHubURL, true)
+
+			_, err := collector.Collect(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+	})
 })
@@ -1,6 +1,7 @@
 package credhub_test
 
 import (
+	"errors"
 	"strings"
 
 	. "github.com/onsi/ginkgo"
@@ -14,29 +15,38 @@ var _ = Describe("Data", func() {
 	It("returns a name", func() {
 		d := NewData(
 			strings.NewReader(""),
+			collector_tar.CertificatesDataType,
 		)
 		Expect(d.Name()).To(Equal(collector_tar.DirectorProductType + "_" + collector_tar.CertificatesDataType))
 	})
 
 	It("returns content for the data", func() {
 		dataReader := strings.NewReader("best-data")
-		d := NewData(dataReader)
+		d := NewData(dataReader, collector_tar.CertificatesDataType)
 		Expect(d.Content()).To(Equal(dataReader))
 	})
 
 	It("returns json as data type", func() {
-		d := NewData(nil)
+		d := NewData(nil, collector_tar.CertificatesDataType)
 		Expect(d.MimeType()).To(Equal("application/json"))
 	})
 
 	It("returns the product type", func() {
-		d := NewData(nil)
+		d := NewData(nil, collector_tar.CertificatesDataType)
 		Expect(d.Type()).To(Equal(collector_tar.DirectorProductType))
 	})
 
 	It("returns the data type", func() {
-		d := NewData(nil)
-		Expect(d.DataType()).To(Equal(collector_tar.CertificatesDataType))
+		d := NewData(nil, CertificateDetailsDataType)
+		Expect(d.DataType()).To(Equal(CertificateDetailsDataType))
+	})
+
+	It("has no error unless the retrieval failed", func() {
+		Expect(NewData(nil, collector_tar.CertificatesDataType).Err()).NotTo(HaveOccurred())
+
+		d := NewFailedData(collector_tar.CertificatesDataType, errors.New("collecting is hard"))
+		Expect(d.Err()).To(MatchError("collecting is hard"))
+		Expect(d.Name()).To(Equal(collector_tar.DirectorProductType + "_" + collector_tar.CertificatesDataType))
 	})
 
 })
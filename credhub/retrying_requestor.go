@@ -0,0 +1,43 @@
+package credhub
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/pivotal-cf/aqueduct-courier/network"
+	"github.com/pkg/errors"
+)
+
+const (
+	UnexpectedStatusErrorFormat = "%s %s returned with unexpected status %d"
+)
+
+// RetryingRequestor retries credhub requests according to a retry policy,
+// giving up once ctx is done. The credhub client hides the response status
+// behind its own error type, so server errors are checked here rather than by
+// the wrapped requestor.
+type RetryingRequestor struct {
+	ctx       context.Context
+	requestor credhubRequestor
+	policy    network.RetryPolicy
+}
+
+func NewRetryingRequestor(ctx context.Context, requestor credhubRequestor, policy network.RetryPolicy) *RetryingRequestor {
+	return &RetryingRequestor{ctx: ctx, requestor: requestor, policy: policy}
+}
+
+func (r *RetryingRequestor) Request(method string, pathStr string, query url.Values, body interface{}, checkServerErr bool) (*http.Response, error) {
+	resp, err := r.policy.Retry(r.ctx, method+" "+pathStr, func() (*http.Response, error) {
+		return r.requestor.Request(method, pathStr, query, body, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if checkServerErr && (resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices) {
+		resp.Body.Close()
+		return nil, errors.Errorf(UnexpectedStatusErrorFormat, method, pathStr, resp.StatusCode)
+	}
+	return resp, nil
+}
@@ -0,0 +1,67 @@
+package credhub_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/aqueduct-courier/credhub"
+	"github.com/pivotal-cf/aqueduct-courier/credhub/credhubfakes"
+	"github.com/pivotal-cf/aqueduct-courier/network"
+)
+
+var _ = Describe("RetryingRequestor", func() {
+	var (
+		requestor *credhubfakes.FakeCredhubRequestor
+		retrying  *RetryingRequestor
+	)
+
+	response := func(statusCode int) *http.Response {
+		return &http.Response{StatusCode: statusCode, Body: ioutil.NopCloser(strings.NewReader(""))}
+	}
+
+	BeforeEach(func() {
+		requestor = new(credhubfakes.FakeCredhubRequestor)
+		retrying = NewRetryingRequestor(context.Background(), requestor, network.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	})
+
+	It("retries server errors without letting the credhub client hide the status", func() {
+		requestor.RequestReturnsOnCall(0, response(http.StatusBadGateway), nil)
+		requestor.RequestReturnsOnCall(1, response(http.StatusOK), nil)
+		query := url.Values{"name": {"some-name"}}
+
+		resp, err := retrying.Request(http.MethodGet, "/api/v1/data", query, nil, true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		Expect(requestor.RequestCallCount()).To(Equal(2))
+		method, path, actualQuery, _, checkServerErr := requestor.RequestArgsForCall(1)
+		Expect(method).To(Equal(http.MethodGet))
+		Expect(path).To(Equal("/api/v1/data"))
+		Expect(actualQuery).To(Equal(query))
+		Expect(checkServerErr).To(BeFalse())
+	})
+
+	It("errors on an unsuccessful status when server errors are checked", func() {
+		requestor.RequestReturns(response(http.StatusForbidden), nil)
+
+		_, err := retrying.Request(http.MethodGet, "/api/v1/certificates", nil, nil, true)
+		Expect(err).To(MatchError(fmt.Sprintf(UnexpectedStatusErrorFormat, http.MethodGet, "/api/v1/certificates", http.StatusForbidden)))
+		Expect(requestor.RequestCallCount()).To(Equal(1))
+	})
+
+	It("returns an unsuccessful response when server errors are not checked", func() {
+		requestor.RequestReturns(response(http.StatusInternalServerError), nil)
+
+		resp, err := retrying.Request(http.MethodGet, "/api/v1/certificates", nil, nil, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+		Expect(requestor.RequestCallCount()).To(Equal(3))
+	})
+})
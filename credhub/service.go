@@ -2,29 +2,45 @@ package credhub
 
 import (
 	"bytes"
+	"context"
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
 	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 )
 
 const (
+	CertificatesPath = "/api/v1/certificates"
+	DataPath         = "/api/v1/data"
+	// InfoPath is requested by the CredHub client, which does not export it,
+	// to find the auth server.
+	InfoPath = "/info"
+
 	ListCertificatesError             = "Failed listing certificates from credhub"
 	ListCertificatesReadError         = "Failed to read certificates response from credhub"
 	ParseCertificatesError            = "Failed to parse certificates response from credhub"
 	GetCertificateDataErrorFormat     = "Failed retrieving certificate %s from credhub"
 	GetCertificateDataReadErrorFormat = "Failed to read certificate %s from credhub"
 	CertificatePEMParseError          = "PEM decoding failed"
+	NoCertificateVersionsError        = "No versions of the certificate were returned"
+
+	// ProgressInterval is how many certificates are read between progress
+	// messages.
+	ProgressInterval = 100
 )
 
 type Service struct {
-	requestor credhubRequestor
+	requestor      credhubRequestor
+	maxConcurrency int
+	logger         *log.Logger
 }
 
 //go:generate counterfeiter . credhubRequestor
@@ -32,86 +48,213 @@ type credhubRequestor interface {
 	Request(method string, pathStr string, query url.Values, body interface{}, checkServerErr bool) (*http.Response, error)
 }
 
-func NewCredhubService(requestor credhubRequestor) *Service {
-	return &Service{requestor: requestor}
+// NewCredhubService returns a service that reads up to maxConcurrency
+// certificates at once, logging its progress to logger.
+func NewCredhubService(requestor credhubRequestor, maxConcurrency int, logger *log.Logger) *Service {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	return &Service{requestor: requestor, maxConcurrency: maxConcurrency, logger: logger}
 }
 
-func (s *Service) Certificates() (io.Reader, error) {
+// Certificates returns the name, validity and issuer of the latest version
+// of each CredHub certificate.
+func (s *Service) Certificates(ctx context.Context) (io.Reader, error) {
+	certificates, _, err := s.CertificateData(ctx)
+	return certificates, err
+}
+
+// CertificateData reads every version of each CredHub certificate, with at
+// most maxConcurrency requests in flight, and returns both the summary
+// Certificates returns and the details of every version. Neither includes
+// private keys or the certificates themselves. A certificate that cannot be
+// read is recorded with its error rather than failing the others, unless ctx
+// is done.
+func (s *Service) CertificateData(ctx context.Context) (io.Reader, io.Reader, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, errors.Wrap(err, ListCertificatesError)
+	}
 	query := url.Values{}
-	resp, err := s.requestor.Request(http.MethodGet, "/api/v1/certificates", query, nil, true)
+	resp, err := s.requestor.Request(http.MethodGet, CertificatesPath, query, nil, true)
 	if err != nil {
-		return nil, errors.Wrap(err, ListCertificatesError)
+		return nil, nil, errors.Wrap(err, ListCertificatesError)
 	}
 
 	defer resp.Body.Close()
 
 	certificatesContent, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, errors.Wrap(err, ListCertificatesReadError)
+		return nil, nil, errors.Wrap(err, ListCertificatesReadError)
 	}
 
-	var parsedCertificates map[string][]struct{ Name string }
+	var parsedCertificates certificateList
 	err = json.Unmarshal(certificatesContent, &parsedCertificates)
 	if err != nil {
-		return nil, errors.Wrap(err, ParseCertificatesError)
+		return nil, nil, errors.Wrap(err, ParseCertificatesError)
 	}
 
-	var certificateInfos []map[string]string
-	for _, certMap := range parsedCertificates["certificates"] {
-		query := url.Values{}
-		query.Set("name", certMap.Name)
-
-		resp, err := s.requestor.Request(http.MethodGet, "/api/v1/data", query, nil, true)
-		if err != nil {
-			return nil, errors.Wrapf(err, GetCertificateDataErrorFormat, certMap.Name)
+	listed := parsedCertificates.Certificates
+	versions, errs := s.retrieveAll(ctx, listed)
+	if ctx.Err() != nil {
+		for _, err := range errs {
+			if err != nil {
+				return nil, nil, err
+			}
 		}
+		return nil, nil, errors.Wrap(ctx.Err(), ListCertificatesError)
+	}
 
-		cert, err := parseCertFromDataResponse(resp.Body)
-		if err != nil {
-			return nil, errors.Wrapf(err, GetCertificateDataReadErrorFormat, certMap.Name)
+	var certificateInfos []map[string]string
+	var certificateDetails []CertificateDetail
+	for index, certificate := range listed {
+		if errs[index] != nil {
+			certificateInfos = append(certificateInfos, map[string]string{
+				"name":  certificate.Name,
+				"error": errs[index].Error(),
+			})
+			certificateDetails = append(certificateDetails, CertificateDetail{Name: certificate.Name, CAName: certificate.SignedBy, Signs: certificate.Signs, Error: errs[index].Error()})
+			continue
 		}
-		resp.Body.Close()
 
+		cert := versions[index][0].cert
 		certificateInfos = append(certificateInfos, map[string]string{
-			"name":       certMap.Name,
+			"name":       certificate.Name,
 			"not_before": cert.NotBefore.Format(time.RFC3339),
 			"not_after":  cert.NotAfter.Format(time.RFC3339),
 			"issuer":     cert.Issuer.String(),
 		})
+		certificateDetails = append(certificateDetails, newCertificateDetail(certificate, versions[index]))
 	}
 
 	jsonBytes, err := json.Marshal(map[string][]map[string]string{"credhub_certificates": certificateInfos})
-	return bytes.NewReader(jsonBytes), nil
+	if err != nil {
+		return nil, nil, err
+	}
+	detailsBytes, err := json.Marshal(map[string][]CertificateDetail{"credhub_certificate_details": certificateDetails})
+	if err != nil {
+		return nil, nil, err
+	}
+	return bytes.NewReader(jsonBytes), bytes.NewReader(detailsBytes), nil
 }
 
-func parseCertFromDataResponse(body io.Reader) (*x509.Certificate, error) {
-	dataContent, err := ioutil.ReadAll(body)
+// retrieveAll reads the versions of the listed certificates, in the order
+// listed, logging progress every ProgressInterval certificates. Once ctx is
+// done no more requests are made, and the certificates not yet read get its
+// error.
+func (s *Service) retrieveAll(ctx context.Context, listed []listedCertificate) ([][]certificateVersion, []error) {
+	versions := make([][]certificateVersion, len(listed))
+	errs := make([]error, len(listed))
+
+	var mutex sync.Mutex
+	retrieved := 0
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.maxConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				versions[index], errs[index] = s.certificateVersions(ctx, listed[index].Name)
+
+				mutex.Lock()
+				retrieved++
+				if retrieved%ProgressInterval == 0 && retrieved < len(listed) {
+					s.logger.Printf("Retrieved %d of %d certificates from CredHub", retrieved, len(listed))
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for index := range listed {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	return versions, errs
+}
+
+func (s *Service) certificateVersions(ctx context.Context, name string) ([]certificateVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrapf(err, GetCertificateDataErrorFormat, name)
+	}
+	query := url.Values{}
+	query.Set("name", name)
+
+	resp, err := s.requestor.Request(http.MethodGet, DataPath, query, nil, true)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(err, GetCertificateDataErrorFormat, name)
 	}
+	defer resp.Body.Close()
 
-	var parsedDataResponse map[string][]struct {
-		Value struct {
-			Certificate string
-		}
+	versions, err := parseCertsFromDataResponse(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, GetCertificateDataReadErrorFormat, name)
 	}
+	return versions, nil
+}
 
-	err = json.Unmarshal(dataContent, &parsedDataResponse)
+type certificateList struct {
+	Certificates []listedCertificate `json:"certificates"`
+}
+
+type listedCertificate struct {
+	Name     string   `json:"name"`
+	SignedBy string   `json:"signed_by"`
+	Signs    []string `json:"signs"`
+	Versions []struct {
+		ID           string `json:"id"`
+		Transitional bool   `json:"transitional"`
+	} `json:"versions"`
+}
+
+type certificateVersion struct {
+	id        string
+	createdAt string
+	cert      *x509.Certificate
+}
+
+// parseCertsFromDataResponse parses every version in a data response, latest
+// first.
+func parseCertsFromDataResponse(body io.Reader) ([]certificateVersion, error) {
+	dataContent, err := ioutil.ReadAll(body)
 	if err != nil {
 		return nil, err
 	}
 
-	certPEMString := parsedDataResponse["data"][0].Value.Certificate
-
-	block, _ := pem.Decode([]byte(certPEMString))
-	if block == nil {
-		return nil, errors.New(CertificatePEMParseError)
+	var parsedDataResponse struct {
+		Data []struct {
+			ID               string `json:"id"`
+			VersionCreatedAt string `json:"version_created_at"`
+			Value            struct {
+				Certificate string `json:"certificate"`
+			} `json:"value"`
+		} `json:"data"`
 	}
 
-	cert, err := x509.ParseCertificate(block.Bytes)
+	err = json.Unmarshal(dataContent, &parsedDataResponse)
 	if err != nil {
 		return nil, err
 	}
+	if len(parsedDataResponse.Data) == 0 {
+		return nil, errors.New(NoCertificateVersionsError)
+	}
+
+	var versions []certificateVersion
+	for _, data := range parsedDataResponse.Data {
+		block, _ := pem.Decode([]byte(data.Value.Certificate))
+		if block == nil {
+			return nil, errors.New(CertificatePEMParseError)
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, certificateVersion{id: data.ID, createdAt: data.VersionCreatedAt, cert: cert})
+	}
 
-	return cert, nil
+	return versions, nil
 }
@@ -2,6 +2,7 @@ package credhub_test
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -11,21 +12,33 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log"
 	"math/big"
 	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 	. "github.com/pivotal-cf/aqueduct-courier/credhub"
 	"github.com/pivotal-cf/aqueduct-courier/credhub/credhubfakes"
 	"github.com/pkg/errors"
 )
 
 var _ = Describe("Service", func() {
+	var (
+		logger         *log.Logger
+		bufferedOutput *gbytes.Buffer
+	)
+
+	BeforeEach(func() {
+		bufferedOutput = gbytes.NewBuffer()
+		logger = log.New(bufferedOutput, "", 0)
+	})
 
 	It("returns the parsed certificate information from credhub", func() {
 		expectedNotBefore1 := time.Now().UTC()
@@ -80,9 +93,9 @@ var _ = Describe("Service", func() {
 			return &response, nil
 		}
 
-		service := NewCredhubService(credhubRequestor)
+		service := NewCredhubService(credhubRequestor, 1, logger)
 
-		reader, err := service.Certificates()
+		reader, err := service.Certificates(context.Background())
 		Expect(err).NotTo(HaveOccurred())
 
 		certContent, err := ioutil.ReadAll(reader)
@@ -110,6 +123,203 @@ var _ = Describe("Service", func() {
 		}))
 	})
 
+	It("returns the details of every version of each certificate", func() {
+		notBefore := time.Now().UTC().Truncate(time.Second)
+		currentCert := makeCert(notBefore, notBefore.Add(time.Hour), "org1-name")
+		transitionalCert := makeCert(notBefore, notBefore.Add(2*time.Hour), "org1-name")
+		previousCert := makeCert(notBefore.Add(-time.Hour), notBefore.Add(time.Minute), "org1-name")
+
+		certListResponse, err := json.Marshal(map[string]interface{}{
+			"certificates": []map[string]interface{}{{
+				"name":      "/some-cert",
+				"signed_by": "/some-ca",
+				"signs":     []string{"/some-leaf"},
+				"versions": []map[string]interface{}{
+					{"id": "transitional-id", "transitional": true},
+					{"id": "current-id", "transitional": false},
+					{"id": "previous-id", "transitional": false},
+				},
+			}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		dataResponse, err := json.Marshal(map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"id": "transitional-id", "version_created_at": "2019-03-01T00:00:00Z", "value": map[string]string{"certificate": transitionalCert, "private_key": "some-private-key"}},
+				{"id": "current-id", "version_created_at": "2019-02-01T00:00:00Z", "value": map[string]string{"certificate": currentCert, "private_key": "some-private-key"}},
+				{"id": "previous-id", "version_created_at": "2019-01-01T00:00:00Z", "value": map[string]string{"certificate": previousCert, "private_key": "some-private-key"}},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		credhubRequestor := new(credhubfakes.FakeCredhubRequestor)
+		credhubRequestor.RequestStub = func(method string, pathStr string, query url.Values, body interface{}, checkServerErr bool) (*http.Response, error) {
+			switch pathStr {
+			case "/api/v1/certificates":
+				return &http.Response{Body: ioutil.NopCloser(bytes.NewReader(certListResponse))}, nil
+			case "/api/v1/data":
+				Expect(query.Get("name")).To(Equal("/some-cert"))
+				return &http.Response{Body: ioutil.NopCloser(bytes.NewReader(dataResponse))}, nil
+			}
+			Fail(fmt.Sprintf("Unexpected request path %s", pathStr))
+			return nil, nil
+		}
+		service := NewCredhubService(credhubRequestor, 1, logger)
+
+		certificatesReader, detailsReader, err := service.CertificateData(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credhubRequestor.RequestCallCount()).To(Equal(2))
+
+		var credhubCertificates map[string][]map[string]string
+		Expect(json.NewDecoder(certificatesReader).Decode(&credhubCertificates)).To(Succeed())
+		Expect(credhubCertificates["credhub_certificates"]).To(HaveLen(1))
+		Expect(credhubCertificates["credhub_certificates"][0]["not_after"]).To(Equal(notBefore.Add(2 * time.Hour).Format(time.RFC3339)))
+
+		detailsContent, err := ioutil.ReadAll(detailsReader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(detailsContent)).NotTo(ContainSubstring("some-private-key"))
+		Expect(string(detailsContent)).NotTo(ContainSubstring("BEGIN CERTIFICATE"))
+
+		var details map[string][]CertificateDetail
+		Expect(json.Unmarshal(detailsContent, &details)).To(Succeed())
+		Expect(details["credhub_certificate_details"]).To(HaveLen(1))
+		detail := details["credhub_certificate_details"][0]
+		Expect(detail.Name).To(Equal("/some-cert"))
+		Expect(detail.CAName).To(Equal("/some-ca"))
+		Expect(detail.Signs).To(Equal([]string{"/some-leaf"}))
+		Expect(detail.Versions).To(HaveLen(3))
+
+		Expect(detail.Versions[0].ID).To(Equal("transitional-id"))
+		Expect(detail.Versions[0].Transitional).To(BeTrue())
+		Expect(detail.Versions[0].Active).To(BeFalse())
+		Expect(detail.Versions[1].ID).To(Equal("current-id"))
+		Expect(detail.Versions[1].CreatedAt).To(Equal("2019-02-01T00:00:00Z"))
+		Expect(detail.Versions[1].Transitional).To(BeFalse())
+		Expect(detail.Versions[1].Active).To(BeTrue())
+		Expect(detail.Versions[2].Active).To(BeFalse())
+		Expect(detail.Versions[2].NotAfter).To(Equal(notBefore.Add(time.Minute).Format(time.RFC3339)))
+
+		version := detail.Versions[1]
+		Expect(version.Subject).To(Equal("O=Acme Co"))
+		Expect(version.Issuer).To(Equal("O=org1-name,C=Melchizedek"))
+		Expect(version.SerialNumber).To(Equal("1"))
+		Expect(version.NotBefore).To(Equal(notBefore.Format(time.RFC3339)))
+		Expect(version.NotAfter).To(Equal(notBefore.Add(time.Hour).Format(time.RFC3339)))
+		Expect(version.IPAddresses).To(Equal([]string{"127.0.0.1"}))
+		Expect(version.KeyAlgorithm).To(Equal("RSA"))
+		Expect(version.KeySize).To(Equal(2048))
+		Expect(version.SignatureAlgorithm).To(Equal("SHA256-RSA"))
+		Expect(version.IsCA).To(BeFalse())
+		Expect(version.SelfSigned).To(BeFalse())
+		Expect(version.KeyUsage).To(Equal([]string{"digital_signature", "key_encipherment"}))
+		Expect(version.ExtKeyUsage).To(Equal([]string{"server_auth"}))
+		Expect(version.SHA256Fingerprint).To(HaveLen(64))
+	})
+
+	It("records an error if a certificate has no versions", func() {
+		credhubRequestor := new(credhubfakes.FakeCredhubRequestor)
+		credhubRequestor.RequestStub = func(method string, pathStr string, query url.Values, body interface{}, checkServerErr bool) (*http.Response, error) {
+			response := http.Response{}
+			switch pathStr {
+			case "/api/v1/certificates":
+				response.Body = &readerCloser{reader: bytes.NewReader(makeCertListResponse("cert1-name-path"))}
+			case "/api/v1/data":
+				response.Body = &readerCloser{reader: strings.NewReader(`{"data": []}`)}
+			default:
+				Fail(fmt.Sprintf("Unexpected request path %s", pathStr))
+			}
+			return &response, nil
+		}
+		service := NewCredhubService(credhubRequestor, 1, logger)
+
+		recorded := recordedCertificateError(service)
+		Expect(recorded).To(ContainSubstring(NoCertificateVersionsError))
+		Expect(recorded).To(ContainSubstring(fmt.Sprintf(GetCertificateDataReadErrorFormat, "cert1-name-path")))
+	})
+
+	It("reads certificates concurrently up to the limit, keeping their order and logging progress", func() {
+		cert := makeCert(time.Now().UTC(), time.Now().UTC().Add(time.Hour), "org1-name")
+		dataResponse, err := json.Marshal(map[string][]map[string]map[string]string{
+			"data": {{"value": {"certificate": cert}}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		var certNames []string
+		for i := 0; i < 250; i++ {
+			certNames = append(certNames, fmt.Sprintf("cert%d", i))
+		}
+		certListResponse := makeCertListResponse(certNames...)
+
+		var mutex sync.Mutex
+		inFlight, maxInFlight := 0, 0
+		credhubRequestor := new(credhubfakes.FakeCredhubRequestor)
+		credhubRequestor.RequestStub = func(method string, pathStr string, query url.Values, body interface{}, checkServerErr bool) (*http.Response, error) {
+			if pathStr == "/api/v1/certificates" {
+				return &http.Response{Body: ioutil.NopCloser(bytes.NewReader(certListResponse))}, nil
+			}
+			mutex.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mutex.Unlock()
+			time.Sleep(time.Millisecond)
+			mutex.Lock()
+			inFlight--
+			mutex.Unlock()
+			return &http.Response{Body: ioutil.NopCloser(bytes.NewReader(dataResponse))}, nil
+		}
+		service := NewCredhubService(credhubRequestor, 3, logger)
+
+		reader, err := service.Certificates(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credhubRequestor.RequestCallCount()).To(Equal(251))
+		Expect(maxInFlight).To(BeNumerically("<=", 3))
+
+		var credhubCertificates map[string][]map[string]string
+		Expect(json.NewDecoder(reader).Decode(&credhubCertificates)).To(Succeed())
+		Expect(credhubCertificates["credhub_certificates"]).To(HaveLen(250))
+		for i, certificate := range credhubCertificates["credhub_certificates"] {
+			Expect(certificate["name"]).To(Equal(certNames[i]))
+		}
+
+		Expect(bufferedOutput).To(gbytes.Say("Retrieved 100 of 250 certificates from CredHub"))
+		Expect(bufferedOutput).To(gbytes.Say("Retrieved 200 of 250 certificates from CredHub"))
+		Expect(bufferedOutput).NotTo(gbytes.Say("Retrieved 250"))
+	})
+
+	It("records the error for a certificate that cannot be read and reads the others", func() {
+		cert := makeCert(time.Now().UTC(), time.Now().UTC().Add(time.Hour), "org1-name")
+		dataResponse, err := json.Marshal(map[string][]map[string]map[string]string{
+			"data": {{"value": {"certificate": cert}}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		credhubRequestor := new(credhubfakes.FakeCredhubRequestor)
+		credhubRequestor.RequestStub = func(method string, pathStr string, query url.Values, body interface{}, checkServerErr bool) (*http.Response, error) {
+			if pathStr == "/api/v1/certificates" {
+				return &http.Response{Body: ioutil.NopCloser(bytes.NewReader(makeCertListResponse("good-cert", "bad-cert")))}, nil
+			}
+			if query.Get("name") == "bad-cert" {
+				return &http.Response{Body: ioutil.NopCloser(strings.NewReader(`{"data": [{"value": {"certificate": "not a certificate"}}]}`))}, nil
+			}
+			return &http.Response{Body: ioutil.NopCloser(bytes.NewReader(dataResponse))}, nil
+		}
+		service := NewCredhubService(credhubRequestor, 2, logger)
+
+		reader, err := service.Certificates(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+
+		var credhubCertificates map[string][]map[string]string
+		Expect(json.NewDecoder(reader).Decode(&credhubCertificates)).To(Succeed())
+		Expect(credhubCertificates["credhub_certificates"]).To(HaveLen(2))
+		Expect(credhubCertificates["credhub_certificates"][0]["name"]).To(Equal("good-cert"))
+		Expect(credhubCertificates["credhub_certificates"][0]).To(HaveKey("not_after"))
+		Expect(credhubCertificates["credhub_certificates"][1]).To(Equal(map[string]string{
+			"name":  "bad-cert",
+			"error": fmt.Sprintf(GetCertificateDataReadErrorFormat, "bad-cert") + ": " + CertificatePEMParseError,
+		}))
+	})
+
 	It("returns an error when fetching a list of certificates fails", func() {
 		credhubRequestor := new(credhubfakes.FakeCredhubRequestor)
 		credhubRequestor.RequestStub = func(method string, pathStr string, query url.Values, body interface{}, checkServerErr bool) (*http.Response, error) {
@@ -121,9 +331,9 @@ var _ = Describe("Service", func() {
 			}
 			return nil, nil
 		}
-		service := NewCredhubService(credhubRequestor)
+		service := NewCredhubService(credhubRequestor, 1, logger)
 
-		_, err := service.Certificates()
+		_, err := service.Certificates(context.Background())
 		Expect(err).To(HaveOccurred())
 		Expect(err).To(MatchError(ContainSubstring("requesting stuff is hard")))
 		Expect(err).To(MatchError(ContainSubstring(ListCertificatesError)))
@@ -141,9 +351,9 @@ var _ = Describe("Service", func() {
 			}
 			return &response, nil
 		}
-		service := NewCredhubService(credhubRequestor)
+		service := NewCredhubService(credhubRequestor, 1, logger)
 
-		_, err := service.Certificates()
+		_, err := service.Certificates(context.Background())
 		Expect(err).To(HaveOccurred())
 		Expect(err).To(MatchError(ContainSubstring("Reading is hard")))
 		Expect(err).To(MatchError(ContainSubstring(ListCertificatesReadError)))
@@ -161,14 +371,14 @@ var _ = Describe("Service", func() {
 			}
 			return &response, nil
 		}
-		service := NewCredhubService(credhubRequestor)
+		service := NewCredhubService(credhubRequestor, 1, logger)
 
-		_, err := service.Certificates()
+		_, err := service.Certificates(context.Background())
 		Expect(err).To(HaveOccurred())
 		Expect(err).To(MatchError(ContainSubstring(ParseCertificatesError)))
 	})
 
-	It("returns an error if fetching certificate data fails", func() {
+	It("records an error if fetching certificate data fails", func() {
 		certListResponse := makeCertListResponse("cert1-name-path")
 
 		credhubRequestor := new(credhubfakes.FakeCredhubRequestor)
@@ -184,15 +394,14 @@ var _ = Describe("Service", func() {
 			}
 			return &response, nil
 		}
-		service := NewCredhubService(credhubRequestor)
+		service := NewCredhubService(credhubRequestor, 1, logger)
 
-		_, err := service.Certificates()
-		Expect(err).To(HaveOccurred())
-		Expect(err).To(MatchError(ContainSubstring("requesting data stuff is hard")))
-		Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(GetCertificateDataErrorFormat, "cert1-name-path"))))
+		recorded := recordedCertificateError(service)
+		Expect(recorded).To(ContainSubstring("requesting data stuff is hard"))
+		Expect(recorded).To(ContainSubstring(fmt.Sprintf(GetCertificateDataErrorFormat, "cert1-name-path")))
 	})
 
-	It("returns an error if reading the certificate data fails", func() {
+	It("records an error if reading the certificate data fails", func() {
 		certListResponse := makeCertListResponse("cert1-name-path")
 
 		credhubRequestor := new(credhubfakes.FakeCredhubRequestor)
@@ -208,15 +417,14 @@ var _ = Describe("Service", func() {
 			}
 			return &response, nil
 		}
-		service := NewCredhubService(credhubRequestor)
+		service := NewCredhubService(credhubRequestor, 1, logger)
 
-		_, err := service.Certificates()
-		Expect(err).To(HaveOccurred())
-		Expect(err).To(MatchError(ContainSubstring("Reading is hard")))
-		Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(GetCertificateDataReadErrorFormat, "cert1-name-path"))))
+		recorded := recordedCertificateError(service)
+		Expect(recorded).To(ContainSubstring("Reading is hard"))
+		Expect(recorded).To(ContainSubstring(fmt.Sprintf(GetCertificateDataReadErrorFormat, "cert1-name-path")))
 	})
 
-	It("returns an error if unmarshalling the certificate data fails", func() {
+	It("records an error if unmarshalling the certificate data fails", func() {
 		certListResponse := makeCertListResponse("cert1-name-path")
 
 		credhubRequestor := new(credhubfakes.FakeCredhubRequestor)
@@ -232,14 +440,13 @@ var _ = Describe("Service", func() {
 			}
 			return &response, nil
 		}
-		service := NewCredhubService(credhubRequestor)
+		service := NewCredhubService(credhubRequestor, 1, logger)
 
-		_, err := service.Certificates()
-		Expect(err).To(HaveOccurred())
-		Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(GetCertificateDataReadErrorFormat, "cert1-name-path"))))
+		recorded := recordedCertificateError(service)
+		Expect(recorded).To(ContainSubstring(fmt.Sprintf(GetCertificateDataReadErrorFormat, "cert1-name-path")))
 	})
 
-	It("returns an error if pem decoding the certificate data fails", func() {
+	It("records an error if pem decoding the certificate data fails", func() {
 		certListResponse := makeCertListResponse("cert1-name-path")
 
 		credhubRequestor := new(credhubfakes.FakeCredhubRequestor)
@@ -255,15 +462,14 @@ var _ = Describe("Service", func() {
 			}
 			return &response, nil
 		}
-		service := NewCredhubService(credhubRequestor)
+		service := NewCredhubService(credhubRequestor, 1, logger)
 
-		_, err := service.Certificates()
-		Expect(err).To(HaveOccurred())
-		Expect(err).To(MatchError(ContainSubstring(CertificatePEMParseError)))
-		Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(GetCertificateDataReadErrorFormat, "cert1-name-path"))))
+		recorded := recordedCertificateError(service)
+		Expect(recorded).To(ContainSubstring(CertificatePEMParseError))
+		Expect(recorded).To(ContainSubstring(fmt.Sprintf(GetCertificateDataReadErrorFormat, "cert1-name-path")))
 	})
 
-	It("returns an error if parsing the certificate data fails", func() {
+	It("records an error if parsing the certificate data fails", func() {
 		certListResponse := makeCertListResponse("cert1-name-path")
 
 		buffer := bytes.NewBuffer([]byte{})
@@ -288,14 +494,48 @@ var _ = Describe("Service", func() {
 			}
 			return &response, nil
 		}
-		service := NewCredhubService(credhubRequestor)
+		service := NewCredhubService(credhubRequestor, 1, logger)
 
-		_, err = service.Certificates()
-		Expect(err).To(HaveOccurred())
-		Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(GetCertificateDataReadErrorFormat, "cert1-name-path"))))
+		recorded := recordedCertificateError(service)
+		Expect(recorded).To(ContainSubstring(fmt.Sprintf(GetCertificateDataReadErrorFormat, "cert1-name-path")))
+	})
+
+	It("stops requesting certificate data once the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		credhubRequestor := new(credhubfakes.FakeCredhubRequestor)
+		credhubRequestor.RequestStub = func(method string, pathStr string, query url.Values, body interface{}, checkServerErr bool) (*http.Response, error) {
+			cancel()
+			return &http.Response{Body: ioutil.NopCloser(bytes.NewReader(makeCertListResponse("cert1-name-path")))}, nil
+		}
+		service := NewCredhubService(credhubRequestor, 1, logger)
+
+		_, err := service.Certificates(ctx)
+		Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(GetCertificateDataErrorFormat, "cert1-name-path"))))
+		Expect(err).To(MatchError(ContainSubstring(context.Canceled.Error())))
+		Expect(credhubRequestor.RequestCallCount()).To(Equal(1))
 	})
 })
 
+// recordedCertificateError returns the error recorded for the only
+// certificate, which must be the same in the certificates and their details.
+func recordedCertificateError(service *Service) string {
+	certificatesReader, detailsReader, err := service.CertificateData(context.Background())
+	Expect(err).NotTo(HaveOccurred())
+
+	var certificates map[string][]map[string]string
+	Expect(json.NewDecoder(certificatesReader).Decode(&certificates)).To(Succeed())
+	Expect(certificates["credhub_certificates"]).To(HaveLen(1))
+	Expect(certificates["credhub_certificates"][0]).NotTo(HaveKey("not_after"))
+
+	var details map[string][]CertificateDetail
+	Expect(json.NewDecoder(detailsReader).Decode(&details)).To(Succeed())
+	Expect(details["credhub_certificate_details"]).To(HaveLen(1))
+	Expect(details["credhub_certificate_details"][0].Versions).To(BeEmpty())
+	Expect(details["credhub_certificate_details"][0].Error).To(Equal(certificates["credhub_certificates"][0]["error"]))
+
+	return certificates["credhub_certificates"][0]["error"]
+}
+
 func makeCertListResponse(certNames ...string) []byte {
 	certListResponseStruct := map[string][]map[string]interface{}{}
 	certListResponseStruct["certificates"] = []map[string]interface{}{}
@@ -0,0 +1,391 @@
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	FileExtension = ".enc"
+
+	ReadKeyFailureFormat     = "Could not read key %s"
+	InvalidPublicKeyFormat   = "%s does not contain an RSA public key"
+	InvalidPrivateKeyFormat  = "%s does not contain an RSA private key"
+	NotEncryptedMessage      = "Archive is not encrypted"
+	InvalidEnvelopeMessage   = "Archive is not a valid encrypted envelope"
+	TamperedEnvelopeMessage  = "Archive could not be decrypted, it is truncated or has been modified"
+	WrongKeyFormat           = "Archive is encrypted for key %s, not %s"
+	DecryptFailureMessage    = "Failed to decrypt archive"
+	EncryptionFailureMessage = "Failed to encrypt archive"
+	EncryptedArchiveFormat   = "Archive is encrypted for key %s, a private key is needed to read it"
+)
+
+// An envelope is the magic, the SHA-256 of the recipient's public key, the
+// length of the wrapped data key and the data key wrapped with RSA-OAEP,
+// followed by the archive sealed with AES-GCM in segments. Each segment's
+// nonce counts the segments and marks the last one, so a reordered or
+// truncated envelope fails to decrypt.
+var magic = []byte("AQCENV1\n")
+
+const (
+	segmentSize  = 64 * 1024
+	dataKeySize  = 32
+	counterBytes = 11
+)
+
+// ReadPublicKey reads a PEM encoded RSA public key, as PKIX or PKCS#1.
+func ReadPublicKey(path string) (*rsa.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, errors.Errorf(InvalidPublicKeyFormat, path)
+	}
+	publicKey, ok := key.(*rsa.PublicKey)
+	if err != nil || !ok {
+		return nil, errors.Errorf(InvalidPublicKeyFormat, path)
+	}
+	return publicKey, nil
+}
+
+// ReadPrivateKey reads a PEM encoded RSA private key, as PKCS#1 or PKCS#8.
+func ReadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, errors.Errorf(InvalidPrivateKeyFormat, path)
+	}
+	privateKey, ok := key.(*rsa.PrivateKey)
+	if err != nil || !ok {
+		return nil, errors.Errorf(InvalidPrivateKeyFormat, path)
+	}
+	return privateKey, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, ReadKeyFailureFormat, path)
+	}
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, errors.Errorf(ReadKeyFailureFormat, path)
+	}
+	return block, nil
+}
+
+// Fingerprint identifies a public key by the SHA-256 of its PKIX encoding.
+func Fingerprint(key *rsa.PublicKey) string {
+	return formatFingerprint(keyDigest(key))
+}
+
+func keyDigest(key *rsa.PublicKey) []byte {
+	der, _ := x509.MarshalPKIXPublicKey(key)
+	sum := sha256.Sum256(der)
+	return sum[:]
+}
+
+func formatFingerprint(digest []byte) string {
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(digest)
+}
+
+// Detect reports whether the file is an encrypted envelope, leaving the file
+// positioned at its start.
+func Detect(file io.ReadSeeker) (bool, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	header := make([]byte, len(magic))
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	return bytes.Equal(header[:n], magic), nil
+}
+
+// Recipient returns the fingerprint of the key an envelope is encrypted for.
+func Recipient(r io.Reader) (string, error) {
+	h, err := readHeader(r)
+	if err != nil {
+		return "", err
+	}
+	return formatFingerprint(h.recipient), nil
+}
+
+type header struct {
+	recipient  []byte
+	wrappedKey []byte
+}
+
+func readHeader(r io.Reader) (header, error) {
+	prefix := make([]byte, len(magic)+sha256.Size+2)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return header{}, errors.New(InvalidEnvelopeMessage)
+	}
+	if !bytes.Equal(prefix[:len(magic)], magic) {
+		return header{}, errors.New(NotEncryptedMessage)
+	}
+
+	h := header{recipient: prefix[len(magic) : len(magic)+sha256.Size]}
+	h.wrappedKey = make([]byte, binary.BigEndian.Uint16(prefix[len(magic)+sha256.Size:]))
+	if _, err := io.ReadFull(r, h.wrappedKey); err != nil {
+		return header{}, errors.New(InvalidEnvelopeMessage)
+	}
+	return h, nil
+}
+
+// NewWriter encrypts what is written to it for the recipient into w. Closing
+// it seals the last segment but does not close w.
+func NewWriter(w io.Writer, recipient *rsa.PublicKey) (io.WriteCloser, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, errors.Wrap(err, EncryptionFailureMessage)
+	}
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, recipient, dataKey, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, EncryptionFailureMessage)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, errors.Wrap(err, EncryptionFailureMessage)
+	}
+
+	var prefix bytes.Buffer
+	prefix.Write(magic)
+	prefix.Write(keyDigest(recipient))
+	binary.Write(&prefix, binary.BigEndian, uint16(len(wrappedKey)))
+	prefix.Write(wrappedKey)
+	if _, err := w.Write(prefix.Bytes()); err != nil {
+		return nil, errors.Wrap(err, EncryptionFailureMessage)
+	}
+
+	return &writer{dst: w, aead: aead, segment: make([]byte, 0, segmentSize)}, nil
+}
+
+type writer struct {
+	dst     io.Writer
+	aead    cipher.AEAD
+	counter uint64
+	segment []byte
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// A full segment is only sealed once more data follows, so the last
+		// segment is always sealed by Close.
+		if len(w.segment) == segmentSize {
+			if err := w.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(w.segment[len(w.segment):segmentSize], p)
+		w.segment = w.segment[:len(w.segment)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (w *writer) Close() error {
+	return w.seal(true)
+}
+
+func (w *writer) seal(last bool) error {
+	sealed := w.aead.Seal(nil, nonce(w.counter, last), w.segment, nil)
+	w.counter++
+	w.segment = w.segment[:0]
+	_, err := w.dst.Write(sealed)
+	return errors.Wrap(err, EncryptionFailureMessage)
+}
+
+// NewReader decrypts the envelope read from r with the recipient's key.
+func NewReader(r io.Reader, key *rsa.PrivateKey) (io.Reader, error) {
+	h, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(h.recipient, keyDigest(&key.PublicKey)) {
+		return nil, errors.Errorf(WrongKeyFormat, formatFingerprint(h.recipient), Fingerprint(&key.PublicKey))
+	}
+
+	dataKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, h.wrappedKey, nil)
+	if err != nil {
+		return nil, errors.New(TamperedEnvelopeMessage)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, errors.Wrap(err, DecryptFailureMessage)
+	}
+	return &reader{src: bufio.NewReader(r), aead: aead}, nil
+}
+
+type reader struct {
+	src       *bufio.Reader
+	aead      cipher.AEAD
+	counter   uint64
+	plaintext []byte
+	done      bool
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for len(r.plaintext) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plaintext)
+	r.plaintext = r.plaintext[n:]
+	return n, nil
+}
+
+func (r *reader) open() error {
+	sealed := make([]byte, segmentSize+r.aead.Overhead())
+	n, err := io.ReadFull(r.src, sealed)
+	last := err == io.ErrUnexpectedEOF
+	if err == io.EOF {
+		return errors.New(TamperedEnvelopeMessage)
+	}
+	if err != nil && !last {
+		return errors.Wrap(err, DecryptFailureMessage)
+	}
+	if !last {
+		_, err := r.src.Peek(1)
+		last = err == io.EOF
+	}
+
+	plaintext, err := r.aead.Open(sealed[:0], nonce(r.counter, last), sealed[:n], nil)
+	if err != nil {
+		return errors.New(TamperedEnvelopeMessage)
+	}
+	r.counter++
+	r.plaintext = plaintext
+	r.done = last
+	return nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func nonce(counter uint64, last bool) []byte {
+	n := make([]byte, counterBytes+1)
+	binary.BigEndian.PutUint64(n[counterBytes-8:counterBytes], counter)
+	if last {
+		n[counterBytes] = 1
+	}
+	return n
+}
+
+// File is an archive opened through Open.
+type File struct {
+	*os.File
+	temporary bool
+}
+
+// Open opens the archive at path for reading. An encrypted archive is
+// decrypted with key into a temporary file, which is removed when the File is
+// closed.
+func Open(path string, key *rsa.PrivateKey) (*File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := Detect(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if !encrypted {
+		return &File{File: file}, nil
+	}
+	defer file.Close()
+
+	if key == nil {
+		recipient, err := Recipient(file)
+		if err != nil {
+			return nil, err
+		}
+		return nil, EncryptedError{Recipient: recipient}
+	}
+
+	plaintext, err := NewReader(file, key)
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := ioutil.TempFile("", "decrypted-archive")
+	if err != nil {
+		return nil, errors.Wrap(err, DecryptFailureMessage)
+	}
+	result := &File{File: decrypted, temporary: true}
+	if _, err := io.Copy(decrypted, plaintext); err != nil {
+		result.Close()
+		return nil, err
+	}
+	if _, err := decrypted.Seek(0, io.SeekStart); err != nil {
+		result.Close()
+		return nil, errors.Wrap(err, DecryptFailureMessage)
+	}
+	return result, nil
+}
+
+func (f *File) Close() error {
+	err := f.File.Close()
+	if f.temporary {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// EncryptedError is returned by Open for an encrypted archive when there is
+// no key to decrypt it with.
+type EncryptedError struct {
+	Recipient string
+}
+
+func (e EncryptedError) Error() string {
+	return fmt.Sprintf(EncryptedArchiveFormat, e.Recipient)
+}
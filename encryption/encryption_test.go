@@ -0,0 +1,205 @@
+package encryption_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/pivotal-cf/aqueduct-courier/encryption"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Encryption", func() {
+	var (
+		tempDir    string
+		key        *rsa.PrivateKey
+		otherKey   *rsa.PrivateKey
+		plaintext  []byte
+		ciphertext []byte
+	)
+
+	encrypt := func(recipient *rsa.PublicKey, contents []byte) []byte {
+		var buf bytes.Buffer
+		writer, err := NewWriter(&buf, recipient)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = writer.Write(contents)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+		return buf.Bytes()
+	}
+
+	decrypt := func(contents []byte, key *rsa.PrivateKey) ([]byte, error) {
+		reader, err := NewReader(bytes.NewReader(contents), key)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.ReadAll(reader)
+	}
+
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(tempDir, name)
+		Expect(ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600)).To(Succeed())
+		return path
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "encryption")
+		Expect(err).NotTo(HaveOccurred())
+
+		if key == nil {
+			key, err = rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).NotTo(HaveOccurred())
+			otherKey, err = rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		plaintext = make([]byte, 150*1024)
+		_, err = rand.Read(plaintext)
+		Expect(err).NotTo(HaveOccurred())
+		ciphertext = encrypt(&key.PublicKey, plaintext)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	table.DescribeTable("round trips contents of any length",
+		func(length int) {
+			contents := bytes.Repeat([]byte("a"), length)
+			encrypted := encrypt(&key.PublicKey, contents)
+			Expect(bytes.Contains(encrypted, contents[:length/2])).To(Equal(length == 0))
+
+			decrypted, err := decrypt(encrypted, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decrypted).To(Equal(contents))
+		},
+		table.Entry("empty", 0),
+		table.Entry("shorter than a segment", 100),
+		table.Entry("exactly one segment", 64*1024),
+		table.Entry("exactly two segments", 128*1024),
+		table.Entry("several segments", 200*1024+1),
+	)
+
+	It("decrypts what was written across several writes", func() {
+		decrypted, err := decrypt(ciphertext, key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decrypted).To(Equal(plaintext))
+	})
+
+	It("refuses to decrypt with a key other than the recipient's", func() {
+		_, err := decrypt(ciphertext, otherKey)
+		Expect(err).To(MatchError(fmt.Sprintf(WrongKeyFormat, Fingerprint(&key.PublicKey), Fingerprint(&otherKey.PublicKey))))
+	})
+
+	It("fails to decrypt a truncated envelope", func() {
+		_, err := decrypt(ciphertext[:len(ciphertext)-64*1024], key)
+		Expect(err).To(MatchError(TamperedEnvelopeMessage))
+	})
+
+	It("fails to decrypt a modified envelope", func() {
+		ciphertext[len(ciphertext)-100] ^= 0xff
+		_, err := decrypt(ciphertext, key)
+		Expect(err).To(MatchError(TamperedEnvelopeMessage))
+	})
+
+	It("fails to decrypt contents that are not an envelope", func() {
+		_, err := decrypt(bytes.Repeat([]byte("not-an-envelope"), 10), key)
+		Expect(err).To(MatchError(NotEncryptedMessage))
+	})
+
+	It("detects envelopes", func() {
+		Expect(Detect(bytes.NewReader(ciphertext))).To(BeTrue())
+		Expect(Detect(bytes.NewReader(plaintext))).To(BeFalse())
+		Expect(Detect(bytes.NewReader(nil))).To(BeFalse())
+	})
+
+	It("reports the recipient of an envelope", func() {
+		Expect(Recipient(bytes.NewReader(ciphertext))).To(Equal(Fingerprint(&key.PublicKey)))
+	})
+
+	Describe("Open", func() {
+		var path string
+
+		BeforeEach(func() {
+			path = filepath.Join(tempDir, "archive.tar.enc")
+			Expect(ioutil.WriteFile(path, ciphertext, 0644)).To(Succeed())
+		})
+
+		It("decrypts an envelope into a file that is removed when closed", func() {
+			archive, err := Open(path, key)
+			Expect(err).NotTo(HaveOccurred())
+			decryptedPath := archive.Name()
+			contents, err := ioutil.ReadAll(archive)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal(plaintext))
+
+			Expect(archive.Close()).To(Succeed())
+			Expect(decryptedPath).NotTo(BeAnExistingFile())
+			Expect(path).To(BeAnExistingFile())
+		})
+
+		It("opens an archive that is not encrypted as it is", func() {
+			Expect(ioutil.WriteFile(path, plaintext, 0644)).To(Succeed())
+			archive, err := Open(path, nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer archive.Close()
+			Expect(archive.Name()).To(Equal(path))
+		})
+
+		It("reports the recipient when there is no key", func() {
+			_, err := Open(path, nil)
+			Expect(err).To(Equal(EncryptedError{Recipient: Fingerprint(&key.PublicKey)}))
+			Expect(err).To(MatchError(fmt.Sprintf(EncryptedArchiveFormat, Fingerprint(&key.PublicKey))))
+		})
+	})
+
+	Describe("reading keys", func() {
+		It("reads PKIX and PKCS#1 public keys", func() {
+			pkix, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ReadPublicKey(writePEM("pkix.pem", "PUBLIC KEY", pkix))).To(Equal(&key.PublicKey))
+			Expect(ReadPublicKey(writePEM("pkcs1.pem", "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&key.PublicKey)))).To(Equal(&key.PublicKey))
+		})
+
+		It("reads PKCS#1 and PKCS#8 private keys", func() {
+			pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+			Expect(err).NotTo(HaveOccurred())
+			privateKey, err := ReadPrivateKey(writePEM("pkcs8.pem", "PRIVATE KEY", pkcs8))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(privateKey.PublicKey).To(Equal(key.PublicKey))
+			privateKey, err = ReadPrivateKey(writePEM("pkcs1.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(privateKey.PublicKey).To(Equal(key.PublicKey))
+		})
+
+		It("rejects keys of the wrong kind", func() {
+			path := writePEM("private.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+			_, err := ReadPublicKey(path)
+			Expect(err).To(MatchError(fmt.Sprintf(InvalidPublicKeyFormat, path)))
+
+			path = writePEM("public.pem", "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&key.PublicKey))
+			_, err = ReadPrivateKey(path)
+			Expect(err).To(MatchError(fmt.Sprintf(InvalidPrivateKeyFormat, path)))
+		})
+
+		It("errors when the key cannot be read", func() {
+			path := filepath.Join(tempDir, "missing.pem")
+			_, err := ReadPublicKey(path)
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(ReadKeyFailureFormat, path))))
+
+			Expect(ioutil.WriteFile(path, []byte("not pem"), 0600)).To(Succeed())
+			_, err = ReadPrivateKey(path)
+			Expect(err).To(MatchError(fmt.Sprintf(ReadKeyFailureFormat, path)))
+		})
+	})
+})
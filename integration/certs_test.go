@@ -0,0 +1,152 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+	"github.com/pivotal-cf/aqueduct-courier/cmd"
+	"github.com/pivotal-cf/aqueduct-courier/report"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+	"github.com/pivotal-cf/telemetry-utils/tar"
+)
+
+var _ = Describe("Certs", func() {
+	var (
+		tempDir      string
+		soon         string
+		later        string
+		certificates string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		soon = time.Now().UTC().Add(10 * 24 * time.Hour).Format(time.RFC3339)
+		later = time.Now().UTC().Add(100 * 24 * time.Hour).Format(time.RFC3339)
+		certificates = fmt.Sprintf(`{"certificates": [
+			{"product_guid": "cf-guid", "property_reference": ".properties.later", "valid_until": %q},
+			{"product_guid": "cf-guid", "property_reference": ".properties.soon", "valid_until": %q}
+		]}`, later, soon)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	Context("with a file from the collect command", func() {
+		var tarFilePath string
+
+		BeforeEach(func() {
+			tarFilePath = filepath.Join(tempDir, "foundation-data")
+			tarFile, err := os.Create(tarFilePath)
+			Expect(err).NotTo(HaveOccurred())
+			writer := tar.NewTarWriter(tarFile)
+			Expect(writer.AddFile([]byte(`{}`), filepath.Join(collector_tar.OpsManagerCollectorDataSetId, collector_tar.MetadataFileName))).To(Succeed())
+			Expect(writer.AddFile([]byte(certificates), filepath.Join(collector_tar.OpsManagerCollectorDataSetId, "ops_manager_certificates"))).To(Succeed())
+			Expect(writer.Close()).To(Succeed())
+			Expect(tarFile.Close()).To(Succeed())
+		})
+
+		It("lists the certificates expiring within the window and exits with the expiring status", func() {
+			command := exec.Command(aqueductBinaryPath, "certs", "--path="+tarFilePath)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(cmd.CertificatesExpiringExitCode))
+			Expect(session.Out).To(gbytes.Say(`EXPIRES\s+DAYS LEFT\s+STATUS\s+SOURCE\s+NAME\s+ISSUER\n`))
+			Expect(session.Out).To(gbytes.Say(`expiring\s+Ops Manager\s+cf-guid .properties.soon`))
+			Expect(session.Out).NotTo(gbytes.Say(`.properties.later`))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.CertificatesExpiringFormat, 1, "30d")))
+		})
+
+		It("lists them as JSON, soonest first", func() {
+			command := exec.Command(aqueductBinaryPath, "certs", "--path="+tarFilePath, "--within=200d", "--format=json")
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(cmd.CertificatesExpiringExitCode))
+
+			var listed []report.Certificate
+			Expect(json.Unmarshal(session.Out.Contents(), &listed)).To(Succeed())
+			Expect(listed).To(HaveLen(2))
+			Expect(listed[0].Name).To(Equal("cf-guid .properties.soon"))
+			Expect(listed[1].Name).To(Equal("cf-guid .properties.later"))
+			Expect(listed[1].Status).To(Equal(report.StatusExpiring))
+		})
+
+		It("succeeds when no certificates expire within the window", func() {
+			command := exec.Command(aqueductBinaryPath, "certs", "--path="+tarFilePath, "--within=72h")
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			Expect(session.Out).To(gbytes.Say(fmt.Sprintf(cmd.NoCertificatesExpiringFormat, "72h")))
+		})
+
+		It("lists certificates whose expiry cannot be read and exits with the expiring status", func() {
+			tarFile, err := os.Create(tarFilePath)
+			Expect(err).NotTo(HaveOccurred())
+			writer := tar.NewTarWriter(tarFile)
+			Expect(writer.AddFile([]byte(`{}`), filepath.Join(collector_tar.OpsManagerCollectorDataSetId, collector_tar.MetadataFileName))).To(Succeed())
+			Expect(writer.AddFile([]byte(`{"certificates": [{"product_guid": "cf-guid", "property_reference": ".properties.unreadable", "valid_until": "soon"}]}`), filepath.Join(collector_tar.OpsManagerCollectorDataSetId, "ops_manager_certificates"))).To(Succeed())
+			Expect(writer.Close()).To(Succeed())
+			Expect(tarFile.Close()).To(Succeed())
+
+			command := exec.Command(aqueductBinaryPath, "certs", "--path="+tarFilePath)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(cmd.CertificatesExpiringExitCode))
+			Expect(session.Out).To(gbytes.Say(`unknown\s+Ops Manager\s+cf-guid .properties.unreadable`))
+			Expect(session.Out).NotTo(gbytes.Say(fmt.Sprintf(cmd.NoCertificatesExpiringFormat, "30d")))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.UnknownExpiriesFormat, 1)))
+		})
+
+		It("fails if the window or format is invalid", func() {
+			command := exec.Command(aqueductBinaryPath, "certs", "--path="+tarFilePath, "--within=soon")
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.InvalidDurationErrorFormat, "soon", cmd.CertsWithinFlag)))
+
+			command = exec.Command(aqueductBinaryPath, "certs", "--path="+tarFilePath, "--format=yaml")
+			session, err = gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.InvalidCertsFormatFormat, "yaml")))
+		})
+	})
+
+	Context("with a foundation", func() {
+		It("reads the certificates from Ops Manager", func() {
+			opsManagerServer := setupOpsManagerServer()
+			defer opsManagerServer.Close()
+			opsManagerServer.RouteToHandler(http.MethodGet, "/api/v0/deployed/certificates", func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(certificates))
+			})
+
+			command := exec.Command(aqueductBinaryPath, "certs", "--url="+opsManagerServer.URL(), "--username=some-username", "--password=some-password", "--insecure-skip-tls-verify")
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(cmd.CertificatesExpiringExitCode))
+			Expect(session.Out).To(gbytes.Say(`cf-guid .properties.soon`))
+		})
+
+		It("fails if neither a file nor a foundation is configured", func() {
+			command := exec.Command(aqueductBinaryPath, "certs")
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.RequiredConfigErrorFormat, "--"+cmd.OpsManagerURLFlag)))
+		})
+	})
+})
@@ -1,9 +1,14 @@
 package integration
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -13,10 +18,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/pivotal-cf/aqueduct-courier/cf"
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
 
 	"github.com/elazarl/goproxy"
 	"github.com/mholt/archiver"
@@ -26,8 +33,15 @@ import (
 	"github.com/onsi/gomega/gbytes"
 	"github.com/onsi/gomega/gexec"
 	"github.com/onsi/gomega/ghttp"
+	"github.com/pivotal-cf/aqueduct-courier/cfapi"
 	"github.com/pivotal-cf/aqueduct-courier/cmd"
+	"github.com/pivotal-cf/aqueduct-courier/compression"
+	"github.com/pivotal-cf/aqueduct-courier/consumption"
+	"github.com/pivotal-cf/aqueduct-courier/credhub"
+	"github.com/pivotal-cf/aqueduct-courier/encryption"
 	"github.com/pivotal-cf/aqueduct-courier/operations"
+	"github.com/pivotal-cf/aqueduct-courier/opsmanager"
+	"github.com/pivotal-cf/aqueduct-courier/signing"
 	"github.com/pivotal-cf/telemetry-utils/collector_tar"
 )
 
@@ -233,6 +247,521 @@ var _ = Describe("Collect", func() {
 		})
 	})
 
+	Context("when partial collection is allowed", func() {
+		BeforeEach(func() {
+			opsManagerServer.RouteToHandler(http.MethodGet, "/api/v0/vm_types", ghttp.RespondWith(http.StatusNotFound, ""))
+			defaultEnvVars[cmd.AllowPartialKey] = "true"
+		})
+
+		It("writes the data it could collect, records the failures and exits with the partial status", func() {
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(cmd.PartialExitCode))
+			Expect(session.Out).To(gbytes.Say(fmt.Sprintf(cmd.PartialCollectionFailureFormat, collector_tar.OpsManagerProductType+" "+collector_tar.VmTypesDataType, "")))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(operations.PartialCollectionFormat, 1)))
+			Expect(session.Err).NotTo(gbytes.Say("USAGE EXAMPLES"))
+
+			tarFilePath := validatedTarFilePath(outputDirPath)
+			Expect(session.Out).To(gbytes.Say(fmt.Sprintf("Wrote output to %s", escapeWindowsPathRegex(tarFilePath))))
+
+			validateSession, err := gexec.Start(exec.Command(aqueductBinaryPath, "validate", "--path="+tarFilePath), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(validateSession).Should(gexec.Exit(0))
+			Expect(validateSession.Out).To(gbytes.Say(fmt.Sprintf("%s %s: not collected: ", collector_tar.OpsManagerProductType, collector_tar.VmTypesDataType)))
+		})
+
+		It("still fails without output when partial collection is not allowed", func() {
+			defaultEnvVars[cmd.AllowPartialKey] = "false"
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(operations.OpsManagerCollectFailureMessage))
+			assertOutputDirEmpty(outputDirPath)
+		})
+	})
+
+	Context("when compression is configured", func() {
+		It("writes a compressed tar file that can be validated and inspected", func() {
+			defaultEnvVars[cmd.CompressionKey] = "zstd"
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			fileInfos, err := ioutil.ReadDir(outputDirPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fileInfos).To(HaveLen(1))
+			Expect(fileInfos[0].Name()).To(MatchRegexp(fmt.Sprintf(`%s%s.tar.zst$`, cmd.OutputFilePrefix, UnixTimestampRegexp)))
+			tarFilePath := filepath.Join(outputDirPath, fileInfos[0].Name())
+
+			contents, err := ioutil.ReadFile(tarFilePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents[:4]).To(Equal([]byte{0x28, 0xb5, 0x2f, 0xfd}))
+
+			validateSession, err := gexec.Start(exec.Command(aqueductBinaryPath, "validate", "--path="+tarFilePath), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(validateSession).Should(gexec.Exit(0))
+			Expect(validateSession.Out).To(gbytes.Say("Success!"))
+
+			inspectSession, err := gexec.Start(exec.Command(aqueductBinaryPath, "inspect", "--path="+tarFilePath), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(inspectSession).Should(gexec.Exit(0))
+			Expect(inspectSession.Out).To(gbytes.Say(collector_tar.OpsManagerCollectorDataSetId))
+		})
+
+		It("fails without output when the compression is not supported", func() {
+			defaultEnvVars[cmd.CompressionKey] = "bzip2"
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(compression.InvalidFormatFormat, "bzip2")))
+			assertOutputDirEmpty(outputDirPath)
+		})
+	})
+
+	Context("when encryption is configured", func() {
+		It("writes a compressed file encrypted for the recipient that can be decrypted and validated", func() {
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).NotTo(HaveOccurred())
+			keyDir, err := ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(keyDir)
+
+			defaultEnvVars[cmd.CompressionKey] = "gzip"
+			defaultEnvVars[cmd.EncryptToKey] = writePublicKey(keyDir, "public.pem", &key.PublicKey)
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			fileInfos, err := ioutil.ReadDir(outputDirPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fileInfos).To(HaveLen(1))
+			Expect(fileInfos[0].Name()).To(MatchRegexp(fmt.Sprintf(`%s%s.tar.gz.enc$`, cmd.OutputFilePrefix, UnixTimestampRegexp)))
+			encryptedFilePath := filepath.Join(outputDirPath, fileInfos[0].Name())
+
+			encryptedFile, err := os.Open(encryptedFilePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(encryption.Recipient(encryptedFile)).To(Equal(encryption.Fingerprint(&key.PublicKey)))
+			encryptedFile.Close()
+
+			decryptSession, err := gexec.Start(exec.Command(aqueductBinaryPath, "decrypt", "--path="+encryptedFilePath, "--private-key="+writePrivateKey(keyDir, "private.pem", key)), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(decryptSession).Should(gexec.Exit(0))
+
+			decryptedFilePath := strings.TrimSuffix(encryptedFilePath, encryption.FileExtension)
+			validateSession, err := gexec.Start(exec.Command(aqueductBinaryPath, "validate", "--path="+decryptedFilePath), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(validateSession).Should(gexec.Exit(0))
+			Expect(validateSession.Out).To(gbytes.Say("Success!"))
+		})
+
+		It("fails without output when the recipient key cannot be read", func() {
+			keyPath := filepath.Join(outputDirPath, "missing.pem")
+			defaultEnvVars[cmd.EncryptToKey] = keyPath
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(encryption.ReadKeyFailureFormat, escapeWindowsPathRegex(keyPath))))
+			assertOutputDirEmpty(outputDirPath)
+		})
+	})
+
+	Context("when signing is configured", func() {
+		var (
+			keyDir              string
+			verificationKeyPath string
+		)
+
+		BeforeEach(func() {
+			var err error
+			keyDir, err = ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+			publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			defaultEnvVars[cmd.SigningKeyKey] = writeSigningKey(keyDir, "signing.pem", privateKey)
+			verificationKeyPath = writeVerificationKey(keyDir, "verification.pem", publicKey)
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(keyDir)).To(Succeed())
+		})
+
+		It("writes a signature next to the file that validate verifies", func() {
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			matches, err := filepath.Glob(filepath.Join(outputDirPath, cmd.OutputFilePrefix+"*.tar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matches).To(HaveLen(1))
+			tarFilePath := matches[0]
+			signaturePath := tarFilePath + signing.SignatureFileExtension
+			Expect(signaturePath).To(BeAnExistingFile())
+			Expect(session.Out).To(gbytes.Say(fmt.Sprintf("Wrote signature to %s", escapeWindowsPathRegex(signaturePath))))
+
+			validateSession, err := gexec.Start(exec.Command(aqueductBinaryPath, "validate", "--path="+tarFilePath, "--verification-key="+verificationKeyPath), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(validateSession).Should(gexec.Exit(0))
+			Expect(validateSession.Out).To(gbytes.Say("Verified signature of"))
+			Expect(validateSession.Out).To(gbytes.Say("Success!"))
+
+			otherPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			otherKeyPath := writeVerificationKey(keyDir, "other.pem", otherPublicKey)
+			validateSession, err = gexec.Start(exec.Command(aqueductBinaryPath, "validate", "--path="+tarFilePath, "--verification-key="+otherKeyPath), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(validateSession).Should(gexec.Exit(1))
+			Expect(validateSession.Err).To(gbytes.Say(fmt.Sprintf(cmd.SignatureVerificationFailureFormat, escapeWindowsPathRegex(tarFilePath))))
+		})
+
+		It("fails without output when the signing key cannot be read", func() {
+			Expect(ioutil.WriteFile(defaultEnvVars[cmd.SigningKeyKey], []byte("not a key"), 0600)).To(Succeed())
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(signing.ReadKeyFailureFormat, escapeWindowsPathRegex(defaultEnvVars[cmd.SigningKeyKey]))))
+			assertOutputDirEmpty(outputDirPath)
+		})
+	})
+
+	Context("when a redaction policy is configured", func() {
+		var (
+			policyDir  string
+			policyPath string
+		)
+
+		BeforeEach(func() {
+			opsManagerServer.RouteToHandler(http.MethodGet, "/api/v0/diagnostic_report", func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"director_configuration": {"ntp_servers": ["10.0.0.1"], "blobstore_type": "local"}, "hostname": "opsman.example.com"}`))
+			})
+			var err error
+			policyDir, err = ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+			policyPath = filepath.Join(policyDir, "policy.yml")
+			Expect(ioutil.WriteFile(policyPath, []byte(`
+rules:
+- data_type: diagnostic_report
+  path: $.hostname
+  action: mask
+`), 0644)).To(Succeed())
+			defaultEnvVars[cmd.RedactionPolicyKey] = policyPath
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(policyDir)).To(Succeed())
+		})
+
+		It("applies the policy along with the default rules and records its hash in the metadata", func() {
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			tmpDir, err := ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(tmpDir)
+			Expect((&archiver.Tar{}).Unarchive(validatedTarFilePath(outputDirPath), tmpDir)).To(Succeed())
+
+			report, err := ioutil.ReadFile(filepath.Join(tmpDir, collector_tar.OpsManagerCollectorDataSetId, collector_tar.OpsManagerProductType+"_"+collector_tar.DiagnosticReportDataType))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report).To(MatchJSON(`{"director_configuration": {"blobstore_type": "local"}, "hostname": "REDACTED"}`))
+
+			policy, err := opsmanager.ReadRedactionPolicy(policyPath)
+			Expect(err).NotTo(HaveOccurred())
+			extensionsContents, err := ioutil.ReadFile(filepath.Join(tmpDir, manifest.ExtensionsFile(collector_tar.OpsManagerCollectorDataSetId)))
+			Expect(err).NotTo(HaveOccurred())
+			var extensions manifest.Extensions
+			Expect(json.Unmarshal(extensionsContents, &extensions)).To(Succeed())
+			Expect(extensions.RedactionPolicyHash).To(Equal(policy.Hash()))
+		})
+
+		It("fails without output when the policy is invalid", func() {
+			Expect(ioutil.WriteFile(policyPath, []byte("rules: [{data_type: app_usage, path: $.name, action: drop}]"), 0644)).To(Succeed())
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(opsmanager.UnknownRedactionDataTypeFormat, collector_tar.AppUsageDataType, escapeWindowsPathRegex(policyPath))))
+			assertOutputDirEmpty(outputDirPath)
+		})
+	})
+
+	Context("when pseudonymization is configured", func() {
+		var mappingDir string
+
+		BeforeEach(func() {
+			opsManagerServer.RouteToHandler(http.MethodGet, "/api/v0/diagnostic_report", func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"hostname": "opsman.example.com", "director_configuration": {"blobstore_type": "local"}}`))
+			})
+			var err error
+			mappingDir, err = ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+			defaultEnvVars[cmd.PseudonymizeKey] = "true"
+			defaultEnvVars[cmd.PseudonymizeSaltKey] = "some-salt-that-is-long-enough"
+			defaultEnvVars[cmd.PseudonymMappingDirKey] = mappingDir
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(mappingDir)).To(Succeed())
+		})
+
+		It("writes pseudonymized data and a local mapping back to the original values", func() {
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			tarFilePath := validatedTarFilePath(outputDirPath)
+			mappingPath := filepath.Join(mappingDir, filepath.Base(tarFilePath)+cmd.PseudonymMappingFileSuffix)
+			Expect(session.Out).To(gbytes.Say(fmt.Sprintf("Wrote pseudonym mapping to %s", escapeWindowsPathRegex(mappingPath))))
+
+			tmpDir, err := ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(tmpDir)
+			Expect((&archiver.Tar{}).Unarchive(tarFilePath, tmpDir)).To(Succeed())
+
+			reportContents, err := ioutil.ReadFile(filepath.Join(tmpDir, collector_tar.OpsManagerCollectorDataSetId, collector_tar.OpsManagerProductType+"_"+collector_tar.DiagnosticReportDataType))
+			Expect(err).NotTo(HaveOccurred())
+			var report map[string]interface{}
+			Expect(json.Unmarshal(reportContents, &report)).To(Succeed())
+			Expect(report["hostname"]).To(MatchRegexp(`^host-[0-9a-f]{16}$`))
+
+			mappingContents, err := ioutil.ReadFile(mappingPath)
+			Expect(err).NotTo(HaveOccurred())
+			var mapping map[string]string
+			Expect(json.Unmarshal(mappingContents, &mapping)).To(Succeed())
+			Expect(mapping).To(HaveKeyWithValue(report["hostname"], "opsman.example.com"))
+
+			validateSession, err := gexec.Start(exec.Command(aqueductBinaryPath, "validate", "--path="+tarFilePath), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(validateSession).Should(gexec.Exit(0))
+		})
+
+		It("fails without output when no salt is configured", func() {
+			delete(defaultEnvVars, cmd.PseudonymizeSaltKey)
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.RequiredConfigErrorFormat, "--"+cmd.PseudonymizeSaltFlag)))
+			assertOutputDirEmpty(outputDirPath)
+		})
+
+		It("fails without output when the mapping would be written to the output directory", func() {
+			defaultEnvVars[cmd.PseudonymMappingDirKey] = outputDirPath
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(cmd.PseudonymMappingInOutputMessage))
+			assertOutputDirEmpty(outputDirPath)
+		})
+
+		It("fails without output when the mapping would be written inside the output directory", func() {
+			defaultEnvVars[cmd.PseudonymMappingDirKey] = filepath.Join(outputDirPath, "mappings")
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(cmd.PseudonymMappingInOutputMessage))
+			assertOutputDirEmpty(outputDirPath)
+		})
+	})
+
+	Context("when a dry run is requested", func() {
+		It("lists the requests it would make and the file it would write without making or writing them", func() {
+			defaultEnvVars[cmd.DryRunKey] = "true"
+			defaultEnvVars[cmd.WithCredhubInfoKey] = "true"
+			defaultEnvVars[cmd.CfApiURLKey] = "https://cf.example.com"
+			defaultEnvVars[cmd.UsageServiceURLKey] = "https://usage.example.com"
+			defaultEnvVars[cmd.UsageServiceClientIDKey] = "some-client"
+			defaultEnvVars[cmd.UsageServiceClientSecretKey] = "some-secret"
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			Expect(session.Out).To(gbytes.Say(cmd.DryRunCollectMessage))
+			for _, path := range []string{
+				opsmanager.TokenPath,
+				opsmanager.PendingChangesPath,
+				opsmanager.DeployedProductsPath,
+				opsmanager.VmTypesPath,
+				opsmanager.DiagnosticReportPath,
+				opsmanager.InstallationsPath,
+				opsmanager.CertificatesPath,
+				opsmanager.CertificateAuthoritiesPath,
+				opsmanager.BoshCredentialsPath,
+				credhub.CertificatesPath,
+				credhub.DataPath,
+				"https://cf.example.com" + cf.RootPath,
+				"https://usage.example.com/system_report/app_usages",
+				"https://usage.example.com/system_report/service_usages",
+				"https://usage.example.com/system_report/task_usages",
+			} {
+				Expect(session.Out).To(gbytes.Say(regexp.QuoteMeta(path)))
+			}
+			Expect(session.Out).To(gbytes.Say(regexp.QuoteMeta(opsmanager.DefaultRedactionPolicy().Hash())))
+			Expect(session.Out).To(gbytes.Say("Would write:"))
+
+			Expect(opsManagerServer.ReceivedRequests()).To(BeEmpty())
+			assertOutputDirEmpty(outputDirPath)
+		})
+
+		It("plans the requests collection makes, in order", func() {
+			defaultEnvVars[cmd.DryRunKey] = "true"
+			defaultEnvVars[cmd.WithCredhubInfoKey] = "true"
+			defaultEnvVars[cmd.CfApiURLKey] = "https://cf.example.com"
+			defaultEnvVars[cmd.UsageServiceURLKey] = "https://usage.example.com"
+			defaultEnvVars[cmd.UsageServiceClientIDKey] = "some-client"
+			defaultEnvVars[cmd.UsageServiceClientSecretKey] = "some-secret"
+			defaultEnvVars[cmd.UsageByOrgKey] = "true"
+			defaultEnvVars[cmd.WithCfInventoryKey] = "true"
+			defaultEnvVars[cmd.CfClientIDKey] = "some-cf-client"
+			defaultEnvVars[cmd.CfClientSecretKey] = "some-cf-secret"
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			omURL := defaultEnvVars[cmd.OpsManagerURLKey]
+			placeholder := `[^\n]+?`
+			pathFormat := func(format string) string {
+				var parts []string
+				for _, part := range strings.Split(format, "%s") {
+					parts = append(parts, regexp.QuoteMeta(part))
+				}
+				return strings.Join(parts, placeholder)
+			}
+			request := func(method, url string) string {
+				return method + `\s+` + url + `\s`
+			}
+			planned := []string{
+				request(http.MethodPost, regexp.QuoteMeta(omURL+opsmanager.TokenPath)),
+				request(http.MethodGet, regexp.QuoteMeta(omURL+opsmanager.PendingChangesPath)),
+				request(http.MethodGet, regexp.QuoteMeta(omURL+opsmanager.DeployedProductsPath)),
+				request(http.MethodGet, regexp.QuoteMeta(omURL+opsmanager.DeployedProductsPath)),
+				request(http.MethodGet, regexp.QuoteMeta(omURL)+pathFormat(opsmanager.ProductResourcesPathFormat)),
+				request(http.MethodGet, regexp.QuoteMeta(omURL)+pathFormat(opsmanager.ProductPropertiesPathFormat)),
+				request(http.MethodGet, regexp.QuoteMeta(omURL+opsmanager.VmTypesPath)),
+				request(http.MethodGet, regexp.QuoteMeta(omURL+opsmanager.DiagnosticReportPath)),
+				request(http.MethodGet, regexp.QuoteMeta(omURL+opsmanager.InstallationsPath)),
+				request(http.MethodGet, regexp.QuoteMeta(omURL+opsmanager.CertificatesPath)),
+				request(http.MethodGet, regexp.QuoteMeta(omURL+opsmanager.CertificateAuthoritiesPath)),
+				request(http.MethodGet, regexp.QuoteMeta(omURL+opsmanager.BoshCredentialsPath)),
+				request(http.MethodGet, placeholder+regexp.QuoteMeta(credhub.InfoPath)),
+				request(http.MethodPost, placeholder+regexp.QuoteMeta(cf.TokenPath)),
+				request(http.MethodGet, placeholder+regexp.QuoteMeta(credhub.CertificatesPath)),
+				request(http.MethodGet, placeholder+regexp.QuoteMeta(credhub.DataPath)+`\S*`),
+				request(http.MethodGet, regexp.QuoteMeta("https://cf.example.com"+cf.RootPath)),
+				request(http.MethodPost, placeholder+regexp.QuoteMeta(cf.TokenPath)),
+			}
+			for _, report := range consumption.SystemReports {
+				planned = append(planned, request(http.MethodGet, regexp.QuoteMeta("https://usage.example.com/"+consumption.SystemReportPathPrefix+"/"+report.ReportName)+`\S*`))
+			}
+			planned = append(planned,
+				request(http.MethodGet, regexp.QuoteMeta("https://cf.example.com"+cf.RootPath)),
+				request(http.MethodPost, placeholder+regexp.QuoteMeta(cf.TokenPath)),
+				request(http.MethodGet, regexp.QuoteMeta("https://cf.example.com"+cf.OrganizationsPath)),
+			)
+			for _, report := range consumption.OrgReports {
+				planned = append(planned, request(http.MethodGet, regexp.QuoteMeta("https://usage.example.com/"+consumption.OrganizationsPathPrefix+"/")+placeholder+regexp.QuoteMeta("/"+report.ReportName+"?")+`\S*`))
+			}
+			planned = append(planned,
+				request(http.MethodGet, regexp.QuoteMeta("https://cf.example.com"+cf.RootPath)),
+				request(http.MethodPost, placeholder+regexp.QuoteMeta(cf.TokenPath)),
+			)
+			for _, resource := range cfapi.Inventory {
+				planned = append(planned, request(http.MethodGet, regexp.QuoteMeta("https://cf.example.com"+resource.Path))+`\s*`+regexp.QuoteMeta(manifest.CfApiDataSetId+"/"+resource.DataType))
+			}
+
+			Expect(session.Out).To(gbytes.Say(`METHOD\s+URL\s+WRITES\n`))
+			for _, line := range planned {
+				Expect(session.Out).To(gbytes.Say(`\A[^\n]*\n?` + line))
+			}
+		})
+
+		It("fails without output when the configuration is invalid", func() {
+			defaultEnvVars[cmd.DryRunKey] = "true"
+			defaultEnvVars[cmd.UsageServiceURLKey] = "https://usage.example.com"
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(cmd.InvalidUsageConfigurationMessage))
+			Expect(opsManagerServer.ReceivedRequests()).To(BeEmpty())
+			assertOutputDirEmpty(outputDirPath)
+		})
+	})
+
+	Context("when collection is stopped", func() {
+		var slowServer *ghttp.Server
+		BeforeEach(func() {
+			slowServer = ghttp.NewServer()
+			slowServer.RouteToHandler(http.MethodPost, "/uaa/oauth/token", func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{
+				"access_token": "some-opsman-token",
+				"token_type": "bearer",
+				"expires_in": 3600
+				}`))
+			})
+			slowServer.RouteToHandler(http.MethodGet, "/api/v0/staged/pending_changes", func(w http.ResponseWriter, req *http.Request) {
+				select {
+				case <-req.Context().Done():
+				case <-time.After(10 * time.Second):
+				}
+			})
+			defaultEnvVars[cmd.OpsManagerURLKey] = slowServer.URL()
+		})
+
+		AfterEach(func() {
+			slowServer.Close()
+		})
+
+		It("fails once the overall timeout elapses and removes the partial output", func() {
+			defaultEnvVars[cmd.TimeoutKey] = "1s"
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session, 5*time.Second).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.TimeoutExceededFormat, "1s")))
+			Expect(session.Err).NotTo(gbytes.Say("USAGE EXAMPLES"))
+			assertOutputDirEmpty(outputDirPath)
+		})
+
+		It("stops when interrupted and removes the partial output", func() {
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(func() int { return len(slowServer.ReceivedRequests()) }).Should(Equal(2))
+
+			session.Interrupt()
+			Eventually(session, 5*time.Second).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.InterruptedFormat, os.Interrupt)))
+			assertOutputDirEmpty(outputDirPath)
+		})
+
+		It("fails if the timeout is invalid", func() {
+			defaultEnvVars[cmd.TimeoutKey] = "later"
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.InvalidDurationErrorFormat, "later", cmd.TimeoutFlag)))
+			assertOutputDirEmpty(outputDirPath)
+		})
+	})
+
 	Context("with usage service client/secret authentication", func() {
 		var (
 			usageService *ghttp.Server
@@ -249,50 +778,197 @@ var _ = Describe("Collect", func() {
 			uaaService.Close()
 		})
 
-		It("succeeds with env variable configuration", func() {
+		It("succeeds with env variable configuration", func() {
+			defaultEnvVars[cmd.CfApiURLKey] = cfService.URL()
+			defaultEnvVars[cmd.UsageServiceURLKey] = usageService.URL()
+			defaultEnvVars[cmd.UsageServiceClientIDKey] = "best-usage-service-client-id"
+			defaultEnvVars[cmd.UsageServiceClientSecretKey] = "best-usage-service-client-secret"
+			defaultEnvVars[cmd.UsageServiceSkipTlsVerifyKey] = "true"
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			tarFilePath := validatedTarFilePath(outputDirPath)
+			assertValidOutput(tarFilePath, collector_tar.OpsManagerCollectorDataSetId, "ops_manager_vm_types", "development")
+			assertValidOutput(tarFilePath, collector_tar.UsageServiceCollectorDataSetId, "app_usage", "development")
+			assertLogging(session, tarFilePath, false, true)
+		})
+
+		It("succeeds with flag configuration", func() {
+			flagValues := map[string]string{
+				cmd.OpsManagerTimeoutFlag:         "1",
+				cmd.OpsManagerURLFlag:             opsManagerServer.URL(),
+				cmd.OpsManagerClientIdFlag:        "whatever",
+				cmd.OpsManagerClientSecretFlag:    "whatever",
+				cmd.SkipTlsVerifyFlag:             "true",
+				cmd.EnvTypeFlag:                   "Development",
+				cmd.OutputPathFlag:                outputDirPath,
+				cmd.CfApiURLFlag:                  cfService.URL(),
+				cmd.UsageServiceURLFlag:           usageService.URL(),
+				cmd.UsageServiceClientIDFlag:      "best-usage-service-client-id",
+				cmd.UsageServiceClientSecretFlag:  "best-usage-service-client-secret",
+				cmd.UsageServiceSkipTlsVerifyFlag: "true",
+			}
+			command := exec.Command(aqueductBinaryPath, "collect")
+			for k, v := range flagValues {
+				command.Args = append(command.Args, fmt.Sprintf("--%s=%s", k, v))
+			}
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			tarFilePath := validatedTarFilePath(outputDirPath)
+			assertValidOutput(tarFilePath, collector_tar.OpsManagerCollectorDataSetId, "ops_manager_vm_types", "development")
+			assertValidOutput(tarFilePath, collector_tar.UsageServiceCollectorDataSetId, "app_usage", "development")
+			assertLogging(session, tarFilePath, false, true)
+		})
+
+		It("collects a report per month of the usage range", func() {
+			defaultEnvVars[cmd.CfApiURLKey] = cfService.URL()
+			defaultEnvVars[cmd.UsageServiceURLKey] = usageService.URL()
+			defaultEnvVars[cmd.UsageServiceClientIDKey] = "best-usage-service-client-id"
+			defaultEnvVars[cmd.UsageServiceClientSecretKey] = "best-usage-service-client-secret"
+			defaultEnvVars[cmd.UsageServiceSkipTlsVerifyKey] = "true"
+			defaultEnvVars[cmd.UsageStartKey] = "2018-01-15"
+			defaultEnvVars[cmd.UsageEndKey] = "2018-02-10"
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			tarFilePath := validatedTarFilePath(outputDirPath)
+			assertValidOutput(tarFilePath, collector_tar.UsageServiceCollectorDataSetId, "app_usage_2018-01", "development")
+			assertValidOutput(tarFilePath, collector_tar.UsageServiceCollectorDataSetId, "task_usage_2018-02", "development")
+
+			var appUsageQueries []string
+			for _, req := range usageService.ReceivedRequests() {
+				if req.URL.Path == "/system_report/app_usages" {
+					appUsageQueries = append(appUsageQueries, req.URL.RawQuery)
+				}
+			}
+			Expect(appUsageQueries).To(Equal([]string{
+				"end=2018-01-31&start=2018-01-15",
+				"end=2018-02-10&start=2018-02-01",
+			}))
+		})
+
+		It("collects each org's usage when asked to", func() {
+			cfService.RouteToHandler(http.MethodGet, "/v3/organizations", func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.Header.Get("Authorization")).To(Equal("Bearer some-uaa-token"))
+				w.Header().Set("Content-Type", "application/json")
+				if req.URL.Query().Get("page") == "2" {
+					w.Write([]byte(`{"pagination": {"next": null}, "resources": [{"guid": "org-2-guid", "name": "org-2"}]}`))
+					return
+				}
+				w.Write([]byte(`{"pagination": {"next": {"href": "` + cfService.URL() + `/v3/organizations?page=2"}}, "resources": [{"guid": "org-1-guid", "name": "org-1"}]}`))
+			})
+			for _, orgGUID := range []string{"org-1-guid", "org-2-guid"} {
+				for _, reportName := range []string{"app_usages", "service_usages", "task_usages"} {
+					usageService.RouteToHandler(http.MethodGet, "/organizations/"+orgGUID+"/"+reportName, func(w http.ResponseWriter, req *http.Request) {
+						Expect(req.Header.Get("Authorization")).To(Equal("Bearer some-uaa-token"))
+						Expect(req.URL.Query().Get("start")).To(Equal("2018-01-01"))
+						w.Header().Set("Content-Type", "application/json")
+						w.Write([]byte(`{"spaces": {}}`))
+					})
+				}
+			}
+
 			defaultEnvVars[cmd.CfApiURLKey] = cfService.URL()
 			defaultEnvVars[cmd.UsageServiceURLKey] = usageService.URL()
 			defaultEnvVars[cmd.UsageServiceClientIDKey] = "best-usage-service-client-id"
 			defaultEnvVars[cmd.UsageServiceClientSecretKey] = "best-usage-service-client-secret"
 			defaultEnvVars[cmd.UsageServiceSkipTlsVerifyKey] = "true"
+			defaultEnvVars[cmd.UsageStartKey] = "2018-01-01"
+			defaultEnvVars[cmd.UsageEndKey] = "2018-01-31"
+			defaultEnvVars[cmd.UsageByOrgKey] = "true"
 			command := buildDefaultCommand(defaultEnvVars)
 			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
 			Expect(err).NotTo(HaveOccurred())
 			Eventually(session).Should(gexec.Exit(0))
 
 			tarFilePath := validatedTarFilePath(outputDirPath)
-			assertValidOutput(tarFilePath, collector_tar.OpsManagerCollectorDataSetId, "ops_manager_vm_types", "development")
-			assertValidOutput(tarFilePath, collector_tar.UsageServiceCollectorDataSetId, "app_usage", "development")
-			assertLogging(session, tarFilePath, false, true)
+			assertValidOutput(tarFilePath, collector_tar.UsageServiceCollectorDataSetId, "app_usage_2018-01", "development")
+			assertValidOutput(tarFilePath, collector_tar.UsageServiceCollectorDataSetId, "org_app_usage_2018-01", "development")
+			assertValidOutput(tarFilePath, collector_tar.UsageServiceCollectorDataSetId, "org_service_usage_2018-01", "development")
+			assertValidOutput(tarFilePath, collector_tar.UsageServiceCollectorDataSetId, "org_task_usage_2018-01", "development")
+			Expect(session.Out).To(gbytes.Say("Collecting usage of 2 orgs from Usage Service"))
 		})
 
-		It("succeeds with flag configuration", func() {
-			flagValues := map[string]string{
-				cmd.OpsManagerTimeoutFlag:         "1",
-				cmd.OpsManagerURLFlag:             opsManagerServer.URL(),
-				cmd.OpsManagerClientIdFlag:        "whatever",
-				cmd.OpsManagerClientSecretFlag:    "whatever",
-				cmd.SkipTlsVerifyFlag:             "true",
-				cmd.EnvTypeFlag:                   "Development",
-				cmd.OutputPathFlag:                outputDirPath,
-				cmd.CfApiURLFlag:                  cfService.URL(),
-				cmd.UsageServiceURLFlag:           usageService.URL(),
-				cmd.UsageServiceClientIDFlag:      "best-usage-service-client-id",
-				cmd.UsageServiceClientSecretFlag:  "best-usage-service-client-secret",
-				cmd.UsageServiceSkipTlsVerifyFlag: "true",
-			}
-			command := exec.Command(aqueductBinaryPath, "collect")
-			for k, v := range flagValues {
-				command.Args = append(command.Args, fmt.Sprintf("--%s=%s", k, v))
+		It("lists the orgs with the CF client credentials when they are given", func() {
+			uaaService.RouteToHandler(http.MethodPost, "/oauth/token", func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				token := "some-uaa-token"
+				if req.Header.Get("authorization") == "Basic "+base64.StdEncoding.EncodeToString([]byte("best-cf-client-id:best-cf-client-secret")) {
+					token = "some-cf-token"
+				}
+				w.Write([]byte(`{"access_token": "` + token + `", "token_type": "bearer", "expires_in": 3600}`))
+			})
+			cfService.RouteToHandler(http.MethodGet, "/v3/organizations", func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.Header.Get("Authorization")).To(Equal("Bearer some-cf-token"))
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"pagination": {"next": null}, "resources": [{"guid": "org-1-guid", "name": "org-1"}]}`))
+			})
+			for _, reportName := range []string{"app_usages", "service_usages", "task_usages"} {
+				usageService.RouteToHandler(http.MethodGet, "/organizations/org-1-guid/"+reportName, func(w http.ResponseWriter, req *http.Request) {
+					Expect(req.Header.Get("Authorization")).To(Equal("Bearer some-uaa-token"))
+					w.Header().Set("Content-Type", "application/json")
+					w.Write([]byte(`{"spaces": {}}`))
+				})
 			}
+
+			defaultEnvVars[cmd.CfApiURLKey] = cfService.URL()
+			defaultEnvVars[cmd.UsageServiceURLKey] = usageService.URL()
+			defaultEnvVars[cmd.UsageServiceClientIDKey] = "best-usage-service-client-id"
+			defaultEnvVars[cmd.UsageServiceClientSecretKey] = "best-usage-service-client-secret"
+			defaultEnvVars[cmd.UsageServiceSkipTlsVerifyKey] = "true"
+			defaultEnvVars[cmd.CfClientIDKey] = "best-cf-client-id"
+			defaultEnvVars[cmd.CfClientSecretKey] = "best-cf-client-secret"
+			defaultEnvVars[cmd.CfSkipTlsVerifyKey] = "true"
+			defaultEnvVars[cmd.UsageStartKey] = "2018-01-01"
+			defaultEnvVars[cmd.UsageEndKey] = "2018-01-31"
+			defaultEnvVars[cmd.UsageByOrgKey] = "true"
+			command := buildDefaultCommand(defaultEnvVars)
 			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
 			Expect(err).NotTo(HaveOccurred())
 			Eventually(session).Should(gexec.Exit(0))
 
 			tarFilePath := validatedTarFilePath(outputDirPath)
-			assertValidOutput(tarFilePath, collector_tar.OpsManagerCollectorDataSetId, "ops_manager_vm_types", "development")
-			assertValidOutput(tarFilePath, collector_tar.UsageServiceCollectorDataSetId, "app_usage", "development")
-			assertLogging(session, tarFilePath, false, true)
+			assertValidOutput(tarFilePath, collector_tar.UsageServiceCollectorDataSetId, "org_app_usage_2018-01", "development")
+			Expect(session.Out).To(gbytes.Say("Collecting usage of 1 orgs from Usage Service"))
+		})
+
+		It("fails naming the missing scope when the CF API forbids listing orgs", func() {
+			cfService.RouteToHandler(http.MethodGet, "/v3/organizations", ghttp.RespondWith(http.StatusForbidden, ""))
+
+			defaultEnvVars[cmd.CfApiURLKey] = cfService.URL()
+			defaultEnvVars[cmd.UsageServiceURLKey] = usageService.URL()
+			defaultEnvVars[cmd.UsageServiceClientIDKey] = "best-usage-service-client-id"
+			defaultEnvVars[cmd.UsageServiceClientSecretKey] = "best-usage-service-client-secret"
+			defaultEnvVars[cmd.UsageServiceSkipTlsVerifyKey] = "true"
+			defaultEnvVars[cmd.UsageByOrgKey] = "true"
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(consumption.ListOrganizationsError))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cf.CFApiUnexpectedResponseStatusErrorFormat, http.StatusForbidden)))
+			assertOutputDirEmpty(outputDirPath)
+		})
+
+		It("fails when both a usage range and a number of months are given", func() {
+			defaultEnvVars[cmd.CfApiURLKey] = cfService.URL()
+			defaultEnvVars[cmd.UsageServiceURLKey] = usageService.URL()
+			defaultEnvVars[cmd.UsageServiceClientIDKey] = "best-usage-service-client-id"
+			defaultEnvVars[cmd.UsageServiceClientSecretKey] = "best-usage-service-client-secret"
+			defaultEnvVars[cmd.UsageStartKey] = "2018-01-15"
+			defaultEnvVars[cmd.UsageMonthsKey] = "3"
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(cmd.UsageRangeConflictMessage))
+			assertOutputDirEmpty(outputDirPath)
 		})
 
 		It("fails if the usage service URL is invalid", func() {
@@ -309,11 +985,32 @@ var _ = Describe("Collect", func() {
 			Expect(session.Err).NotTo(gbytes.Say("USAGE EXAMPLES"))
 		})
 
-		It("fails if getting the UAA URL fails", func() {
-			cfService.RouteToHandler(http.MethodGet, "/v2/info", func(w http.ResponseWriter, req *http.Request) {
-				w.WriteHeader(500)
+		It("discovers UAA from the v2 info endpoint when the v3 root endpoint is unavailable", func() {
+			cfService.RouteToHandler(http.MethodGet, "/", func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
 			})
 
+			defaultEnvVars[cmd.UsageServiceURLKey] = usageService.URL()
+			defaultEnvVars[cmd.CfApiURLKey] = cfService.URL()
+			defaultEnvVars[cmd.UsageServiceClientIDKey] = "best-usage-service-client-id"
+			defaultEnvVars[cmd.UsageServiceClientSecretKey] = "best-usage-service-client-secret"
+			defaultEnvVars[cmd.UsageServiceSkipTlsVerifyKey] = "true"
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			tarFilePath := validatedTarFilePath(outputDirPath)
+			assertValidOutput(tarFilePath, collector_tar.UsageServiceCollectorDataSetId, "app_usage", "development")
+		})
+
+		It("fails if getting the UAA URL fails", func() {
+			for _, discoveryPath := range []string{"/", "/v2/info"} {
+				cfService.RouteToHandler(http.MethodGet, discoveryPath, func(w http.ResponseWriter, req *http.Request) {
+					w.WriteHeader(500)
+				})
+			}
+
 			defaultEnvVars[cmd.UsageServiceURLKey] = usageService.URL()
 			defaultEnvVars[cmd.CfApiURLKey] = cfService.URL()
 			defaultEnvVars[cmd.UsageServiceClientIDKey] = "best-usage-service-client-id"
@@ -411,6 +1108,112 @@ var _ = Describe("Collect", func() {
 		)
 	})
 
+	Context("when CF inventory collection is enabled", func() {
+		var (
+			usageService *ghttp.Server
+			cfService    *ghttp.Server
+			uaaService   *ghttp.Server
+		)
+
+		BeforeEach(func() {
+			uaaService, cfService, usageService = setupUsageService("")
+			for _, resource := range cfapi.Inventory {
+				cfService.RouteToHandler(http.MethodGet, resource.Path, func(w http.ResponseWriter, req *http.Request) {
+					Expect(req.Header.Get("Authorization")).To(Equal("Bearer some-uaa-token"))
+					w.Header().Set("Content-Type", "application/json")
+					w.Write([]byte(`{"pagination": {"next": null}, "resources": []}`))
+				})
+			}
+			cfService.RouteToHandler(http.MethodGet, "/v3/apps", func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.Header.Get("Authorization")).To(Equal("Bearer some-uaa-token"))
+				w.Header().Set("Content-Type", "application/json")
+				if req.URL.Query().Get("page") == "2" {
+					w.Write([]byte(`{"pagination": {"next": null}, "resources": [{"guid": "app-2-guid", "name": "app-2", "state": "STOPPED"}]}`))
+					return
+				}
+				w.Write([]byte(`{"pagination": {"next": {"href": "/v3/apps?page=2&per_page=100"}}, "resources": [{"guid": "app-1-guid", "name": "app-1", "state": "STARTED", "metadata": {"annotations": {"SECRET": "shh"}}}]}`))
+			})
+
+			defaultEnvVars[cmd.CfApiURLKey] = cfService.URL()
+			defaultEnvVars[cmd.WithCfInventoryKey] = "true"
+			defaultEnvVars[cmd.CfClientIDKey] = "best-usage-service-client-id"
+			defaultEnvVars[cmd.CfClientSecretKey] = "best-usage-service-client-secret"
+			defaultEnvVars[cmd.CfSkipTlsVerifyKey] = "true"
+		})
+
+		AfterEach(func() {
+			usageService.Close()
+			cfService.Close()
+			uaaService.Close()
+		})
+
+		It("writes every page of the inventory to the cf_api data set", func() {
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			Expect(session.Out).To(gbytes.Say("Collecting data from CF API"))
+
+			tarFilePath := validatedTarFilePath(outputDirPath)
+			assertValidOutput(tarFilePath, collector_tar.OpsManagerCollectorDataSetId, "ops_manager_vm_types", "development")
+			for _, dataType := range cfapi.DataTypes() {
+				assertValidOutput(tarFilePath, manifest.CfApiDataSetId, dataType, "development")
+			}
+
+			tmpDir, err := ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(tmpDir)
+			Expect((&archiver.Tar{}).Unarchive(tarFilePath, tmpDir)).To(Succeed())
+			apps, err := ioutil.ReadFile(filepath.Join(tmpDir, manifest.CfApiDataSetId, cfapi.AppsDataType))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(apps)).To(ContainSubstring(`"count":2`))
+			Expect(string(apps)).To(ContainSubstring("app-1-guid"))
+			Expect(string(apps)).To(ContainSubstring("app-2-guid"))
+			Expect(string(apps)).NotTo(ContainSubstring("SECRET"))
+		})
+
+		It("writes each process's instances, memory and disk, applying a redaction policy for processes", func() {
+			cfService.RouteToHandler(http.MethodGet, "/v3/processes", func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"pagination": {"next": null}, "resources": [{"guid": "process-1-guid", "type": "web", "command": "secret-command", "instances": 2, "memory_in_mb": 1024, "disk_in_mb": 512}]}`))
+			})
+			policyDir, err := ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(policyDir)
+			policyPath := filepath.Join(policyDir, "policy.yml")
+			Expect(ioutil.WriteFile(policyPath, []byte(`
+rules:
+- data_type: processes
+  path: $.processes[*].type
+  action: mask
+`), 0644)).To(Succeed())
+			defaultEnvVars[cmd.RedactionPolicyKey] = policyPath
+
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			tmpDir, err := ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(tmpDir)
+			Expect((&archiver.Tar{}).Unarchive(validatedTarFilePath(outputDirPath), tmpDir)).To(Succeed())
+			processes, err := ioutil.ReadFile(filepath.Join(tmpDir, manifest.CfApiDataSetId, cfapi.ProcessesDataType))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(processes).To(MatchJSON(`{"count": 1, "processes": [{"guid": "process-1-guid", "type": "REDACTED", "instances": 2, "memory_in_mb": 1024, "disk_in_mb": 512}]}`))
+		})
+
+		It("fails when the CF client credentials are missing", func() {
+			delete(defaultEnvVars, cmd.CfClientSecretKey)
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Eventually(session.Err).Should(gbytes.Say(cmd.InvalidCfInventoryConfigurationMessage))
+			assertOutputDirEmpty(outputDirPath)
+		})
+	})
+
 	Context("when credhub collection is enabled", func() {
 		var credhubServer *ghttp.Server
 
@@ -436,6 +1239,7 @@ var _ = Describe("Collect", func() {
 				cmd.EnvTypeFlag:                "Development",
 				cmd.SkipTlsVerifyFlag:          "true",
 				cmd.CollectFromCredhubFlag:     "true",
+				cmd.CredhubSkipTlsVerifyFlag:   "true",
 				cmd.OutputPathFlag:             outputDirPath,
 			}
 			command := exec.Command(aqueductBinaryPath, "collect")
@@ -453,15 +1257,97 @@ var _ = Describe("Collect", func() {
 		It("collects information from credhub as well as ops manager with env variable configuration", func() {
 			defaultEnvVars[cmd.OpsManagerURLKey] = opsManagerServer.URL()
 			defaultEnvVars[cmd.WithCredhubInfoKey] = "true"
+			defaultEnvVars[cmd.CredhubSkipTlsVerifyKey] = "true"
 			command := buildDefaultCommand(defaultEnvVars)
 			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
 			Expect(err).NotTo(HaveOccurred())
 			Eventually(session).Should(gexec.Exit(0))
 			tarFilePath := validatedTarFilePath(outputDirPath)
 			assertValidOutput(tarFilePath, collector_tar.OpsManagerCollectorDataSetId, "p-bosh_certificates", "development")
+			assertValidOutput(tarFilePath, collector_tar.OpsManagerCollectorDataSetId, "p-bosh_certificate_details", "development")
 			assertLogging(session, tarFilePath, true, false)
 		})
 
+		It("verifies credhub with the configured CA certificates", func() {
+			caCertFile, err := ioutil.TempFile("", "credhub-ca")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.Remove(caCertFile.Name())
+			Expect(pem.Encode(caCertFile, &pem.Block{Type: "CERTIFICATE", Bytes: credhubServer.HTTPTestServer.Certificate().Raw})).To(Succeed())
+			Expect(caCertFile.Close()).To(Succeed())
+
+			defaultEnvVars[cmd.OpsManagerURLKey] = opsManagerServer.URL()
+			defaultEnvVars[cmd.WithCredhubInfoKey] = "true"
+			defaultEnvVars[cmd.CredhubCACertKey] = caCertFile.Name()
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			tarFilePath := validatedTarFilePath(outputDirPath)
+			assertValidOutput(tarFilePath, collector_tar.OpsManagerCollectorDataSetId, "p-bosh_certificates", "development")
+		})
+
+		It("verifies credhub with the Ops Manager root CA", func() {
+			rootCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: credhubServer.HTTPTestServer.Certificate().Raw})
+			opsManagerServer.RouteToHandler(http.MethodGet, opsmanager.RootCACertificatePath, func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				contents, err := json.Marshal(map[string]string{"root_ca_certificate_pem": string(rootCA)})
+				Expect(err).NotTo(HaveOccurred())
+				w.Write(contents)
+			})
+
+			defaultEnvVars[cmd.OpsManagerURLKey] = opsManagerServer.URL()
+			defaultEnvVars[cmd.WithCredhubInfoKey] = "true"
+			defaultEnvVars[cmd.CredhubUseOpsManagerCAKey] = "true"
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			tarFilePath := validatedTarFilePath(outputDirPath)
+			assertValidOutput(tarFilePath, collector_tar.OpsManagerCollectorDataSetId, "p-bosh_certificates", "development")
+		})
+
+		It("collects from the configured credhub URL instead of the BOSH Director", func() {
+			opsManagerServer.RouteToHandler(http.MethodGet, "/api/v0/deployed/director/credentials/bosh_commandline_credentials", func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{ "credential": "BOSH_CLIENT=best_client BOSH_CLIENT_SECRET=best_secret BOSH_ENVIRONMENT=director.invalid bosh "}`))
+			})
+
+			defaultEnvVars[cmd.OpsManagerURLKey] = opsManagerServer.URL()
+			defaultEnvVars[cmd.WithCredhubInfoKey] = "true"
+			defaultEnvVars[cmd.CredhubSkipTlsVerifyKey] = "true"
+			defaultEnvVars[cmd.CredhubURLKey] = credhubServer.URL()
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			Expect(session.Out).To(gbytes.Say("Collecting data from CredHub at " + regexp.QuoteMeta(credhubServer.URL())))
+		})
+
+		It("errors if credhub's certificate cannot be verified", func() {
+			defaultEnvVars[cmd.OpsManagerURLKey] = opsManagerServer.URL()
+			defaultEnvVars[cmd.WithCredhubInfoKey] = "true"
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(cmd.CredhubClientError))
+			Expect(session.Err).To(gbytes.Say("certificate"))
+			assertOutputDirEmpty(outputDirPath)
+		})
+
+		It("errors if skipping TLS verification is combined with CA certificates", func() {
+			defaultEnvVars[cmd.OpsManagerURLKey] = opsManagerServer.URL()
+			defaultEnvVars[cmd.WithCredhubInfoKey] = "true"
+			defaultEnvVars[cmd.CredhubSkipTlsVerifyKey] = "true"
+			defaultEnvVars[cmd.CredhubUseOpsManagerCAKey] = "true"
+			command := buildDefaultCommand(defaultEnvVars)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(regexp.QuoteMeta(cmd.CredhubTLSConflictMessage)))
+			assertOutputDirEmpty(outputDirPath)
+		})
+
 		It("errors if fetching credentials for credhub auth fails", func() {
 			opsManagerServer.RouteToHandler(http.MethodGet, "/api/v0/deployed/director/credentials/bosh_commandline_credentials", func(w http.ResponseWriter, req *http.Request) {
 				w.WriteHeader(500)
@@ -473,6 +1359,7 @@ var _ = Describe("Collect", func() {
 				cmd.EnvTypeFlag:                "Development",
 				cmd.SkipTlsVerifyFlag:          "true",
 				cmd.CollectFromCredhubFlag:     "true",
+				cmd.CredhubSkipTlsVerifyFlag:   "true",
 				cmd.OutputPathFlag:             outputDirPath,
 			}
 			command := exec.Command(aqueductBinaryPath, "collect")
@@ -498,6 +1385,7 @@ var _ = Describe("Collect", func() {
 				cmd.EnvTypeFlag:                "Development",
 				cmd.SkipTlsVerifyFlag:          "true",
 				cmd.CollectFromCredhubFlag:     "true",
+				cmd.CredhubSkipTlsVerifyFlag:   "true",
 				cmd.OutputPathFlag:             outputDirPath,
 			}
 			command := exec.Command(aqueductBinaryPath, "collect")
@@ -599,6 +1487,7 @@ var _ = Describe("Collect", func() {
 			defaultEnvVars[cmd.UsageServiceClientSecretKey] = "best-usage-service-client-secret"
 			defaultEnvVars[cmd.UsageServiceSkipTlsVerifyKey] = "true"
 			defaultEnvVars[cmd.WithCredhubInfoKey] = "true"
+			defaultEnvVars[cmd.CredhubSkipTlsVerifyKey] = "true"
 
 			defaultEnvVars["HTTPS_PROXY"] = fmt.Sprintf("http://localhost:%d", listenerPort)
 
@@ -613,6 +1502,133 @@ var _ = Describe("Collect", func() {
 		})
 	})
 
+	Context("with a foundations config file", func() {
+		var (
+			configPath    string
+			failingServer *ghttp.Server
+		)
+
+		writeConfig := func(config string) {
+			Expect(ioutil.WriteFile(configPath, []byte(config), 0644)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			configFile, err := ioutil.TempFile("", "foundations.yml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(configFile.Close()).To(Succeed())
+			configPath = configFile.Name()
+
+			failingServer = ghttp.NewServer()
+			failingServer.RouteToHandler(http.MethodPost, "/uaa/oauth/token", ghttp.RespondWith(http.StatusUnauthorized, ""))
+		})
+
+		AfterEach(func() {
+			failingServer.Close()
+			Expect(os.RemoveAll(configPath)).To(Succeed())
+		})
+
+		It("writes a file for each foundation and continues past failing foundations", func() {
+			writeConfig(fmt.Sprintf(`
+foundations:
+- name: east
+  url: %s
+  username: some-username
+  password: some-password
+  env-type: production
+  insecure-skip-tls-verify: true
+- name: broken
+  url: %s
+  client-id: some-client-id
+  client-secret: some-client-secret
+  env-type: sandbox
+- name: west
+  url: %s
+  client-id: some-client-id
+  client-secret: some-client-secret
+  env-type: qa
+  insecure-skip-tls-verify: true
+`, opsManagerServer.URL(), failingServer.URL(), opsManagerServer.URL()))
+
+			command := exec.Command(aqueductBinaryPath, "collect", "--"+cmd.FoundationsConfigFlag, configPath, "--"+cmd.OutputPathFlag, outputDirPath)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+
+			eastTarFilePath := foundationTarFilePath(outputDirPath, "east")
+			westTarFilePath := foundationTarFilePath(outputDirPath, "west")
+			assertValidOutput(eastTarFilePath, collector_tar.OpsManagerCollectorDataSetId, "ops_manager_vm_types", "production")
+			assertValidOutput(westTarFilePath, collector_tar.OpsManagerCollectorDataSetId, "ops_manager_vm_types", "qa")
+			fileInfos, err := ioutil.ReadDir(outputDirPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fileInfos).To(HaveLen(2))
+
+			Expect(session.Out).To(gbytes.Say("Collecting from foundation east"))
+			Expect(session.Out).To(gbytes.Say("Collecting from foundation broken"))
+			Expect(session.Out).To(gbytes.Say("Failed to collect from foundation broken"))
+			Expect(session.Out).To(gbytes.Say("Collecting from foundation west"))
+			Expect(session.Out).To(gbytes.Say(`east\s+Wrote ` + escapeWindowsPathRegex(eastTarFilePath)))
+			Expect(session.Out).To(gbytes.Say(`broken\s+Failed: ` + operations.OpsManagerCollectFailureMessage))
+			Expect(session.Out).To(gbytes.Say(`west\s+Wrote ` + escapeWindowsPathRegex(westTarFilePath)))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.FoundationsFailedFormat, 1, 3)))
+			Expect(session.Err).NotTo(gbytes.Say("USAGE EXAMPLES"))
+		})
+
+		It("falls back to flags and env variables for settings a foundation leaves out", func() {
+			writeConfig(`
+foundations:
+- name: east
+  env-type: production
+- name: west
+  env-type: qa
+`)
+
+			command := buildDefaultCommand(defaultEnvVars)
+			command.Args = append(command.Args, "--"+cmd.FoundationsConfigFlag, configPath)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			assertValidOutput(foundationTarFilePath(outputDirPath, "east"), collector_tar.OpsManagerCollectorDataSetId, "ops_manager_vm_types", "production")
+			assertValidOutput(foundationTarFilePath(outputDirPath, "west"), collector_tar.OpsManagerCollectorDataSetId, "ops_manager_vm_types", "qa")
+			Expect(session.Out).To(gbytes.Say("Success!\n"))
+		})
+
+		It("does not carry settings from one foundation over to the next", func() {
+			writeConfig(fmt.Sprintf(`
+foundations:
+- name: east
+  url: %s
+  username: some-username
+  password: some-password
+  env-type: production
+  insecure-skip-tls-verify: true
+- name: west
+  url: %s
+  insecure-skip-tls-verify: true
+`, opsManagerServer.URL(), opsManagerServer.URL()))
+
+			command := exec.Command(aqueductBinaryPath, "collect", "--"+cmd.FoundationsConfigFlag, configPath, "--"+cmd.OutputPathFlag, outputDirPath)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Out).To(gbytes.Say(`west\s+Failed: ` + fmt.Sprintf(cmd.RequiredConfigErrorFormat, "--"+cmd.EnvTypeFlag)))
+		})
+
+		It("fails without collecting when the config file is invalid", func() {
+			writeConfig(`
+foundations:
+- name: east
+  not-a-setting: true
+`)
+
+			command := exec.Command(aqueductBinaryPath, "collect", "--"+cmd.FoundationsConfigFlag, configPath, "--"+cmd.OutputPathFlag, outputDirPath)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.UnknownFoundationSettingFormat, "not-a-setting", "east")))
+			assertOutputDirEmpty(outputDirPath)
+		})
+	})
+
 	It("fails if the required variables are not set", func() {
 		command := exec.Command(aqueductBinaryPath, "collect")
 		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
@@ -652,6 +1668,16 @@ var _ = Describe("Collect", func() {
 		assertOutputDirEmpty(outputDirPath)
 	})
 
+	It("fails if the ops manager max concurrency is invalid", func() {
+		defaultEnvVars[cmd.OpsManagerMaxConcurrencyKey] = "0"
+		command := buildDefaultCommand(defaultEnvVars)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(1))
+		Expect(session.Err).To(gbytes.Say(cmd.InvalidMaxConcurrencyMessage))
+		assertOutputDirEmpty(outputDirPath)
+	})
+
 	It("fails if the output directory does not exist", func() {
 		defaultEnvVars[cmd.OutputPathKey] = "/not/a/real/path"
 		command := buildDefaultCommand(defaultEnvVars)
@@ -685,11 +1711,21 @@ func validatedTarFilePath(outputDirPath string) string {
 	return filepath.Join(outputDirPath, fileInfos[0].Name())
 }
 
+func foundationTarFilePath(outputDirPath, foundationName string) string {
+	matches, err := filepath.Glob(filepath.Join(outputDirPath, cmd.OutputFilePrefix+foundationName+"_*.tar"))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(matches).To(HaveLen(1), fmt.Sprintf("Expected output dir %s to include a single file for %s", outputDirPath, foundationName))
+	Expect(filepath.Base(matches[0])).To(MatchRegexp(fmt.Sprintf(`%s%s_%s.tar$`, cmd.OutputFilePrefix, foundationName, UnixTimestampRegexp)))
+	return matches[0]
+}
+
 func assertMetadataFileIsCorrect(contentDir, expectedEnvType, dataSetType string) {
 	content, err := ioutil.ReadFile(filepath.Join(contentDir, dataSetType, collector_tar.MetadataFileName))
 	Expect(err).NotTo(HaveOccurred(), "Expected metadata file to exist but did not")
 	var metadata collector_tar.Metadata
-	Expect(json.Unmarshal(content, &metadata)).To(Succeed())
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	Expect(decoder.Decode(&metadata)).To(Succeed(), "Expected metadata file to only have fields known to telemetry-utils")
 	Expect(metadata.EnvType).To(Equal(expectedEnvType))
 	Expect(metadata.CollectorVersion).To(Equal(testVersion))
 }
@@ -821,6 +1857,14 @@ func setupUsageServerServiceHandlers(uaaService, cfService, usageService *ghttp.
 				}`))
 	})
 
+	cfService.RouteToHandler(http.MethodGet, "/", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if uaaServiceURLOverride != "" {
+			w.Write([]byte(`{ "links": { "uaa": { "href": "` + uaaServiceURLOverride + `" } } }`))
+		} else {
+			w.Write([]byte(`{ "links": { "uaa": { "href": "` + uaaService.URL() + `" } } }`))
+		}
+	})
 	cfService.RouteToHandler(http.MethodGet, "/v2/info", func(w http.ResponseWriter, req *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		if uaaServiceURLOverride != "" {
@@ -0,0 +1,191 @@
+package integration
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+	"github.com/pivotal-cf/aqueduct-courier/cmd"
+	"github.com/pivotal-cf/aqueduct-courier/encryption"
+)
+
+var _ = Describe("Decrypt", func() {
+	var (
+		tempDir           string
+		tarFilePath       string
+		encryptedFilePath string
+		privateKeyPath    string
+		key               *rsa.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+		tarFilePath = generateValidDataTarFile(tempDir)
+
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		privateKeyPath = writePrivateKey(tempDir, "private.pem", key)
+		encryptedFilePath = encryptFile(tarFilePath, &key.PublicKey)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("writes the decrypted file next to the encrypted one", func() {
+		original, err := ioutil.ReadFile(tarFilePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Remove(tarFilePath)).To(Succeed())
+
+		command := exec.Command(aqueductBinaryPath, "decrypt", "--path="+encryptedFilePath, "--private-key="+privateKeyPath)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(session.Out).To(gbytes.Say(fmt.Sprintf("Wrote decrypted output to %s", escapeWindowsPathRegex(tarFilePath))))
+		Expect(session.Out).To(gbytes.Say("Success!"))
+
+		decrypted, err := ioutil.ReadFile(tarFilePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decrypted).To(Equal(original))
+	})
+
+	It("writes the decrypted file to the given output path", func() {
+		outputPath := filepath.Join(tempDir, "decrypted.tar")
+		command := exec.Command(aqueductBinaryPath, "decrypt", "--path="+encryptedFilePath, "--private-key="+privateKeyPath, "--output="+outputPath)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(outputPath).To(BeAnExistingFile())
+	})
+
+	It("does not overwrite an existing file", func() {
+		command := exec.Command(aqueductBinaryPath, "decrypt", "--path="+encryptedFilePath, "--private-key="+privateKeyPath)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(1))
+		Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.CreateDecryptedFailureFormat, escapeWindowsPathRegex(tarFilePath))))
+	})
+
+	It("fails with a key the file was not encrypted for", func() {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		otherKeyPath := writePrivateKey(tempDir, "other.pem", otherKey)
+		Expect(os.Remove(tarFilePath)).To(Succeed())
+
+		command := exec.Command(aqueductBinaryPath, "decrypt", "--path="+encryptedFilePath, "--private-key="+otherKeyPath)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(1))
+		Expect(session.Err).To(gbytes.Say(regexp.QuoteMeta(fmt.Sprintf(encryption.WrongKeyFormat, encryption.Fingerprint(&key.PublicKey), encryption.Fingerprint(&otherKey.PublicKey)))))
+		Expect(tarFilePath).NotTo(BeAnExistingFile())
+	})
+
+	It("requires an output path when the file does not have the encrypted extension", func() {
+		command := exec.Command(aqueductBinaryPath, "decrypt", "--path="+tarFilePath, "--private-key="+privateKeyPath)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(1))
+		Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.DecryptOutputRequiredFormat, escapeWindowsPathRegex(tarFilePath), encryption.FileExtension)))
+	})
+
+	It("fails if required flags have not been set", func() {
+		command := exec.Command(aqueductBinaryPath, "decrypt")
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(1))
+		Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.RequiredConfigErrorFormat, "--path, --private-key")))
+	})
+
+	It("validates an encrypted file with the private key", func() {
+		command := exec.Command(aqueductBinaryPath, "validate", "--path="+encryptedFilePath, "--private-key="+privateKeyPath)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(session.Out).To(gbytes.Say("Success!"))
+	})
+
+	It("fails to validate an encrypted file without the private key", func() {
+		command := exec.Command(aqueductBinaryPath, "validate", "--path="+encryptedFilePath)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(1))
+		Expect(session.Err).To(gbytes.Say(regexp.QuoteMeta(fmt.Sprintf(encryption.EncryptedArchiveFormat, encryption.Fingerprint(&key.PublicKey)))))
+	})
+
+	It("inspects an encrypted file with the private key", func() {
+		command := exec.Command(aqueductBinaryPath, "inspect", "--path="+encryptedFilePath, "--private-key="+privateKeyPath)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(session.Out).To(gbytes.Say(`Data set:\s+some-data-set-name1\n`))
+	})
+
+	It("fails, reporting who an encrypted file is for, when inspecting it without the private key", func() {
+		command := exec.Command(aqueductBinaryPath, "inspect", "--path="+encryptedFilePath)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(1))
+		Expect(session.Err).To(gbytes.Say(regexp.QuoteMeta(fmt.Sprintf(cmd.EncryptedArchiveFormat, encryptedFilePath, encryption.Fingerprint(&key.PublicKey), cmd.PrivateKeyFlag))))
+	})
+})
+
+func writePrivateKey(dir, name string, key *rsa.PrivateKey) string {
+	path := filepath.Join(dir, name)
+	Expect(ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600)).To(Succeed())
+	return path
+}
+
+func writePublicKey(dir, name string, key *rsa.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	path := filepath.Join(dir, name)
+	Expect(ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644)).To(Succeed())
+	return path
+}
+
+func writeSigningKey(dir, name string, key ed25519.PrivateKey) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	path := filepath.Join(dir, name)
+	Expect(ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)).To(Succeed())
+	return path
+}
+
+func writeVerificationKey(dir, name string, key ed25519.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	path := filepath.Join(dir, name)
+	Expect(ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644)).To(Succeed())
+	return path
+}
+
+func encryptFile(path string, recipient *rsa.PublicKey) string {
+	contents, err := ioutil.ReadFile(path)
+	Expect(err).NotTo(HaveOccurred())
+
+	encryptedPath := path + encryption.FileExtension
+	encryptedFile, err := os.Create(encryptedPath)
+	Expect(err).NotTo(HaveOccurred())
+	defer encryptedFile.Close()
+
+	writer, err := encryption.NewWriter(encryptedFile, recipient)
+	Expect(err).NotTo(HaveOccurred())
+	_, err = writer.Write(contents)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(writer.Close()).To(Succeed())
+	return encryptedPath
+}
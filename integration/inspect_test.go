@@ -0,0 +1,101 @@
+package integration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+	"github.com/pivotal-cf/aqueduct-courier/cmd"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+	"github.com/pivotal-cf/telemetry-utils/tar"
+)
+
+var _ = Describe("Inspect", func() {
+	var (
+		tempDir     string
+		tarFilePath string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		tarFilePath = generateValidDataTarFile(tempDir)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("lists the metadata and files of each data set", func() {
+		command := exec.Command(aqueductBinaryPath, "inspect", "--path="+tarFilePath)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(session.Out).To(gbytes.Say(`Data set:\s+some-data-set-name1\n`))
+		Expect(session.Out).To(gbytes.Say(`EnvType:`))
+		Expect(session.Out).To(gbytes.Say(`CollectorVersion:`))
+		Expect(session.Out).To(gbytes.Say(`NAME\s+PRODUCT TYPE\s+DATA TYPE\s+MIME TYPE\s+SIZE\s+MD5\n`))
+		Expect(session.Out).To(gbytes.Say(`file1\s+0\s+`))
+		Expect(session.Out).To(gbytes.Say(`Data set:\s+some-data-set-name2\n`))
+		Expect(session.Out).To(gbytes.Say(`file2\s+0\s+`))
+	})
+
+	It("pretty-prints a single file", func() {
+		jsonTarFilePath := filepath.Join(tempDir, "json-foundation-data")
+		jsonTarFile, err := os.Create(jsonTarFilePath)
+		Expect(err).NotTo(HaveOccurred())
+		writer := tar.NewTarWriter(jsonTarFile)
+		Expect(writer.AddFile([]byte(`{"vm_types":[{"name":"small"}]}`), filepath.Join(collector_tar.OpsManagerCollectorDataSetId, "ops_manager_vm_types"))).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
+		Expect(jsonTarFile.Close()).To(Succeed())
+
+		command := exec.Command(aqueductBinaryPath, "inspect", "--path="+jsonTarFilePath, "--show="+filepath.Join(collector_tar.OpsManagerCollectorDataSetId, "ops_manager_vm_types"))
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(string(session.Out.Contents())).To(Equal(strings.Join([]string{
+			`{`,
+			`  "vm_types": [`,
+			`    {`,
+			`      "name": "small"`,
+			`    }`,
+			`  ]`,
+			`}`,
+			``,
+		}, "\n")))
+	})
+
+	It("fails if the file to show does not exist", func() {
+		command := exec.Command(aqueductBinaryPath, "inspect", "--path="+tarFilePath, "--show=not-a-file")
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(1))
+		Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.InspectFailureFormat, escapeWindowsPathRegex(tarFilePath))))
+	})
+
+	It("fails if the required flags have not been set", func() {
+		command := exec.Command(aqueductBinaryPath, "inspect")
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(1))
+		Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.RequiredConfigErrorFormat, "--"+cmd.DataTarFilePathFlag)))
+		Expect(session.Err).To(gbytes.Say("USAGE EXAMPLES"))
+	})
+
+	It("fails if the passed in path to tar file is invalid", func() {
+		command := exec.Command(aqueductBinaryPath, "inspect", "--path=invalid-path")
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(1))
+		Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.FileNotFoundErrorFormat, "invalid-path")))
+	})
+})
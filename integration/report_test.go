@@ -0,0 +1,75 @@
+package integration
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+	"github.com/pivotal-cf/aqueduct-courier/cmd"
+	"github.com/pivotal-cf/aqueduct-courier/report"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+	"github.com/pivotal-cf/telemetry-utils/tar"
+)
+
+var _ = Describe("Report", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("writes an HTML report of the collected data", func() {
+		tarFilePath := filepath.Join(tempDir, "foundation-data")
+		tarFile, err := os.Create(tarFilePath)
+		Expect(err).NotTo(HaveOccurred())
+		writer := tar.NewTarWriter(tarFile)
+		Expect(writer.AddFile([]byte(`{"EnvType": "production"}`), filepath.Join(collector_tar.OpsManagerCollectorDataSetId, collector_tar.MetadataFileName))).To(Succeed())
+		Expect(writer.AddFile([]byte(`[{"type": "cf", "product_version": "2.6.3"}]`), filepath.Join(collector_tar.OpsManagerCollectorDataSetId, "ops_manager_deployed_products"))).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
+		Expect(tarFile.Close()).To(Succeed())
+
+		reportPath := filepath.Join(tempDir, "report.html")
+		command := exec.Command(aqueductBinaryPath, "report", "--path="+tarFilePath, "--out="+reportPath)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(session.Out).To(gbytes.Say("Wrote report to " + escapeWindowsPathRegex(reportPath)))
+
+		contents, err := ioutil.ReadFile(reportPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(ContainSubstring("<td>production</td>"))
+		Expect(string(contents)).To(ContainSubstring("<td>cf</td><td>2.6.3</td>"))
+	})
+
+	It("fails without writing a report when the file has no Ops Manager data", func() {
+		tarFilePath := generateValidDataTarFile(tempDir)
+		reportPath := filepath.Join(tempDir, "report.html")
+		command := exec.Command(aqueductBinaryPath, "report", "--path="+tarFilePath, "--out="+reportPath)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(1))
+		Expect(session.Err).To(gbytes.Say(report.NoOpsManagerDataMessage))
+		Expect(reportPath).NotTo(BeAnExistingFile())
+	})
+
+	It("fails if required flags have not been set", func() {
+		command := exec.Command(aqueductBinaryPath, "report")
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(1))
+		requiredFlags := []string{"--" + cmd.DataTarFilePathFlag, "--" + cmd.ReportOutputPathFlag}
+		Expect(session.Err).To(gbytes.Say(strings.Join(requiredFlags, ", ")))
+	})
+})
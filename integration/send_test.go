@@ -1,7 +1,10 @@
 package integration
 
 import (
+	"bytes"
+	"crypto/ed25519"
 	"crypto/md5"
+	"crypto/rand"
 	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
@@ -14,7 +17,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/elazarl/goproxy"
 	. "github.com/onsi/ginkgo"
@@ -25,6 +31,8 @@ import (
 	"github.com/onsi/gomega/gexec"
 	"github.com/onsi/gomega/ghttp"
 	"github.com/pivotal-cf/aqueduct-courier/cmd"
+	"github.com/pivotal-cf/aqueduct-courier/compression"
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
 	"github.com/pivotal-cf/aqueduct-courier/operations"
 	"github.com/pivotal-cf/telemetry-utils/tar"
 )
@@ -192,6 +200,125 @@ var _ = Describe("Send", func() {
 			})
 		})
 
+		Context("compressed data", func() {
+			var compressedContents []byte
+
+			BeforeEach(func() {
+				srcContentBytes, err := ioutil.ReadFile(sourceDataTarFilePath)
+				Expect(err).NotTo(HaveOccurred())
+				var compressed bytes.Buffer
+				writer, err := compression.NewWriter(&compressed, compression.Gzip)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = writer.Write(srcContentBytes)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(writer.Close()).To(Succeed())
+				compressedContents = compressed.Bytes()
+				Expect(ioutil.WriteFile(sourceDataTarFilePath, compressedContents, 0644)).To(Succeed())
+
+				dataLoader.RouteToHandler(http.MethodPost, operations.PostPath, ghttp.CombineHandlers(
+					ghttp.VerifyHeader(http.Header{
+						"Content-Type":                   []string{operations.TarMimeType},
+						operations.ContentEncodingHeader: []string{compression.Gzip},
+					}),
+					ghttp.VerifyBody(compressedContents),
+					ghttp.RespondWith(http.StatusCreated, ""),
+				))
+			})
+
+			It("validates the compressed file and sends it with its content encoding", func() {
+				command := exec.Command(binaryPath, "send", "--path="+sourceDataTarFilePath, "--api-key="+validApiKey)
+				session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(0))
+				Expect(len(dataLoader.ReceivedRequests())).To(Equal(1))
+				Expect(session.Out).To(gbytes.Say("Success!\n"))
+			})
+		})
+
+		Context("chunked upload", func() {
+			var (
+				srcContentBytes []byte
+				received        *bytes.Buffer
+				chunksToFail    map[int]bool
+				chunkCount      int
+			)
+
+			BeforeEach(func() {
+				var err error
+				srcContentBytes, err = ioutil.ReadFile(sourceDataTarFilePath)
+				Expect(err).NotTo(HaveOccurred())
+				received = &bytes.Buffer{}
+				chunksToFail = map[int]bool{}
+				chunkCount = 0
+
+				uploadPath := operations.UploadsPath + "/some-upload-id"
+				dataLoader.RouteToHandler(http.MethodPost, operations.UploadsPath, ghttp.CombineHandlers(
+					ghttp.VerifyHeader(http.Header{
+						"Authorization":                           []string{fmt.Sprintf("Bearer %s", validApiKey)},
+						operations.UploadLengthHeader:             []string{strconv.Itoa(len(srcContentBytes))},
+						operations.HTTPSenderVersionRequestHeader: []string{testVersion},
+					}),
+					ghttp.RespondWith(http.StatusCreated, `{"upload_id": "some-upload-id"}`),
+				))
+				dataLoader.RouteToHandler(http.MethodHead, uploadPath, func(w http.ResponseWriter, req *http.Request) {
+					w.Header().Set(operations.UploadOffsetHeader, strconv.Itoa(received.Len()))
+					w.WriteHeader(http.StatusOK)
+				})
+				dataLoader.RouteToHandler(http.MethodPatch, uploadPath, func(w http.ResponseWriter, req *http.Request) {
+					chunkCount++
+					if chunksToFail[chunkCount] {
+						w.WriteHeader(http.StatusBadGateway)
+						return
+					}
+					Expect(req.Header.Get(operations.UploadOffsetHeader)).To(Equal(strconv.Itoa(received.Len())))
+					chunk, err := ioutil.ReadAll(req.Body)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(len(chunk)).To(BeNumerically("<=", 1024))
+					received.Write(chunk)
+					w.Header().Set(operations.UploadOffsetHeader, strconv.Itoa(received.Len()))
+					w.WriteHeader(http.StatusNoContent)
+				})
+				dataLoader.RouteToHandler(http.MethodPost, uploadPath+operations.UploadCompletePath, ghttp.RespondWith(http.StatusCreated, ""))
+			})
+
+			It("uploads the file in chunks", func() {
+				command := exec.Command(binaryPath, "send", "--path="+sourceDataTarFilePath, "--api-key="+validApiKey, "--"+cmd.ChunkSizeFlag+"=1")
+				session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(0))
+				Expect(received.Bytes()).To(Equal(srcContentBytes))
+				Expect(chunkCount).To(BeNumerically(">", 1))
+				Expect(session.Out).To(gbytes.Say("Success!\n"))
+			})
+
+			It("resumes an interrupted upload where it left off", func() {
+				chunksToFail[2] = true
+
+				command := exec.Command(binaryPath, "send", "--path="+sourceDataTarFilePath, "--api-key="+validApiKey, "--"+cmd.ChunkSizeFlag+"=1", "--"+cmd.RetryMaxAttemptsFlag+"=1")
+				session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1))
+				Expect(session.Err).To(gbytes.Say(cmd.SendFailureMessage))
+				Expect(sourceDataTarFilePath + operations.UploadStateFileSuffix).To(BeAnExistingFile())
+				Expect(received.Len()).To(Equal(1024))
+
+				command = exec.Command(binaryPath, "send", "--path="+sourceDataTarFilePath, "--api-key="+validApiKey, "--"+cmd.ChunkSizeFlag+"=1")
+				session, err = gexec.Start(command, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(0))
+				Expect(received.Bytes()).To(Equal(srcContentBytes))
+				Expect(sourceDataTarFilePath + operations.UploadStateFileSuffix).NotTo(BeAnExistingFile())
+
+				var startRequests int
+				for _, req := range dataLoader.ReceivedRequests() {
+					if req.Method == http.MethodPost && req.URL.Path == operations.UploadsPath {
+						startRequests++
+					}
+				}
+				Expect(startRequests).To(Equal(1))
+			})
+		})
+
 		It("exits non-zero when sending to pivotal fails", func() {
 			dataLoader.RouteToHandler(http.MethodPost, operations.PostPath, ghttp.RespondWith(http.StatusUnauthorized, ""))
 
@@ -203,6 +330,107 @@ var _ = Describe("Send", func() {
 			Expect(session.Err).NotTo(gbytes.Say("USAGE EXAMPLES"))
 		})
 
+		It("retries sending when the data loader responds with a server error", func() {
+			srcContentBytes, err := ioutil.ReadFile(sourceDataTarFilePath)
+			Expect(err).NotTo(HaveOccurred())
+			dataLoader.AppendHandlers(
+				ghttp.RespondWith(http.StatusBadGateway, ""),
+				ghttp.CombineHandlers(ghttp.VerifyBody(srcContentBytes), ghttp.RespondWith(http.StatusCreated, "")),
+			)
+
+			command := exec.Command(binaryPath, "send", "--path="+sourceDataTarFilePath, "--api-key="+validApiKey, "--"+cmd.RetryBaseDelayFlag+"=10ms", "--"+cmd.RetryMaxJitterFlag+"=0s")
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			Expect(dataLoader.ReceivedRequests()).To(HaveLen(2))
+			Expect(session.Out).To(gbytes.Say(`Retrying POST .* \(attempt 2 of 3\): 502 Bad Gateway`))
+			Expect(session.Out).To(gbytes.Say("Success!\n"))
+		})
+
+		It("fails once the overall timeout elapses", func() {
+			dataLoader.RouteToHandler(http.MethodPost, operations.PostPath, func(w http.ResponseWriter, req *http.Request) {
+				select {
+				case <-req.Context().Done():
+				case <-time.After(10 * time.Second):
+				}
+			})
+
+			command := exec.Command(binaryPath, "send", "--path="+sourceDataTarFilePath, "--api-key="+validApiKey, "--"+cmd.TimeoutFlag+"=1s")
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session, 5*time.Second).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.TimeoutExceededFormat, "1s")))
+			Expect(session.Err).To(gbytes.Say(cmd.SendFailureMessage))
+		})
+
+		It("fails if the retry configuration is invalid", func() {
+			command := exec.Command(binaryPath, "send", "--path="+sourceDataTarFilePath, "--api-key="+validApiKey, "--"+cmd.RetryBaseDelayFlag+"=soon")
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.InvalidDurationErrorFormat, "soon", cmd.RetryBaseDelayFlag)))
+		})
+
+		It("prints the request and a summary of the file without sending it on a dry run", func() {
+			apiKey := "some-long-secret-api-key"
+			command := exec.Command(binaryPath, "send", "--path="+sourceDataTarFilePath, "--api-key="+apiKey, "--"+cmd.DryRunFlag)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			Expect(dataLoader.ReceivedRequests()).To(BeEmpty())
+
+			Expect(session.Out).To(gbytes.Say(cmd.DryRunSendMessage))
+			Expect(session.Out).To(gbytes.Say(regexp.QuoteMeta(fmt.Sprintf("POST %s%s", dataLoader.URL(), operations.PostPath))))
+			Expect(session.Out).To(gbytes.Say(regexp.QuoteMeta("Bearer " + operations.MaskedAPIKey + "-key")))
+			Expect(session.Out).To(gbytes.Say(operations.HTTPSenderVersionRequestHeader))
+			Expect(session.Out).To(gbytes.Say("Data set:"))
+			Expect(session.Out).NotTo(gbytes.Say(apiKey))
+			Expect(session.Out).NotTo(gbytes.Say("Success!"))
+		})
+
+		It("refuses to send a file that fails validation", func() {
+			invalidFilePath := filepath.Join(tempDir, "invalid-foundation-data")
+			Expect(ioutil.WriteFile(invalidFilePath, []byte("not-a-tar"), 0644)).To(Succeed())
+
+			command := exec.Command(binaryPath, "send", "--path="+invalidFilePath, "--api-key="+validApiKey)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(cmd.SendFailureMessage))
+			Expect(session.Err).To(gbytes.Say(operations.ValidateDataFileError))
+			Expect(dataLoader.ReceivedRequests()).To(BeEmpty())
+		})
+
+		It("refuses to send a file without a signature when a verification key is configured", func() {
+			publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			keyPath := writeVerificationKey(tempDir, "verification.pem", publicKey)
+
+			command := exec.Command(binaryPath, "send", "--path="+sourceDataTarFilePath, "--api-key="+validApiKey, "--verification-key="+keyPath)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say(operations.VerifySignatureFailureMessage))
+			Expect(dataLoader.ReceivedRequests()).To(BeEmpty())
+		})
+
+		It("sends a file without validating it when validation is skipped", func() {
+			invalidFilePath := filepath.Join(tempDir, "invalid-foundation-data")
+			Expect(ioutil.WriteFile(invalidFilePath, []byte("not-a-tar"), 0644)).To(Succeed())
+			dataLoader.RouteToHandler(http.MethodPost, operations.PostPath, ghttp.CombineHandlers(
+				ghttp.VerifyBody([]byte("not-a-tar")),
+				ghttp.RespondWith(http.StatusCreated, ""),
+			))
+
+			command := exec.Command(binaryPath, "send", "--path="+invalidFilePath, "--api-key="+validApiKey, "--"+cmd.SkipValidationFlag)
+			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			Expect(len(dataLoader.ReceivedRequests())).To(Equal(1))
+			Expect(session.Out).To(gbytes.Say(fmt.Sprintf("Skipping validation of %s\n", escapeWindowsPathRegex(invalidFilePath))))
+			Expect(session.Out).To(gbytes.Say("Success!\n"))
+		})
+
 		It("fails if required flags have not been set", func() {
 			command := exec.Command(binaryPath, "send")
 			session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
@@ -275,24 +503,24 @@ func generateValidDataTarFile(destinationDir string) string {
 	defer writer.Close()
 
 	Expect(writer.AddFile([]byte{}, filepath.Join("some-data-set-name1", "file1"))).To(Succeed())
-	Expect(writer.AddFile([]byte{}, filepath.Join("some-data-set-name2", "file1"))).To(Succeed())
+	Expect(writer.AddFile([]byte{}, filepath.Join("some-data-set-name2", "file2"))).To(Succeed())
 	sum := md5.Sum([]byte{})
 	emptyFileChecksum := base64.StdEncoding.EncodeToString(sum[:])
 
-	var metadata collector_tar.Metadata
-	metadata.FileDigests = []collector_tar.FileDigest{
+	var metadata manifest.Metadata
+	metadata.FileDigests = []manifest.FileDigest{
 		{Name: "file1", MD5Checksum: emptyFileChecksum},
 	}
 	metadataContents, err := json.Marshal(metadata)
 	Expect(err).NotTo(HaveOccurred())
 	Expect(writer.AddFile(metadataContents, filepath.Join("some-data-set-name1", collector_tar.MetadataFileName))).To(Succeed())
 
-	metadata.FileDigests = []collector_tar.FileDigest{
+	metadata.FileDigests = []manifest.FileDigest{
 		{Name: "file2", MD5Checksum: emptyFileChecksum},
 	}
 	metadataContents, err = json.Marshal(metadata)
 	Expect(err).NotTo(HaveOccurred())
-	Expect(writer.AddFile(metadataContents, filepath.Join("some-data-set-name1", collector_tar.MetadataFileName))).To(Succeed())
+	Expect(writer.AddFile(metadataContents, filepath.Join("some-data-set-name2", collector_tar.MetadataFileName))).To(Succeed())
 
 	return tarFilePath
 }
@@ -0,0 +1,104 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+	"github.com/pivotal-cf/aqueduct-courier/cmd"
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
+	"github.com/pivotal-cf/aqueduct-courier/operations"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+	"github.com/pivotal-cf/telemetry-utils/tar"
+)
+
+var _ = Describe("Validate", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("reports each file and succeeds for a valid tar with flag configuration", func() {
+		tarFilePath := generateValidDataTarFile(tempDir)
+
+		command := exec.Command(aqueductBinaryPath, "validate", "--path="+tarFilePath)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(session.Out).To(gbytes.Say(fmt.Sprintf("Validating %s\n", escapeWindowsPathRegex(tarFilePath))))
+		Expect(session.Out).To(gbytes.Say("some-data-set-name1:\n"))
+		Expect(session.Out).To(gbytes.Say(fmt.Sprintf("  file1: %s\n", operations.FileStatusValid)))
+		Expect(session.Out).To(gbytes.Say("some-data-set-name2:\n"))
+		Expect(session.Out).To(gbytes.Say(fmt.Sprintf("  file2: %s\n", operations.FileStatusValid)))
+		Expect(session.Out).To(gbytes.Say("Success!\n"))
+	})
+
+	It("succeeds with the path as an env variable", func() {
+		tarFilePath := generateValidDataTarFile(tempDir)
+
+		command := exec.Command(aqueductBinaryPath, "validate")
+		command.Env = append(os.Environ(), fmt.Sprintf("%s=%s", cmd.DataTarFilePathKey, tarFilePath))
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(session.Out).To(gbytes.Say("Success!\n"))
+	})
+
+	It("reports the failing files and exits non-zero for an invalid tar", func() {
+		tarFilePath := filepath.Join(tempDir, "invalid-foundation-data")
+		tarFile, err := os.Create(tarFilePath)
+		Expect(err).NotTo(HaveOccurred())
+		writer := tar.NewTarWriter(tarFile)
+		Expect(writer.AddFile([]byte("tampered"), filepath.Join("some-data-set-name", "file1"))).To(Succeed())
+		metadataContents, err := json.Marshal(manifest.Metadata{FileDigests: []manifest.FileDigest{
+			{Name: "file1", MD5Checksum: "not-the-checksum"},
+			{Name: "file2", MD5Checksum: "not-the-checksum"},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.AddFile(metadataContents, filepath.Join("some-data-set-name", collector_tar.MetadataFileName))).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
+		Expect(tarFile.Close()).To(Succeed())
+
+		command := exec.Command(aqueductBinaryPath, "validate", "--path="+tarFilePath)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(1))
+		Expect(session.Out).To(gbytes.Say(fmt.Sprintf("  file1: %s\n", operations.FileStatusChecksumInvalid)))
+		Expect(session.Out).To(gbytes.Say(fmt.Sprintf("  file2: %s\n", operations.FileStatusMissing)))
+		Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.ValidationFailureFormat, escapeWindowsPathRegex(tarFilePath))))
+		Expect(session.Err).NotTo(gbytes.Say("USAGE EXAMPLES"))
+	})
+
+	It("fails if the required flags have not been set", func() {
+		command := exec.Command(aqueductBinaryPath, "validate")
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(1))
+		requiredFlags := []string{"--" + cmd.DataTarFilePathFlag}
+		Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.RequiredConfigErrorFormat, strings.Join(requiredFlags, ", "))))
+		Expect(session.Err).To(gbytes.Say("USAGE EXAMPLES"))
+	})
+
+	It("fails if the passed in path to tar file is invalid", func() {
+		command := exec.Command(aqueductBinaryPath, "validate", "--path=invalid-path")
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(1))
+		Expect(session.Err).To(gbytes.Say(fmt.Sprintf(cmd.FileNotFoundErrorFormat, "invalid-path")))
+	})
+})
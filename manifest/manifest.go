@@ -0,0 +1,251 @@
+package manifest
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+	"github.com/pkg/errors"
+)
+
+const (
+	CfApiDataSetId = "cf_api"
+
+	// ExtensionsDataSetId is the data set holding, for each other data set, a
+	// file of what its metadata records beyond the telemetry-utils schema. It
+	// has metadata of its own, so loaders that only know that schema still
+	// accept the archive.
+	ExtensionsDataSetId = "metadata_extensions"
+
+	ReadMetadataFailureFormat   = "Unable to read metadata for data set %s"
+	InvalidFailureRecordMessage = "Metadata has invalid failure records"
+)
+
+// Metadata is a data set's metadata together with its extensions.
+type Metadata struct {
+	EnvType             string
+	CollectedAt         string
+	CollectionId        string
+	FoundationId        string
+	FileDigests         []FileDigest
+	CollectorVersion    string
+	Failures            []Failure `json:",omitempty"`
+	RedactionPolicyHash string    `json:",omitempty"`
+	Pseudonymized       bool      `json:",omitempty"`
+}
+
+type FileDigest struct {
+	Name           string
+	MimeType       string
+	MD5Checksum    string
+	SHA256Checksum string `json:",omitempty"`
+	ProductType    string
+	DataType       string
+	// Start and End are the first and last days, as YYYY-MM-DD, of a usage
+	// report for a range of days.
+	Start string `json:",omitempty"`
+	End   string `json:",omitempty"`
+}
+
+// Failure records data that could not be collected during a partial collection.
+type Failure struct {
+	ProductType string
+	DataType    string
+	Start       string `json:",omitempty"`
+	End         string `json:",omitempty"`
+	Error       string
+}
+
+// Extensions is what a data set's metadata records beyond the telemetry-utils
+// schema.
+type Extensions struct {
+	Files               []FileExtensions `json:",omitempty"`
+	Failures            []Failure        `json:",omitempty"`
+	RedactionPolicyHash string           `json:",omitempty"`
+	Pseudonymized       bool             `json:",omitempty"`
+}
+
+type FileExtensions struct {
+	Name           string
+	SHA256Checksum string `json:",omitempty"`
+	Start          string `json:",omitempty"`
+	End            string `json:",omitempty"`
+}
+
+// SHA256Checksum returns the digest recorded as a FileDigest's SHA256Checksum.
+func SHA256Checksum(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// MD5Checksum returns the digest recorded as a FileDigest's MD5Checksum.
+func MD5Checksum(contents []byte) string {
+	sum := md5.Sum(contents)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// ExtensionsFile is where the extensions of a data set are in the archive.
+func ExtensionsFile(dataSet string) string {
+	return filepath.Join(ExtensionsDataSetId, dataSet)
+}
+
+// Split separates the metadata telemetry-utils can read from the extensions.
+func (m Metadata) Split() (collector_tar.Metadata, Extensions) {
+	metadata := collector_tar.Metadata{
+		EnvType:          m.EnvType,
+		CollectedAt:      m.CollectedAt,
+		CollectionId:     m.CollectionId,
+		FoundationId:     m.FoundationId,
+		CollectorVersion: m.CollectorVersion,
+	}
+	extensions := Extensions{
+		Failures:            m.Failures,
+		RedactionPolicyHash: m.RedactionPolicyHash,
+		Pseudonymized:       m.Pseudonymized,
+	}
+	for _, digest := range m.FileDigests {
+		metadata.FileDigests = append(metadata.FileDigests, collector_tar.FileDigest{
+			Name:        digest.Name,
+			MimeType:    digest.MimeType,
+			MD5Checksum: digest.MD5Checksum,
+			ProductType: digest.ProductType,
+			DataType:    digest.DataType,
+		})
+		extensions.Files = append(extensions.Files, FileExtensions{
+			Name:           digest.Name,
+			SHA256Checksum: digest.SHA256Checksum,
+			Start:          digest.Start,
+			End:            digest.End,
+		})
+	}
+	return metadata, extensions
+}
+
+// Extend adds the extensions to the metadata.
+func (m *Metadata) Extend(extensions Extensions) {
+	m.Failures = append(m.Failures, extensions.Failures...)
+	if extensions.RedactionPolicyHash != "" {
+		m.RedactionPolicyHash = extensions.RedactionPolicyHash
+	}
+	m.Pseudonymized = m.Pseudonymized || extensions.Pseudonymized
+	for _, file := range extensions.Files {
+		for i := range m.FileDigests {
+			if m.FileDigests[i].Name == file.Name {
+				m.FileDigests[i].SHA256Checksum = file.SHA256Checksum
+				m.FileDigests[i].Start = file.Start
+				m.FileDigests[i].End = file.End
+			}
+		}
+	}
+}
+
+type fileReader interface {
+	ReadFile(fileName string) ([]byte, error)
+}
+
+// Read reads a data set's metadata along with its extensions, when fileMd5s,
+// the files in the archive, has them.
+func Read(archive fileReader, fileMd5s map[string]string, dataSet string) (Metadata, error) {
+	var metadata Metadata
+	contents, err := archive.ReadFile(filepath.Join(dataSet, collector_tar.MetadataFileName))
+	if err != nil {
+		return metadata, errors.Wrapf(err, ReadMetadataFailureFormat, dataSet)
+	}
+	if err := json.Unmarshal(contents, &metadata); err != nil {
+		return metadata, errors.Wrapf(err, ReadMetadataFailureFormat, dataSet)
+	}
+
+	if _, exists := fileMd5s[ExtensionsFile(dataSet)]; !exists {
+		return metadata, nil
+	}
+	var extensions Extensions
+	contents, err = archive.ReadFile(ExtensionsFile(dataSet))
+	if err != nil {
+		return metadata, errors.Wrapf(err, ReadMetadataFailureFormat, dataSet)
+	}
+	if err := json.Unmarshal(contents, &extensions); err != nil {
+		return metadata, errors.Wrapf(err, ReadMetadataFailureFormat, dataSet)
+	}
+	metadata.Extend(extensions)
+	return metadata, nil
+}
+
+// CheckFailures checks that every failure names what failed and why, and
+// that nothing is recorded as both collected and failed.
+func (m Metadata) CheckFailures() error {
+	for _, failure := range m.Failures {
+		if failure.DataType == "" || failure.Error == "" {
+			return errors.New(InvalidFailureRecordMessage)
+		}
+		for _, digest := range m.FileDigests {
+			if digest.ProductType == failure.ProductType && digest.DataType == failure.DataType &&
+				digest.Start == failure.Start && digest.End == failure.End {
+				return errors.New(InvalidFailureRecordMessage)
+			}
+		}
+	}
+	return nil
+}
+
+type tarWriter interface {
+	AddFile(contents []byte, fileName string) error
+}
+
+// Writer writes each data set's metadata and extensions, then the metadata
+// of the extensions data set itself.
+type Writer struct {
+	tarWriter  tarWriter
+	extensions []collector_tar.FileDigest
+}
+
+func NewWriter(tarWriter tarWriter) *Writer {
+	return &Writer{tarWriter: tarWriter}
+}
+
+// Write writes the metadata of a data set and its extensions.
+func (w *Writer) Write(dataSet string, metadata Metadata) error {
+	schemaMetadata, extensions := metadata.Split()
+	metadataContents, err := json.Marshal(schemaMetadata)
+	if err != nil {
+		return err
+	}
+	extensionsContents, err := json.Marshal(extensions)
+	if err != nil {
+		return err
+	}
+
+	if err := w.tarWriter.AddFile(metadataContents, filepath.Join(dataSet, collector_tar.MetadataFileName)); err != nil {
+		return err
+	}
+	if err := w.tarWriter.AddFile(extensionsContents, ExtensionsFile(dataSet)); err != nil {
+		return err
+	}
+	w.extensions = append(w.extensions, collector_tar.FileDigest{
+		Name:        dataSet,
+		MimeType:    "application/json",
+		MD5Checksum: MD5Checksum(extensionsContents),
+		DataType:    ExtensionsDataSetId,
+	})
+	return nil
+}
+
+// Close writes the metadata of the extensions data set, taking everything
+// but the file digests from base.
+func (w *Writer) Close(base Metadata) error {
+	metadata := collector_tar.Metadata{
+		EnvType:          base.EnvType,
+		CollectedAt:      base.CollectedAt,
+		CollectionId:     base.CollectionId,
+		FoundationId:     base.FoundationId,
+		CollectorVersion: base.CollectorVersion,
+		FileDigests:      w.extensions,
+	}
+	contents, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	return w.tarWriter.AddFile(contents, filepath.Join(ExtensionsDataSetId, collector_tar.MetadataFileName))
+}
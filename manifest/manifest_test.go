@@ -0,0 +1,194 @@
+package manifest_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/aqueduct-courier/manifest"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+)
+
+type archiveFiles map[string][]byte
+
+func (a archiveFiles) AddFile(contents []byte, fileName string) error {
+	a[fileName] = contents
+	return nil
+}
+
+func (a archiveFiles) ReadFile(fileName string) ([]byte, error) {
+	contents, exists := a[fileName]
+	if !exists {
+		return nil, io.EOF
+	}
+	return contents, nil
+}
+
+func (a archiveFiles) fileMd5s() map[string]string {
+	fileMd5s := map[string]string{}
+	for fileName, contents := range a {
+		fileMd5s[fileName] = MD5Checksum(contents)
+	}
+	return fileMd5s
+}
+
+type failingWriter struct{}
+
+func (failingWriter) AddFile([]byte, string) error {
+	return errors.New("tarring is hard")
+}
+
+var _ = Describe("Manifest", func() {
+	var metadata Metadata
+
+	BeforeEach(func() {
+		metadata = Metadata{
+			EnvType:          "development",
+			CollectedAt:      "2018-03-01T00:00:00Z",
+			CollectionId:     "some-collection-id",
+			FoundationId:     "some-foundation-id",
+			CollectorVersion: "0.0.1-version",
+			FileDigests: []FileDigest{{
+				Name:           "app_usage_2018-01",
+				MimeType:       "application/json",
+				MD5Checksum:    MD5Checksum([]byte("january-content")),
+				SHA256Checksum: SHA256Checksum([]byte("january-content")),
+				DataType:       collector_tar.AppUsageDataType,
+				Start:          "2018-01-01",
+				End:            "2018-01-31",
+			}},
+			Failures:            []Failure{{DataType: collector_tar.AppUsageDataType, Start: "2018-02-01", End: "2018-02-28", Error: "retrieving is hard"}},
+			RedactionPolicyHash: "sha256:some-policy-hash",
+			Pseudonymized:       true,
+		}
+	})
+
+	strictlyDecode := func(contents []byte) collector_tar.Metadata {
+		var schemaMetadata collector_tar.Metadata
+		decoder := json.NewDecoder(bytes.NewReader(contents))
+		decoder.DisallowUnknownFields()
+		Expect(decoder.Decode(&schemaMetadata)).To(Succeed())
+		return schemaMetadata
+	}
+
+	Describe("Split and Extend", func() {
+		It("splits the metadata into the telemetry-utils schema and extensions that extend it back", func() {
+			schemaMetadata, extensions := metadata.Split()
+			Expect(schemaMetadata).To(Equal(collector_tar.Metadata{
+				EnvType:          "development",
+				CollectedAt:      "2018-03-01T00:00:00Z",
+				CollectionId:     "some-collection-id",
+				FoundationId:     "some-foundation-id",
+				CollectorVersion: "0.0.1-version",
+				FileDigests: []collector_tar.FileDigest{{
+					Name:        "app_usage_2018-01",
+					MimeType:    "application/json",
+					MD5Checksum: MD5Checksum([]byte("january-content")),
+					DataType:    collector_tar.AppUsageDataType,
+				}},
+			}))
+
+			extended := Metadata{
+				EnvType:          schemaMetadata.EnvType,
+				CollectedAt:      schemaMetadata.CollectedAt,
+				CollectionId:     schemaMetadata.CollectionId,
+				FoundationId:     schemaMetadata.FoundationId,
+				CollectorVersion: schemaMetadata.CollectorVersion,
+				FileDigests: []FileDigest{{
+					Name:        "app_usage_2018-01",
+					MimeType:    "application/json",
+					MD5Checksum: MD5Checksum([]byte("january-content")),
+					DataType:    collector_tar.AppUsageDataType,
+				}},
+			}
+			extended.Extend(extensions)
+			Expect(extended).To(Equal(metadata))
+		})
+	})
+
+	Describe("Writer", func() {
+		It("writes metadata telemetry-utils can read, with the extensions in their own data set", func() {
+			files := archiveFiles{}
+			writer := NewWriter(files)
+			Expect(writer.Write(collector_tar.UsageServiceCollectorDataSetId, metadata)).To(Succeed())
+			Expect(writer.Close(metadata)).To(Succeed())
+
+			Expect(files).To(HaveLen(3))
+			schemaMetadata := strictlyDecode(files[filepath.Join(collector_tar.UsageServiceCollectorDataSetId, collector_tar.MetadataFileName)])
+			Expect(schemaMetadata.FileDigests).To(HaveLen(1))
+
+			extensionsMetadata := strictlyDecode(files[filepath.Join(ExtensionsDataSetId, collector_tar.MetadataFileName)])
+			Expect(extensionsMetadata.CollectionId).To(Equal("some-collection-id"))
+			Expect(extensionsMetadata.FileDigests).To(Equal([]collector_tar.FileDigest{{
+				Name:        collector_tar.UsageServiceCollectorDataSetId,
+				MimeType:    "application/json",
+				MD5Checksum: MD5Checksum(files[ExtensionsFile(collector_tar.UsageServiceCollectorDataSetId)]),
+				DataType:    ExtensionsDataSetId,
+			}}))
+		})
+
+		It("returns an error when a file cannot be written", func() {
+			writer := NewWriter(failingWriter{})
+			Expect(writer.Write(collector_tar.UsageServiceCollectorDataSetId, metadata)).To(MatchError("tarring is hard"))
+			Expect(writer.Close(metadata)).To(MatchError("tarring is hard"))
+		})
+	})
+
+	Describe("Read", func() {
+		It("reads what was written", func() {
+			files := archiveFiles{}
+			writer := NewWriter(files)
+			Expect(writer.Write(collector_tar.UsageServiceCollectorDataSetId, metadata)).To(Succeed())
+			Expect(writer.Close(metadata)).To(Succeed())
+
+			read, err := Read(files, files.fileMd5s(), collector_tar.UsageServiceCollectorDataSetId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(read).To(Equal(metadata))
+		})
+
+		It("reads metadata with the extensions inline", func() {
+			contents, err := json.Marshal(metadata)
+			Expect(err).NotTo(HaveOccurred())
+			files := archiveFiles{filepath.Join(collector_tar.UsageServiceCollectorDataSetId, collector_tar.MetadataFileName): contents}
+
+			read, err := Read(files, files.fileMd5s(), collector_tar.UsageServiceCollectorDataSetId)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(read).To(Equal(metadata))
+		})
+
+		It("returns an error when the metadata is missing or invalid", func() {
+			files := archiveFiles{}
+			_, err := Read(files, files.fileMd5s(), collector_tar.UsageServiceCollectorDataSetId)
+			Expect(err).To(MatchError(ContainSubstring(ReadMetadataFailureFormat, collector_tar.UsageServiceCollectorDataSetId)))
+
+			files[filepath.Join(collector_tar.UsageServiceCollectorDataSetId, collector_tar.MetadataFileName)] = []byte("{}")
+			files[ExtensionsFile(collector_tar.UsageServiceCollectorDataSetId)] = []byte("not json")
+			_, err = Read(files, files.fileMd5s(), collector_tar.UsageServiceCollectorDataSetId)
+			Expect(err).To(MatchError(ContainSubstring(ReadMetadataFailureFormat, collector_tar.UsageServiceCollectorDataSetId)))
+		})
+	})
+
+	Describe("CheckFailures", func() {
+		It("accepts failures of data that was not collected", func() {
+			Expect(metadata.CheckFailures()).To(Succeed())
+		})
+
+		It("rejects failures without a data type or error", func() {
+			metadata.Failures = []Failure{{Error: "retrieving is hard"}}
+			Expect(metadata.CheckFailures()).To(MatchError(InvalidFailureRecordMessage))
+
+			metadata.Failures = []Failure{{DataType: collector_tar.AppUsageDataType}}
+			Expect(metadata.CheckFailures()).To(MatchError(InvalidFailureRecordMessage))
+		})
+
+		It("rejects failures of data that was collected", func() {
+			metadata.Failures[0].Start = "2018-01-01"
+			metadata.Failures[0].End = "2018-01-31"
+			Expect(metadata.CheckFailures()).To(MatchError(InvalidFailureRecordMessage))
+		})
+	})
+})
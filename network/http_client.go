@@ -1,12 +1,17 @@
 package network
 
 import (
+	"context"
 	"crypto/tls"
 	"net"
 	"net/http"
 	"time"
 )
 
+type httpClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
 func NewClient(skipTLSVerification bool) *http.Client {
 	return &http.Client{
 		Transport: &http.Transport{
@@ -22,3 +27,18 @@ func NewClient(skipTLSVerification bool) *http.Client {
 		},
 	}
 }
+
+// ContextClient sends every request with the context it was created with,
+// for clients whose requests are built by code that does not take a context.
+type ContextClient struct {
+	ctx    context.Context
+	client httpClient
+}
+
+func NewContextClient(ctx context.Context, client httpClient) *ContextClient {
+	return &ContextClient{ctx: ctx, client: client}
+}
+
+func (cc *ContextClient) Do(req *http.Request) (*http.Response, error) {
+	return cc.client.Do(req.WithContext(cc.ctx))
+}
@@ -1,6 +1,7 @@
 package network_test
 
 import (
+	"context"
 	"crypto/tls"
 	"log"
 	"net/http"
@@ -58,6 +59,23 @@ var _ = Describe("Client", func() {
 		})
 	})
 
+	Describe("ContextClient", func() {
+		It("sends requests with its context", func() {
+			server.RouteToHandler(http.MethodGet, "/", func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			req, err := http.NewRequest(http.MethodGet, server.URL(), nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = NewContextClient(ctx, NewClient(true)).Do(req)
+			Expect(err).To(MatchError(ContainSubstring(context.Canceled.Error())))
+			Expect(server.ReceivedRequests()).To(BeEmpty())
+		})
+	})
+
 	Describe("MinVersion", func() {
 		BeforeEach(func() {
 			server.HTTPTestServer.TLS.MaxVersion = tls.VersionTLS11
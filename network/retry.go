@@ -0,0 +1,166 @@
+package network
+
+import (
+	"context"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+const (
+	RetryLogFormat   = "Retrying %s in %s (attempt %d of %d): %s"
+	RetryAfterHeader = "Retry-After"
+
+	// DefaultMaxDelay caps the delay of policies without a MaxDelay.
+	DefaultMaxDelay = time.Minute
+)
+
+// RetryPolicy retries connection failures and 5xx responses with exponential
+// backoff. Other errors and responses are returned after a single attempt.
+// Neither the backoff nor a delay asked for with Retry-After is longer than
+// MaxDelay, plus jitter for the backoff.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	MaxJitter   time.Duration
+	Logger      *log.Logger
+}
+
+// Retry stops waiting between attempts as soon as ctx is done.
+func (p RetryPolicy) Retry(ctx context.Context, description string, attempt func() (*http.Response, error)) (*http.Response, error) {
+	for attemptNumber := 1; ; attemptNumber++ {
+		resp, err := attempt()
+
+		retryable, reason, retryAfter := classify(resp, err)
+		if !retryable || attemptNumber >= p.MaxAttempts {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		delay := p.delay(attemptNumber)
+		if retryAfter > p.maxDelay() {
+			retryAfter = p.maxDelay()
+		}
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		if p.Logger != nil {
+			p.Logger.Printf(RetryLogFormat, description, delay, attemptNumber+1, p.MaxAttempts, reason)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// delay doubles BaseDelay for each earlier retry, stopping once it reaches
+// the maximum rather than overflowing.
+func (p RetryPolicy) delay(attemptNumber int) time.Duration {
+	maxDelay := p.maxDelay()
+	delay := p.BaseDelay
+	for retry := 1; retry < attemptNumber && delay < maxDelay; retry++ {
+		if delay > maxDelay/2 {
+			delay = maxDelay
+			break
+		}
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if p.MaxJitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(p.MaxJitter)))
+	}
+	return delay
+}
+
+func (p RetryPolicy) maxDelay() time.Duration {
+	if p.MaxDelay <= 0 {
+		return DefaultMaxDelay
+	}
+	return p.MaxDelay
+}
+
+type RetryingClient struct {
+	client httpClient
+	policy RetryPolicy
+}
+
+func NewRetryingClient(client httpClient, policy RetryPolicy) *RetryingClient {
+	return &RetryingClient{client: client, policy: policy}
+}
+
+// Do retries the request according to the policy. Requests with a body are
+// only retried when the body can be replayed through GetBody.
+func (rc *RetryingClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.GetBody == nil {
+		return rc.client.Do(req)
+	}
+
+	attempted := false
+	return rc.policy.Retry(req.Context(), req.Method+" "+req.URL.String(), func() (*http.Response, error) {
+		if attempted && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		attempted = true
+		return rc.client.Do(req)
+	})
+}
+
+func classify(resp *http.Response, err error) (bool, string, time.Duration) {
+	if err != nil {
+		return isConnectionError(err), err.Error(), 0
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return true, resp.Status, retryAfter(resp)
+	}
+	return false, "", 0
+}
+
+// Requests that exceed the client timeout are not retried, so a configured
+// timeout still bounds how long a request can take.
+func isConnectionError(err error) bool {
+	cause := errors.Cause(err)
+	if urlErr, ok := cause.(*url.Error); ok {
+		cause = urlErr.Err
+	}
+
+	switch typedErr := cause.(type) {
+	case *oauth2.RetrieveError:
+		return typedErr.Response != nil && typedErr.Response.StatusCode >= http.StatusInternalServerError
+	case *net.OpError:
+		return typedErr.Op == "dial" || typedErr.Op == "read" || typedErr.Op == "write"
+	}
+	return cause == io.EOF || cause == io.ErrUnexpectedEOF
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get(RetryAfterHeader)
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
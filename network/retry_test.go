@@ -0,0 +1,187 @@
+package network_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/aqueduct-courier/network"
+)
+
+var _ = Describe("RetryingClient", func() {
+	var (
+		server *ghttp.Server
+		output *gbytes.Buffer
+		policy RetryPolicy
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		output = gbytes.NewBuffer()
+		policy = RetryPolicy{
+			MaxAttempts: 3,
+			BaseDelay:   time.Millisecond,
+			Logger:      log.New(output, "", 0),
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("retries 5xx responses and logs each retry", func() {
+		server.AppendHandlers(
+			ghttp.RespondWith(http.StatusBadGateway, ""),
+			ghttp.RespondWith(http.StatusServiceUnavailable, ""),
+			ghttp.RespondWith(http.StatusOK, "success"),
+		)
+		req, err := http.NewRequest(http.MethodGet, server.URL()+"/some-path", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := NewRetryingClient(http.DefaultClient, policy).Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(server.ReceivedRequests()).To(HaveLen(3))
+		Expect(output).To(gbytes.Say(`Retrying GET .*/some-path in .* \(attempt 2 of 3\): 502 Bad Gateway`))
+		Expect(output).To(gbytes.Say(`Retrying GET .*/some-path in .* \(attempt 3 of 3\): 503 Service Unavailable`))
+	})
+
+	It("returns the last response once the attempts are exhausted", func() {
+		server.AppendHandlers(
+			ghttp.RespondWith(http.StatusInternalServerError, ""),
+			ghttp.RespondWith(http.StatusInternalServerError, ""),
+			ghttp.RespondWith(http.StatusInternalServerError, "last"),
+		)
+		req, err := http.NewRequest(http.MethodGet, server.URL(), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := NewRetryingClient(http.DefaultClient, policy).Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal("last"))
+		Expect(server.ReceivedRequests()).To(HaveLen(3))
+	})
+
+	It("does not retry 4xx responses", func() {
+		server.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, ""))
+		req, err := http.NewRequest(http.MethodGet, server.URL(), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := NewRetryingClient(http.DefaultClient, policy).Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(server.ReceivedRequests()).To(HaveLen(1))
+		Expect(output.Contents()).To(BeEmpty())
+	})
+
+	It("retries connection errors", func() {
+		url := server.URL()
+		server.Close()
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = NewRetryingClient(http.DefaultClient, policy).Do(req)
+		Expect(err).To(MatchError(ContainSubstring("connection refused")))
+		Expect(output).To(gbytes.Say(`attempt 2 of 3`))
+		Expect(output).To(gbytes.Say(`attempt 3 of 3`))
+	})
+
+	It("waits for the delay requested with Retry-After", func() {
+		server.AppendHandlers(
+			ghttp.RespondWith(http.StatusServiceUnavailable, "", http.Header{RetryAfterHeader: {"1"}}),
+			ghttp.RespondWith(http.StatusOK, ""),
+		)
+		req, err := http.NewRequest(http.MethodGet, server.URL(), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		start := time.Now()
+		_, err = NewRetryingClient(http.DefaultClient, policy).Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically(">=", time.Second))
+		Expect(output).To(gbytes.Say(`in 1s`))
+	})
+
+	It("waits no longer than the maximum delay for Retry-After", func() {
+		server.AppendHandlers(
+			ghttp.RespondWith(http.StatusServiceUnavailable, "", http.Header{RetryAfterHeader: {"3600"}}),
+			ghttp.RespondWith(http.StatusOK, ""),
+		)
+		policy.MaxDelay = 10 * time.Millisecond
+		req, err := http.NewRequest(http.MethodGet, server.URL(), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		start := time.Now()
+		_, err = NewRetryingClient(http.DefaultClient, policy).Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+		Expect(output).To(gbytes.Say(`in 10ms`))
+	})
+
+	It("stops doubling the delay once it reaches the maximum", func() {
+		policy.MaxAttempts = 100
+		policy.BaseDelay = time.Hour
+		policy.MaxDelay = time.Millisecond
+		attempts := 0
+
+		start := time.Now()
+		resp, err := policy.Retry(context.Background(), "some request", func() (*http.Response, error) {
+			attempts++
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable", Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(attempts).To(Equal(100))
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+		Expect(string(output.Contents())).NotTo(ContainSubstring("in -"))
+		Expect(output).To(gbytes.Say(`Retrying some request in 1ms \(attempt 100 of 100\)`))
+	})
+
+	It("stops waiting to retry once the request context is done", func() {
+		server.AppendHandlers(ghttp.RespondWith(http.StatusServiceUnavailable, "", http.Header{RetryAfterHeader: {"10"}}))
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		req, err := http.NewRequest(http.MethodGet, server.URL(), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		start := time.Now()
+		_, err = NewRetryingClient(http.DefaultClient, policy).Do(req.WithContext(ctx))
+		Expect(err).To(Equal(context.DeadlineExceeded))
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+		Expect(server.ReceivedRequests()).To(HaveLen(1))
+	})
+
+	It("replays the request body on each attempt", func() {
+		server.AppendHandlers(
+			ghttp.CombineHandlers(ghttp.VerifyBody([]byte("request-body")), ghttp.RespondWith(http.StatusBadGateway, "")),
+			ghttp.CombineHandlers(ghttp.VerifyBody([]byte("request-body")), ghttp.RespondWith(http.StatusOK, "")),
+		)
+		req, err := http.NewRequest(http.MethodPost, server.URL(), bytes.NewReader([]byte("request-body")))
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := NewRetryingClient(http.DefaultClient, policy).Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("does not retry requests whose body cannot be replayed", func() {
+		server.AppendHandlers(ghttp.RespondWith(http.StatusBadGateway, ""))
+		req, err := http.NewRequest(http.MethodPost, server.URL(), ioutil.NopCloser(strings.NewReader("request-body")))
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, err := NewRetryingClient(http.DefaultClient, policy).Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadGateway))
+		Expect(server.ReceivedRequests()).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,234 @@
+package operations
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	UploadsPath           = "/collections/batch/uploads"
+	UploadCompletePath    = "/complete"
+	UploadOffsetHeader    = "Upload-Offset"
+	UploadLengthHeader    = "Upload-Length"
+	ChunkMimeType         = "application/octet-stream"
+	UploadStateFileSuffix = ".upload"
+
+	StartUploadFailedMessage      = "Failed to start upload session"
+	UploadChunkFailedFormat       = "Failed to upload chunk at offset %d"
+	CompleteUploadFailedMessage   = "Failed to complete upload session"
+	InvalidUploadOffsetFormat     = "Data loader acknowledged invalid offset %s"
+	WriteUploadStateFailedMessage = "Failed to record upload progress"
+)
+
+type uploadState struct {
+	UploadID string `json:"upload_id"`
+	Offset   int64  `json:"offset"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+}
+
+type chunkedUpload struct {
+	ctx           context.Context
+	client        httpClient
+	file          *os.File
+	encoding      string
+	uploadsURL    string
+	apiToken      string
+	senderVersion string
+	chunkSize     int64
+	statePath     string
+}
+
+func (cu *chunkedUpload) send() error {
+	size, checksum, err := fileSizeAndChecksum(cu.file)
+	if err != nil {
+		return errors.Wrap(err, ReadDataFileError)
+	}
+
+	state, resumed := cu.resumableState(size, checksum)
+	if !resumed {
+		state, err = cu.start(size, checksum)
+		if err != nil {
+			return err
+		}
+	}
+
+	for state.Offset < state.Size {
+		state.Offset, err = cu.uploadChunk(state)
+		if err != nil {
+			return err
+		}
+		if err := cu.saveState(state); err != nil {
+			return err
+		}
+	}
+
+	req, err := cu.newRequest(http.MethodPost, cu.uploadsURL+"/"+state.UploadID+UploadCompletePath, nil)
+	if err != nil {
+		return errors.Wrap(err, RequestCreationFailureMessage)
+	}
+	resp, err := cu.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, CompleteUploadFailedMessage)
+	}
+	defer resp.Body.Close()
+	if err := checkStatusCode(resp); err != nil {
+		return err
+	}
+
+	os.Remove(cu.statePath)
+	return nil
+}
+
+func (cu *chunkedUpload) resumableState(size int64, checksum string) (uploadState, bool) {
+	var state uploadState
+	stateContents, err := ioutil.ReadFile(cu.statePath)
+	if err != nil || json.Unmarshal(stateContents, &state) != nil {
+		return uploadState{}, false
+	}
+	if state.UploadID == "" || state.Size != size || state.Checksum != checksum {
+		return uploadState{}, false
+	}
+
+	req, err := cu.newRequest(http.MethodHead, cu.uploadsURL+"/"+state.UploadID, nil)
+	if err != nil {
+		return uploadState{}, false
+	}
+	resp, err := cu.client.Do(req)
+	if err != nil {
+		return uploadState{}, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return uploadState{}, false
+	}
+
+	offset, err := parseUploadOffset(resp, size)
+	if err != nil {
+		return uploadState{}, false
+	}
+	state.Offset = offset
+	return state, true
+}
+
+func (cu *chunkedUpload) start(size int64, checksum string) (uploadState, error) {
+	req, err := cu.startRequest(size)
+	if err != nil {
+		return uploadState{}, errors.Wrap(err, RequestCreationFailureMessage)
+	}
+
+	resp, err := cu.client.Do(req)
+	if err != nil {
+		return uploadState{}, errors.Wrap(err, StartUploadFailedMessage)
+	}
+	defer resp.Body.Close()
+	if err := checkStatusCode(resp); err != nil {
+		return uploadState{}, err
+	}
+
+	var session struct {
+		UploadID string `json:"upload_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil || session.UploadID == "" {
+		return uploadState{}, errors.New(StartUploadFailedMessage)
+	}
+
+	state := uploadState{UploadID: session.UploadID, Size: size, Checksum: checksum}
+	return state, cu.saveState(state)
+}
+
+func (cu *chunkedUpload) uploadChunk(state uploadState) (int64, error) {
+	length := cu.chunkSize
+	if remaining := state.Size - state.Offset; remaining < length {
+		length = remaining
+	}
+
+	chunk := io.NewSectionReader(cu.file, state.Offset, length)
+	req, err := cu.newRequest(http.MethodPatch, cu.uploadsURL+"/"+state.UploadID, chunk)
+	if err != nil {
+		return state.Offset, errors.Wrap(err, RequestCreationFailureMessage)
+	}
+	setReplayableBody(req, chunk)
+	req.ContentLength = length
+	req.Header.Set("Content-Type", ChunkMimeType)
+	req.Header.Set(UploadOffsetHeader, strconv.FormatInt(state.Offset, 10))
+
+	resp, err := cu.client.Do(req)
+	if err != nil {
+		return state.Offset, errors.Wrapf(err, UploadChunkFailedFormat, state.Offset)
+	}
+	defer resp.Body.Close()
+	if err := checkResponseStatus(resp, http.StatusNoContent); err != nil {
+		return state.Offset, errors.Wrapf(err, UploadChunkFailedFormat, state.Offset)
+	}
+
+	offset, err := parseUploadOffset(resp, state.Size)
+	if err != nil || offset <= state.Offset {
+		return state.Offset, errors.Errorf(InvalidUploadOffsetFormat, resp.Header.Get(UploadOffsetHeader))
+	}
+	return offset, nil
+}
+
+func (cu *chunkedUpload) saveState(state uploadState) error {
+	stateContents, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrap(err, WriteUploadStateFailedMessage)
+	}
+	return errors.Wrap(ioutil.WriteFile(cu.statePath, stateContents, 0600), WriteUploadStateFailedMessage)
+}
+
+func (cu *chunkedUpload) startRequest(size int64) (*http.Request, error) {
+	req, err := cu.newRequest(http.MethodPost, cu.uploadsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", TarMimeType)
+	if cu.encoding != "" {
+		req.Header.Set(ContentEncodingHeader, cu.encoding)
+	}
+	req.Header.Set(UploadLengthHeader, strconv.FormatInt(size, 10))
+	return req, nil
+}
+
+func (cu *chunkedUpload) newRequest(method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(cu.ctx)
+	req.Header.Set(AuthorizationHeaderKey, "Bearer "+cu.apiToken)
+	req.Header.Set(HTTPSenderVersionRequestHeader, cu.senderVersion)
+	return req, nil
+}
+
+func parseUploadOffset(resp *http.Response, size int64) (int64, error) {
+	offset, err := strconv.ParseInt(resp.Header.Get(UploadOffsetHeader), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if offset < 0 || offset > size {
+		return 0, errors.Errorf(InvalidUploadOffsetFormat, resp.Header.Get(UploadOffsetHeader))
+	}
+	return offset, nil
+}
+
+func fileSizeAndChecksum(file *os.File) (int64, string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, "", err
+	}
+	hash := md5.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, base64.StdEncoding.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,185 @@
+package operations_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/aqueduct-courier/operations"
+	"github.com/pivotal-cf/aqueduct-courier/operations/operationsfakes"
+)
+
+var _ = Describe("Chunked sending", func() {
+	const uploadID = "some-upload-id"
+
+	var (
+		client      *operationsfakes.FakeHttpClient
+		tmpFile     *os.File
+		tarContent  []byte
+		received    *bytes.Buffer
+		completed   bool
+		failOnChunk int
+		chunkCount  int
+		sender      SendExecutor
+	)
+
+	response := func(statusCode int, offset int) *http.Response {
+		resp := &http.Response{StatusCode: statusCode, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(""))}
+		if offset >= 0 {
+			resp.Header.Set(UploadOffsetHeader, strconv.Itoa(offset))
+		}
+		return resp
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpFile, err = ioutil.TempFile("", "")
+		Expect(err).NotTo(HaveOccurred())
+		tarContent = []byte("0123456789abcdefghij-tar-content")
+		Expect(ioutil.WriteFile(tmpFile.Name(), tarContent, 0644)).To(Succeed())
+		Expect(tmpFile.Close()).To(Succeed())
+
+		received = &bytes.Buffer{}
+		completed = false
+		failOnChunk = -1
+		chunkCount = 0
+
+		client = new(operationsfakes.FakeHttpClient)
+		client.DoStub = func(req *http.Request) (*http.Response, error) {
+			Expect(req.Header.Get("Authorization")).To(Equal("Bearer some-key"))
+			uploadURL := "http://example.com" + UploadsPath + "/" + uploadID
+
+			switch {
+			case req.Method == http.MethodPost && req.URL.String() == "http://example.com"+UploadsPath:
+				Expect(req.Header.Get(UploadLengthHeader)).To(Equal(strconv.Itoa(len(tarContent))))
+				body, err := json.Marshal(map[string]string{"upload_id": uploadID})
+				Expect(err).NotTo(HaveOccurred())
+				return &http.Response{StatusCode: http.StatusCreated, Body: ioutil.NopCloser(bytes.NewReader(body))}, nil
+			case req.Method == http.MethodHead && req.URL.String() == uploadURL:
+				return response(http.StatusOK, received.Len()), nil
+			case req.Method == http.MethodPatch && req.URL.String() == uploadURL:
+				chunkCount++
+				if chunkCount == failOnChunk {
+					return nil, errors.New("connection reset")
+				}
+				Expect(req.Header.Get(UploadOffsetHeader)).To(Equal(strconv.Itoa(received.Len())))
+				chunk, err := ioutil.ReadAll(req.Body)
+				Expect(err).NotTo(HaveOccurred())
+				received.Write(chunk)
+				return response(http.StatusNoContent, received.Len()), nil
+			case req.Method == http.MethodPost && req.URL.String() == uploadURL+UploadCompletePath:
+				completed = true
+				return response(http.StatusCreated, -1), nil
+			}
+
+			Fail(fmt.Sprintf("unexpected request %s %s", req.Method, req.URL))
+			return nil, nil
+		}
+
+		sender = NewSender(true, 10, nil, nil)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpFile.Name())).To(Succeed())
+		Expect(os.RemoveAll(tmpFile.Name() + UploadStateFileSuffix)).To(Succeed())
+	})
+
+	It("uploads the file in chunks of the configured size and completes the upload", func() {
+		Expect(sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")).To(Succeed())
+
+		Expect(chunkCount).To(Equal(4))
+		Expect(received.Bytes()).To(Equal(tarContent))
+		Expect(completed).To(BeTrue())
+		Expect(tmpFile.Name() + UploadStateFileSuffix).NotTo(BeAnExistingFile())
+	})
+
+	It("starts the upload with the content encoding of a compressed file", func() {
+		tarContent = append([]byte{0x1f, 0x8b}, tarContent...)
+		Expect(ioutil.WriteFile(tmpFile.Name(), tarContent, 0644)).To(Succeed())
+
+		Expect(sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")).To(Succeed())
+
+		start := client.DoArgsForCall(0)
+		Expect(start.Method).To(Equal(http.MethodPost))
+		Expect(start.Header.Get(ContentEncodingHeader)).To(Equal("gzip"))
+		Expect(received.Bytes()).To(Equal(tarContent))
+	})
+
+	It("sends every request with the given context", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		Expect(sender.Send(ctx, client, tmpFile.Name(), "http://example.com", "some-key", "")).To(Succeed())
+
+		for i := 0; i < client.DoCallCount(); i++ {
+			Expect(client.DoArgsForCall(i).Context()).To(Equal(ctx))
+		}
+	})
+
+	It("resumes from the last acknowledged offset after a failure", func() {
+		failOnChunk = 3
+		err := sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")
+		Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(UploadChunkFailedFormat, 20))))
+		Expect(err).To(MatchError(ContainSubstring("connection reset")))
+		Expect(completed).To(BeFalse())
+		Expect(tmpFile.Name() + UploadStateFileSuffix).To(BeAnExistingFile())
+
+		Expect(sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")).To(Succeed())
+		Expect(countRequests(client, http.MethodPost, "http://example.com"+UploadsPath)).To(Equal(1))
+		Expect(received.Bytes()).To(Equal(tarContent))
+		Expect(completed).To(BeTrue())
+	})
+
+	It("starts a new upload when the file has changed since the previous attempt", func() {
+		failOnChunk = 2
+		Expect(sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")).NotTo(Succeed())
+
+		received.Reset()
+		tarContent = []byte("different-tar-content")
+		Expect(ioutil.WriteFile(tmpFile.Name(), tarContent, 0644)).To(Succeed())
+
+		Expect(sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")).To(Succeed())
+		Expect(countRequests(client, http.MethodPost, "http://example.com"+UploadsPath)).To(Equal(2))
+		Expect(received.Bytes()).To(Equal(tarContent))
+	})
+
+	It("errors when the upload session cannot be started", func() {
+		client.DoStub = nil
+		client.DoReturns(&http.Response{StatusCode: http.StatusUnauthorized, Body: ioutil.NopCloser(strings.NewReader(""))}, nil)
+
+		err := sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")
+		Expect(err).To(MatchError(UnauthorizedErrorMessage))
+	})
+
+	It("errors when the data loader acknowledges an invalid offset", func() {
+		originalStub := client.DoStub
+		client.DoStub = func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodPatch {
+				return response(http.StatusNoContent, 0), nil
+			}
+			return originalStub(req)
+		}
+
+		err := sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")
+		Expect(err).To(MatchError(fmt.Sprintf(InvalidUploadOffsetFormat, "0")))
+	})
+})
+
+func countRequests(client *operationsfakes.FakeHttpClient, method, url string) int {
+	count := 0
+	for i := 0; i < client.DoCallCount(); i++ {
+		req := client.DoArgsForCall(i)
+		if req.Method == method && req.URL.String() == url {
+			count++
+		}
+	}
+	return count
+}
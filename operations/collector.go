@@ -1,18 +1,19 @@
 package operations
 
 import (
-	"crypto/md5"
-	"encoding/base64"
-	"encoding/json"
+	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"path/filepath"
 	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/pivotal-cf/aqueduct-courier/cfapi"
 	"github.com/pivotal-cf/aqueduct-courier/consumption"
 
 	"github.com/pivotal-cf/aqueduct-courier/credhub"
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
 
 	"github.com/pivotal-cf/aqueduct-courier/opsmanager"
 	"github.com/pivotal-cf/telemetry-utils/collector_tar"
@@ -23,24 +24,42 @@ const (
 	OpsManagerCollectFailureMessage = "Failed collecting from Operations Manager"
 	CredhubCollectFailureMessage    = "Failed collecting from Credhub"
 	UsageCollectFailureMessage      = "Failed collecting from Usage Service"
+	CfApiCollectFailureMessage      = "Failed collecting from CF API"
 	DataWriteFailureMessage         = "Failed writing data"
 	ContentReadingFailureMessage    = "Failed to read content"
 	UUIDGenerationErrorMessage      = "unable to generate UUID"
+	PartialCollectionFormat         = "Failed to collect %d of the requested data, see the metadata for details"
+	PseudonymizeFailureFormat       = "Failed to pseudonymize %s"
 )
 
+// PartialCollectionError is returned once the data that could be collected
+// has been written and the failures recorded in the metadata.
+type PartialCollectionError struct {
+	Failures []manifest.Failure
+}
+
+func (e PartialCollectionError) Error() string {
+	return fmt.Sprintf(PartialCollectionFormat, len(e.Failures))
+}
+
 //go:generate counterfeiter . omDataCollector
 type omDataCollector interface {
-	Collect() ([]opsmanager.Data, string, error)
+	Collect(ctx context.Context) ([]opsmanager.Data, string, error)
 }
 
 //go:generate counterfeiter . credhubDataCollector
 type credhubDataCollector interface {
-	Collect() (credhub.Data, error)
+	Collect(ctx context.Context) ([]credhub.Data, error)
 }
 
 //go:generate counterfeiter . consumptionDataCollector
 type consumptionDataCollector interface {
-	Collect() ([]consumption.Data, error)
+	Collect(ctx context.Context) ([]consumption.Data, error)
+}
+
+//go:generate counterfeiter . cfApiDataCollector
+type cfApiDataCollector interface {
+	Collect(ctx context.Context) ([]cfapi.Data, error)
 }
 
 //go:generate counterfeiter . tarWriter
@@ -54,27 +73,47 @@ type uuidProvider interface {
 	NewV4() (uuid.UUID, error)
 }
 
+//go:generate counterfeiter . pseudonymizer
+type pseudonymizer interface {
+	Value(value string) string
+	Text(text string) string
+	JSON(contents []byte) ([]byte, error)
+}
+
 type collectedData interface {
 	Name() string
 	MimeType() string
 	DataType() string
 	Type() string
 	Content() io.Reader
+	Err() error
+}
+
+// rangedData is data for a range of days, such as a monthly usage report.
+type rangedData interface {
+	Range() consumption.ReportRange
 }
 
 type CollectExecutor struct {
 	opsmanagerDC  omDataCollector
 	credhubDC     credhubDataCollector
 	consumptionDC consumptionDataCollector
+	cfApiDC       cfApiDataCollector
 	tarWriter     tarWriter
 	uuidProvider  uuidProvider
+	// redactionPolicyHash identifies the policy the Ops Manager and CF API
+	// data was redacted with, and is recorded in their metadata.
+	redactionPolicyHash string
+	// pseudonymizer replaces the identifiers in the data and metadata before
+	// they are written, unless it is nil.
+	pseudonymizer pseudonymizer
 }
 
-func NewCollector(opsmanagerDC omDataCollector, credhubDC credhubDataCollector, consumptionDC consumptionDataCollector, tarWriter tarWriter, uuidProvider uuidProvider) *CollectExecutor {
-	return &CollectExecutor{opsmanagerDC: opsmanagerDC, credhubDC: credhubDC, consumptionDC: consumptionDC, tarWriter: tarWriter, uuidProvider: uuidProvider}
+func NewCollector(opsmanagerDC omDataCollector, credhubDC credhubDataCollector, consumptionDC consumptionDataCollector, cfApiDC cfApiDataCollector, tarWriter tarWriter, uuidProvider uuidProvider, redactionPolicyHash string, pseudonymizer pseudonymizer) *CollectExecutor {
+	return &CollectExecutor{opsmanagerDC: opsmanagerDC, credhubDC: credhubDC, consumptionDC: consumptionDC, cfApiDC: cfApiDC, tarWriter: tarWriter, uuidProvider: uuidProvider, redactionPolicyHash: redactionPolicyHash, pseudonymizer: pseudonymizer}
 }
 
-func (ce *CollectExecutor) Collect(envType, collectorVersion string) error {
+func (ce *CollectExecutor) Collect(ctx context.Context, envType, collectorVersion string) error {
 	defer ce.tarWriter.Close()
 
 	collectionID, err := ce.uuidProvider.NewV4()
@@ -82,25 +121,31 @@ func (ce *CollectExecutor) Collect(envType, collectorVersion string) error {
 		return errors.Wrap(err, UUIDGenerationErrorMessage)
 	}
 
-	omDatas, foundationId, err := ce.opsmanagerDC.Collect()
+	omDatas, foundationId, err := ce.opsmanagerDC.Collect(ctx)
 	if err != nil {
 		return errors.Wrap(err, OpsManagerCollectFailureMessage)
 	}
+	if ce.pseudonymizer != nil {
+		foundationId = ce.pseudonymizer.Value(foundationId)
+	}
 
-	opsManagerMetadata := collector_tar.Metadata{
-		CollectorVersion: collectorVersion,
-		EnvType:          envType,
-		CollectionId:     collectionID.String(),
-		FoundationId:     foundationId,
-		CollectedAt:      time.Now().UTC().Format(time.RFC3339),
+	opsManagerMetadata := manifest.Metadata{
+		CollectorVersion:    collectorVersion,
+		EnvType:             envType,
+		CollectionId:        collectionID.String(),
+		FoundationId:        foundationId,
+		CollectedAt:         time.Now().UTC().Format(time.RFC3339),
+		RedactionPolicyHash: ce.redactionPolicyHash,
+		Pseudonymized:       ce.pseudonymizer != nil,
 	}
 
-	usageMetadata := collector_tar.Metadata{
+	usageMetadata := manifest.Metadata{
 		CollectorVersion: collectorVersion,
 		EnvType:          envType,
 		CollectionId:     opsManagerMetadata.CollectionId,
 		FoundationId:     foundationId,
 		CollectedAt:      opsManagerMetadata.CollectedAt,
+		Pseudonymized:    opsManagerMetadata.Pseudonymized,
 	}
 
 	for _, omData := range omDatas {
@@ -111,28 +156,27 @@ func (ce *CollectExecutor) Collect(envType, collectorVersion string) error {
 	}
 
 	if ce.credhubDC != nil {
-		chData, err := ce.credhubDC.Collect()
+		chDatas, err := ce.credhubDC.Collect(ctx)
 		if err != nil {
 			return errors.Wrap(err, CredhubCollectFailureMessage)
 		}
 
-		err = ce.addData(chData, &opsManagerMetadata, collector_tar.OpsManagerCollectorDataSetId)
-		if err != nil {
-			return err
+		for _, chData := range chDatas {
+			err = ce.addData(chData, &opsManagerMetadata, collector_tar.OpsManagerCollectorDataSetId)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
-	metadataContents, err := json.Marshal(opsManagerMetadata)
-	if err != nil {
-		return err
-	}
-	err = ce.tarWriter.AddFile(metadataContents, filepath.Join(collector_tar.OpsManagerCollectorDataSetId, collector_tar.MetadataFileName))
+	metadataWriter := manifest.NewWriter(ce.tarWriter)
+	err = metadataWriter.Write(collector_tar.OpsManagerCollectorDataSetId, opsManagerMetadata)
 	if err != nil {
 		return errors.Wrap(err, DataWriteFailureMessage)
 	}
 
 	if ce.consumptionDC != nil {
-		usageData, err := ce.consumptionDC.Collect()
+		usageData, err := ce.consumptionDC.Collect(ctx)
 		if err != nil {
 			return errors.Wrap(err, UsageCollectFailureMessage)
 		}
@@ -143,38 +187,103 @@ func (ce *CollectExecutor) Collect(envType, collectorVersion string) error {
 				return err
 			}
 		}
-		usageMetadataContents, err := json.Marshal(usageMetadata)
+		err = metadataWriter.Write(collector_tar.UsageServiceCollectorDataSetId, usageMetadata)
 		if err != nil {
-			return err
+			return errors.Wrap(err, DataWriteFailureMessage)
 		}
+	}
 
-		err = ce.tarWriter.AddFile(usageMetadataContents, filepath.Join(collector_tar.UsageServiceCollectorDataSetId, collector_tar.MetadataFileName))
+	cfApiMetadata := manifest.Metadata{
+		CollectorVersion:    collectorVersion,
+		EnvType:             envType,
+		CollectionId:        opsManagerMetadata.CollectionId,
+		FoundationId:        foundationId,
+		CollectedAt:         opsManagerMetadata.CollectedAt,
+		RedactionPolicyHash: ce.redactionPolicyHash,
+		Pseudonymized:       opsManagerMetadata.Pseudonymized,
+	}
+
+	if ce.cfApiDC != nil {
+		cfApiData, err := ce.cfApiDC.Collect(ctx)
+		if err != nil {
+			return errors.Wrap(err, CfApiCollectFailureMessage)
+		}
+
+		for _, data := range cfApiData {
+			err = ce.addData(data, &cfApiMetadata, manifest.CfApiDataSetId)
+			if err != nil {
+				return err
+			}
+		}
+		err = metadataWriter.Write(manifest.CfApiDataSetId, cfApiMetadata)
 		if err != nil {
 			return errors.Wrap(err, DataWriteFailureMessage)
 		}
 	}
 
+	err = metadataWriter.Close(opsManagerMetadata)
+	if err != nil {
+		return errors.Wrap(err, DataWriteFailureMessage)
+	}
+
+	var failures []manifest.Failure
+	failures = append(failures, opsManagerMetadata.Failures...)
+	failures = append(failures, usageMetadata.Failures...)
+	failures = append(failures, cfApiMetadata.Failures...)
+	if len(failures) > 0 {
+		return PartialCollectionError{Failures: failures}
+	}
+
 	return nil
 }
 
-func (ce *CollectExecutor) addData(collectedData collectedData, metadata *collector_tar.Metadata, dataSetType string) error {
+func (ce *CollectExecutor) addData(collectedData collectedData, metadata *manifest.Metadata, dataSetType string) error {
+	var start, end string
+	if ranged, ok := collectedData.(rangedData); ok && !ranged.Range().IsZero() {
+		start = ranged.Range().Start.Format(consumption.DateFormat)
+		end = ranged.Range().End.Format(consumption.DateFormat)
+	}
+
+	if err := collectedData.Err(); err != nil {
+		failure := err.Error()
+		if ce.pseudonymizer != nil {
+			failure = ce.pseudonymizer.Text(failure)
+		}
+		metadata.Failures = append(metadata.Failures, manifest.Failure{
+			ProductType: collectedData.Type(),
+			DataType:    collectedData.DataType(),
+			Start:       start,
+			End:         end,
+			Error:       failure,
+		})
+		return nil
+	}
+
 	dataContents, err := ioutil.ReadAll(collectedData.Content())
 	if err != nil {
 		return errors.Wrap(err, ContentReadingFailureMessage)
 	}
+	if ce.pseudonymizer != nil && collectedData.MimeType() == "application/json" {
+		dataContents, err = ce.pseudonymizer.JSON(dataContents)
+		if err != nil {
+			return errors.Wrapf(err, PseudonymizeFailureFormat, collectedData.Name())
+		}
+	}
 
 	err = ce.tarWriter.AddFile(dataContents, filepath.Join(dataSetType, collectedData.Name()))
 	if err != nil {
 		return errors.Wrap(err, DataWriteFailureMessage)
 	}
 
-	md5Sum := md5.Sum([]byte(dataContents))
-	metadata.FileDigests = append(metadata.FileDigests, collector_tar.FileDigest{
-		Name:        collectedData.Name(),
-		MimeType:    collectedData.MimeType(),
-		ProductType: collectedData.Type(),
-		DataType:    collectedData.DataType(),
-		MD5Checksum: base64.StdEncoding.EncodeToString(md5Sum[:]),
+	metadata.FileDigests = append(metadata.FileDigests, manifest.FileDigest{
+		Name:           collectedData.Name(),
+		MimeType:       collectedData.MimeType(),
+		ProductType:    collectedData.Type(),
+		DataType:       collectedData.DataType(),
+		MD5Checksum:    manifest.MD5Checksum(dataContents),
+		SHA256Checksum: manifest.SHA256Checksum(dataContents),
+		Start:          start,
+		End:            end,
 	})
 	return nil
 }
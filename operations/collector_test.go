@@ -1,16 +1,21 @@
 package operations_test
 
 import (
+	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/pivotal-cf/aqueduct-courier/cfapi"
 	"github.com/pivotal-cf/aqueduct-courier/consumption"
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
 	"github.com/pivotal-cf/aqueduct-courier/operations"
 
 	"github.com/pivotal-cf/aqueduct-courier/credhub"
@@ -42,9 +47,25 @@ var _ = Describe("DataCollector", func() {
 			return uuid.FromString(uuidString)
 		}
 
-		collector = NewCollector(omDataCollector, nil, nil, tarWriter, uuidProvider)
+		collector = NewCollector(omDataCollector, nil, nil, nil, tarWriter, uuidProvider, "sha256:some-policy-hash", nil)
 	})
 
+	writtenMetadata := func(dataSet string) manifest.Metadata {
+		files := archiveFiles{}
+		for i := 0; i < tarWriter.AddFileCallCount(); i++ {
+			contents, fileName := tarWriter.AddFileArgsForCall(i)
+			files[fileName] = contents
+		}
+		var schemaMetadata collector_tar.Metadata
+		decoder := json.NewDecoder(bytes.NewReader(files[filepath.Join(dataSet, collector_tar.MetadataFileName)]))
+		decoder.DisallowUnknownFields()
+		Expect(decoder.Decode(&schemaMetadata)).To(Succeed())
+
+		metadata, err := manifest.Read(files, files.fileMd5s(), dataSet)
+		Expect(err).NotTo(HaveOccurred())
+		return metadata
+	}
+
 	It("collects opsmanager data and writes it", func() {
 		expectedD1Contents := "d1-content"
 		md5SumD1 := md5.Sum([]byte(expectedD1Contents))
@@ -61,10 +82,10 @@ var _ = Describe("DataCollector", func() {
 		collectorVersion := "0.0.1-version"
 		envType := "most-production"
 
-		err := collector.Collect(envType, collectorVersion)
+		err := collector.Collect(context.Background(), envType, collectorVersion)
 		Expect(err).NotTo(HaveOccurred())
 
-		Expect(tarWriter.AddFileCallCount()).To(Equal(3))
+		Expect(tarWriter.AddFileCallCount()).To(Equal(5))
 
 		expectedD1Path := filepath.Join(collector_tar.OpsManagerCollectorDataSetId, d1.Name())
 		d1Contents, d1Path := tarWriter.AddFileArgsForCall(0)
@@ -77,19 +98,28 @@ var _ = Describe("DataCollector", func() {
 		Expect(d2Path).To(Equal(expectedD2Path))
 
 		expectedMetadataPath := filepath.Join(collector_tar.OpsManagerCollectorDataSetId, collector_tar.MetadataFileName)
-		metadataContents, metadataPath := tarWriter.AddFileArgsForCall(2)
+		_, metadataPath := tarWriter.AddFileArgsForCall(2)
 		Expect(metadataPath).To(Equal(expectedMetadataPath))
+		_, extensionsPath := tarWriter.AddFileArgsForCall(3)
+		Expect(extensionsPath).To(Equal(manifest.ExtensionsFile(collector_tar.OpsManagerCollectorDataSetId)))
+		_, extensionsMetadataPath := tarWriter.AddFileArgsForCall(4)
+		Expect(extensionsMetadataPath).To(Equal(filepath.Join(manifest.ExtensionsDataSetId, collector_tar.MetadataFileName)))
 
-		var metadata collector_tar.Metadata
-		Expect(json.Unmarshal(metadataContents, &metadata)).To(Succeed())
+		metadata := writtenMetadata(collector_tar.OpsManagerCollectorDataSetId)
 		Expect(metadata.CollectorVersion).To(Equal(collectorVersion))
 		Expect(metadata.EnvType).To(Equal(envType))
 		Expect(metadata.FileDigests).To(ConsistOf(
-			collector_tar.FileDigest{Name: d1.Name(), MimeType: d1.MimeType(), MD5Checksum: d1ContentMd5, ProductType: d1.Type(), DataType: d1.DataType()},
-			collector_tar.FileDigest{Name: d2.Name(), MimeType: d2.MimeType(), MD5Checksum: d2ContentMd5, ProductType: d2.Type(), DataType: d2.DataType()},
+			manifest.FileDigest{Name: d1.Name(), MimeType: d1.MimeType(), MD5Checksum: d1ContentMd5, SHA256Checksum: manifest.SHA256Checksum([]byte(expectedD1Contents)), ProductType: d1.Type(), DataType: d1.DataType()},
+			manifest.FileDigest{Name: d2.Name(), MimeType: d2.MimeType(), MD5Checksum: d2ContentMd5, SHA256Checksum: manifest.SHA256Checksum([]byte(expectedD2Contents)), ProductType: d2.Type(), DataType: d2.DataType()},
 		))
 		Expect(metadata.FoundationId).To(Equal(foundationId))
 		Expect(metadata.CollectionId).To(Equal(uuidString))
+		Expect(metadata.RedactionPolicyHash).To(Equal("sha256:some-policy-hash"))
+
+		extensionsMetadata := writtenMetadata(manifest.ExtensionsDataSetId)
+		Expect(extensionsMetadata.CollectionId).To(Equal(uuidString))
+		Expect(extensionsMetadata.FileDigests).To(HaveLen(1))
+		Expect(extensionsMetadata.FileDigests[0].Name).To(Equal(collector_tar.OpsManagerCollectorDataSetId))
 		collectedAtTime, err := time.Parse(time.RFC3339, metadata.CollectedAt)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(collectedAtTime.Location()).To(Equal(time.UTC))
@@ -98,10 +128,25 @@ var _ = Describe("DataCollector", func() {
 		Expect(tarWriter.CloseCallCount()).To(Equal(1))
 	})
 
+	It("passes the context to the data collectors", func() {
+		credhubDataCollector := new(operationsfakes.FakeCredhubDataCollector)
+		credhubDataCollector.CollectReturns([]credhub.Data{credhub.NewData(strings.NewReader(""), collector_tar.CertificatesDataType)}, nil)
+		consumptionDataCollector := new(operationsfakes.FakeConsumptionDataCollector)
+		collector = NewCollector(omDataCollector, credhubDataCollector, consumptionDataCollector, nil, tarWriter, uuidProvider, "", nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		Expect(collector.Collect(ctx, "", "")).To(Succeed())
+
+		Expect(omDataCollector.CollectArgsForCall(0)).To(Equal(ctx))
+		Expect(credhubDataCollector.CollectArgsForCall(0)).To(Equal(ctx))
+		Expect(consumptionDataCollector.CollectArgsForCall(0)).To(Equal(ctx))
+	})
+
 	It("returns an error when the ops manager collection errors", func() {
 		omDataCollector.CollectReturns([]opsmanager.Data{}, "", errors.New("collecting is hard"))
 
-		err := collector.Collect("", "")
+		err := collector.Collect(context.Background(), "", "")
 		Expect(tarWriter.CloseCallCount()).To(Equal(1))
 		Expect(err).To(MatchError(ContainSubstring(OpsManagerCollectFailureMessage)))
 		Expect(err).To(MatchError(ContainSubstring("collecting is hard")))
@@ -113,7 +158,7 @@ var _ = Describe("DataCollector", func() {
 		failingData := opsmanager.NewData(failingReader, "d1", "best-kind")
 		omDataCollector.CollectReturns([]opsmanager.Data{failingData}, "", nil)
 
-		err := collector.Collect("", "")
+		err := collector.Collect(context.Background(), "", "")
 		Expect(tarWriter.CloseCallCount()).To(Equal(1))
 		Expect(err).To(MatchError(ContainSubstring(ContentReadingFailureMessage)))
 		Expect(err).To(MatchError(ContainSubstring("reading is hard")))
@@ -124,7 +169,7 @@ var _ = Describe("DataCollector", func() {
 		omDataCollector.CollectReturns([]opsmanager.Data{data}, "", nil)
 		tarWriter.AddFileReturnsOnCall(0, errors.New("tarring is hard"))
 
-		err := collector.Collect("", "")
+		err := collector.Collect(context.Background(), "", "")
 		Expect(tarWriter.CloseCallCount()).To(Equal(1))
 		Expect(err).To(MatchError(ContainSubstring(DataWriteFailureMessage)))
 		Expect(err).To(MatchError(ContainSubstring("tarring is hard")))
@@ -137,7 +182,7 @@ var _ = Describe("DataCollector", func() {
 			}
 			return nil
 		}
-		err := collector.Collect("", "")
+		err := collector.Collect(context.Background(), "", "")
 		Expect(tarWriter.CloseCallCount()).To(Equal(1))
 		Expect(err).To(MatchError(ContainSubstring(DataWriteFailureMessage)))
 		Expect(err).To(MatchError(ContainSubstring("tarring is hard")))
@@ -146,7 +191,7 @@ var _ = Describe("DataCollector", func() {
 	It("returns an error when a UUID cannot be generated", func() {
 		uuidProvider.NewV4Returns(uuid.UUID{}, errors.New("generating a UUID is hard"))
 
-		err := collector.Collect("", "")
+		err := collector.Collect(context.Background(), "", "")
 		Expect(err).To(MatchError(ContainSubstring(operations.UUIDGenerationErrorMessage)))
 		Expect(err).To(MatchError(ContainSubstring("generating a UUID is hard")))
 	})
@@ -159,7 +204,7 @@ var _ = Describe("DataCollector", func() {
 
 		BeforeEach(func() {
 			credhubDataCollector = new(operationsfakes.FakeCredhubDataCollector)
-			collectorWithCredhub = NewCollector(omDataCollector, credhubDataCollector, nil, tarWriter, uuidProvider)
+			collectorWithCredhub = NewCollector(omDataCollector, credhubDataCollector, nil, nil, tarWriter, uuidProvider, "", nil)
 		})
 
 		It("collects credhub data and writes it", func() {
@@ -173,43 +218,49 @@ var _ = Describe("DataCollector", func() {
 			expectedCHContents := "ch-content"
 			md5SumCH := md5.Sum([]byte(expectedCHContents))
 			chContentMd5 := base64.StdEncoding.EncodeToString(md5SumCH[:])
-			chData := credhub.NewData(strings.NewReader(expectedCHContents))
-			credhubDataCollector.CollectReturns(chData, nil)
+			chData := credhub.NewData(strings.NewReader(expectedCHContents), collector_tar.CertificatesDataType)
+			expectedCHDetailsContents := "ch-details-content"
+			md5SumCHDetails := md5.Sum([]byte(expectedCHDetailsContents))
+			chDetailsContentMd5 := base64.StdEncoding.EncodeToString(md5SumCHDetails[:])
+			chDetailsData := credhub.NewData(strings.NewReader(expectedCHDetailsContents), credhub.CertificateDetailsDataType)
+			credhubDataCollector.CollectReturns([]credhub.Data{chData, chDetailsData}, nil)
 
 			collectorVersion := "0.0.1-version"
 			envType := "most-production"
 
-			err := collectorWithCredhub.Collect(envType, collectorVersion)
+			err := collectorWithCredhub.Collect(context.Background(), envType, collectorVersion)
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(tarWriter.AddFileCallCount()).To(Equal(3))
+			Expect(tarWriter.AddFileCallCount()).To(Equal(6))
 
 			chContents, credhubDataPath := tarWriter.AddFileArgsForCall(1)
 			Expect(string(chContents)).To(Equal(expectedCHContents))
+			Expect(credhubDataPath).To(Equal(filepath.Join(collector_tar.OpsManagerCollectorDataSetId, "p-bosh_certificates")))
 
-			expectedCredhubDataPath := filepath.Join(collector_tar.OpsManagerCollectorDataSetId, chData.Name())
-			Expect(credhubDataPath).To(Equal(expectedCredhubDataPath))
+			chDetailsContents, credhubDetailsPath := tarWriter.AddFileArgsForCall(2)
+			Expect(string(chDetailsContents)).To(Equal(expectedCHDetailsContents))
+			Expect(credhubDetailsPath).To(Equal(filepath.Join(collector_tar.OpsManagerCollectorDataSetId, "p-bosh_certificate_details")))
 
 			expectedMetadataPath := filepath.Join(collector_tar.OpsManagerCollectorDataSetId, collector_tar.MetadataFileName)
-			metadataContents, metadataPath := tarWriter.AddFileArgsForCall(2)
+			_, metadataPath := tarWriter.AddFileArgsForCall(3)
 
 			Expect(metadataPath).To(Equal(expectedMetadataPath))
-			var metadata collector_tar.Metadata
-			Expect(json.Unmarshal(metadataContents, &metadata)).To(Succeed())
+			metadata := writtenMetadata(collector_tar.OpsManagerCollectorDataSetId)
 			Expect(metadata.CollectorVersion).To(Equal(collectorVersion))
 			Expect(metadata.EnvType).To(Equal(envType))
 			Expect(metadata.FileDigests).To(ConsistOf(
-				collector_tar.FileDigest{Name: d1.Name(), MimeType: d1.MimeType(), MD5Checksum: d1ContentMd5, ProductType: d1.Type(), DataType: d1.DataType()},
-				collector_tar.FileDigest{Name: chData.Name(), MimeType: chData.MimeType(), MD5Checksum: chContentMd5, ProductType: chData.Type(), DataType: chData.DataType()},
+				manifest.FileDigest{Name: d1.Name(), MimeType: d1.MimeType(), MD5Checksum: d1ContentMd5, SHA256Checksum: manifest.SHA256Checksum([]byte(expectedD1Contents)), ProductType: d1.Type(), DataType: d1.DataType()},
+				manifest.FileDigest{Name: chData.Name(), MimeType: chData.MimeType(), MD5Checksum: chContentMd5, SHA256Checksum: manifest.SHA256Checksum([]byte(expectedCHContents)), ProductType: chData.Type(), DataType: chData.DataType()},
+				manifest.FileDigest{Name: chDetailsData.Name(), MimeType: chDetailsData.MimeType(), MD5Checksum: chDetailsContentMd5, SHA256Checksum: manifest.SHA256Checksum([]byte(expectedCHDetailsContents)), ProductType: chDetailsData.Type(), DataType: chDetailsData.DataType()},
 			))
 
 			Expect(tarWriter.CloseCallCount()).To(Equal(1))
 		})
 
 		It("returns an error when the credhub collection errors", func() {
-			credhubDataCollector.CollectReturns(credhub.Data{}, errors.New("collecting is hard"))
+			credhubDataCollector.CollectReturns([]credhub.Data{}, errors.New("collecting is hard"))
 
-			err := collectorWithCredhub.Collect("", "")
+			err := collectorWithCredhub.Collect(context.Background(), "", "")
 			Expect(tarWriter.CloseCallCount()).To(Equal(1))
 			Expect(err).To(MatchError(ContainSubstring(CredhubCollectFailureMessage)))
 			Expect(err).To(MatchError(ContainSubstring("collecting is hard")))
@@ -218,18 +269,18 @@ var _ = Describe("DataCollector", func() {
 		It("returns an error when reading the credhub data content fails", func() {
 			failingReader := new(operationsfakes.FakeReader)
 			failingReader.ReadReturns(0, errors.New("reading is hard"))
-			failingData := credhub.NewData(failingReader)
-			credhubDataCollector.CollectReturns(failingData, nil)
+			failingData := credhub.NewData(failingReader, collector_tar.CertificatesDataType)
+			credhubDataCollector.CollectReturns([]credhub.Data{failingData}, nil)
 
-			err := collectorWithCredhub.Collect("", "")
+			err := collectorWithCredhub.Collect(context.Background(), "", "")
 			Expect(tarWriter.CloseCallCount()).To(Equal(1))
 			Expect(err).To(MatchError(ContainSubstring(ContentReadingFailureMessage)))
 			Expect(err).To(MatchError(ContainSubstring("reading is hard")))
 		})
 
 		It("returns an error when adding credhub data to the tar file fails", func() {
-			credhubData := credhub.NewData(strings.NewReader(""))
-			credhubDataCollector.CollectReturns(credhubData, nil)
+			credhubData := credhub.NewData(strings.NewReader(""), collector_tar.CertificatesDataType)
+			credhubDataCollector.CollectReturns([]credhub.Data{credhubData}, nil)
 			tarWriter.AddFileStub = func(content []byte, filePath string) error {
 				if filePath == filepath.Join(collector_tar.OpsManagerCollectorDataSetId, credhubData.Name()) {
 					return errors.New("tarring is hard")
@@ -237,13 +288,120 @@ var _ = Describe("DataCollector", func() {
 				return nil
 			}
 
-			err := collectorWithCredhub.Collect("", "")
+			err := collectorWithCredhub.Collect(context.Background(), "", "")
 			Expect(tarWriter.CloseCallCount()).To(Equal(1))
 			Expect(err).To(MatchError(ContainSubstring(DataWriteFailureMessage)))
 			Expect(err).To(MatchError(ContainSubstring("tarring is hard")))
 		})
 	})
 
+	Describe("partial collection", func() {
+		It("writes the collected data, records the failures in the metadata and returns a partial collection error", func() {
+			d1 := opsmanager.NewData(strings.NewReader("d1-content"), "d1", "best-kind")
+			failedOmData := opsmanager.NewFailedData("d2", "worse-kind", errors.New("retrieving is hard"))
+			omDataCollector.CollectReturns([]opsmanager.Data{d1, failedOmData}, "", nil)
+			credhubDataCollector := new(operationsfakes.FakeCredhubDataCollector)
+			credhubDataCollector.CollectReturns([]credhub.Data{credhub.NewFailedData(collector_tar.CertificatesDataType, errors.New("credhub is hard"))}, nil)
+			consumptionDataCollector := new(operationsfakes.FakeConsumptionDataCollector)
+			failedUsageData := consumption.NewFailedData(collector_tar.TaskUsageDataType, errors.New("usage is hard"))
+			consumptionDataCollector.CollectReturns([]consumption.Data{failedUsageData}, nil)
+			collector = NewCollector(omDataCollector, credhubDataCollector, consumptionDataCollector, nil, tarWriter, uuidProvider, "", nil)
+
+			err := collector.Collect(context.Background(), "", "")
+			Expect(err).To(MatchError(fmt.Sprintf(PartialCollectionFormat, 3)))
+			partialErr, ok := err.(PartialCollectionError)
+			Expect(ok).To(BeTrue())
+			Expect(partialErr.Failures).To(Equal([]manifest.Failure{
+				{ProductType: "d2", DataType: "worse-kind", Error: "retrieving is hard"},
+				{ProductType: collector_tar.DirectorProductType, DataType: collector_tar.CertificatesDataType, Error: "credhub is hard"},
+				{ProductType: "", DataType: collector_tar.TaskUsageDataType, Error: "usage is hard"},
+			}))
+
+			Expect(tarWriter.AddFileCallCount()).To(Equal(6))
+			_, d1Path := tarWriter.AddFileArgsForCall(0)
+			Expect(d1Path).To(Equal(filepath.Join(collector_tar.OpsManagerCollectorDataSetId, d1.Name())))
+
+			omMetadata := writtenMetadata(collector_tar.OpsManagerCollectorDataSetId)
+			Expect(omMetadata.FileDigests).To(HaveLen(1))
+			Expect(omMetadata.Failures).To(Equal(partialErr.Failures[:2]))
+
+			usageMetadata := writtenMetadata(collector_tar.UsageServiceCollectorDataSetId)
+			Expect(usageMetadata.FileDigests).To(BeEmpty())
+			Expect(usageMetadata.Failures).To(Equal(partialErr.Failures[2:]))
+		})
+
+		It("omits failures from the metadata when everything is collected", func() {
+			omDataCollector.CollectReturns([]opsmanager.Data{opsmanager.NewData(strings.NewReader(""), "d1", "best-kind")}, "", nil)
+
+			Expect(collector.Collect(context.Background(), "", "")).To(Succeed())
+			extensionsContents, extensionsPath := tarWriter.AddFileArgsForCall(2)
+			Expect(extensionsPath).To(Equal(manifest.ExtensionsFile(collector_tar.OpsManagerCollectorDataSetId)))
+			Expect(string(extensionsContents)).NotTo(ContainSubstring("Failures"))
+		})
+	})
+
+	Describe("pseudonymization", func() {
+		var pseudonymizer *operationsfakes.FakePseudonymizer
+
+		BeforeEach(func() {
+			pseudonymizer = new(operationsfakes.FakePseudonymizer)
+			pseudonymizer.ValueStub = func(value string) string { return "pseudonym-of-" + value }
+			pseudonymizer.TextStub = func(text string) string { return "pseudonymized " + text }
+			pseudonymizer.JSONStub = func(contents []byte) ([]byte, error) { return []byte(`{"pseudonymized": true}`), nil }
+		})
+
+		It("pseudonymizes the data, foundation id and failures before writing them", func() {
+			d1 := opsmanager.NewData(strings.NewReader(`{"guid": "some-guid"}`), "d1", "best-kind")
+			failedOmData := opsmanager.NewFailedData("d2", "worse-kind", errors.New("retrieving from 10.0.0.1 is hard"))
+			omDataCollector.CollectReturns([]opsmanager.Data{d1, failedOmData}, "p-bosh-guid", nil)
+			consumptionDataCollector := new(operationsfakes.FakeConsumptionDataCollector)
+			consumptionDataCollector.CollectReturns([]consumption.Data{consumption.NewData(strings.NewReader(`{}`), collector_tar.AppUsageDataType)}, nil)
+			collector = NewCollector(omDataCollector, nil, consumptionDataCollector, nil, tarWriter, uuidProvider, "", pseudonymizer)
+
+			err := collector.Collect(context.Background(), "", "")
+			Expect(err).To(MatchError(fmt.Sprintf(PartialCollectionFormat, 1)))
+
+			Expect(pseudonymizer.JSONCallCount()).To(Equal(2))
+			Expect(string(pseudonymizer.JSONArgsForCall(0))).To(Equal(`{"guid": "some-guid"}`))
+			d1Contents, _ := tarWriter.AddFileArgsForCall(0)
+			Expect(string(d1Contents)).To(Equal(`{"pseudonymized": true}`))
+
+			omMetadata := writtenMetadata(collector_tar.OpsManagerCollectorDataSetId)
+			Expect(omMetadata.Pseudonymized).To(BeTrue())
+			Expect(omMetadata.FoundationId).To(Equal("pseudonym-of-p-bosh-guid"))
+			Expect(omMetadata.FileDigests[0].SHA256Checksum).To(Equal(manifest.SHA256Checksum([]byte(`{"pseudonymized": true}`))))
+			Expect(omMetadata.Failures).To(Equal([]manifest.Failure{
+				{ProductType: "d2", DataType: "worse-kind", Error: "pseudonymized retrieving from 10.0.0.1 is hard"},
+			}))
+
+			usageMetadata := writtenMetadata(collector_tar.UsageServiceCollectorDataSetId)
+			Expect(usageMetadata.Pseudonymized).To(BeTrue())
+			Expect(usageMetadata.FoundationId).To(Equal("pseudonym-of-p-bosh-guid"))
+		})
+
+		It("returns an error when the data cannot be pseudonymized", func() {
+			d1 := opsmanager.NewData(strings.NewReader("not json"), "d1", "best-kind")
+			omDataCollector.CollectReturns([]opsmanager.Data{d1}, "", nil)
+			pseudonymizer.JSONReturns(nil, errors.New("pseudonymizing is hard"))
+			pseudonymizer.JSONStub = nil
+			collector = NewCollector(omDataCollector, nil, nil, nil, tarWriter, uuidProvider, "", pseudonymizer)
+
+			err := collector.Collect(context.Background(), "", "")
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(PseudonymizeFailureFormat, d1.Name()))))
+			Expect(err).To(MatchError(ContainSubstring("pseudonymizing is hard")))
+			Expect(tarWriter.AddFileCallCount()).To(Equal(0))
+		})
+
+		It("does not mark the metadata as pseudonymized without a pseudonymizer", func() {
+			omDataCollector.CollectReturns([]opsmanager.Data{opsmanager.NewData(strings.NewReader(""), "d1", "best-kind")}, "", nil)
+
+			Expect(collector.Collect(context.Background(), "", "")).To(Succeed())
+			extensionsContents, extensionsPath := tarWriter.AddFileArgsForCall(2)
+			Expect(extensionsPath).To(Equal(manifest.ExtensionsFile(collector_tar.OpsManagerCollectorDataSetId)))
+			Expect(string(extensionsContents)).NotTo(ContainSubstring("Pseudonymized"))
+		})
+	})
+
 	Describe("consumption collection", func() {
 		var (
 			collectorWithConsumption *CollectExecutor
@@ -252,7 +410,7 @@ var _ = Describe("DataCollector", func() {
 
 		BeforeEach(func() {
 			consumptionDataCollector = new(operationsfakes.FakeConsumptionDataCollector)
-			collectorWithConsumption = NewCollector(omDataCollector, nil, consumptionDataCollector, tarWriter, uuidProvider)
+			collectorWithConsumption = NewCollector(omDataCollector, nil, consumptionDataCollector, nil, tarWriter, uuidProvider, "", nil)
 		})
 
 		It("collects consumption data and writes it", func() {
@@ -277,43 +435,74 @@ var _ = Describe("DataCollector", func() {
 			collectorVersion := "0.0.1-version"
 			envType := "most-production"
 
-			err := collectorWithConsumption.Collect(envType, collectorVersion)
+			err := collectorWithConsumption.Collect(context.Background(), envType, collectorVersion)
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(tarWriter.AddFileCallCount()).To(Equal(5))
+			Expect(tarWriter.AddFileCallCount()).To(Equal(8))
 
 			expectedAppUsageConsumptionDataPath := filepath.Join(collector_tar.UsageServiceCollectorDataSetId, appUsageConsumptionData.Name())
-			appUsageConsumptionContents, appUsageConsumptionDataPath := tarWriter.AddFileArgsForCall(2)
+			appUsageConsumptionContents, appUsageConsumptionDataPath := tarWriter.AddFileArgsForCall(3)
 			Expect(string(appUsageConsumptionContents)).To(Equal(expectedAppUsageConsumptionContents))
 			Expect(appUsageConsumptionDataPath).To(Equal(expectedAppUsageConsumptionDataPath))
 
 			expectedServiceUsageConsumptionDataPath := filepath.Join(collector_tar.UsageServiceCollectorDataSetId, serviceUsageConsumptionData.Name())
-			serviceUsageConsumptionContents, serviceConsumptionDataPath := tarWriter.AddFileArgsForCall(3)
+			serviceUsageConsumptionContents, serviceConsumptionDataPath := tarWriter.AddFileArgsForCall(4)
 			Expect(string(serviceUsageConsumptionContents)).To(Equal(expectedServiceUsageConsumptionContents))
 			Expect(serviceConsumptionDataPath).To(Equal(expectedServiceUsageConsumptionDataPath))
 
 			expectedMetadataPath := filepath.Join(collector_tar.UsageServiceCollectorDataSetId, collector_tar.MetadataFileName)
-			metadataContents, metadataPath := tarWriter.AddFileArgsForCall(4)
+			_, metadataPath := tarWriter.AddFileArgsForCall(5)
 			Expect(metadataPath).To(Equal(expectedMetadataPath))
 
-			var metadata collector_tar.Metadata
-			Expect(json.Unmarshal(metadataContents, &metadata)).To(Succeed())
+			metadata := writtenMetadata(collector_tar.UsageServiceCollectorDataSetId)
 			Expect(metadata.CollectorVersion).To(Equal(collectorVersion))
 			Expect(metadata.CollectionId).To(Equal(uuidString))
 			Expect(metadata.FoundationId).To(Equal(foundationId))
 			Expect(metadata.EnvType).To(Equal(envType))
 			Expect(metadata.FileDigests).To(ConsistOf(
-				collector_tar.FileDigest{Name: appUsageConsumptionData.Name(), MimeType: appUsageConsumptionData.MimeType(), MD5Checksum: appUsageContentMd5, ProductType: appUsageConsumptionData.Type(), DataType: appUsageConsumptionData.DataType()},
-				collector_tar.FileDigest{Name: serviceUsageConsumptionData.Name(), MimeType: serviceUsageConsumptionData.MimeType(), MD5Checksum: serviceUsageContentMd5, ProductType: serviceUsageConsumptionData.Type(), DataType: serviceUsageConsumptionData.DataType()},
+				manifest.FileDigest{Name: appUsageConsumptionData.Name(), MimeType: appUsageConsumptionData.MimeType(), MD5Checksum: appUsageContentMd5, SHA256Checksum: manifest.SHA256Checksum([]byte(expectedAppUsageConsumptionContents)), ProductType: appUsageConsumptionData.Type(), DataType: appUsageConsumptionData.DataType()},
+				manifest.FileDigest{Name: serviceUsageConsumptionData.Name(), MimeType: serviceUsageConsumptionData.MimeType(), MD5Checksum: serviceUsageContentMd5, SHA256Checksum: manifest.SHA256Checksum([]byte(expectedServiceUsageConsumptionContents)), ProductType: serviceUsageConsumptionData.Type(), DataType: serviceUsageConsumptionData.DataType()},
 			))
 
 			Expect(tarWriter.CloseCallCount()).To(Equal(1))
 		})
 
+		It("records the range of each usage report in the metadata", func() {
+			omDataCollector.CollectReturns([]opsmanager.Data{}, "", nil)
+			january := consumption.ReportRange{
+				Start: time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2018, time.January, 31, 0, 0, 0, 0, time.UTC),
+			}
+			february := consumption.ReportRange{
+				Start: time.Date(2018, time.February, 1, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2018, time.February, 28, 0, 0, 0, 0, time.UTC),
+			}
+			januaryData := consumption.NewReportData(strings.NewReader("january-content"), collector_tar.AppUsageDataType, january)
+			februaryData := consumption.NewFailedReportData(collector_tar.AppUsageDataType, february, errors.New("retrieving is hard"))
+			consumptionDataCollector.CollectReturns([]consumption.Data{januaryData, februaryData}, nil)
+
+			err := collectorWithConsumption.Collect(context.Background(), "", "")
+			Expect(err).To(BeAssignableToTypeOf(PartialCollectionError{}))
+
+			_, januaryPath := tarWriter.AddFileArgsForCall(2)
+			Expect(januaryPath).To(Equal(filepath.Join(collector_tar.UsageServiceCollectorDataSetId, collector_tar.AppUsageDataType+"_2018-01")))
+
+			metadata := writtenMetadata(collector_tar.UsageServiceCollectorDataSetId)
+			Expect(metadata.FileDigests).To(HaveLen(1))
+			Expect(metadata.FileDigests[0].Start).To(Equal("2018-01-01"))
+			Expect(metadata.FileDigests[0].End).To(Equal("2018-01-31"))
+			Expect(metadata.Failures).To(Equal([]manifest.Failure{{
+				DataType: collector_tar.AppUsageDataType,
+				Start:    "2018-02-01",
+				End:      "2018-02-28",
+				Error:    "retrieving is hard",
+			}}))
+		})
+
 		It("returns an error when the consumption collection errors", func() {
 			consumptionDataCollector.CollectReturns([]consumption.Data{}, errors.New("collecting is hard"))
 
-			err := collectorWithConsumption.Collect("", "")
+			err := collectorWithConsumption.Collect(context.Background(), "", "")
 			Expect(tarWriter.CloseCallCount()).To(Equal(1))
 			Expect(err).To(MatchError(ContainSubstring(UsageCollectFailureMessage)))
 			Expect(err).To(MatchError(ContainSubstring("collecting is hard")))
@@ -325,7 +514,7 @@ var _ = Describe("DataCollector", func() {
 			failingData := consumption.NewData(failingReader, "app-instances")
 			consumptionDataCollector.CollectReturns([]consumption.Data{failingData}, nil)
 
-			err := collectorWithConsumption.Collect("", "")
+			err := collectorWithConsumption.Collect(context.Background(), "", "")
 			Expect(tarWriter.CloseCallCount()).To(Equal(1))
 			Expect(err).To(MatchError(ContainSubstring(ContentReadingFailureMessage)))
 			Expect(err).To(MatchError(ContainSubstring("reading is hard")))
@@ -341,7 +530,7 @@ var _ = Describe("DataCollector", func() {
 				return nil
 			}
 
-			err := collectorWithConsumption.Collect("", "")
+			err := collectorWithConsumption.Collect(context.Background(), "", "")
 			Expect(tarWriter.CloseCallCount()).To(Equal(1))
 			Expect(err).To(MatchError(ContainSubstring(DataWriteFailureMessage)))
 			Expect(err).To(MatchError(ContainSubstring("tarring is hard")))
@@ -356,7 +545,7 @@ var _ = Describe("DataCollector", func() {
 				return nil
 			}
 
-			err := collectorWithConsumption.Collect("", "")
+			err := collectorWithConsumption.Collect(context.Background(), "", "")
 			Expect(tarWriter.CloseCallCount()).To(Equal(1))
 			Expect(err).To(MatchError(ContainSubstring(DataWriteFailureMessage)))
 			Expect(err).To(MatchError(ContainSubstring("tarring is hard")))
@@ -364,6 +553,70 @@ var _ = Describe("DataCollector", func() {
 
 	})
 
+	Describe("CF API collection", func() {
+		var (
+			collectorWithCfApi *CollectExecutor
+			cfApiDataCollector *operationsfakes.FakeCfApiDataCollector
+		)
+
+		BeforeEach(func() {
+			cfApiDataCollector = new(operationsfakes.FakeCfApiDataCollector)
+			collectorWithCfApi = NewCollector(omDataCollector, nil, nil, cfApiDataCollector, tarWriter, uuidProvider, "sha256:some-policy-hash", nil)
+			omDataCollector.CollectReturns([]opsmanager.Data{}, "p-bosh-guid", nil)
+		})
+
+		It("writes the CF API data with its own metadata", func() {
+			appsContents := `{"count": 0, "apps": []}`
+			cfApiDataCollector.CollectReturns([]cfapi.Data{
+				cfapi.NewData(strings.NewReader(appsContents), cfapi.AppsDataType),
+				cfapi.NewFailedData(cfapi.StacksDataType, errors.New("listing is hard")),
+			}, nil)
+
+			err := collectorWithCfApi.Collect(context.Background(), "development", "0.0.1-version")
+			Expect(err).To(Equal(PartialCollectionError{Failures: []manifest.Failure{{DataType: cfapi.StacksDataType, Error: "listing is hard"}}}))
+
+			Expect(tarWriter.AddFileCallCount()).To(Equal(6))
+			contents, appsPath := tarWriter.AddFileArgsForCall(2)
+			Expect(appsPath).To(Equal(filepath.Join(manifest.CfApiDataSetId, cfapi.AppsDataType)))
+			Expect(string(contents)).To(Equal(appsContents))
+
+			_, metadataPath := tarWriter.AddFileArgsForCall(3)
+			Expect(metadataPath).To(Equal(filepath.Join(manifest.CfApiDataSetId, collector_tar.MetadataFileName)))
+			metadata := writtenMetadata(manifest.CfApiDataSetId)
+			Expect(metadata.CollectionId).To(Equal(uuidString))
+			Expect(metadata.FoundationId).To(Equal("p-bosh-guid"))
+			Expect(metadata.EnvType).To(Equal("development"))
+			Expect(metadata.CollectorVersion).To(Equal("0.0.1-version"))
+			Expect(metadata.RedactionPolicyHash).To(Equal("sha256:some-policy-hash"))
+			Expect(metadata.FileDigests).To(HaveLen(1))
+			Expect(metadata.FileDigests[0].DataType).To(Equal(cfapi.AppsDataType))
+			Expect(metadata.Failures).To(HaveLen(1))
+		})
+
+		It("returns an error when the CF API collection errors", func() {
+			cfApiDataCollector.CollectReturns(nil, errors.New("collecting is hard"))
+
+			err := collectorWithCfApi.Collect(context.Background(), "", "")
+			Expect(tarWriter.CloseCallCount()).To(Equal(1))
+			Expect(err).To(MatchError(ContainSubstring(CfApiCollectFailureMessage)))
+			Expect(err).To(MatchError(ContainSubstring("collecting is hard")))
+		})
+
+		It("returns an error when adding the metadata to the tar file fails", func() {
+			cfApiDataCollector.CollectReturns([]cfapi.Data{}, nil)
+			tarWriter.AddFileStub = func(contents []byte, filePath string) error {
+				if filePath == filepath.Join(manifest.CfApiDataSetId, collector_tar.MetadataFileName) {
+					return errors.New("tarring is hard")
+				}
+				return nil
+			}
+
+			err := collectorWithCfApi.Collect(context.Background(), "", "")
+			Expect(err).To(MatchError(ContainSubstring(DataWriteFailureMessage)))
+			Expect(err).To(MatchError(ContainSubstring("tarring is hard")))
+		})
+	})
+
 })
 
 //go:generate counterfeiter . reader
@@ -0,0 +1,96 @@
+package operations
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+	"github.com/pkg/errors"
+)
+
+const (
+	ReadMetadataFailureFormat = manifest.ReadMetadataFailureFormat
+	ReadFileFailureFormat     = "Unable to read %s"
+	InvalidJSONFileFormat     = "%s does not contain valid JSON"
+
+	UnknownFileSize = -1
+)
+
+type FileSummary struct {
+	manifest.FileDigest
+	Size int
+}
+
+type DataSetSummary struct {
+	Name     string
+	Metadata manifest.Metadata
+	Files    []FileSummary
+}
+
+type InspectExecutor struct {
+	tarReader tarReader
+}
+
+func NewInspector(tarReader tarReader) *InspectExecutor {
+	return &InspectExecutor{tarReader: tarReader}
+}
+
+func (ie *InspectExecutor) Inspect() ([]DataSetSummary, error) {
+	fileMd5s, err := ie.tarReader.FileMd5s()
+	if err != nil {
+		return nil, errors.Wrap(err, ListTarContentsFailureMessage)
+	}
+
+	var dataSets []string
+	for filePath := range fileMd5s {
+		pathParts := strings.SplitN(filepath.ToSlash(filePath), "/", 2)
+		if len(pathParts) == 2 && pathParts[1] == collector_tar.MetadataFileName {
+			dataSets = append(dataSets, pathParts[0])
+		}
+	}
+	sort.Strings(dataSets)
+
+	var summaries []DataSetSummary
+	for _, dataSet := range dataSets {
+		summary := DataSetSummary{Name: dataSet}
+
+		summary.Metadata, err = manifest.Read(ie.tarReader, fileMd5s, dataSet)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, digest := range summary.Metadata.FileDigests {
+			fileSummary := FileSummary{FileDigest: digest, Size: UnknownFileSize}
+			if _, exists := fileMd5s[filepath.Join(dataSet, digest.Name)]; exists {
+				contents, err := ie.tarReader.ReadFile(filepath.Join(dataSet, digest.Name))
+				if err != nil {
+					return nil, errors.Wrapf(err, ReadFileFailureFormat, filepath.Join(dataSet, digest.Name))
+				}
+				fileSummary.Size = len(contents)
+			}
+			summary.Files = append(summary.Files, fileSummary)
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
+}
+
+func (ie *InspectExecutor) Show(fileName string) ([]byte, error) {
+	contents, err := ie.tarReader.ReadFile(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, ReadFileFailureFormat, fileName)
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, contents, "", "  "); err != nil {
+		return nil, errors.Wrapf(err, InvalidJSONFileFormat, fileName)
+	}
+
+	return indented.Bytes(), nil
+}
@@ -0,0 +1,128 @@
+package operations_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
+	. "github.com/pivotal-cf/aqueduct-courier/operations"
+	"github.com/pivotal-cf/aqueduct-courier/operations/operationsfakes"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+)
+
+var _ = Describe("Inspector", func() {
+	var (
+		tarReader *operationsfakes.FakeTarReader
+		files     map[string][]byte
+		inspector *InspectExecutor
+	)
+
+	BeforeEach(func() {
+		files = map[string][]byte{}
+		tarReader = new(operationsfakes.FakeTarReader)
+		tarReader.FileMd5sStub = func() (map[string]string, error) {
+			fileMd5s := map[string]string{}
+			for name := range files {
+				fileMd5s[name] = "some-checksum"
+			}
+			return fileMd5s, nil
+		}
+		tarReader.ReadFileStub = func(name string) ([]byte, error) {
+			contents, exists := files[name]
+			if !exists {
+				return nil, errors.New("no such file")
+			}
+			return contents, nil
+		}
+
+		inspector = NewInspector(tarReader)
+	})
+
+	Describe("Inspect", func() {
+		It("summarizes the metadata and files of each data set", func() {
+			omMetadata := manifest.Metadata{
+				EnvType:          "production",
+				CollectionId:     "collection-id",
+				FoundationId:     "foundation-id",
+				CollectedAt:      "2018-10-01T00:00:00Z",
+				CollectorVersion: "1.0.0",
+				FileDigests: []manifest.FileDigest{
+					{Name: "d1", ProductType: "best-kind", DataType: "resources", MimeType: "application/json", MD5Checksum: "d1-checksum"},
+					{Name: "d2", ProductType: "best-kind", DataType: "properties", MimeType: "application/json", MD5Checksum: "d2-checksum"},
+				},
+			}
+			usageMetadata := manifest.Metadata{
+				EnvType:     "production",
+				FileDigests: []manifest.FileDigest{{Name: "app_usage"}},
+			}
+			omMetadataContents, err := json.Marshal(omMetadata)
+			Expect(err).NotTo(HaveOccurred())
+			usageMetadataContents, err := json.Marshal(usageMetadata)
+			Expect(err).NotTo(HaveOccurred())
+			files[filepath.Join("usage_service", collector_tar.MetadataFileName)] = usageMetadataContents
+			files[filepath.Join("usage_service", "app_usage")] = []byte("{}")
+			files[filepath.Join("opsmanager", collector_tar.MetadataFileName)] = omMetadataContents
+			files[filepath.Join("opsmanager", "d1")] = []byte("d1-content")
+
+			summaries, err := inspector.Inspect()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(summaries).To(Equal([]DataSetSummary{
+				{
+					Name:     "opsmanager",
+					Metadata: omMetadata,
+					Files: []FileSummary{
+						{FileDigest: omMetadata.FileDigests[0], Size: len("d1-content")},
+						{FileDigest: omMetadata.FileDigests[1], Size: UnknownFileSize},
+					},
+				},
+				{
+					Name:     "usage_service",
+					Metadata: usageMetadata,
+					Files:    []FileSummary{{FileDigest: usageMetadata.FileDigests[0], Size: 2}},
+				},
+			}))
+		})
+
+		It("returns an error when the tar contents cannot be listed", func() {
+			tarReader.FileMd5sStub = nil
+			tarReader.FileMd5sReturns(nil, errors.New("listing is hard"))
+
+			_, err := inspector.Inspect()
+			Expect(err).To(MatchError(ContainSubstring(ListTarContentsFailureMessage)))
+			Expect(err).To(MatchError(ContainSubstring("listing is hard")))
+		})
+
+		It("returns an error when a metadata file is invalid", func() {
+			files[filepath.Join("opsmanager", collector_tar.MetadataFileName)] = []byte("{not json")
+
+			_, err := inspector.Inspect()
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(ReadMetadataFailureFormat, "opsmanager"))))
+		})
+	})
+
+	Describe("Show", func() {
+		It("pretty-prints the requested file", func() {
+			files[filepath.Join("opsmanager", "d1")] = []byte(`{"some":{"nested":"value"}}`)
+
+			contents, err := inspector.Show(filepath.Join("opsmanager", "d1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("{\n  \"some\": {\n    \"nested\": \"value\"\n  }\n}"))
+		})
+
+		It("returns an error when the file cannot be read", func() {
+			_, err := inspector.Show("not-a-file")
+			Expect(err).To(MatchError(ContainSubstring("no such file")))
+		})
+
+		It("returns an error when the file is not JSON", func() {
+			files["not-json"] = []byte("{not json")
+
+			_, err := inspector.Show("not-json")
+			Expect(err).To(MatchError(ContainSubstring("not-json does not contain valid JSON")))
+		})
+	})
+})
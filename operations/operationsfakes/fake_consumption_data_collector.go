@@ -2,15 +2,17 @@
 package operationsfakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/pivotal-cf/aqueduct-courier/consumption"
 )
 
 type FakeConsumptionDataCollector struct {
-	CollectStub        func() ([]consumption.Data, error)
+	CollectStub        func(context.Context) ([]consumption.Data, error)
 	collectMutex       sync.RWMutex
 	collectArgsForCall []struct {
+		arg1 context.Context
 	}
 	collectReturns struct {
 		result1 []consumption.Data
@@ -24,15 +26,16 @@ type FakeConsumptionDataCollector struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeConsumptionDataCollector) Collect() ([]consumption.Data, error) {
+func (fake *FakeConsumptionDataCollector) Collect(arg1 context.Context) ([]consumption.Data, error) {
 	fake.collectMutex.Lock()
 	ret, specificReturn := fake.collectReturnsOnCall[len(fake.collectArgsForCall)]
 	fake.collectArgsForCall = append(fake.collectArgsForCall, struct {
-	}{})
-	fake.recordInvocation("Collect", []interface{}{})
+		arg1 context.Context
+	}{arg1})
+	fake.recordInvocation("Collect", []interface{}{arg1})
 	fake.collectMutex.Unlock()
 	if fake.CollectStub != nil {
-		return fake.CollectStub()
+		return fake.CollectStub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -47,12 +50,19 @@ func (fake *FakeConsumptionDataCollector) CollectCallCount() int {
 	return len(fake.collectArgsForCall)
 }
 
-func (fake *FakeConsumptionDataCollector) CollectCalls(stub func() ([]consumption.Data, error)) {
+func (fake *FakeConsumptionDataCollector) CollectCalls(stub func(context.Context) ([]consumption.Data, error)) {
 	fake.collectMutex.Lock()
 	defer fake.collectMutex.Unlock()
 	fake.CollectStub = stub
 }
 
+func (fake *FakeConsumptionDataCollector) CollectArgsForCall(i int) context.Context {
+	fake.collectMutex.RLock()
+	defer fake.collectMutex.RUnlock()
+	argsForCall := fake.collectArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeConsumptionDataCollector) CollectReturns(result1 []consumption.Data, result2 error) {
 	fake.collectMutex.Lock()
 	defer fake.collectMutex.Unlock()
@@ -2,37 +2,40 @@
 package operationsfakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/pivotal-cf/aqueduct-courier/credhub"
 )
 
 type FakeCredhubDataCollector struct {
-	CollectStub        func() (credhub.Data, error)
+	CollectStub        func(context.Context) ([]credhub.Data, error)
 	collectMutex       sync.RWMutex
 	collectArgsForCall []struct {
+		arg1 context.Context
 	}
 	collectReturns struct {
-		result1 credhub.Data
+		result1 []credhub.Data
 		result2 error
 	}
 	collectReturnsOnCall map[int]struct {
-		result1 credhub.Data
+		result1 []credhub.Data
 		result2 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeCredhubDataCollector) Collect() (credhub.Data, error) {
+func (fake *FakeCredhubDataCollector) Collect(arg1 context.Context) ([]credhub.Data, error) {
 	fake.collectMutex.Lock()
 	ret, specificReturn := fake.collectReturnsOnCall[len(fake.collectArgsForCall)]
 	fake.collectArgsForCall = append(fake.collectArgsForCall, struct {
-	}{})
-	fake.recordInvocation("Collect", []interface{}{})
+		arg1 context.Context
+	}{arg1})
+	fake.recordInvocation("Collect", []interface{}{arg1})
 	fake.collectMutex.Unlock()
 	if fake.CollectStub != nil {
-		return fake.CollectStub()
+		return fake.CollectStub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -47,34 +50,41 @@ func (fake *FakeCredhubDataCollector) CollectCallCount() int {
 	return len(fake.collectArgsForCall)
 }
 
-func (fake *FakeCredhubDataCollector) CollectCalls(stub func() (credhub.Data, error)) {
+func (fake *FakeCredhubDataCollector) CollectCalls(stub func(context.Context) ([]credhub.Data, error)) {
 	fake.collectMutex.Lock()
 	defer fake.collectMutex.Unlock()
 	fake.CollectStub = stub
 }
 
-func (fake *FakeCredhubDataCollector) CollectReturns(result1 credhub.Data, result2 error) {
+func (fake *FakeCredhubDataCollector) CollectArgsForCall(i int) context.Context {
+	fake.collectMutex.RLock()
+	defer fake.collectMutex.RUnlock()
+	argsForCall := fake.collectArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCredhubDataCollector) CollectReturns(result1 []credhub.Data, result2 error) {
 	fake.collectMutex.Lock()
 	defer fake.collectMutex.Unlock()
 	fake.CollectStub = nil
 	fake.collectReturns = struct {
-		result1 credhub.Data
+		result1 []credhub.Data
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeCredhubDataCollector) CollectReturnsOnCall(i int, result1 credhub.Data, result2 error) {
+func (fake *FakeCredhubDataCollector) CollectReturnsOnCall(i int, result1 []credhub.Data, result2 error) {
 	fake.collectMutex.Lock()
 	defer fake.collectMutex.Unlock()
 	fake.CollectStub = nil
 	if fake.collectReturnsOnCall == nil {
 		fake.collectReturnsOnCall = make(map[int]struct {
-			result1 credhub.Data
+			result1 []credhub.Data
 			result2 error
 		})
 	}
 	fake.collectReturnsOnCall[i] = struct {
-		result1 credhub.Data
+		result1 []credhub.Data
 		result2 error
 	}{result1, result2}
 }
@@ -2,15 +2,17 @@
 package operationsfakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/pivotal-cf/aqueduct-courier/opsmanager"
 )
 
 type FakeOmDataCollector struct {
-	CollectStub        func() ([]opsmanager.Data, string, error)
+	CollectStub        func(context.Context) ([]opsmanager.Data, string, error)
 	collectMutex       sync.RWMutex
 	collectArgsForCall []struct {
+		arg1 context.Context
 	}
 	collectReturns struct {
 		result1 []opsmanager.Data
@@ -26,15 +28,16 @@ type FakeOmDataCollector struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeOmDataCollector) Collect() ([]opsmanager.Data, string, error) {
+func (fake *FakeOmDataCollector) Collect(arg1 context.Context) ([]opsmanager.Data, string, error) {
 	fake.collectMutex.Lock()
 	ret, specificReturn := fake.collectReturnsOnCall[len(fake.collectArgsForCall)]
 	fake.collectArgsForCall = append(fake.collectArgsForCall, struct {
-	}{})
-	fake.recordInvocation("Collect", []interface{}{})
+		arg1 context.Context
+	}{arg1})
+	fake.recordInvocation("Collect", []interface{}{arg1})
 	fake.collectMutex.Unlock()
 	if fake.CollectStub != nil {
-		return fake.CollectStub()
+		return fake.CollectStub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2, ret.result3
@@ -49,12 +52,19 @@ func (fake *FakeOmDataCollector) CollectCallCount() int {
 	return len(fake.collectArgsForCall)
 }
 
-func (fake *FakeOmDataCollector) CollectCalls(stub func() ([]opsmanager.Data, string, error)) {
+func (fake *FakeOmDataCollector) CollectCalls(stub func(context.Context) ([]opsmanager.Data, string, error)) {
 	fake.collectMutex.Lock()
 	defer fake.collectMutex.Unlock()
 	fake.CollectStub = stub
 }
 
+func (fake *FakeOmDataCollector) CollectArgsForCall(i int) context.Context {
+	fake.collectMutex.RLock()
+	defer fake.collectMutex.RUnlock()
+	argsForCall := fake.collectArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeOmDataCollector) CollectReturns(result1 []opsmanager.Data, result2 string, result3 error) {
 	fake.collectMutex.Lock()
 	defer fake.collectMutex.Unlock()
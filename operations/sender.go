@@ -1,12 +1,20 @@
 package operations
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"encoding/json"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strings"
 
+	"github.com/pivotal-cf/aqueduct-courier/compression"
+	"github.com/pivotal-cf/aqueduct-courier/encryption"
+	"github.com/pivotal-cf/aqueduct-courier/signing"
+	"github.com/pivotal-cf/telemetry-utils/tar"
 	"github.com/pkg/errors"
 )
 
@@ -14,30 +22,53 @@ const (
 	AuthorizationHeaderKey         = "Authorization"
 	PostPath                       = "/collections/batch"
 	TarMimeType                    = "application/tar"
+	ContentEncodingHeader          = "Content-Encoding"
 	HTTPSenderVersionRequestHeader = "Pivotal-Telemetry-Sender-Version"
+	MaskedAPIKey                   = "********"
 
 	RequestCreationFailureMessage = "Failed make request object"
 	PostFailedMessage             = "Failed to do request"
 	ReadDataFileError             = "Unable to read data file"
+	ValidateDataFileError         = "Unable to validate data file"
+	VerifySignatureFailureMessage = "Refusing to send data file without a valid signature"
+	InvalidDataFileErrorFormat    = "Refusing to send invalid data file: %s"
 	UnauthorizedErrorMessage      = "User is not authorized to perform this action"
 	UnexpectedServerErrorFormat   = "There was an issue sending collector_tar. Please try again or contact your Pivotal field team if this error persists. Error ID %s"
 )
 
-type SendExecutor struct{}
+type SendExecutor struct {
+	skipValidation  bool
+	chunkSize       int64
+	privateKey      *rsa.PrivateKey
+	verificationKey ed25519.PublicKey
+}
+
+// NewSender returns a sender that decrypts encrypted data files with
+// privateKey, which may be nil to refuse to send them. Unless
+// verificationKey is nil, data files are only sent when the signature next to
+// them was made with it.
+func NewSender(skipValidation bool, chunkSize int64, privateKey *rsa.PrivateKey, verificationKey ed25519.PublicKey) SendExecutor {
+	return SendExecutor{skipValidation: skipValidation, chunkSize: chunkSize, privateKey: privateKey, verificationKey: verificationKey}
+}
 
 //go:generate counterfeiter . httpClient
 type httpClient interface {
 	Do(*http.Request) (*http.Response, error)
 }
 
-func (s SendExecutor) Send(client httpClient, tarFilePath, dataLoaderURL, apiToken, senderVersion string) error {
-	file, err := os.Open(tarFilePath)
+func (s SendExecutor) Send(ctx context.Context, client httpClient, tarFilePath, dataLoaderURL, apiToken, senderVersion string) error {
+	archive, format, err := s.openDataFile(tarFilePath)
 	if err != nil {
-		return errors.Wrap(err, ReadDataFileError)
+		return err
 	}
-	defer file.Close()
+	defer archive.Close()
+	file := archive.File
 
-	req, err := makeFileUploadRequest(file, apiToken, dataLoaderURL+PostPath, senderVersion)
+	if s.chunkSize > 0 {
+		return s.chunkedUpload(ctx, client, file, format, tarFilePath, dataLoaderURL, apiToken, senderVersion).send()
+	}
+
+	req, err := makeFileUploadRequest(ctx, file, apiToken, dataLoaderURL+PostPath, senderVersion, compression.ContentEncoding(format))
 	if err != nil {
 		return errors.Wrap(err, RequestCreationFailureMessage)
 	}
@@ -50,21 +81,156 @@ func (s SendExecutor) Send(client httpClient, tarFilePath, dataLoaderURL, apiTok
 	return checkStatusCode(resp)
 }
 
-func makeFileUploadRequest(bodyReader io.Reader, apiToken, uploadURL, senderVersion string) (*http.Request, error) {
-	req, err := http.NewRequest(http.MethodPost, uploadURL, bodyReader)
+// SendPlan describes the request a send starts with. A chunked send follows
+// it with a request per chunk and one to complete the upload.
+type SendPlan struct {
+	Method string
+	URL    string
+	Header http.Header
+	Size   int64
+	Chunks int64
+}
+
+// Plan checks the data file as Send does and describes the request Send
+// would start with, without making it. The API key is masked in the
+// Authorization header.
+func (s SendExecutor) Plan(tarFilePath, dataLoaderURL, apiToken, senderVersion string) (SendPlan, error) {
+	archive, format, err := s.openDataFile(tarFilePath)
+	if err != nil {
+		return SendPlan{}, err
+	}
+	defer archive.Close()
+	file := archive.File
+
+	info, err := file.Stat()
+	if err != nil {
+		return SendPlan{}, errors.Wrap(err, ReadDataFileError)
+	}
+
+	var req *http.Request
+	var chunks int64
+	if s.chunkSize > 0 {
+		req, err = s.chunkedUpload(context.Background(), nil, file, format, tarFilePath, dataLoaderURL, apiToken, senderVersion).startRequest(info.Size())
+		chunks = (info.Size() + s.chunkSize - 1) / s.chunkSize
+	} else {
+		req, err = makeFileUploadRequest(context.Background(), file, apiToken, dataLoaderURL+PostPath, senderVersion, compression.ContentEncoding(format))
+	}
+	if err != nil {
+		return SendPlan{}, errors.Wrap(err, RequestCreationFailureMessage)
+	}
+
+	req.Header.Set(AuthorizationHeaderKey, "Bearer "+maskAPIKey(apiToken))
+	return SendPlan{Method: req.Method, URL: req.URL.String(), Header: req.Header, Size: info.Size(), Chunks: chunks}, nil
+}
+
+// maskAPIKey keeps only the last few characters of a key long enough that
+// they do not give it away.
+func maskAPIKey(apiToken string) string {
+	if len(apiToken) < 16 {
+		return MaskedAPIKey
+	}
+	return MaskedAPIKey + apiToken[len(apiToken)-4:]
+}
+
+// openDataFile decrypts the data file when it is encrypted and checks it,
+// returning it along with its compression format.
+func (s SendExecutor) openDataFile(tarFilePath string) (*encryption.File, string, error) {
+	archive, err := encryption.Open(tarFilePath, s.privateKey)
+	if err != nil {
+		return nil, "", errors.Wrap(err, ReadDataFileError)
+	}
+
+	format, err := compression.Detect(archive.File)
+	if err != nil {
+		archive.Close()
+		return nil, "", errors.Wrap(err, ReadDataFileError)
+	}
+
+	if !s.skipValidation || s.verificationKey != nil {
+		err = s.checkDataFile(archive.Name(), tarFilePath+signing.SignatureFileExtension)
+		if err != nil {
+			archive.Close()
+			return nil, "", err
+		}
+	}
+	return archive, format, nil
+}
+
+func (s SendExecutor) chunkedUpload(ctx context.Context, client httpClient, file *os.File, format, tarFilePath, dataLoaderURL, apiToken, senderVersion string) *chunkedUpload {
+	return &chunkedUpload{
+		ctx:           ctx,
+		client:        client,
+		file:          file,
+		encoding:      compression.ContentEncoding(format),
+		uploadsURL:    dataLoaderURL + UploadsPath,
+		apiToken:      apiToken,
+		senderVersion: senderVersion,
+		chunkSize:     s.chunkSize,
+		statePath:     tarFilePath + UploadStateFileSuffix,
+	}
+}
+
+// checkDataFile verifies and validates the uncompressed contents of the data
+// file, so the file itself is sent as it is.
+func (s SendExecutor) checkDataFile(tarFilePath, signaturePath string) error {
+	archive, err := compression.Open(tarFilePath)
+	if err != nil {
+		return errors.Wrap(err, ReadDataFileError)
+	}
+	defer archive.Close()
+	tarReader := tar.NewTarReader(archive)
+
+	if s.verificationKey != nil {
+		if err := signing.VerifyFile(signaturePath, s.verificationKey, tarReader); err != nil {
+			return errors.Wrap(err, VerifySignatureFailureMessage)
+		}
+	}
+	if s.skipValidation {
+		return nil
+	}
+
+	report, err := NewValidator(tarReader).Validate()
+	if err != nil {
+		return errors.Wrap(err, ValidateDataFileError)
+	}
+	if !report.Valid() {
+		return errors.Errorf(InvalidDataFileErrorFormat, strings.Join(report.Failures(), ", "))
+	}
+	return nil
+}
+
+func makeFileUploadRequest(ctx context.Context, bodyReader io.ReadSeeker, apiToken, uploadURL, senderVersion, contentEncoding string) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, uploadURL, ioutil.NopCloser(bodyReader))
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
+	setReplayableBody(req, bodyReader)
 	req.Header.Set(AuthorizationHeaderKey, "Bearer "+apiToken)
 	req.Header.Set(HTTPSenderVersionRequestHeader, senderVersion)
 	req.Header.Set("Content-Type", TarMimeType)
+	if contentEncoding != "" {
+		req.Header.Set(ContentEncodingHeader, contentEncoding)
+	}
 
 	return req, nil
 }
 
+// setReplayableBody lets the request be retried by rewinding its body.
+func setReplayableBody(req *http.Request, body io.ReadSeeker) {
+	req.GetBody = func() (io.ReadCloser, error) {
+		_, err := body.Seek(0, io.SeekStart)
+		return ioutil.NopCloser(body), err
+	}
+}
+
 func checkStatusCode(resp *http.Response) error {
+	return checkResponseStatus(resp, http.StatusCreated)
+}
+
+func checkResponseStatus(resp *http.Response, expectedStatus int) error {
 	switch statusCode := resp.StatusCode; statusCode {
-	case http.StatusCreated:
+	case expectedStatus:
 		return nil
 	case http.StatusUnauthorized:
 		return errors.New(UnauthorizedErrorMessage)
@@ -1,17 +1,32 @@
 package operations_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
 
 	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/aqueduct-courier/compression"
+	"github.com/pivotal-cf/aqueduct-courier/encryption"
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
 	. "github.com/pivotal-cf/aqueduct-courier/operations"
 	"github.com/pivotal-cf/aqueduct-courier/operations/operationsfakes"
+	"github.com/pivotal-cf/aqueduct-courier/signing"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+	"github.com/pivotal-cf/telemetry-utils/tar"
 	"github.com/pkg/errors"
 )
 
@@ -26,17 +41,27 @@ var _ = Describe("Sender", func() {
 	)
 
 	BeforeEach(func() {
-		sender = SendExecutor{}
+		sender = NewSender(false, 0, nil, nil)
 		client = new(operationsfakes.FakeHttpClient)
 
 		tmpFile, err = ioutil.TempFile("", "")
 		Expect(err).NotTo(HaveOccurred())
 
-		tarContent = "tar-content"
-		_, err = tmpFile.Write([]byte(tarContent))
+		writer := tar.NewTarWriter(tmpFile)
+		Expect(writer.AddFile([]byte("d1-content"), filepath.Join("some-data-set", "d1"))).To(Succeed())
+		md5Sum := md5.Sum([]byte("d1-content"))
+		metadataContents, err := json.Marshal(manifest.Metadata{FileDigests: []manifest.FileDigest{
+			{Name: "d1", MD5Checksum: base64.StdEncoding.EncodeToString(md5Sum[:])},
+		}})
 		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.AddFile(metadataContents, filepath.Join("some-data-set", collector_tar.MetadataFileName))).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
 		Expect(tmpFile.Close()).To(Succeed())
 
+		tarBytes, err := ioutil.ReadFile(tmpFile.Name())
+		Expect(err).NotTo(HaveOccurred())
+		tarContent = string(tarBytes)
+
 		emptyBody := ioutil.NopCloser(strings.NewReader(""))
 
 		client.DoStub = func(request *http.Request) (response *http.Response, e error) {
@@ -54,7 +79,7 @@ var _ = Describe("Sender", func() {
 
 	It("posts to the data loader with the file as content", func() {
 		senderVersion := "best-sender-version"
-		Expect(sender.Send(client, tmpFile.Name(), "http://example.com", "some-key", senderVersion)).To(Succeed(), "")
+		Expect(sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", senderVersion)).To(Succeed(), "")
 
 		Expect(client.DoCallCount()).To(Equal(1))
 		req := client.DoArgsForCall(0)
@@ -65,19 +90,27 @@ var _ = Describe("Sender", func() {
 	})
 
 	It("posts to the data loader with the correct API key in the header", func() {
-		Expect(sender.Send(client, tmpFile.Name(), "http://example.com", "some-key", "")).To(Succeed())
+		Expect(sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")).To(Succeed())
 		req := client.DoArgsForCall(0)
 		Expect(req.Header.Get("Authorization")).To(Equal("Bearer some-key"))
 	})
 
+	It("sends the request with the given context", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		Expect(sender.Send(ctx, client, tmpFile.Name(), "http://example.com", "some-key", "")).To(Succeed())
+		req := client.DoArgsForCall(0)
+		Expect(req.Context()).To(Equal(ctx))
+	})
+
 	It("fails if the request object cannot be created", func() {
-		err := sender.Send(client, tmpFile.Name(), "127.0.0.1:a", "some-key", "")
+		err := sender.Send(context.Background(), client, tmpFile.Name(), "127.0.0.1:a", "some-key", "")
 		Expect(err).To(MatchError(ContainSubstring(RequestCreationFailureMessage)))
 	})
 
 	It("errors when the POST cannot be completed", func() {
 		client.DoReturns(nil, errors.New("doing requests is hard"))
-		err := sender.Send(client, tmpFile.Name(), "http://example.com", "some-key", "")
+		err := sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")
 		Expect(err).To(MatchError(ContainSubstring("doing requests is hard")))
 		Expect(err).To(MatchError(ContainSubstring(PostFailedMessage)))
 	})
@@ -86,20 +119,20 @@ var _ = Describe("Sender", func() {
 		emptyBody := ioutil.NopCloser(strings.NewReader(""))
 		client.DoReturns(&http.Response{StatusCode: http.StatusUnauthorized, Body: emptyBody}, nil)
 
-		err := sender.Send(client, tmpFile.Name(), "http://example.com", "invalid-key", "")
+		err := sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "invalid-key", "")
 		Expect(err).To(MatchError(UnauthorizedErrorMessage))
 	})
 
 	It("errors if the error response cannot be read", func() {
 		client.DoReturns(&http.Response{StatusCode: http.StatusExpectationFailed, Body: ioutil.NopCloser(&badReader{})}, nil)
-		err := sender.Send(client, tmpFile.Name(), "http://example.com", "invalid-key", "")
+		err := sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "invalid-key", "")
 		Expect(err).To(MatchError(fmt.Sprintf(UnexpectedServerErrorFormat, "unknown")))
 	})
 
 	It("errors if the error response cannot be read into the expected structure", func() {
 		badBody := ioutil.NopCloser(strings.NewReader(`{not json`))
 		client.DoReturns(&http.Response{StatusCode: http.StatusExpectationFailed, Body: badBody}, nil)
-		err := sender.Send(client, tmpFile.Name(), "http://example.com", "invalid-key", "")
+		err := sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "invalid-key", "")
 		Expect(err).To(MatchError(fmt.Sprintf(UnexpectedServerErrorFormat, "unknown")))
 	})
 
@@ -107,14 +140,243 @@ var _ = Describe("Sender", func() {
 		emptyBody := ioutil.NopCloser(strings.NewReader(`{"error": {"uuid": "error-uuid"}}`))
 		client.DoReturns(&http.Response{StatusCode: http.StatusExpectationFailed, Body: emptyBody}, nil)
 
-		err := sender.Send(client, tmpFile.Name(), "http://example.com", "invalid-key", "")
+		err := sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "invalid-key", "")
 		Expect(err).To(MatchError(fmt.Sprintf(UnexpectedServerErrorFormat, "error-uuid")))
 	})
 
+	It("does not post when the tar file fails validation", func() {
+		Expect(ioutil.WriteFile(tmpFile.Name(), []byte("not-a-tar"), 0644)).To(Succeed())
+
+		err := sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")
+		Expect(err).To(MatchError(ContainSubstring(ValidateDataFileError)))
+		Expect(client.DoCallCount()).To(Equal(0))
+	})
+
+	It("reports the invalid files when the tar file contents do not match the metadata", func() {
+		tamperedFile, err := os.Create(tmpFile.Name())
+		Expect(err).NotTo(HaveOccurred())
+		writer := tar.NewTarWriter(tamperedFile)
+		Expect(writer.AddFile([]byte("tampered-content"), filepath.Join("some-data-set", "d1"))).To(Succeed())
+		metadataContents, err := json.Marshal(manifest.Metadata{FileDigests: []manifest.FileDigest{
+			{Name: "d1", MD5Checksum: "original-checksum"},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.AddFile(metadataContents, filepath.Join("some-data-set", collector_tar.MetadataFileName))).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
+		Expect(tamperedFile.Close()).To(Succeed())
+
+		err = sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")
+		Expect(err).To(MatchError(fmt.Sprintf(InvalidDataFileErrorFormat, strings.Join([]string{
+			fmt.Sprintf("%s: %s", filepath.Join("some-data-set", "d1"), FileStatusChecksumInvalid),
+			fmt.Sprintf("some-data-set: %s", collector_tar.InvalidFilesInTarMessageError),
+		}, ", "))))
+		Expect(client.DoCallCount()).To(Equal(0))
+	})
+
+	It("posts the tar file without validating it when validation is skipped", func() {
+		Expect(ioutil.WriteFile(tmpFile.Name(), []byte("not-a-tar"), 0644)).To(Succeed())
+
+		sender = NewSender(true, 0, nil, nil)
+		Expect(sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")).To(Succeed())
+		Expect(client.DoCallCount()).To(Equal(1))
+		Expect(string(doBodyContents)).To(Equal("not-a-tar"))
+	})
+
+	It("posts an uncompressed tar file without a content encoding", func() {
+		Expect(sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")).To(Succeed())
+		req := client.DoArgsForCall(0)
+		Expect(req.Header.Get("Content-Type")).To(Equal(TarMimeType))
+		Expect(req.Header.Get(ContentEncodingHeader)).To(BeEmpty())
+	})
+
+	It("validates the contents of a compressed tar file and posts it as it is with its content encoding", func() {
+		var compressed bytes.Buffer
+		writer, err := compression.NewWriter(&compressed, compression.Zstd)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = writer.Write([]byte(tarContent))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+		Expect(ioutil.WriteFile(tmpFile.Name(), compressed.Bytes(), 0644)).To(Succeed())
+
+		Expect(sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")).To(Succeed())
+		req := client.DoArgsForCall(0)
+		Expect(req.Header.Get("Content-Type")).To(Equal(TarMimeType))
+		Expect(req.Header.Get(ContentEncodingHeader)).To(Equal("zstd"))
+		Expect(doBodyContents).To(Equal(compressed.Bytes()))
+	})
+
+	It("does not post a compressed tar file whose contents fail validation", func() {
+		var compressed bytes.Buffer
+		writer, err := compression.NewWriter(&compressed, compression.Gzip)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = writer.Write([]byte("not-a-tar"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+		Expect(ioutil.WriteFile(tmpFile.Name(), compressed.Bytes(), 0644)).To(Succeed())
+
+		err = sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")
+		Expect(err).To(MatchError(ContainSubstring(ValidateDataFileError)))
+		Expect(client.DoCallCount()).To(Equal(0))
+	})
+
+	Context("when the file is encrypted", func() {
+		var key *rsa.PrivateKey
+
+		BeforeEach(func() {
+			var err error
+			key, err = rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).NotTo(HaveOccurred())
+
+			var encrypted bytes.Buffer
+			writer, err := encryption.NewWriter(&encrypted, &key.PublicKey)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = writer.Write([]byte(tarContent))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(writer.Close()).To(Succeed())
+			Expect(ioutil.WriteFile(tmpFile.Name(), encrypted.Bytes(), 0644)).To(Succeed())
+		})
+
+		It("decrypts it with the private key and posts its contents", func() {
+			sender = NewSender(false, 0, key, nil)
+			Expect(sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")).To(Succeed())
+			Expect(client.DoCallCount()).To(Equal(1))
+			Expect(string(doBodyContents)).To(Equal(tarContent))
+		})
+
+		It("refuses to send it without a private key", func() {
+			err := sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(encryption.EncryptedArchiveFormat, encryption.Fingerprint(&key.PublicKey)))))
+			Expect(client.DoCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when a verification key is configured", func() {
+		var (
+			publicKey  ed25519.PublicKey
+			privateKey ed25519.PrivateKey
+			signature  signing.Signature
+		)
+
+		BeforeEach(func() {
+			var err error
+			publicKey, privateKey, err = ed25519.GenerateKey(rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+
+			signedFile, err := os.Create(tmpFile.Name())
+			Expect(err).NotTo(HaveOccurred())
+			recorder := signing.NewMetadataRecorder(tar.NewTarWriter(signedFile))
+			Expect(recorder.AddFile([]byte("d1-content"), filepath.Join("some-data-set", "d1"))).To(Succeed())
+			metadata := manifest.Metadata{FileDigests: []manifest.FileDigest{{
+				Name:           "d1",
+				MD5Checksum:    manifest.MD5Checksum([]byte("d1-content")),
+				SHA256Checksum: manifest.SHA256Checksum([]byte("d1-content")),
+			}}}
+			metadataWriter := manifest.NewWriter(recorder)
+			Expect(metadataWriter.Write("some-data-set", metadata)).To(Succeed())
+			Expect(metadataWriter.Close(metadata)).To(Succeed())
+			Expect(recorder.Close()).To(Succeed())
+			Expect(signedFile.Close()).To(Succeed())
+
+			signature = signing.Sign(privateKey, recorder.MetadataFiles)
+			Expect(signature.WriteFile(tmpFile.Name() + signing.SignatureFileExtension)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(tmpFile.Name() + signing.SignatureFileExtension)).To(Succeed())
+		})
+
+		It("posts the file when its signature is valid", func() {
+			sender = NewSender(false, 0, nil, publicKey)
+			Expect(sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")).To(Succeed())
+			Expect(client.DoCallCount()).To(Equal(1))
+		})
+
+		It("verifies the signature even when validation is skipped", func() {
+			otherPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+
+			sender = NewSender(true, 0, nil, otherPublicKey)
+			err = sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")
+			Expect(err).To(MatchError(ContainSubstring(VerifySignatureFailureMessage)))
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(signing.WrongSigningKeyFormat, signing.KeyID(publicKey), signing.KeyID(otherPublicKey)))))
+			Expect(client.DoCallCount()).To(Equal(0))
+		})
+
+		It("does not post a data file changed after signing, even when validation is skipped", func() {
+			tamperedFile, err := os.Create(tmpFile.Name())
+			Expect(err).NotTo(HaveOccurred())
+			tamperedWriter := tar.NewTarWriter(tamperedFile)
+			Expect(tamperedWriter.AddFile([]byte("d1-tampered"), filepath.Join("some-data-set", "d1"))).To(Succeed())
+			metadata := manifest.Metadata{FileDigests: []manifest.FileDigest{{
+				Name:           "d1",
+				MD5Checksum:    manifest.MD5Checksum([]byte("d1-content")),
+				SHA256Checksum: manifest.SHA256Checksum([]byte("d1-content")),
+			}}}
+			metadataWriter := manifest.NewWriter(tamperedWriter)
+			Expect(metadataWriter.Write("some-data-set", metadata)).To(Succeed())
+			Expect(metadataWriter.Close(metadata)).To(Succeed())
+			Expect(tamperedWriter.Close()).To(Succeed())
+			Expect(tamperedFile.Close()).To(Succeed())
+
+			sender = NewSender(true, 0, nil, publicKey)
+			err = sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")
+			Expect(err).To(MatchError(ContainSubstring(VerifySignatureFailureMessage)))
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(signing.ModifiedFileFormat, filepath.Join("some-data-set", "d1")))))
+			Expect(client.DoCallCount()).To(Equal(0))
+		})
+
+		It("does not post a file without a signature", func() {
+			Expect(os.Remove(tmpFile.Name() + signing.SignatureFileExtension)).To(Succeed())
+
+			sender = NewSender(false, 0, nil, publicKey)
+			err := sender.Send(context.Background(), client, tmpFile.Name(), "http://example.com", "some-key", "")
+			Expect(err).To(MatchError(ContainSubstring(VerifySignatureFailureMessage)))
+			Expect(client.DoCallCount()).To(Equal(0))
+		})
+	})
+
 	It("when the tarFile does not exist", func() {
-		err := sender.Send(client, "path/to/not/the/tarFile", "http://example.com", "some-key", "")
+		err := sender.Send(context.Background(), client, "path/to/not/the/tarFile", "http://example.com", "some-key", "")
 		Expect(err).To(MatchError(ContainSubstring(ReadDataFileError)))
 	})
+
+	Describe("Plan", func() {
+		It("describes the post it would make with the API key masked", func() {
+			plan, err := sender.Plan(tmpFile.Name(), "http://example.com", "some-very-long-api-key", "best-sender-version")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(plan.Method).To(Equal(http.MethodPost))
+			Expect(plan.URL).To(Equal("http://example.com" + PostPath))
+			Expect(plan.Header.Get("Authorization")).To(Equal("Bearer " + MaskedAPIKey + "-key"))
+			Expect(plan.Header.Get(HTTPSenderVersionRequestHeader)).To(Equal("best-sender-version"))
+			Expect(plan.Size).To(Equal(int64(len(tarContent))))
+			Expect(plan.Chunks).To(BeZero())
+			Expect(client.DoCallCount()).To(Equal(0))
+		})
+
+		It("masks a short API key entirely", func() {
+			plan, err := sender.Plan(tmpFile.Name(), "http://example.com", "some-key", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plan.Header.Get("Authorization")).To(Equal("Bearer " + MaskedAPIKey))
+		})
+
+		It("describes the request starting a chunked upload", func() {
+			sender = NewSender(false, 100, nil, nil)
+			plan, err := sender.Plan(tmpFile.Name(), "http://example.com", "some-key", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(plan.Method).To(Equal(http.MethodPost))
+			Expect(plan.URL).To(Equal("http://example.com" + UploadsPath))
+			Expect(plan.Header.Get(UploadLengthHeader)).To(Equal(fmt.Sprint(len(tarContent))))
+			Expect(plan.Chunks).To(Equal(int64((len(tarContent) + 99) / 100)))
+		})
+
+		It("fails as sending would when the tar file fails validation", func() {
+			Expect(ioutil.WriteFile(tmpFile.Name(), []byte("not-a-tar"), 0644)).To(Succeed())
+			_, err := sender.Plan(tmpFile.Name(), "http://example.com", "some-key", "")
+			Expect(err).To(MatchError(ContainSubstring(ValidateDataFileError)))
+		})
+	})
 })
 
 type badReader struct{}
@@ -0,0 +1,224 @@
+package operations
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+	"github.com/pkg/errors"
+)
+
+const (
+	ListTarContentsFailureMessage = "Unable to list contents of tar"
+
+	FileStatusValid           = "OK"
+	FileStatusMissing         = "missing from tar"
+	FileStatusExtra           = "not listed in metadata"
+	FileStatusChecksumInvalid = "checksum does not match metadata"
+	FileStatusNameInvalid     = "invalid file name"
+	FileStatusOutsideDataSet  = "not within a data set directory"
+)
+
+//go:generate counterfeiter . tarReader
+type tarReader interface {
+	ReadFile(fileName string) ([]byte, error)
+	FileMd5s() (map[string]string, error)
+}
+
+type FileReport struct {
+	Name   string
+	Status string
+}
+
+func (fr FileReport) Valid() bool {
+	return fr.Status == FileStatusValid
+}
+
+type DataSetReport struct {
+	Name     string
+	Files    []FileReport
+	Failures []manifest.Failure
+	Err      error
+}
+
+func (dsr DataSetReport) Valid() bool {
+	return dsr.Err == nil
+}
+
+type ValidationReport struct {
+	DataSets []DataSetReport
+}
+
+func (vr ValidationReport) Valid() bool {
+	for _, dataSet := range vr.DataSets {
+		if !dataSet.Valid() {
+			return false
+		}
+	}
+	return true
+}
+
+// Partial reports whether any data set records data that could not be
+// collected.
+func (vr ValidationReport) Partial() bool {
+	for _, dataSet := range vr.DataSets {
+		if len(dataSet.Failures) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (vr ValidationReport) Failures() []string {
+	var failures []string
+	for _, dataSet := range vr.DataSets {
+		for _, file := range dataSet.Files {
+			if !file.Valid() {
+				failures = append(failures, fmt.Sprintf("%s: %s", filepath.Join(dataSet.Name, file.Name), file.Status))
+			}
+		}
+		if !dataSet.Valid() && dataSet.Name != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", dataSet.Name, dataSet.Err))
+		}
+	}
+	return failures
+}
+
+type ValidateExecutor struct {
+	tarReader tarReader
+}
+
+func NewValidator(tarReader tarReader) *ValidateExecutor {
+	return &ValidateExecutor{tarReader: tarReader}
+}
+
+func (ve *ValidateExecutor) Validate() (ValidationReport, error) {
+	fileMd5s, err := ve.tarReader.FileMd5s()
+	if err != nil {
+		return ValidationReport{}, errors.Wrap(err, ListTarContentsFailureMessage)
+	}
+
+	dataSetMd5s := map[string]map[string]string{}
+	var looseFiles []FileReport
+	for filePath, checksum := range fileMd5s {
+		pathParts := strings.SplitN(filepath.ToSlash(filePath), "/", 2)
+		if len(pathParts) != 2 {
+			looseFiles = append(looseFiles, FileReport{Name: filePath, Status: FileStatusOutsideDataSet})
+			continue
+		}
+		if _, exists := dataSetMd5s[pathParts[0]]; !exists {
+			dataSetMd5s[pathParts[0]] = map[string]string{}
+		}
+		dataSetMd5s[pathParts[0]][pathParts[1]] = checksum
+	}
+
+	var dataSets []string
+	for dataSet := range dataSetMd5s {
+		dataSets = append(dataSets, dataSet)
+	}
+	sort.Strings(dataSets)
+
+	var report ValidationReport
+	for _, dataSet := range dataSets {
+		dsReader := &dataSetReader{tarReader: ve.tarReader, dataSet: dataSet, fileMd5s: dataSetMd5s[dataSet]}
+		metadata, err := manifest.Read(ve.tarReader, fileMd5s, dataSet)
+		metadataValid := err == nil
+
+		dataSetReport := DataSetReport{
+			Name: dataSet,
+			Err:  collector_tar.NewFileValidator(dsReader).Validate(),
+		}
+		if metadataValid {
+			dataSetReport.Files = fileReports(metadata, dsReader)
+			dataSetReport.Failures = metadata.Failures
+			if dataSetReport.Err == nil {
+				dataSetReport.Err = checkExtensions(metadata, dataSetReport.Files)
+			}
+		}
+		report.DataSets = append(report.DataSets, dataSetReport)
+	}
+
+	if len(looseFiles) > 0 {
+		sort.Slice(looseFiles, func(i, j int) bool { return looseFiles[i].Name < looseFiles[j].Name })
+		report.DataSets = append(report.DataSets, DataSetReport{
+			Files: looseFiles,
+			Err:   errors.New(collector_tar.ExtraFilesInTarMessageError),
+		})
+	}
+
+	return report, nil
+}
+
+// checkExtensions checks what the metadata extensions record, which
+// telemetry-utils does not know to check.
+func checkExtensions(metadata manifest.Metadata, files []FileReport) error {
+	for _, file := range files {
+		if file.Status == FileStatusChecksumInvalid {
+			return errors.New(collector_tar.InvalidFilesInTarMessageError)
+		}
+	}
+	return metadata.CheckFailures()
+}
+
+func fileReports(metadata manifest.Metadata, dsReader *dataSetReader) []FileReport {
+	unlisted := map[string]string{}
+	for name, checksum := range dsReader.fileMd5s {
+		unlisted[name] = checksum
+	}
+	delete(unlisted, collector_tar.MetadataFileName)
+
+	var reports []FileReport
+	for _, digest := range metadata.FileDigests {
+		report := FileReport{Name: digest.Name, Status: FileStatusValid}
+		checksum, exists := unlisted[digest.Name]
+		switch {
+		case strings.Contains(digest.Name, ".") || strings.Contains(digest.Name, "/"):
+			report.Status = FileStatusNameInvalid
+		case !exists:
+			report.Status = FileStatusMissing
+		case checksum != digest.MD5Checksum:
+			report.Status = FileStatusChecksumInvalid
+		case digest.SHA256Checksum != "" && !sha256Matches(dsReader, digest):
+			report.Status = FileStatusChecksumInvalid
+		}
+		delete(unlisted, digest.Name)
+		reports = append(reports, report)
+	}
+
+	var extraFiles []string
+	for name := range unlisted {
+		extraFiles = append(extraFiles, name)
+	}
+	sort.Strings(extraFiles)
+	for _, name := range extraFiles {
+		reports = append(reports, FileReport{Name: name, Status: FileStatusExtra})
+	}
+
+	return reports
+}
+
+func sha256Matches(dsReader *dataSetReader, digest manifest.FileDigest) bool {
+	contents, err := dsReader.ReadFile(digest.Name)
+	return err == nil && manifest.SHA256Checksum(contents) == digest.SHA256Checksum
+}
+
+type dataSetReader struct {
+	tarReader tarReader
+	dataSet   string
+	fileMd5s  map[string]string
+}
+
+func (r *dataSetReader) ReadFile(fileName string) ([]byte, error) {
+	return r.tarReader.ReadFile(filepath.Join(r.dataSet, fileName))
+}
+
+func (r *dataSetReader) FileMd5s() (map[string]string, error) {
+	fileMd5s := map[string]string{}
+	for name, checksum := range r.fileMd5s {
+		fileMd5s[name] = checksum
+	}
+	return fileMd5s, nil
+}
@@ -0,0 +1,237 @@
+package operations_test
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
+	. "github.com/pivotal-cf/aqueduct-courier/operations"
+	"github.com/pivotal-cf/aqueduct-courier/operations/operationsfakes"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+)
+
+var _ = Describe("Validator", func() {
+	var (
+		tarReader *operationsfakes.FakeTarReader
+		files     map[string][]byte
+		validator *ValidateExecutor
+	)
+
+	checksum := func(contents []byte) string {
+		sum := md5.Sum(contents)
+		return base64.StdEncoding.EncodeToString(sum[:])
+	}
+
+	addMetadata := func(dataSet string, digests ...manifest.FileDigest) {
+		metadataContents, err := json.Marshal(manifest.Metadata{FileDigests: digests})
+		Expect(err).NotTo(HaveOccurred())
+		files[filepath.Join(dataSet, collector_tar.MetadataFileName)] = metadataContents
+	}
+
+	addExtendedMetadata := func(dataSet string, metadata manifest.Metadata) {
+		writer := manifest.NewWriter(archiveFiles(files))
+		Expect(writer.Write(dataSet, metadata)).To(Succeed())
+		Expect(writer.Close(metadata)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		files = map[string][]byte{}
+		tarReader = new(operationsfakes.FakeTarReader)
+		tarReader.FileMd5sStub = func() (map[string]string, error) {
+			fileMd5s := map[string]string{}
+			for name, contents := range files {
+				fileMd5s[name] = checksum(contents)
+			}
+			return fileMd5s, nil
+		}
+		tarReader.ReadFileStub = func(name string) ([]byte, error) {
+			contents, exists := files[name]
+			if !exists {
+				return nil, errors.New("no such file")
+			}
+			return contents, nil
+		}
+
+		validator = NewValidator(tarReader)
+	})
+
+	It("reports every file in every data set as valid", func() {
+		files[filepath.Join("opsmanager", "d1")] = []byte("d1-content")
+		addMetadata("opsmanager", manifest.FileDigest{Name: "d1", MD5Checksum: checksum([]byte("d1-content"))})
+		files[filepath.Join("usage_service", "d2")] = []byte("d2-content")
+		addMetadata("usage_service", manifest.FileDigest{Name: "d2", MD5Checksum: checksum([]byte("d2-content"))})
+
+		report, err := validator.Validate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Valid()).To(BeTrue())
+		Expect(report.DataSets).To(Equal([]DataSetReport{
+			{Name: "opsmanager", Files: []FileReport{{Name: "d1", Status: FileStatusValid}}},
+			{Name: "usage_service", Files: []FileReport{{Name: "d2", Status: FileStatusValid}}},
+		}))
+	})
+
+	It("reports missing, extra, modified and invalidly named files", func() {
+		files[filepath.Join("opsmanager", "modified")] = []byte("new-content")
+		files[filepath.Join("opsmanager", "extra")] = []byte("extra-content")
+		addMetadata("opsmanager",
+			manifest.FileDigest{Name: "modified", MD5Checksum: checksum([]byte("old-content"))},
+			manifest.FileDigest{Name: "missing", MD5Checksum: checksum([]byte("missing-content"))},
+			manifest.FileDigest{Name: "invalid.name", MD5Checksum: checksum([]byte(""))},
+		)
+
+		report, err := validator.Validate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Valid()).To(BeFalse())
+		Expect(report.DataSets).To(HaveLen(1))
+		Expect(report.DataSets[0].Err).To(HaveOccurred())
+		Expect(report.DataSets[0].Files).To(Equal([]FileReport{
+			{Name: "modified", Status: FileStatusChecksumInvalid},
+			{Name: "missing", Status: FileStatusMissing},
+			{Name: "invalid.name", Status: FileStatusNameInvalid},
+			{Name: "extra", Status: FileStatusExtra},
+		}))
+	})
+
+	It("checks SHA-256 digests when the metadata records them", func() {
+		files[filepath.Join("opsmanager", "d1")] = []byte("d1-content")
+		files[filepath.Join("opsmanager", "d2")] = []byte("d2-content")
+		addExtendedMetadata("opsmanager", manifest.Metadata{FileDigests: []manifest.FileDigest{
+			{Name: "d1", MD5Checksum: checksum([]byte("d1-content")), SHA256Checksum: manifest.SHA256Checksum([]byte("d1-content"))},
+			{Name: "d2", MD5Checksum: checksum([]byte("d2-content")), SHA256Checksum: manifest.SHA256Checksum([]byte("other-content"))},
+		}})
+
+		report, err := validator.Validate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Valid()).To(BeFalse())
+		Expect(report.DataSets).To(Equal([]DataSetReport{
+			{Name: manifest.ExtensionsDataSetId, Files: []FileReport{{Name: "opsmanager", Status: FileStatusValid}}},
+			{
+				Name: "opsmanager",
+				Files: []FileReport{
+					{Name: "d1", Status: FileStatusValid},
+					{Name: "d2", Status: FileStatusChecksumInvalid},
+				},
+				Err: report.DataSets[1].Err,
+			},
+		}))
+		Expect(report.DataSets[1].Err).To(MatchError(collector_tar.InvalidFilesInTarMessageError))
+	})
+
+	It("rejects extended fields written into the metadata itself", func() {
+		files[filepath.Join("opsmanager", "d1")] = []byte("d1-content")
+		metadataContents, err := json.Marshal(manifest.Metadata{
+			FileDigests: []manifest.FileDigest{{Name: "d1", MD5Checksum: checksum([]byte("d1-content")), SHA256Checksum: manifest.SHA256Checksum([]byte("d1-content"))}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		files[filepath.Join("opsmanager", collector_tar.MetadataFileName)] = metadataContents
+
+		report, err := validator.Validate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Valid()).To(BeFalse())
+		Expect(report.DataSets[0].Err).To(MatchError(ContainSubstring(collector_tar.InvalidMetadataFileError)))
+	})
+
+	It("reports the failures recorded by a partial collection", func() {
+		files[filepath.Join("opsmanager", "d1")] = []byte("d1-content")
+		failures := []manifest.Failure{{ProductType: "p1", DataType: "properties", Error: "retrieving is hard"}}
+		addExtendedMetadata("opsmanager", manifest.Metadata{
+			FileDigests: []manifest.FileDigest{{Name: "d1", ProductType: "p1", DataType: "resources", MD5Checksum: checksum([]byte("d1-content"))}},
+			Failures:    failures,
+		})
+
+		report, err := validator.Validate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Valid()).To(BeTrue())
+		Expect(report.Partial()).To(BeTrue())
+		Expect(report.DataSets[1].Failures).To(Equal(failures))
+	})
+
+	It("reports failure records for data that was collected as invalid", func() {
+		files[filepath.Join("opsmanager", "d1")] = []byte("d1-content")
+		addExtendedMetadata("opsmanager", manifest.Metadata{
+			FileDigests: []manifest.FileDigest{{Name: "d1", ProductType: "p1", DataType: "resources", MD5Checksum: checksum([]byte("d1-content"))}},
+			Failures:    []manifest.Failure{{ProductType: "p1", DataType: "resources", Error: "retrieving is hard"}},
+		})
+
+		report, err := validator.Validate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Valid()).To(BeFalse())
+		Expect(report.DataSets[1].Err).To(MatchError(manifest.InvalidFailureRecordMessage))
+	})
+
+	It("accepts failure records for other ranges of data that was collected", func() {
+		files[filepath.Join("usage_service", "app_usage_2018-01")] = []byte("january-content")
+		addExtendedMetadata("usage_service", manifest.Metadata{
+			FileDigests: []manifest.FileDigest{{Name: "app_usage_2018-01", DataType: "app_usage", Start: "2018-01-01", End: "2018-01-31", MD5Checksum: checksum([]byte("january-content"))}},
+			Failures:    []manifest.Failure{{DataType: "app_usage", Start: "2018-02-01", End: "2018-02-28", Error: "retrieving is hard"}},
+		})
+
+		report, err := validator.Validate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Valid()).To(BeTrue())
+		Expect(report.Partial()).To(BeTrue())
+	})
+
+	It("reports a data set without metadata as invalid", func() {
+		files[filepath.Join("opsmanager", "d1")] = []byte("d1-content")
+
+		report, err := validator.Validate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Valid()).To(BeFalse())
+		Expect(report.DataSets[0].Err).To(MatchError(ContainSubstring(collector_tar.ReadMetadataFileError)))
+	})
+
+	It("reports files outside of a data set directory as invalid", func() {
+		files["loose-file"] = []byte("")
+
+		report, err := validator.Validate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Valid()).To(BeFalse())
+		Expect(report.DataSets).To(Equal([]DataSetReport{
+			{
+				Files: []FileReport{{Name: "loose-file", Status: FileStatusOutsideDataSet}},
+				Err:   report.DataSets[0].Err,
+			},
+		}))
+		Expect(report.DataSets[0].Err).To(MatchError(collector_tar.ExtraFilesInTarMessageError))
+	})
+
+	It("returns an error when the tar contents cannot be listed", func() {
+		tarReader.FileMd5sStub = nil
+		tarReader.FileMd5sReturns(nil, errors.New("listing is hard"))
+
+		_, err := validator.Validate()
+		Expect(err).To(MatchError(ContainSubstring(ListTarContentsFailureMessage)))
+		Expect(err).To(MatchError(ContainSubstring("listing is hard")))
+	})
+})
+
+// archiveFiles reads and writes files in a map, keyed by name.
+type archiveFiles map[string][]byte
+
+func (a archiveFiles) AddFile(contents []byte, fileName string) error {
+	a[fileName] = contents
+	return nil
+}
+
+func (a archiveFiles) ReadFile(fileName string) ([]byte, error) {
+	contents, exists := a[fileName]
+	if !exists {
+		return nil, io.EOF
+	}
+	return contents, nil
+}
+
+func (a archiveFiles) fileMd5s() map[string]string {
+	fileMd5s := map[string]string{}
+	for fileName, contents := range a {
+		fileMd5s[fileName] = manifest.MD5Checksum(contents)
+	}
+	return fileMd5s
+}
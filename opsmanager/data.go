@@ -9,12 +9,18 @@ type Data struct {
 	reader      io.Reader
 	productType string
 	dataType    string
+	err         error
 }
 
 func NewData(reader io.Reader, productType, dataType string) Data {
 	return Data{reader: reader, productType: productType, dataType: dataType}
 }
 
+// NewFailedData records a retrieval that failed during a partial collection.
+func NewFailedData(productType, dataType string, err error) Data {
+	return Data{productType: productType, dataType: dataType, err: err}
+}
+
 func (d Data) Name() string {
 	return fmt.Sprintf("%s_%s", d.productType, d.dataType)
 }
@@ -34,3 +40,7 @@ func (d Data) Type() string {
 func (d Data) DataType() string {
 	return d.dataType
 }
+
+func (d Data) Err() error {
+	return d.err
+}
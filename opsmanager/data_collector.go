@@ -1,9 +1,11 @@
 package opsmanager
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
+	"sync"
 
 	"github.com/pivotal-cf/om/api"
 	"github.com/pivotal-cf/telemetry-utils/collector_tar"
@@ -29,17 +31,23 @@ type DeployedProductsLister interface {
 
 //go:generate counterfeiter . OmService
 type OmService interface {
-	ProductResources(guid string) (io.Reader, error)
-	ProductProperties(guid string) (io.Reader, error)
-	VmTypes() (io.Reader, error)
-	DiagnosticReport() (io.Reader, error)
-	DeployedProducts() (io.Reader, error)
-	Installations() (io.Reader, error)
-	Certificates() (io.Reader, error)
-	CertificateAuthorities() (io.Reader, error)
+	ProductResources(ctx context.Context, guid string) (io.Reader, error)
+	ProductProperties(ctx context.Context, guid string) (io.Reader, error)
+	VmTypes(ctx context.Context) (io.Reader, error)
+	DiagnosticReport(ctx context.Context) (io.Reader, error)
+	DeployedProducts(ctx context.Context) (io.Reader, error)
+	Installations(ctx context.Context) (io.Reader, error)
+	Certificates(ctx context.Context) (io.Reader, error)
+	CertificateAuthorities(ctx context.Context) (io.Reader, error)
 }
 
-type dataRetriever func() (io.Reader, error)
+type dataRetriever func(context.Context) (io.Reader, error)
+
+type retrieval struct {
+	retriever   dataRetriever
+	productType string
+	dataType    string
+}
 
 type DataCollector struct {
 	logger                log.Logger
@@ -47,19 +55,29 @@ type DataCollector struct {
 	opsManagerURL         string
 	pendingChangesService PendingChangesLister
 	deployProductsService DeployedProductsLister
+	maxConcurrency        int
+	allowPartial          bool
 }
 
-func NewDataCollector(logger log.Logger, oms OmService, omURL string, pcs PendingChangesLister, dps DeployedProductsLister) *DataCollector {
+// NewDataCollector returns a collector that stops at the first failed
+// retrieval, or with allowPartial records failed retrievals as failed data
+// and carries on.
+func NewDataCollector(logger log.Logger, oms OmService, omURL string, pcs PendingChangesLister, dps DeployedProductsLister, maxConcurrency int, allowPartial bool) *DataCollector {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
 	return &DataCollector{
 		logger:                logger,
 		omService:             oms,
 		opsManagerURL:         omURL,
 		pendingChangesService: pcs,
 		deployProductsService: dps,
+		maxConcurrency:        maxConcurrency,
+		allowPartial:          allowPartial,
 	}
 }
 
-func (dc *DataCollector) Collect() ([]Data, string, error) {
+func (dc *DataCollector) Collect(ctx context.Context) ([]Data, string, error) {
 	dc.logger.Printf("Collecting data from Operations Manager at %s", dc.opsManagerURL)
 
 	var foundationId string
@@ -76,51 +94,34 @@ func (dc *DataCollector) Collect() ([]Data, string, error) {
 	if err != nil {
 		return []Data{}, "", errors.Wrap(err, DeployedProductsFailedMessage)
 	}
+	if err := ctx.Err(); err != nil {
+		return []Data{}, "", errors.Wrap(err, DeployedProductsFailedMessage)
+	}
 
-	var d []Data
-
-	d, err = appendRetrievedData(d, dc.omService.DeployedProducts, collector_tar.OpsManagerProductType, collector_tar.DeployedProductsDataType)
-	if err != nil {
-		return []Data{}, "", err
+	retrievals := []retrieval{
+		{dc.omService.DeployedProducts, collector_tar.OpsManagerProductType, collector_tar.DeployedProductsDataType},
 	}
 
 	for _, product := range pl {
 		if product.Type != collector_tar.DirectorProductType {
-			d, err = appendRetrievedData(d, dc.productResourcesCaller(product.GUID), product.Type, collector_tar.ResourcesDataType)
-			if err != nil {
-				return []Data{}, "", err
-			}
-
-			d, err = appendRetrievedData(d, dc.productPropertiesCaller(product.GUID), product.Type, collector_tar.PropertiesDataType)
-			if err != nil {
-				return []Data{}, "", err
-			}
+			retrievals = append(retrievals,
+				retrieval{dc.productResourcesCaller(product.GUID), product.Type, collector_tar.ResourcesDataType},
+				retrieval{dc.productPropertiesCaller(product.GUID), product.Type, collector_tar.PropertiesDataType},
+			)
 		} else {
 			foundationId = product.GUID
 		}
 	}
 
-	d, err = appendRetrievedData(d, dc.omService.VmTypes, collector_tar.OpsManagerProductType, collector_tar.VmTypesDataType)
-	if err != nil {
-		return []Data{}, "", err
-	}
-
-	d, err = appendRetrievedData(d, dc.omService.DiagnosticReport, collector_tar.OpsManagerProductType, collector_tar.DiagnosticReportDataType)
-	if err != nil {
-		return []Data{}, "", err
-	}
+	retrievals = append(retrievals,
+		retrieval{dc.omService.VmTypes, collector_tar.OpsManagerProductType, collector_tar.VmTypesDataType},
+		retrieval{dc.omService.DiagnosticReport, collector_tar.OpsManagerProductType, collector_tar.DiagnosticReportDataType},
+		retrieval{dc.omService.Installations, collector_tar.OpsManagerProductType, collector_tar.InstallationsDataType},
+		retrieval{dc.omService.Certificates, collector_tar.OpsManagerProductType, collector_tar.CertificatesDataType},
+		retrieval{dc.omService.CertificateAuthorities, collector_tar.OpsManagerProductType, collector_tar.CertificateAuthoritiesDataType},
+	)
 
-	d, err = appendRetrievedData(d, dc.omService.Installations, collector_tar.OpsManagerProductType, collector_tar.InstallationsDataType)
-	if err != nil {
-		return []Data{}, "", err
-	}
-
-	d, err = appendRetrievedData(d, dc.omService.Certificates, collector_tar.OpsManagerProductType, collector_tar.CertificatesDataType)
-	if err != nil {
-		return []Data{}, "", err
-	}
-
-	d, err = appendRetrievedData(d, dc.omService.CertificateAuthorities, collector_tar.OpsManagerProductType, collector_tar.CertificateAuthoritiesDataType)
+	d, err := retrieveAll(ctx, retrievals, dc.maxConcurrency, dc.allowPartial)
 	if err != nil {
 		return []Data{}, "", err
 	}
@@ -129,14 +130,14 @@ func (dc *DataCollector) Collect() ([]Data, string, error) {
 }
 
 func (dc DataCollector) productResourcesCaller(guid string) dataRetriever {
-	return func() (io.Reader, error) {
-		return dc.omService.ProductResources(guid)
+	return func(ctx context.Context) (io.Reader, error) {
+		return dc.omService.ProductResources(ctx, guid)
 	}
 }
 
 func (dc DataCollector) productPropertiesCaller(guid string) dataRetriever {
-	return func() (io.Reader, error) {
-		return dc.omService.ProductProperties(guid)
+	return func(ctx context.Context) (io.Reader, error) {
+		return dc.omService.ProductProperties(ctx, guid)
 	}
 }
 
@@ -149,11 +150,78 @@ func hasPendingChanges(changeList []api.ProductChange) bool {
 	return false
 }
 
-func appendRetrievedData(d []Data, retriever dataRetriever, productType, dataType string) ([]Data, error) {
-	output, err := retriever()
-	if err != nil {
-		return d, errors.Wrap(err, fmt.Sprintf(RequestorFailureErrorFormat, productType, dataType))
+// retrieveAll runs the retrievals with at most maxConcurrency in flight and
+// returns their data in the order given. Once a retrieval fails, no later
+// retrieval is started and the contexts of later ones already running are
+// cancelled, so they send no further requests; a request already sent is not
+// interrupted. Earlier retrievals are left to finish, so the error returned
+// is always the one from the earliest failing retrieval. With allowPartial,
+// failed retrievals are returned as failed data instead, unless ctx is done.
+func retrieveAll(ctx context.Context, retrievals []retrieval, maxConcurrency int, allowPartial bool) ([]Data, error) {
+	data := make([]Data, len(retrievals))
+	errs := make([]error, len(retrievals))
+	contexts := make([]context.Context, len(retrievals))
+	cancels := make([]context.CancelFunc, len(retrievals))
+	for index := range retrievals {
+		contexts[index], cancels[index] = context.WithCancel(ctx)
+		defer cancels[index]()
 	}
 
-	return append(d, NewData(output, productType, dataType)), nil
+	var mutex sync.Mutex
+	firstFailure := len(retrievals)
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < maxConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				mutex.Lock()
+				skip := index > firstFailure
+				mutex.Unlock()
+				if skip {
+					continue
+				}
+
+				r := retrievals[index]
+				output, err := r.retriever(contexts[index])
+				if err != nil {
+					errs[index] = errors.Wrap(err, fmt.Sprintf(RequestorFailureErrorFormat, r.productType, r.dataType))
+					if allowPartial {
+						data[index] = NewFailedData(r.productType, r.dataType, errs[index])
+						continue
+					}
+					mutex.Lock()
+					if index < firstFailure {
+						firstFailure = index
+						for _, cancel := range cancels[index+1:] {
+							cancel()
+						}
+					}
+					mutex.Unlock()
+					continue
+				}
+				data[index] = NewData(output, r.productType, r.dataType)
+			}
+		}()
+	}
+
+	for index := range retrievals {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	if firstFailure < len(retrievals) {
+		return nil, errs[firstFailure]
+	}
+	if ctx.Err() != nil {
+		for _, err := range errs {
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return data, nil
 }
@@ -1,10 +1,13 @@
 package opsmanager_test
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/onsi/gomega/gbytes"
 
@@ -39,7 +42,7 @@ var _ = Describe("DataCollector", func() {
 		pendingChangesLister = new(opsmanagerfakes.FakePendingChangesLister)
 		deployedProductsLister = new(opsmanagerfakes.FakeDeployedProductsLister)
 
-		dataCollector = NewDataCollector(*logger, omService, omURL, pendingChangesLister, deployedProductsLister)
+		dataCollector = NewDataCollector(*logger, omService, omURL, pendingChangesLister, deployedProductsLister, 4, false)
 	})
 
 	It("returns an error if there are pending changes with an action other than unchanged", func() {
@@ -48,7 +51,7 @@ var _ = Describe("DataCollector", func() {
 		}
 		pendingChangesLister.ListStagedPendingChangesReturns(nonEmptyPendingChanges, nil)
 
-		data, foundationId, err := dataCollector.Collect()
+		data, foundationId, err := dataCollector.Collect(context.Background())
 		Expect(data).To(BeEmpty())
 		Expect(foundationId).To(BeEmpty())
 		Expect(err).To(MatchError(PendingChangesExistsMessage))
@@ -57,7 +60,7 @@ var _ = Describe("DataCollector", func() {
 	It("returns an error if listing pending changes errors", func() {
 		pendingChangesLister.ListStagedPendingChangesReturns(api.PendingChangesOutput{}, errors.New("Listing things is hard"))
 
-		data, foundationId, err := dataCollector.Collect()
+		data, foundationId, err := dataCollector.Collect(context.Background())
 		Expect(data).To(BeEmpty())
 		Expect(foundationId).To(BeEmpty())
 		Expect(err).To(MatchError(ContainSubstring(PendingChangesFailedMessage)))
@@ -67,13 +70,24 @@ var _ = Describe("DataCollector", func() {
 	It("returns an error if listing deployed products errors", func() {
 		deployedProductsLister.ListDeployedProductsReturns([]api.DeployedProductOutput{}, errors.New("Listing things is hard"))
 
-		data, foundationId, err := dataCollector.Collect()
+		data, foundationId, err := dataCollector.Collect(context.Background())
 		Expect(data).To(BeEmpty())
 		Expect(foundationId).To(BeEmpty())
 		Expect(err).To(MatchError(ContainSubstring(DeployedProductsFailedMessage)))
 		Expect(err).To(MatchError(ContainSubstring("Listing things is hard")))
 	})
 
+	It("returns an error without retrieving data if the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		data, foundationId, err := dataCollector.Collect(ctx)
+		Expect(data).To(BeEmpty())
+		Expect(foundationId).To(BeEmpty())
+		Expect(err).To(MatchError(ContainSubstring(context.Canceled.Error())))
+		Expect(omService.DeployedProductsCallCount()).To(Equal(0))
+	})
+
 	It("returns an error when omService.ProductResources errors", func() {
 		deployedProductsLister.ListDeployedProductsReturns(
 			[]api.DeployedProductOutput{
@@ -83,7 +97,7 @@ var _ = Describe("DataCollector", func() {
 			nil,
 		)
 		omService.ProductResourcesReturns(nil, errors.New("Requesting things is hard"))
-		collectedData, foundationId, err := dataCollector.Collect()
+		collectedData, foundationId, err := dataCollector.Collect(context.Background())
 		assertOmServiceFailure(collectedData, foundationId, err, "best-product-1", collector_tar.ResourcesDataType, "Requesting things is hard")
 	})
 
@@ -96,43 +110,43 @@ var _ = Describe("DataCollector", func() {
 			nil,
 		)
 		omService.ProductPropertiesReturns(nil, errors.New("Requesting things is hard"))
-		collectedData, foundationId, err := dataCollector.Collect()
+		collectedData, foundationId, err := dataCollector.Collect(context.Background())
 		assertOmServiceFailure(collectedData, foundationId, err, "best-product-1", collector_tar.PropertiesDataType, "Requesting things is hard")
 	})
 
 	It("returns an error when omService.VmTypes errors", func() {
 		omService.VmTypesReturns(nil, errors.New("Requesting things is hard"))
-		collectedData, foundationId, err := dataCollector.Collect()
+		collectedData, foundationId, err := dataCollector.Collect(context.Background())
 		assertOmServiceFailure(collectedData, foundationId, err, collector_tar.OpsManagerProductType, collector_tar.VmTypesDataType, "Requesting things is hard")
 	})
 
 	It("returns an error when omService.DiagnosticReport errors", func() {
 		omService.DiagnosticReportReturns(nil, errors.New("Requesting things is hard"))
-		collectedData, foundationId, err := dataCollector.Collect()
+		collectedData, foundationId, err := dataCollector.Collect(context.Background())
 		assertOmServiceFailure(collectedData, foundationId, err, collector_tar.OpsManagerProductType, collector_tar.DiagnosticReportDataType, "Requesting things is hard")
 	})
 
 	It("returns an error when omService.DeployedProducts errors", func() {
 		omService.DeployedProductsReturns(nil, errors.New("Requesting things is hard"))
-		collectedData, foundationId, err := dataCollector.Collect()
+		collectedData, foundationId, err := dataCollector.Collect(context.Background())
 		assertOmServiceFailure(collectedData, foundationId, err, collector_tar.OpsManagerProductType, collector_tar.DeployedProductsDataType, "Requesting things is hard")
 	})
 
 	It("returns an error when omService.Installations errors", func() {
 		omService.InstallationsReturns(nil, errors.New("Requesting things is hard"))
-		collectedData, foundationId, err := dataCollector.Collect()
+		collectedData, foundationId, err := dataCollector.Collect(context.Background())
 		assertOmServiceFailure(collectedData, foundationId, err, collector_tar.OpsManagerProductType, collector_tar.InstallationsDataType, "Requesting things is hard")
 	})
 
 	It("returns an error when omService.Certificates errors", func() {
 		omService.CertificatesReturns(nil, errors.New("Requesting things is hard"))
-		collectedData, foundationId, err := dataCollector.Collect()
+		collectedData, foundationId, err := dataCollector.Collect(context.Background())
 		assertOmServiceFailure(collectedData, foundationId, err, collector_tar.OpsManagerProductType, collector_tar.CertificatesDataType, "Requesting things is hard")
 	})
 
 	It("returns an error when omService.CertificateAuthorities errors", func() {
 		omService.CertificateAuthoritiesReturns(nil, errors.New("Requesting things is hard"))
-		collectedData, foundationId, err := dataCollector.Collect()
+		collectedData, foundationId, err := dataCollector.Collect(context.Background())
 		assertOmServiceFailure(collectedData, foundationId, err, collector_tar.OpsManagerProductType, collector_tar.CertificateAuthoritiesDataType, "Requesting things is hard")
 	})
 
@@ -157,11 +171,11 @@ var _ = Describe("DataCollector", func() {
 			{Type: "best-product-2", GUID: "p2-guid"},
 		}
 		deployedProductsLister.ListDeployedProductsReturns(append([]api.DeployedProductOutput{directorProduct}, deployedProducts...), nil)
-		for i, r := range resourcesReaders {
-			omService.ProductResourcesReturnsOnCall(i, r, nil)
+		omService.ProductResourcesStub = func(_ context.Context, guid string) (io.Reader, error) {
+			return map[string]io.Reader{"p1-guid": resourcesReaders[0], "p2-guid": resourcesReaders[1]}[guid], nil
 		}
-		for i, r := range propertiesReaders {
-			omService.ProductPropertiesReturnsOnCall(i, r, nil)
+		omService.ProductPropertiesStub = func(_ context.Context, guid string) (io.Reader, error) {
+			return map[string]io.Reader{"p1-guid": propertiesReaders[0], "p2-guid": propertiesReaders[1]}[guid], nil
 		}
 		omService.VmTypesReturns(vmTypesReader, nil)
 		omService.DiagnosticReportReturns(diagnosticReportReader, nil)
@@ -170,7 +184,7 @@ var _ = Describe("DataCollector", func() {
 		omService.CertificatesReturns(certificatesReader, nil)
 		omService.CertificateAuthoritiesReturns(certificateAuthoritiesReader, nil)
 
-		collectedData, foundationId, err := dataCollector.Collect()
+		collectedData, foundationId, err := dataCollector.Collect(context.Background())
 		Expect(err).ToNot(HaveOccurred())
 		Expect(bufferedOutput).To(gbytes.Say("Collecting data from Operations Manager at some-opsmanager-url"))
 		Expect(foundationId).To(Equal("p-bosh-always-first"))
@@ -229,7 +243,7 @@ var _ = Describe("DataCollector", func() {
 	})
 
 	It("succeeds if there are no deployed products", func() {
-		collectedData, foundationId, err := dataCollector.Collect()
+		collectedData, foundationId, err := dataCollector.Collect(context.Background())
 		Expect(err).ToNot(HaveOccurred())
 		Expect(foundationId).To(Equal(""))
 		Expect(collectedData).To(ConsistOf(
@@ -241,6 +255,153 @@ var _ = Describe("DataCollector", func() {
 			NewData(nil, collector_tar.OpsManagerProductType, collector_tar.CertificateAuthoritiesDataType),
 		))
 	})
+
+	Context("with concurrent retrieval", func() {
+		var (
+			mutex       sync.Mutex
+			inFlight    int
+			maxInFlight int
+		)
+
+		slowReader := func(content string) func(context.Context, string) (io.Reader, error) {
+			return func(context.Context, string) (io.Reader, error) {
+				mutex.Lock()
+				inFlight++
+				if inFlight > maxInFlight {
+					maxInFlight = inFlight
+				}
+				mutex.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				mutex.Lock()
+				inFlight--
+				mutex.Unlock()
+				return strings.NewReader(content), nil
+			}
+		}
+
+		BeforeEach(func() {
+			inFlight = 0
+			maxInFlight = 0
+			var products []api.DeployedProductOutput
+			for i := 0; i < 6; i++ {
+				products = append(products, api.DeployedProductOutput{Type: fmt.Sprintf("product-%d", i), GUID: fmt.Sprintf("guid-%d", i)})
+			}
+			deployedProductsLister.ListDeployedProductsReturns(products, nil)
+			omService.ProductResourcesStub = slowReader("resources")
+			omService.ProductPropertiesStub = slowReader("properties")
+		})
+
+		It("retrieves at most the configured number of resources at once and keeps the output order", func() {
+			dataCollector = NewDataCollector(*logger, omService, omURL, pendingChangesLister, deployedProductsLister, 3, false)
+			collectedData, _, err := dataCollector.Collect(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(maxInFlight).To(BeNumerically(">", 1))
+			Expect(maxInFlight).To(BeNumerically("<=", 3))
+
+			var names []string
+			for _, data := range collectedData {
+				names = append(names, data.Name())
+			}
+			expectedNames := []string{collector_tar.OpsManagerProductType + "_" + collector_tar.DeployedProductsDataType}
+			for i := 0; i < 6; i++ {
+				expectedNames = append(expectedNames,
+					fmt.Sprintf("product-%d_%s", i, collector_tar.ResourcesDataType),
+					fmt.Sprintf("product-%d_%s", i, collector_tar.PropertiesDataType),
+				)
+			}
+			expectedNames = append(expectedNames,
+				collector_tar.OpsManagerProductType+"_"+collector_tar.VmTypesDataType,
+				collector_tar.OpsManagerProductType+"_"+collector_tar.DiagnosticReportDataType,
+				collector_tar.OpsManagerProductType+"_"+collector_tar.InstallationsDataType,
+				collector_tar.OpsManagerProductType+"_"+collector_tar.CertificatesDataType,
+				collector_tar.OpsManagerProductType+"_"+collector_tar.CertificateAuthoritiesDataType,
+			)
+			Expect(names).To(Equal(expectedNames))
+		})
+
+		It("reports the earliest failure and does not start later retrievals", func() {
+			omService.ProductPropertiesStub = func(_ context.Context, guid string) (io.Reader, error) {
+				if guid == "guid-1" || guid == "guid-4" {
+					return nil, fmt.Errorf("failed for %s", guid)
+				}
+				return strings.NewReader("properties"), nil
+			}
+			dataCollector = NewDataCollector(*logger, omService, omURL, pendingChangesLister, deployedProductsLister, 1, false)
+
+			collectedData, foundationId, err := dataCollector.Collect(context.Background())
+			assertOmServiceFailure(collectedData, foundationId, err, "product-1", collector_tar.PropertiesDataType, "failed for guid-1")
+			Expect(omService.ProductResourcesCallCount()).To(Equal(2))
+			Expect(omService.VmTypesCallCount()).To(Equal(0))
+		})
+
+		It("cancels the contexts of later retrievals still running after the earliest failure", func() {
+			omService.ProductPropertiesStub = func(ctx context.Context, guid string) (io.Reader, error) {
+				if guid == "guid-0" {
+					return nil, errors.New("failed for guid-0")
+				}
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			omService.ProductResourcesStub = func(ctx context.Context, guid string) (io.Reader, error) {
+				if guid == "guid-0" {
+					return strings.NewReader("resources"), nil
+				}
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			dataCollector = NewDataCollector(*logger, omService, omURL, pendingChangesLister, deployedProductsLister, 4, false)
+
+			collectedData, foundationId, err := dataCollector.Collect(context.Background())
+			assertOmServiceFailure(collectedData, foundationId, err, "product-0", collector_tar.PropertiesDataType, "failed for guid-0")
+		})
+
+		Context("when partial collection is allowed", func() {
+			BeforeEach(func() {
+				omService.ProductPropertiesStub = func(_ context.Context, guid string) (io.Reader, error) {
+					if guid == "guid-1" || guid == "guid-4" {
+						return nil, fmt.Errorf("failed for %s", guid)
+					}
+					return strings.NewReader("properties"), nil
+				}
+				dataCollector = NewDataCollector(*logger, omService, omURL, pendingChangesLister, deployedProductsLister, 2, true)
+			})
+
+			It("retrieves everything and returns failed data for the failing retrievals", func() {
+				collectedData, _, err := dataCollector.Collect(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(collectedData).To(HaveLen(18))
+				Expect(omService.ProductResourcesCallCount()).To(Equal(6))
+				Expect(omService.VmTypesCallCount()).To(Equal(1))
+
+				var failed []Data
+				for _, data := range collectedData {
+					if data.Err() != nil {
+						failed = append(failed, data)
+					}
+				}
+				Expect(failed).To(HaveLen(2))
+				Expect(failed[0].Name()).To(Equal("product-1_" + collector_tar.PropertiesDataType))
+				Expect(failed[0].Err()).To(MatchError(ContainSubstring(fmt.Sprintf(RequestorFailureErrorFormat, "product-1", collector_tar.PropertiesDataType))))
+				Expect(failed[0].Err()).To(MatchError(ContainSubstring("failed for guid-1")))
+				Expect(failed[1].Name()).To(Equal("product-4_" + collector_tar.PropertiesDataType))
+			})
+
+			It("returns an error when the context is done", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				omService.VmTypesStub = func(context.Context) (io.Reader, error) {
+					cancel()
+					return nil, context.Canceled
+				}
+
+				collectedData, foundationId, err := dataCollector.Collect(ctx)
+				Expect(collectedData).To(BeEmpty())
+				Expect(foundationId).To(BeEmpty())
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
 })
 
 func assertOmServiceFailure(d []Data, foundationId string, err error, productType, dataType, causeErrorMessage string) {
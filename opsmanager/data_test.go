@@ -1,6 +1,8 @@
 package opsmanager_test
 
 import (
+	"errors"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -39,4 +41,12 @@ var _ = Describe("data", func() {
 		d := NewData(nil, "no-matter", "bar")
 		Expect(d.DataType()).To(Equal("bar"))
 	})
+
+	It("has no error unless the retrieval failed", func() {
+		Expect(NewData(nil, "foo", "bar").Err()).NotTo(HaveOccurred())
+
+		d := NewFailedData("foo", "bar", errors.New("collecting is hard"))
+		Expect(d.Err()).To(MatchError("collecting is hard"))
+		Expect(d.Name()).To(Equal("foo_bar"))
+	})
 })
@@ -2,6 +2,7 @@
 package opsmanagerfakes
 
 import (
+	"context"
 	"io"
 	"sync"
 
@@ -9,10 +10,11 @@ import (
 )
 
 type FakeOmService struct {
-	ProductResourcesStub        func(guid string) (io.Reader, error)
+	ProductResourcesStub        func(context.Context, string) (io.Reader, error)
 	productResourcesMutex       sync.RWMutex
 	productResourcesArgsForCall []struct {
-		guid string
+		arg1 context.Context
+		arg2 string
 	}
 	productResourcesReturns struct {
 		result1 io.Reader
@@ -22,10 +24,11 @@ type FakeOmService struct {
 		result1 io.Reader
 		result2 error
 	}
-	ProductPropertiesStub        func(guid string) (io.Reader, error)
+	ProductPropertiesStub        func(context.Context, string) (io.Reader, error)
 	productPropertiesMutex       sync.RWMutex
 	productPropertiesArgsForCall []struct {
-		guid string
+		arg1 context.Context
+		arg2 string
 	}
 	productPropertiesReturns struct {
 		result1 io.Reader
@@ -35,10 +38,12 @@ type FakeOmService struct {
 		result1 io.Reader
 		result2 error
 	}
-	VmTypesStub        func() (io.Reader, error)
+	VmTypesStub        func(context.Context) (io.Reader, error)
 	vmTypesMutex       sync.RWMutex
-	vmTypesArgsForCall []struct{}
-	vmTypesReturns     struct {
+	vmTypesArgsForCall []struct {
+		arg1 context.Context
+	}
+	vmTypesReturns struct {
 		result1 io.Reader
 		result2 error
 	}
@@ -46,10 +51,12 @@ type FakeOmService struct {
 		result1 io.Reader
 		result2 error
 	}
-	DiagnosticReportStub        func() (io.Reader, error)
+	DiagnosticReportStub        func(context.Context) (io.Reader, error)
 	diagnosticReportMutex       sync.RWMutex
-	diagnosticReportArgsForCall []struct{}
-	diagnosticReportReturns     struct {
+	diagnosticReportArgsForCall []struct {
+		arg1 context.Context
+	}
+	diagnosticReportReturns struct {
 		result1 io.Reader
 		result2 error
 	}
@@ -57,10 +64,12 @@ type FakeOmService struct {
 		result1 io.Reader
 		result2 error
 	}
-	DeployedProductsStub        func() (io.Reader, error)
+	DeployedProductsStub        func(context.Context) (io.Reader, error)
 	deployedProductsMutex       sync.RWMutex
-	deployedProductsArgsForCall []struct{}
-	deployedProductsReturns     struct {
+	deployedProductsArgsForCall []struct {
+		arg1 context.Context
+	}
+	deployedProductsReturns struct {
 		result1 io.Reader
 		result2 error
 	}
@@ -68,10 +77,12 @@ type FakeOmService struct {
 		result1 io.Reader
 		result2 error
 	}
-	InstallationsStub        func() (io.Reader, error)
+	InstallationsStub        func(context.Context) (io.Reader, error)
 	installationsMutex       sync.RWMutex
-	installationsArgsForCall []struct{}
-	installationsReturns     struct {
+	installationsArgsForCall []struct {
+		arg1 context.Context
+	}
+	installationsReturns struct {
 		result1 io.Reader
 		result2 error
 	}
@@ -79,10 +90,12 @@ type FakeOmService struct {
 		result1 io.Reader
 		result2 error
 	}
-	CertificatesStub        func() (io.Reader, error)
+	CertificatesStub        func(context.Context) (io.Reader, error)
 	certificatesMutex       sync.RWMutex
-	certificatesArgsForCall []struct{}
-	certificatesReturns     struct {
+	certificatesArgsForCall []struct {
+		arg1 context.Context
+	}
+	certificatesReturns struct {
 		result1 io.Reader
 		result2 error
 	}
@@ -90,10 +103,12 @@ type FakeOmService struct {
 		result1 io.Reader
 		result2 error
 	}
-	CertificateAuthoritiesStub        func() (io.Reader, error)
+	CertificateAuthoritiesStub        func(context.Context) (io.Reader, error)
 	certificateAuthoritiesMutex       sync.RWMutex
-	certificateAuthoritiesArgsForCall []struct{}
-	certificateAuthoritiesReturns     struct {
+	certificateAuthoritiesArgsForCall []struct {
+		arg1 context.Context
+	}
+	certificateAuthoritiesReturns struct {
 		result1 io.Reader
 		result2 error
 	}
@@ -105,21 +120,23 @@ type FakeOmService struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeOmService) ProductResources(guid string) (io.Reader, error) {
+func (fake *FakeOmService) ProductResources(arg1 context.Context, arg2 string) (io.Reader, error) {
 	fake.productResourcesMutex.Lock()
 	ret, specificReturn := fake.productResourcesReturnsOnCall[len(fake.productResourcesArgsForCall)]
 	fake.productResourcesArgsForCall = append(fake.productResourcesArgsForCall, struct {
-		guid string
-	}{guid})
-	fake.recordInvocation("ProductResources", []interface{}{guid})
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("ProductResources", []interface{}{arg1, arg2})
 	fake.productResourcesMutex.Unlock()
 	if fake.ProductResourcesStub != nil {
-		return fake.ProductResourcesStub(guid)
+		return fake.ProductResourcesStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	return fake.productResourcesReturns.result1, fake.productResourcesReturns.result2
+	fakeReturns := fake.productResourcesReturns
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeOmService) ProductResourcesCallCount() int {
@@ -128,13 +145,22 @@ func (fake *FakeOmService) ProductResourcesCallCount() int {
 	return len(fake.productResourcesArgsForCall)
 }
 
-func (fake *FakeOmService) ProductResourcesArgsForCall(i int) string {
+func (fake *FakeOmService) ProductResourcesCalls(stub func(context.Context, string) (io.Reader, error)) {
+	fake.productResourcesMutex.Lock()
+	defer fake.productResourcesMutex.Unlock()
+	fake.ProductResourcesStub = stub
+}
+
+func (fake *FakeOmService) ProductResourcesArgsForCall(i int) (context.Context, string) {
 	fake.productResourcesMutex.RLock()
 	defer fake.productResourcesMutex.RUnlock()
-	return fake.productResourcesArgsForCall[i].guid
+	argsForCall := fake.productResourcesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeOmService) ProductResourcesReturns(result1 io.Reader, result2 error) {
+	fake.productResourcesMutex.Lock()
+	defer fake.productResourcesMutex.Unlock()
 	fake.ProductResourcesStub = nil
 	fake.productResourcesReturns = struct {
 		result1 io.Reader
@@ -143,6 +169,8 @@ func (fake *FakeOmService) ProductResourcesReturns(result1 io.Reader, result2 er
 }
 
 func (fake *FakeOmService) ProductResourcesReturnsOnCall(i int, result1 io.Reader, result2 error) {
+	fake.productResourcesMutex.Lock()
+	defer fake.productResourcesMutex.Unlock()
 	fake.ProductResourcesStub = nil
 	if fake.productResourcesReturnsOnCall == nil {
 		fake.productResourcesReturnsOnCall = make(map[int]struct {
@@ -156,21 +184,23 @@ func (fake *FakeOmService) ProductResourcesReturnsOnCall(i int, result1 io.Reade
 	}{result1, result2}
 }
 
-func (fake *FakeOmService) ProductProperties(guid string) (io.Reader, error) {
+func (fake *FakeOmService) ProductProperties(arg1 context.Context, arg2 string) (io.Reader, error) {
 	fake.productPropertiesMutex.Lock()
 	ret, specificReturn := fake.productPropertiesReturnsOnCall[len(fake.productPropertiesArgsForCall)]
 	fake.productPropertiesArgsForCall = append(fake.productPropertiesArgsForCall, struct {
-		guid string
-	}{guid})
-	fake.recordInvocation("ProductProperties", []interface{}{guid})
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("ProductProperties", []interface{}{arg1, arg2})
 	fake.productPropertiesMutex.Unlock()
 	if fake.ProductPropertiesStub != nil {
-		return fake.ProductPropertiesStub(guid)
+		return fake.ProductPropertiesStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	return fake.productPropertiesReturns.result1, fake.productPropertiesReturns.result2
+	fakeReturns := fake.productPropertiesReturns
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeOmService) ProductPropertiesCallCount() int {
@@ -179,13 +209,22 @@ func (fake *FakeOmService) ProductPropertiesCallCount() int {
 	return len(fake.productPropertiesArgsForCall)
 }
 
-func (fake *FakeOmService) ProductPropertiesArgsForCall(i int) string {
+func (fake *FakeOmService) ProductPropertiesCalls(stub func(context.Context, string) (io.Reader, error)) {
+	fake.productPropertiesMutex.Lock()
+	defer fake.productPropertiesMutex.Unlock()
+	fake.ProductPropertiesStub = stub
+}
+
+func (fake *FakeOmService) ProductPropertiesArgsForCall(i int) (context.Context, string) {
 	fake.productPropertiesMutex.RLock()
 	defer fake.productPropertiesMutex.RUnlock()
-	return fake.productPropertiesArgsForCall[i].guid
+	argsForCall := fake.productPropertiesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeOmService) ProductPropertiesReturns(result1 io.Reader, result2 error) {
+	fake.productPropertiesMutex.Lock()
+	defer fake.productPropertiesMutex.Unlock()
 	fake.ProductPropertiesStub = nil
 	fake.productPropertiesReturns = struct {
 		result1 io.Reader
@@ -194,6 +233,8 @@ func (fake *FakeOmService) ProductPropertiesReturns(result1 io.Reader, result2 e
 }
 
 func (fake *FakeOmService) ProductPropertiesReturnsOnCall(i int, result1 io.Reader, result2 error) {
+	fake.productPropertiesMutex.Lock()
+	defer fake.productPropertiesMutex.Unlock()
 	fake.ProductPropertiesStub = nil
 	if fake.productPropertiesReturnsOnCall == nil {
 		fake.productPropertiesReturnsOnCall = make(map[int]struct {
@@ -207,19 +248,22 @@ func (fake *FakeOmService) ProductPropertiesReturnsOnCall(i int, result1 io.Read
 	}{result1, result2}
 }
 
-func (fake *FakeOmService) VmTypes() (io.Reader, error) {
+func (fake *FakeOmService) VmTypes(arg1 context.Context) (io.Reader, error) {
 	fake.vmTypesMutex.Lock()
 	ret, specificReturn := fake.vmTypesReturnsOnCall[len(fake.vmTypesArgsForCall)]
-	fake.vmTypesArgsForCall = append(fake.vmTypesArgsForCall, struct{}{})
-	fake.recordInvocation("VmTypes", []interface{}{})
+	fake.vmTypesArgsForCall = append(fake.vmTypesArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	fake.recordInvocation("VmTypes", []interface{}{arg1})
 	fake.vmTypesMutex.Unlock()
 	if fake.VmTypesStub != nil {
-		return fake.VmTypesStub()
+		return fake.VmTypesStub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	return fake.vmTypesReturns.result1, fake.vmTypesReturns.result2
+	fakeReturns := fake.vmTypesReturns
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeOmService) VmTypesCallCount() int {
@@ -228,7 +272,22 @@ func (fake *FakeOmService) VmTypesCallCount() int {
 	return len(fake.vmTypesArgsForCall)
 }
 
+func (fake *FakeOmService) VmTypesCalls(stub func(context.Context) (io.Reader, error)) {
+	fake.vmTypesMutex.Lock()
+	defer fake.vmTypesMutex.Unlock()
+	fake.VmTypesStub = stub
+}
+
+func (fake *FakeOmService) VmTypesArgsForCall(i int) context.Context {
+	fake.vmTypesMutex.RLock()
+	defer fake.vmTypesMutex.RUnlock()
+	argsForCall := fake.vmTypesArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeOmService) VmTypesReturns(result1 io.Reader, result2 error) {
+	fake.vmTypesMutex.Lock()
+	defer fake.vmTypesMutex.Unlock()
 	fake.VmTypesStub = nil
 	fake.vmTypesReturns = struct {
 		result1 io.Reader
@@ -237,6 +296,8 @@ func (fake *FakeOmService) VmTypesReturns(result1 io.Reader, result2 error) {
 }
 
 func (fake *FakeOmService) VmTypesReturnsOnCall(i int, result1 io.Reader, result2 error) {
+	fake.vmTypesMutex.Lock()
+	defer fake.vmTypesMutex.Unlock()
 	fake.VmTypesStub = nil
 	if fake.vmTypesReturnsOnCall == nil {
 		fake.vmTypesReturnsOnCall = make(map[int]struct {
@@ -250,19 +311,22 @@ func (fake *FakeOmService) VmTypesReturnsOnCall(i int, result1 io.Reader, result
 	}{result1, result2}
 }
 
-func (fake *FakeOmService) DiagnosticReport() (io.Reader, error) {
+func (fake *FakeOmService) DiagnosticReport(arg1 context.Context) (io.Reader, error) {
 	fake.diagnosticReportMutex.Lock()
 	ret, specificReturn := fake.diagnosticReportReturnsOnCall[len(fake.diagnosticReportArgsForCall)]
-	fake.diagnosticReportArgsForCall = append(fake.diagnosticReportArgsForCall, struct{}{})
-	fake.recordInvocation("DiagnosticReport", []interface{}{})
+	fake.diagnosticReportArgsForCall = append(fake.diagnosticReportArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	fake.recordInvocation("DiagnosticReport", []interface{}{arg1})
 	fake.diagnosticReportMutex.Unlock()
 	if fake.DiagnosticReportStub != nil {
-		return fake.DiagnosticReportStub()
+		return fake.DiagnosticReportStub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	return fake.diagnosticReportReturns.result1, fake.diagnosticReportReturns.result2
+	fakeReturns := fake.diagnosticReportReturns
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeOmService) DiagnosticReportCallCount() int {
@@ -271,7 +335,22 @@ func (fake *FakeOmService) DiagnosticReportCallCount() int {
 	return len(fake.diagnosticReportArgsForCall)
 }
 
+func (fake *FakeOmService) DiagnosticReportCalls(stub func(context.Context) (io.Reader, error)) {
+	fake.diagnosticReportMutex.Lock()
+	defer fake.diagnosticReportMutex.Unlock()
+	fake.DiagnosticReportStub = stub
+}
+
+func (fake *FakeOmService) DiagnosticReportArgsForCall(i int) context.Context {
+	fake.diagnosticReportMutex.RLock()
+	defer fake.diagnosticReportMutex.RUnlock()
+	argsForCall := fake.diagnosticReportArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeOmService) DiagnosticReportReturns(result1 io.Reader, result2 error) {
+	fake.diagnosticReportMutex.Lock()
+	defer fake.diagnosticReportMutex.Unlock()
 	fake.DiagnosticReportStub = nil
 	fake.diagnosticReportReturns = struct {
 		result1 io.Reader
@@ -280,6 +359,8 @@ func (fake *FakeOmService) DiagnosticReportReturns(result1 io.Reader, result2 er
 }
 
 func (fake *FakeOmService) DiagnosticReportReturnsOnCall(i int, result1 io.Reader, result2 error) {
+	fake.diagnosticReportMutex.Lock()
+	defer fake.diagnosticReportMutex.Unlock()
 	fake.DiagnosticReportStub = nil
 	if fake.diagnosticReportReturnsOnCall == nil {
 		fake.diagnosticReportReturnsOnCall = make(map[int]struct {
@@ -293,19 +374,22 @@ func (fake *FakeOmService) DiagnosticReportReturnsOnCall(i int, result1 io.Reade
 	}{result1, result2}
 }
 
-func (fake *FakeOmService) DeployedProducts() (io.Reader, error) {
+func (fake *FakeOmService) DeployedProducts(arg1 context.Context) (io.Reader, error) {
 	fake.deployedProductsMutex.Lock()
 	ret, specificReturn := fake.deployedProductsReturnsOnCall[len(fake.deployedProductsArgsForCall)]
-	fake.deployedProductsArgsForCall = append(fake.deployedProductsArgsForCall, struct{}{})
-	fake.recordInvocation("DeployedProducts", []interface{}{})
+	fake.deployedProductsArgsForCall = append(fake.deployedProductsArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	fake.recordInvocation("DeployedProducts", []interface{}{arg1})
 	fake.deployedProductsMutex.Unlock()
 	if fake.DeployedProductsStub != nil {
-		return fake.DeployedProductsStub()
+		return fake.DeployedProductsStub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	return fake.deployedProductsReturns.result1, fake.deployedProductsReturns.result2
+	fakeReturns := fake.deployedProductsReturns
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeOmService) DeployedProductsCallCount() int {
@@ -314,7 +398,22 @@ func (fake *FakeOmService) DeployedProductsCallCount() int {
 	return len(fake.deployedProductsArgsForCall)
 }
 
+func (fake *FakeOmService) DeployedProductsCalls(stub func(context.Context) (io.Reader, error)) {
+	fake.deployedProductsMutex.Lock()
+	defer fake.deployedProductsMutex.Unlock()
+	fake.DeployedProductsStub = stub
+}
+
+func (fake *FakeOmService) DeployedProductsArgsForCall(i int) context.Context {
+	fake.deployedProductsMutex.RLock()
+	defer fake.deployedProductsMutex.RUnlock()
+	argsForCall := fake.deployedProductsArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeOmService) DeployedProductsReturns(result1 io.Reader, result2 error) {
+	fake.deployedProductsMutex.Lock()
+	defer fake.deployedProductsMutex.Unlock()
 	fake.DeployedProductsStub = nil
 	fake.deployedProductsReturns = struct {
 		result1 io.Reader
@@ -323,6 +422,8 @@ func (fake *FakeOmService) DeployedProductsReturns(result1 io.Reader, result2 er
 }
 
 func (fake *FakeOmService) DeployedProductsReturnsOnCall(i int, result1 io.Reader, result2 error) {
+	fake.deployedProductsMutex.Lock()
+	defer fake.deployedProductsMutex.Unlock()
 	fake.DeployedProductsStub = nil
 	if fake.deployedProductsReturnsOnCall == nil {
 		fake.deployedProductsReturnsOnCall = make(map[int]struct {
@@ -336,19 +437,22 @@ func (fake *FakeOmService) DeployedProductsReturnsOnCall(i int, result1 io.Reade
 	}{result1, result2}
 }
 
-func (fake *FakeOmService) Installations() (io.Reader, error) {
+func (fake *FakeOmService) Installations(arg1 context.Context) (io.Reader, error) {
 	fake.installationsMutex.Lock()
 	ret, specificReturn := fake.installationsReturnsOnCall[len(fake.installationsArgsForCall)]
-	fake.installationsArgsForCall = append(fake.installationsArgsForCall, struct{}{})
-	fake.recordInvocation("Installations", []interface{}{})
+	fake.installationsArgsForCall = append(fake.installationsArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	fake.recordInvocation("Installations", []interface{}{arg1})
 	fake.installationsMutex.Unlock()
 	if fake.InstallationsStub != nil {
-		return fake.InstallationsStub()
+		return fake.InstallationsStub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	return fake.installationsReturns.result1, fake.installationsReturns.result2
+	fakeReturns := fake.installationsReturns
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeOmService) InstallationsCallCount() int {
@@ -357,7 +461,22 @@ func (fake *FakeOmService) InstallationsCallCount() int {
 	return len(fake.installationsArgsForCall)
 }
 
+func (fake *FakeOmService) InstallationsCalls(stub func(context.Context) (io.Reader, error)) {
+	fake.installationsMutex.Lock()
+	defer fake.installationsMutex.Unlock()
+	fake.InstallationsStub = stub
+}
+
+func (fake *FakeOmService) InstallationsArgsForCall(i int) context.Context {
+	fake.installationsMutex.RLock()
+	defer fake.installationsMutex.RUnlock()
+	argsForCall := fake.installationsArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeOmService) InstallationsReturns(result1 io.Reader, result2 error) {
+	fake.installationsMutex.Lock()
+	defer fake.installationsMutex.Unlock()
 	fake.InstallationsStub = nil
 	fake.installationsReturns = struct {
 		result1 io.Reader
@@ -366,6 +485,8 @@ func (fake *FakeOmService) InstallationsReturns(result1 io.Reader, result2 error
 }
 
 func (fake *FakeOmService) InstallationsReturnsOnCall(i int, result1 io.Reader, result2 error) {
+	fake.installationsMutex.Lock()
+	defer fake.installationsMutex.Unlock()
 	fake.InstallationsStub = nil
 	if fake.installationsReturnsOnCall == nil {
 		fake.installationsReturnsOnCall = make(map[int]struct {
@@ -379,19 +500,22 @@ func (fake *FakeOmService) InstallationsReturnsOnCall(i int, result1 io.Reader,
 	}{result1, result2}
 }
 
-func (fake *FakeOmService) Certificates() (io.Reader, error) {
+func (fake *FakeOmService) Certificates(arg1 context.Context) (io.Reader, error) {
 	fake.certificatesMutex.Lock()
 	ret, specificReturn := fake.certificatesReturnsOnCall[len(fake.certificatesArgsForCall)]
-	fake.certificatesArgsForCall = append(fake.certificatesArgsForCall, struct{}{})
-	fake.recordInvocation("Certificates", []interface{}{})
+	fake.certificatesArgsForCall = append(fake.certificatesArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	fake.recordInvocation("Certificates", []interface{}{arg1})
 	fake.certificatesMutex.Unlock()
 	if fake.CertificatesStub != nil {
-		return fake.CertificatesStub()
+		return fake.CertificatesStub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	return fake.certificatesReturns.result1, fake.certificatesReturns.result2
+	fakeReturns := fake.certificatesReturns
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeOmService) CertificatesCallCount() int {
@@ -400,7 +524,22 @@ func (fake *FakeOmService) CertificatesCallCount() int {
 	return len(fake.certificatesArgsForCall)
 }
 
+func (fake *FakeOmService) CertificatesCalls(stub func(context.Context) (io.Reader, error)) {
+	fake.certificatesMutex.Lock()
+	defer fake.certificatesMutex.Unlock()
+	fake.CertificatesStub = stub
+}
+
+func (fake *FakeOmService) CertificatesArgsForCall(i int) context.Context {
+	fake.certificatesMutex.RLock()
+	defer fake.certificatesMutex.RUnlock()
+	argsForCall := fake.certificatesArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeOmService) CertificatesReturns(result1 io.Reader, result2 error) {
+	fake.certificatesMutex.Lock()
+	defer fake.certificatesMutex.Unlock()
 	fake.CertificatesStub = nil
 	fake.certificatesReturns = struct {
 		result1 io.Reader
@@ -409,6 +548,8 @@ func (fake *FakeOmService) CertificatesReturns(result1 io.Reader, result2 error)
 }
 
 func (fake *FakeOmService) CertificatesReturnsOnCall(i int, result1 io.Reader, result2 error) {
+	fake.certificatesMutex.Lock()
+	defer fake.certificatesMutex.Unlock()
 	fake.CertificatesStub = nil
 	if fake.certificatesReturnsOnCall == nil {
 		fake.certificatesReturnsOnCall = make(map[int]struct {
@@ -422,19 +563,22 @@ func (fake *FakeOmService) CertificatesReturnsOnCall(i int, result1 io.Reader, r
 	}{result1, result2}
 }
 
-func (fake *FakeOmService) CertificateAuthorities() (io.Reader, error) {
+func (fake *FakeOmService) CertificateAuthorities(arg1 context.Context) (io.Reader, error) {
 	fake.certificateAuthoritiesMutex.Lock()
 	ret, specificReturn := fake.certificateAuthoritiesReturnsOnCall[len(fake.certificateAuthoritiesArgsForCall)]
-	fake.certificateAuthoritiesArgsForCall = append(fake.certificateAuthoritiesArgsForCall, struct{}{})
-	fake.recordInvocation("CertificateAuthorities", []interface{}{})
+	fake.certificateAuthoritiesArgsForCall = append(fake.certificateAuthoritiesArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	fake.recordInvocation("CertificateAuthorities", []interface{}{arg1})
 	fake.certificateAuthoritiesMutex.Unlock()
 	if fake.CertificateAuthoritiesStub != nil {
-		return fake.CertificateAuthoritiesStub()
+		return fake.CertificateAuthoritiesStub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	return fake.certificateAuthoritiesReturns.result1, fake.certificateAuthoritiesReturns.result2
+	fakeReturns := fake.certificateAuthoritiesReturns
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeOmService) CertificateAuthoritiesCallCount() int {
@@ -443,7 +587,22 @@ func (fake *FakeOmService) CertificateAuthoritiesCallCount() int {
 	return len(fake.certificateAuthoritiesArgsForCall)
 }
 
+func (fake *FakeOmService) CertificateAuthoritiesCalls(stub func(context.Context) (io.Reader, error)) {
+	fake.certificateAuthoritiesMutex.Lock()
+	defer fake.certificateAuthoritiesMutex.Unlock()
+	fake.CertificateAuthoritiesStub = stub
+}
+
+func (fake *FakeOmService) CertificateAuthoritiesArgsForCall(i int) context.Context {
+	fake.certificateAuthoritiesMutex.RLock()
+	defer fake.certificateAuthoritiesMutex.RUnlock()
+	argsForCall := fake.certificateAuthoritiesArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeOmService) CertificateAuthoritiesReturns(result1 io.Reader, result2 error) {
+	fake.certificateAuthoritiesMutex.Lock()
+	defer fake.certificateAuthoritiesMutex.Unlock()
 	fake.CertificateAuthoritiesStub = nil
 	fake.certificateAuthoritiesReturns = struct {
 		result1 io.Reader
@@ -452,6 +611,8 @@ func (fake *FakeOmService) CertificateAuthoritiesReturns(result1 io.Reader, resu
 }
 
 func (fake *FakeOmService) CertificateAuthoritiesReturnsOnCall(i int, result1 io.Reader, result2 error) {
+	fake.certificateAuthoritiesMutex.Lock()
+	defer fake.certificateAuthoritiesMutex.Unlock()
 	fake.CertificateAuthoritiesStub = nil
 	if fake.certificateAuthoritiesReturnsOnCall == nil {
 		fake.certificateAuthoritiesReturnsOnCall = make(map[int]struct {
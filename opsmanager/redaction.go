@@ -0,0 +1,86 @@
+package opsmanager
+
+import (
+	"github.com/pivotal-cf/aqueduct-courier/redaction"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+	"github.com/pkg/errors"
+)
+
+const UnknownRedactionDataTypeFormat = "Unknown data type %s in redaction policy %s"
+
+var redactableDataTypes = []string{
+	redaction.AllDataTypes,
+	collector_tar.ResourcesDataType,
+	collector_tar.VmTypesDataType,
+	collector_tar.DiagnosticReportDataType,
+	collector_tar.DeployedProductsDataType,
+	collector_tar.InstallationsDataType,
+	collector_tar.PropertiesDataType,
+	collector_tar.CertificatesDataType,
+	collector_tar.CertificateAuthoritiesDataType,
+}
+
+// DefaultRedactionPolicy drops installation user names and NTP servers, and
+// keeps only product properties of types that cannot hold secrets or
+// customer specific values.
+func DefaultRedactionPolicy() redaction.Policy {
+	return redaction.Policy{Rules: []redaction.Rule{
+		{
+			DataType: collector_tar.InstallationsDataType,
+			Path:     "$.installations[*].user_name",
+			Action:   redaction.Drop,
+		},
+		{
+			DataType: collector_tar.DiagnosticReportDataType,
+			Path:     "$.director_configuration.ntp_servers",
+			Action:   redaction.Drop,
+		},
+		{
+			DataType: collector_tar.PropertiesDataType,
+			Path:     "$.properties.*",
+			Action:   redaction.Drop,
+			KeepIf: &redaction.Condition{
+				Field: "type",
+				In: []string{
+					"integer",
+					"boolean",
+					"dropdown_select",
+					"multi_select_options",
+					"selector",
+					"vm_type_dropdown",
+					"disk_type_dropdown",
+				},
+			},
+		},
+	}}
+}
+
+// ReadRedactionPolicy reads the rules at policyPath, which are applied after
+// those of the default policy so they can only redact more. Besides those
+// collected from Ops Manager, rules may be for any of otherDataTypes.
+func ReadRedactionPolicy(policyPath string, otherDataTypes ...string) (redaction.Policy, error) {
+	policy, err := redaction.ReadPolicy(policyPath)
+	if err != nil {
+		return redaction.Policy{}, err
+	}
+	for _, rule := range policy.Rules {
+		if !isRedactableDataType(rule.DataType, otherDataTypes) {
+			return redaction.Policy{}, errors.Errorf(UnknownRedactionDataTypeFormat, rule.DataType, policyPath)
+		}
+	}
+	return DefaultRedactionPolicy().Extend(policy), nil
+}
+
+func isRedactableDataType(dataType string, otherDataTypes []string) bool {
+	for _, redactable := range redactableDataTypes {
+		if dataType == redactable {
+			return true
+		}
+	}
+	for _, redactable := range otherDataTypes {
+		if dataType == redactable {
+			return true
+		}
+	}
+	return false
+}
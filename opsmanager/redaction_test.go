@@ -0,0 +1,82 @@
+package opsmanager_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/pivotal-cf/aqueduct-courier/opsmanager"
+	"github.com/pivotal-cf/aqueduct-courier/redaction"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+)
+
+var _ = Describe("ReadRedactionPolicy", func() {
+	var policyPath string
+
+	BeforeEach(func() {
+		tempDir, err := ioutil.TempDir("", "redaction")
+		Expect(err).NotTo(HaveOccurred())
+		policyPath = filepath.Join(tempDir, "policy.yml")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filepath.Dir(policyPath))).To(Succeed())
+	})
+
+	It("applies the rules in the file after the default rules", func() {
+		Expect(ioutil.WriteFile(policyPath, []byte(`
+rules:
+- data_type: vm_types
+  path: $.vm_types[*].name
+  action: hash
+`), 0644)).To(Succeed())
+
+		policy, err := ReadRedactionPolicy(policyPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(policy).To(Equal(DefaultRedactionPolicy().Extend(redaction.Policy{Rules: []redaction.Rule{
+			{DataType: collector_tar.VmTypesDataType, Path: "$.vm_types[*].name", Action: redaction.Hash},
+		}})))
+		Expect(policy.Hash()).NotTo(Equal(DefaultRedactionPolicy().Hash()))
+	})
+
+	It("errors when a rule is for a data type that is not collected from Ops Manager", func() {
+		Expect(ioutil.WriteFile(policyPath, []byte(`
+rules:
+- data_type: app_usage
+  path: $.name
+  action: drop
+`), 0644)).To(Succeed())
+
+		_, err := ReadRedactionPolicy(policyPath)
+		Expect(err).To(MatchError(fmt.Sprintf(UnknownRedactionDataTypeFormat, collector_tar.AppUsageDataType, policyPath)))
+	})
+
+	It("accepts rules for the other data types given", func() {
+		Expect(ioutil.WriteFile(policyPath, []byte(`
+rules:
+- data_type: apps
+  path: $.apps[*].name
+  action: mask
+`), 0644)).To(Succeed())
+
+		_, err := ReadRedactionPolicy(policyPath)
+		Expect(err).To(MatchError(fmt.Sprintf(UnknownRedactionDataTypeFormat, "apps", policyPath)))
+
+		policy, err := ReadRedactionPolicy(policyPath, "organizations", "apps")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(policy).To(Equal(DefaultRedactionPolicy().Extend(redaction.Policy{Rules: []redaction.Rule{
+			{DataType: "apps", Path: "$.apps[*].name", Action: redaction.Mask},
+		}})))
+	})
+
+	It("errors when the policy is invalid", func() {
+		Expect(ioutil.WriteFile(policyPath, []byte(`rules: [{data_type: vm_types, path: name, action: drop}]`), 0644)).To(Succeed())
+
+		_, err := ReadRedactionPolicy(policyPath)
+		Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(redaction.ReadPolicyFailureFormat, policyPath))))
+	})
+})
@@ -2,6 +2,7 @@ package opsmanager
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,7 +10,9 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/pivotal-cf/aqueduct-courier/redaction"
 	"github.com/pivotal-cf/om/api"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
 	"github.com/pkg/errors"
 )
 
@@ -23,6 +26,11 @@ const (
 	CertificatesPath            = "/api/v0/deployed/certificates"
 	CertificateAuthoritiesPath  = "/api/v0/certificate_authorities"
 	BoshCredentialsPath         = "/api/v0/deployed/director/credentials/bosh_commandline_credentials"
+	RootCACertificatePath       = "/api/v0/security/root_ca_certificate"
+	// TokenPath and PendingChangesPath are requested by the om client, which
+	// does not export them.
+	TokenPath          = "/uaa/oauth/token"
+	PendingChangesPath = "/api/v0/staged/pending_changes"
 
 	ReadResponseBodyFailureFormat      = "Unable to read response from %s"
 	InvalidResponseErrorFormat         = "Invalid response format for request to %s"
@@ -33,6 +41,9 @@ const (
 
 type Service struct {
 	Requestor Requestor
+	// RedactionPolicy is applied to the data returned by each method. The
+	// DefaultRedactionPolicy is applied when it is nil.
+	RedactionPolicy *redaction.Policy
 }
 
 type BoshCredential struct {
@@ -74,8 +85,8 @@ type Requestor interface {
 	Curl(input api.RequestServiceCurlInput) (api.RequestServiceCurlOutput, error)
 }
 
-func (s *Service) Installations() (io.Reader, error) {
-	contents, err := s.makeRequest(InstallationsPath)
+func (s *Service) Installations(ctx context.Context) (io.Reader, error) {
+	contents, err := s.makeRequest(ctx, InstallationsPath)
 	if err != nil {
 		return nil, err
 	}
@@ -84,20 +95,12 @@ func (s *Service) Installations() (io.Reader, error) {
 	if err := json.Unmarshal(contents, &i); err != nil {
 		return nil, errors.Wrapf(err, InvalidResponseErrorFormat, InstallationsPath)
 	}
-	for _, installation := range i.Installations {
-		delete(installation, "user_name")
-	}
-
-	redactedContent, err := json.Marshal(i)
-	if err != nil {
-		return nil, err
-	}
 
-	return bytes.NewReader(redactedContent), nil
+	return s.redactValue(collector_tar.InstallationsDataType, InstallationsPath, i)
 }
 
-func (s *Service) CertificateAuthorities() (io.Reader, error) {
-	contents, err := s.makeRequest(CertificateAuthoritiesPath)
+func (s *Service) CertificateAuthorities(ctx context.Context) (io.Reader, error) {
+	contents, err := s.makeRequest(ctx, CertificateAuthoritiesPath)
 	if err != nil {
 		return nil, err
 	}
@@ -107,28 +110,24 @@ func (s *Service) CertificateAuthorities() (io.Reader, error) {
 		return nil, errors.Wrapf(err, InvalidResponseErrorFormat, CertificateAuthoritiesPath)
 	}
 
-	redactedContent, err := json.Marshal(ca)
-	if err != nil {
-		return nil, err
-	}
-
-	return bytes.NewReader(redactedContent), nil
+	return s.redactValue(collector_tar.CertificateAuthoritiesDataType, CertificateAuthoritiesPath, ca)
 }
-func (s *Service) Certificates() (io.Reader, error) {
-	return s.makeRequestReader(CertificatesPath)
+
+func (s *Service) Certificates(ctx context.Context) (io.Reader, error) {
+	return s.makeRedactedRequest(ctx, collector_tar.CertificatesDataType, CertificatesPath)
 }
 
-func (s *Service) DeployedProducts() (io.Reader, error) {
-	return s.makeRequestReader(DeployedProductsPath)
+func (s *Service) DeployedProducts(ctx context.Context) (io.Reader, error) {
+	return s.makeRedactedRequest(ctx, collector_tar.DeployedProductsDataType, DeployedProductsPath)
 }
 
-func (s *Service) ProductResources(guid string) (io.Reader, error) {
-	return s.makeRequestReader(fmt.Sprintf(ProductResourcesPathFormat, guid))
+func (s *Service) ProductResources(ctx context.Context, guid string) (io.Reader, error) {
+	return s.makeRedactedRequest(ctx, collector_tar.ResourcesDataType, fmt.Sprintf(ProductResourcesPathFormat, guid))
 }
 
-func (s *Service) ProductProperties(guid string) (io.Reader, error) {
+func (s *Service) ProductProperties(ctx context.Context, guid string) (io.Reader, error) {
 	productPropertiesPath := fmt.Sprintf(ProductPropertiesPathFormat, guid)
-	contents, err := s.makeRequest(productPropertiesPath)
+	contents, err := s.makeRequest(ctx, productPropertiesPath)
 	if err != nil {
 		return nil, err
 	}
@@ -137,49 +136,30 @@ func (s *Service) ProductProperties(guid string) (io.Reader, error) {
 	if err := json.Unmarshal(contents, &ps); err != nil {
 		return nil, errors.Wrapf(err, InvalidResponseErrorFormat, productPropertiesPath)
 	}
-	for propertyName, property := range ps.Properties {
-		if !allowedPropertyType(property.Type) {
-			delete(ps.Properties, propertyName)
-		}
-	}
 
-	redactedContent, err := json.Marshal(ps)
-	if err != nil {
-		return nil, err
-	}
-
-	return bytes.NewReader(redactedContent), nil
+	return s.redactValue(collector_tar.PropertiesDataType, productPropertiesPath, ps)
 }
 
-func (s *Service) VmTypes() (io.Reader, error) {
-	return s.makeRequestReader(VmTypesPath)
+func (s *Service) VmTypes(ctx context.Context) (io.Reader, error) {
+	return s.makeRedactedRequest(ctx, collector_tar.VmTypesDataType, VmTypesPath)
 }
 
-func (s *Service) DiagnosticReport() (io.Reader, error) {
-	diagnosticReportBytes, err := s.makeRequest(DiagnosticReportPath)
+func (s *Service) DiagnosticReport(ctx context.Context) (io.Reader, error) {
+	diagnosticReportBytes, err := s.makeRequest(ctx, DiagnosticReportPath)
 	if err != nil {
 		return nil, err
 	}
 
-	var diagnosticReportMap map[string]interface{}
-	err = json.Unmarshal(diagnosticReportBytes, &diagnosticReportMap)
+	redactedDiagnosticReport, err := s.redactionPolicy().Apply(collector_tar.DiagnosticReportDataType, diagnosticReportBytes)
 	if err != nil {
 		return nil, errors.Wrap(err, UnmarshalResponseError)
 	}
 
-	val, _ := diagnosticReportMap["director_configuration"].(map[string]interface{})
-	delete(val, "ntp_servers")
-
-	redactedDiagnosticReport, err := json.Marshal(diagnosticReportMap)
-	if err != nil {
-		return nil, err
-	}
-
 	return bytes.NewReader(redactedDiagnosticReport), nil
 }
 
-func (s *Service) BoshCredentials() (BoshCredential, error) {
-	credBytes, err := s.makeRequest(BoshCredentialsPath)
+func (s *Service) BoshCredentials(ctx context.Context) (BoshCredential, error) {
+	credBytes, err := s.makeRequest(ctx, BoshCredentialsPath)
 	if err != nil {
 		return BoshCredential{}, err
 	}
@@ -211,15 +191,67 @@ func (s *Service) BoshCredentials() (BoshCredential, error) {
 	return bCred, nil
 }
 
-func (s *Service) makeRequestReader(path string) (io.Reader, error) {
-	content, err := s.makeRequest(path)
+// RootCACertificate returns the PEM of the Ops Manager root CA, which signs
+// the certificates of the BOSH Director and its CredHub.
+func (s *Service) RootCACertificate(ctx context.Context) (string, error) {
+	certBytes, err := s.makeRequest(ctx, RootCACertificatePath)
+	if err != nil {
+		return "", err
+	}
+
+	var rootCA struct {
+		Cert string `json:"root_ca_certificate_pem"`
+	}
+	err = json.Unmarshal(certBytes, &rootCA)
+	if err != nil || rootCA.Cert == "" {
+		return "", errors.Errorf(InvalidResponseErrorFormat, RootCACertificatePath)
+	}
+	return rootCA.Cert, nil
+}
+
+func (s *Service) makeRedactedRequest(ctx context.Context, dataType, path string) (io.Reader, error) {
+	content, err := s.makeRequest(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return s.redact(dataType, path, content)
+}
+
+// redactValue redacts the JSON encoding of a response that has already been
+// narrowed to the fields that are collected.
+func (s *Service) redactValue(dataType, path string, value interface{}) (io.Reader, error) {
+	content, err := json.Marshal(value)
 	if err != nil {
 		return nil, err
 	}
-	return bytes.NewReader(content), nil
+	return s.redact(dataType, path, content)
+}
+
+func (s *Service) redact(dataType, path string, content []byte) (io.Reader, error) {
+	redacted, err := s.redactionPolicy().Apply(dataType, content)
+	if err != nil {
+		return nil, errors.Wrapf(err, InvalidResponseErrorFormat, path)
+	}
+	return bytes.NewReader(redacted), nil
+}
+
+// redactionPolicy is the default policy unless the service was given one.
+func (s *Service) redactionPolicy() redaction.Policy {
+	if s.RedactionPolicy == nil {
+		return DefaultRedactionPolicy()
+	}
+	return *s.RedactionPolicy
 }
 
-func (s *Service) makeRequest(path string) ([]byte, error) {
+// makeRequest does not start a request once ctx is done, but cannot
+// interrupt one it has sent: the requestor takes no context, so a request in
+// flight is only cancelled through the context its http client was built
+// with.
+func (s *Service) makeRequest(ctx context.Context, path string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrapf(err, RequestFailureErrorFormat, http.MethodGet, path)
+	}
+
 	input := api.RequestServiceCurlInput{
 		Path:   path,
 		Method: http.MethodGet,
@@ -240,21 +272,3 @@ func (s *Service) makeRequest(path string) ([]byte, error) {
 	}
 	return contents, nil
 }
-
-func allowedPropertyType(propertyType string) bool {
-	allowedTypes := []string{
-		"integer",
-		"boolean",
-		"dropdown_select",
-		"multi_select_options",
-		"selector",
-		"vm_type_dropdown",
-		"disk_type_dropdown",
-	}
-	for _, p := range allowedTypes {
-		if propertyType == p {
-			return true
-		}
-	}
-	return false
-}
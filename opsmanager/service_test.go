@@ -2,6 +2,7 @@ package opsmanager_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,7 +16,9 @@ import (
 
 	. "github.com/pivotal-cf/aqueduct-courier/opsmanager"
 	"github.com/pivotal-cf/aqueduct-courier/opsmanager/opsmanagerfakes"
+	"github.com/pivotal-cf/aqueduct-courier/redaction"
 	"github.com/pivotal-cf/om/api"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
 )
 
 var _ = Describe("Service", func() {
@@ -36,7 +39,7 @@ var _ = Describe("Service", func() {
 
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
 
-			actual, err := service.DeployedProducts()
+			actual, err := service.DeployedProducts(context.Background())
 			Expect(err).NotTo(HaveOccurred())
 			Expect(body.isClosed).To(BeTrue())
 			content, err := ioutil.ReadAll(actual)
@@ -50,7 +53,7 @@ var _ = Describe("Service", func() {
 		It("returns an error when requestor errors", func() {
 			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusOK}, errors.New("Requesting things is hard"))
 
-			actual, err := service.DeployedProducts()
+			actual, err := service.DeployedProducts(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(RequestFailureErrorFormat, http.MethodGet, DeployedProductsPath),
@@ -58,11 +61,24 @@ var _ = Describe("Service", func() {
 			Expect(err).To(MatchError(ContainSubstring("Requesting things is hard")))
 		})
 
+		It("does not make a request once the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			actual, err := service.DeployedProducts(ctx)
+			Expect(actual).To(BeNil())
+			Expect(err).To(MatchError(ContainSubstring(
+				fmt.Sprintf(RequestFailureErrorFormat, http.MethodGet, DeployedProductsPath),
+			)))
+			Expect(err).To(MatchError(ContainSubstring(context.Canceled.Error())))
+			Expect(requestor.CurlCallCount()).To(Equal(0))
+		})
+
 		It("returns an error when requestor returns a non 200 status code", func() {
 			body := &readerCloser{}
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusBadGateway}, nil)
 
-			actual, err := service.DeployedProducts()
+			actual, err := service.DeployedProducts(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(body.isClosed).To(BeTrue())
 			Expect(err).To(MatchError(fmt.Sprintf(
@@ -85,7 +101,7 @@ var _ = Describe("Service", func() {
 
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
 
-			actual, err := service.ProductResources(productGUID)
+			actual, err := service.ProductResources(context.Background(), productGUID)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(body.isClosed).To(BeTrue())
 			content, err := ioutil.ReadAll(actual)
@@ -100,7 +116,7 @@ var _ = Describe("Service", func() {
 		It("returns an error when requestor errors", func() {
 			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusOK}, errors.New("Requesting things is hard"))
 
-			actual, err := service.ProductResources(productGUID)
+			actual, err := service.ProductResources(context.Background(), productGUID)
 			Expect(actual).To(BeNil())
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(RequestFailureErrorFormat, http.MethodGet, expectedProductPath),
@@ -112,7 +128,7 @@ var _ = Describe("Service", func() {
 			body := &readerCloser{}
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusBadGateway}, nil)
 
-			actual, err := service.ProductResources(productGUID)
+			actual, err := service.ProductResources(context.Background(), productGUID)
 			Expect(actual).To(BeNil())
 			Expect(body.isClosed).To(BeTrue())
 			Expect(err).To(MatchError(fmt.Sprintf(
@@ -204,7 +220,7 @@ var _ = Describe("Service", func() {
 			delete(expectedProperties["properties"], "remove1")
 			delete(expectedProperties["properties"], "remove2")
 
-			actual, err := service.ProductProperties(productGUID)
+			actual, err := service.ProductProperties(context.Background(), productGUID)
 			Expect(err).NotTo(HaveOccurred())
 			actualContent, err := ioutil.ReadAll(actual)
 			Expect(err).NotTo(HaveOccurred())
@@ -224,7 +240,7 @@ var _ = Describe("Service", func() {
 
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: ioutil.NopCloser(badReader), StatusCode: http.StatusOK}, nil)
 
-			actual, err := service.ProductProperties(productGUID)
+			actual, err := service.ProductProperties(context.Background(), productGUID)
 			Expect(actual).To(BeNil())
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(ReadResponseBodyFailureFormat, expectedProductPropertiesPath),
@@ -237,7 +253,7 @@ var _ = Describe("Service", func() {
 
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
 
-			actual, err := service.ProductProperties(productGUID)
+			actual, err := service.ProductProperties(context.Background(), productGUID)
 			Expect(actual).To(BeNil())
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(InvalidResponseErrorFormat, expectedProductPropertiesPath),
@@ -247,7 +263,7 @@ var _ = Describe("Service", func() {
 		It("returns an error when requestor errors", func() {
 			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusOK}, errors.New("Requesting things is hard"))
 
-			actual, err := service.ProductProperties(productGUID)
+			actual, err := service.ProductProperties(context.Background(), productGUID)
 			Expect(actual).To(BeNil())
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(RequestFailureErrorFormat, http.MethodGet, expectedProductPropertiesPath),
@@ -259,7 +275,7 @@ var _ = Describe("Service", func() {
 			body := &readerCloser{}
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusBadGateway}, nil)
 
-			actual, err := service.ProductProperties(productGUID)
+			actual, err := service.ProductProperties(context.Background(), productGUID)
 			Expect(actual).To(BeNil())
 			Expect(body.isClosed).To(BeTrue())
 			Expect(err).To(MatchError(fmt.Sprintf(
@@ -274,7 +290,7 @@ var _ = Describe("Service", func() {
 
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
 
-			actual, err := service.VmTypes()
+			actual, err := service.VmTypes(context.Background())
 			Expect(err).NotTo(HaveOccurred())
 			Expect(body.isClosed).To(BeTrue())
 			content, err := ioutil.ReadAll(actual)
@@ -288,7 +304,7 @@ var _ = Describe("Service", func() {
 		It("returns an error when requestor errors", func() {
 			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusOK}, errors.New("Requesting things is hard"))
 
-			actual, err := service.VmTypes()
+			actual, err := service.VmTypes(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(RequestFailureErrorFormat, http.MethodGet, VmTypesPath),
@@ -300,7 +316,7 @@ var _ = Describe("Service", func() {
 			body := &readerCloser{}
 			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusBadGateway, Body: body}, nil)
 
-			actual, err := service.VmTypes()
+			actual, err := service.VmTypes(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(body.isClosed).To(BeTrue())
 			Expect(err).To(MatchError(fmt.Sprintf(
@@ -337,7 +353,7 @@ var _ = Describe("Service", func() {
 			body := &readerCloser{reader: strings.NewReader(rawDiagnosticReportContents)}
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
 
-			actual, err := service.DiagnosticReport()
+			actual, err := service.DiagnosticReport(context.Background())
 			Expect(err).NotTo(HaveOccurred())
 			Expect(body.isClosed).To(BeTrue())
 			actualContent, err := ioutil.ReadAll(actual)
@@ -356,7 +372,7 @@ var _ = Describe("Service", func() {
 			body := &readerCloser{reader: bytes.NewReader([]byte(`something-invalid`))}
 			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusOK, Body: body}, nil)
 
-			actual, err := service.DiagnosticReport()
+			actual, err := service.DiagnosticReport(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(err).To(MatchError(ContainSubstring(UnmarshalResponseError)))
 			Expect(err).To(MatchError(ContainSubstring("invalid character")))
@@ -365,7 +381,7 @@ var _ = Describe("Service", func() {
 		It("returns an error when requestor errors", func() {
 			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusOK}, errors.New("Requesting things is hard"))
 
-			actual, err := service.DiagnosticReport()
+			actual, err := service.DiagnosticReport(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(RequestFailureErrorFormat, http.MethodGet, DiagnosticReportPath),
@@ -377,7 +393,7 @@ var _ = Describe("Service", func() {
 			body := &readerCloser{}
 			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusBadGateway, Body: body}, nil)
 
-			actual, err := service.DiagnosticReport()
+			actual, err := service.DiagnosticReport(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(body.isClosed).To(BeTrue())
 			Expect(err).To(MatchError(fmt.Sprintf(
@@ -392,7 +408,7 @@ var _ = Describe("Service", func() {
 
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
 
-			actual, err := service.Installations()
+			actual, err := service.Installations(context.Background())
 			Expect(err).NotTo(HaveOccurred())
 			actualContent, err := ioutil.ReadAll(actual)
 			Expect(err).NotTo(HaveOccurred())
@@ -409,7 +425,7 @@ var _ = Describe("Service", func() {
 
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: ioutil.NopCloser(badReader), StatusCode: http.StatusOK}, nil)
 
-			actual, err := service.Installations()
+			actual, err := service.Installations(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(ReadResponseBodyFailureFormat, InstallationsPath),
@@ -422,7 +438,7 @@ var _ = Describe("Service", func() {
 
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
 
-			actual, err := service.Installations()
+			actual, err := service.Installations(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(InvalidResponseErrorFormat, InstallationsPath),
@@ -432,7 +448,7 @@ var _ = Describe("Service", func() {
 		It("returns an error when requestor errors", func() {
 			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusOK}, errors.New("Requesting things is hard"))
 
-			actual, err := service.Installations()
+			actual, err := service.Installations(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(RequestFailureErrorFormat, http.MethodGet, InstallationsPath),
@@ -444,7 +460,7 @@ var _ = Describe("Service", func() {
 			body := &readerCloser{}
 			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusBadGateway, Body: body}, nil)
 
-			actual, err := service.Installations()
+			actual, err := service.Installations(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(body.isClosed).To(BeTrue())
 			Expect(err).To(MatchError(fmt.Sprintf(
@@ -459,7 +475,7 @@ var _ = Describe("Service", func() {
 
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
 
-			actual, err := service.Certificates()
+			actual, err := service.Certificates(context.Background())
 			Expect(err).NotTo(HaveOccurred())
 			actualContent, err := ioutil.ReadAll(actual)
 			Expect(err).NotTo(HaveOccurred())
@@ -472,7 +488,7 @@ var _ = Describe("Service", func() {
 		It("returns an error when requestor errors", func() {
 			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusOK}, errors.New("Requesting things is hard"))
 
-			actual, err := service.Certificates()
+			actual, err := service.Certificates(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(RequestFailureErrorFormat, http.MethodGet, CertificatesPath),
@@ -484,7 +500,7 @@ var _ = Describe("Service", func() {
 			body := &readerCloser{}
 			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusBadGateway, Body: body}, nil)
 
-			actual, err := service.Certificates()
+			actual, err := service.Certificates(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(body.isClosed).To(BeTrue())
 			Expect(err).To(MatchError(fmt.Sprintf(
@@ -508,7 +524,7 @@ var _ = Describe("Service", func() {
 }]}`)}
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
 
-			actual, err := service.CertificateAuthorities()
+			actual, err := service.CertificateAuthorities(context.Background())
 			Expect(err).NotTo(HaveOccurred())
 			actualContent, err := ioutil.ReadAll(actual)
 			Expect(err).NotTo(HaveOccurred())
@@ -533,7 +549,7 @@ var _ = Describe("Service", func() {
 
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: ioutil.NopCloser(badReader), StatusCode: http.StatusOK}, nil)
 
-			actual, err := service.CertificateAuthorities()
+			actual, err := service.CertificateAuthorities(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(ReadResponseBodyFailureFormat, CertificateAuthoritiesPath),
@@ -546,7 +562,7 @@ var _ = Describe("Service", func() {
 
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
 
-			actual, err := service.CertificateAuthorities()
+			actual, err := service.CertificateAuthorities(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(InvalidResponseErrorFormat, CertificateAuthoritiesPath),
@@ -556,7 +572,7 @@ var _ = Describe("Service", func() {
 		It("returns an error when requestor errors", func() {
 			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusOK}, errors.New("Requesting things is hard"))
 
-			actual, err := service.CertificateAuthorities()
+			actual, err := service.CertificateAuthorities(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(RequestFailureErrorFormat, http.MethodGet, CertificateAuthoritiesPath),
@@ -568,7 +584,7 @@ var _ = Describe("Service", func() {
 			body := &readerCloser{}
 			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusBadGateway, Body: body}, nil)
 
-			actual, err := service.CertificateAuthorities()
+			actual, err := service.CertificateAuthorities(context.Background())
 			Expect(actual).To(BeNil())
 			Expect(body.isClosed).To(BeTrue())
 			Expect(err).To(MatchError(fmt.Sprintf(
@@ -582,7 +598,7 @@ var _ = Describe("Service", func() {
 			body := &readerCloser{reader: strings.NewReader(`{ "credential": "BOSH_CLIENT=best_client BOSH_CLIENT_SECRET=best_secret BOSH_CA_CERT=/cool/path BOSH_ENVIRONMENT=10.9.8.7 bosh "}`)}
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
 
-			actual, err := service.BoshCredentials()
+			actual, err := service.BoshCredentials(context.Background())
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(actual.ClientID).To(Equal("best_client"))
@@ -600,7 +616,7 @@ var _ = Describe("Service", func() {
 
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: ioutil.NopCloser(badReader), StatusCode: http.StatusOK}, nil)
 
-			actual, err := service.BoshCredentials()
+			actual, err := service.BoshCredentials(context.Background())
 			Expect(actual).To(Equal(BoshCredential{}))
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(ReadResponseBodyFailureFormat, BoshCredentialsPath),
@@ -613,7 +629,7 @@ var _ = Describe("Service", func() {
 
 			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
 
-			actual, err := service.BoshCredentials()
+			actual, err := service.BoshCredentials(context.Background())
 			Expect(actual).To(Equal(BoshCredential{}))
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(InvalidResponseErrorFormat, BoshCredentialsPath),
@@ -623,7 +639,7 @@ var _ = Describe("Service", func() {
 		It("returns an error when requestor errors", func() {
 			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusOK}, errors.New("Requesting things is hard"))
 
-			actual, err := service.BoshCredentials()
+			actual, err := service.BoshCredentials(context.Background())
 			Expect(actual).To(Equal(BoshCredential{}))
 			Expect(err).To(MatchError(ContainSubstring(
 				fmt.Sprintf(RequestFailureErrorFormat, http.MethodGet, BoshCredentialsPath),
@@ -635,7 +651,7 @@ var _ = Describe("Service", func() {
 			body := &readerCloser{}
 			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusBadGateway, Body: body}, nil)
 
-			actual, err := service.BoshCredentials()
+			actual, err := service.BoshCredentials(context.Background())
 			Expect(actual).To(Equal(BoshCredential{}))
 			Expect(body.isClosed).To(BeTrue())
 			Expect(err).To(MatchError(fmt.Sprintf(
@@ -643,6 +659,82 @@ var _ = Describe("Service", func() {
 			)))
 		})
 	})
+
+	Describe("RootCACertificate", func() {
+		It("returns the root CA certificate", func() {
+			body := &readerCloser{reader: strings.NewReader(`{"root_ca_certificate_pem": "-----BEGIN CERTIFICATE-----\nsome-cert\n-----END CERTIFICATE-----\n"}`)}
+			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
+
+			actual, err := service.RootCACertificate(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(Equal("-----BEGIN CERTIFICATE-----\nsome-cert\n-----END CERTIFICATE-----\n"))
+
+			Expect(requestor.CurlCallCount()).To(Equal(1))
+			input := requestor.CurlArgsForCall(0)
+			Expect(input).To(Equal(api.RequestServiceCurlInput{Path: RootCACertificatePath, Method: http.MethodGet}))
+		})
+
+		It("errors if the response has no certificate", func() {
+			body := &readerCloser{reader: strings.NewReader(`{}`)}
+			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
+
+			_, err := service.RootCACertificate(context.Background())
+			Expect(err).To(MatchError(fmt.Sprintf(InvalidResponseErrorFormat, RootCACertificatePath)))
+		})
+
+		It("returns an error when requestor returns a non 200 status code", func() {
+			body := &readerCloser{}
+			requestor.CurlReturns(api.RequestServiceCurlOutput{StatusCode: http.StatusNotFound, Body: body}, nil)
+
+			_, err := service.RootCACertificate(context.Background())
+			Expect(err).To(MatchError(fmt.Sprintf(
+				RequestUnexpectedStatusErrorFormat, http.MethodGet, RootCACertificatePath, http.StatusNotFound,
+			)))
+		})
+	})
+
+	Describe("with a redaction policy", func() {
+		BeforeEach(func() {
+			policy := DefaultRedactionPolicy().Extend(redaction.Policy{Rules: []redaction.Rule{
+				{DataType: collector_tar.VmTypesDataType, Path: "$.vm_types[*].name", Action: redaction.Mask},
+				{DataType: redaction.AllDataTypes, Path: "$..guid", Action: redaction.Drop},
+			}})
+			service.RedactionPolicy = &policy
+		})
+
+		It("applies the rules for the data type of each method", func() {
+			body := &readerCloser{reader: strings.NewReader(`{"vm_types": [{"name": "custom", "guid": "some-guid", "cpu": 2}]}`)}
+			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
+
+			actual, err := service.VmTypes(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			content, err := ioutil.ReadAll(actual)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(content).To(MatchJSON(`{"vm_types": [{"name": "REDACTED", "cpu": 2}]}`))
+		})
+
+		It("applies the rules for all data types along with the default rules", func() {
+			body := &readerCloser{reader: strings.NewReader(`{"installations": [{"user_name": "foo", "guid": "some-guid", "other": 42}]}`)}
+			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
+
+			actual, err := service.Installations(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			content, err := ioutil.ReadAll(actual)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(content).To(MatchJSON(`{"installations": [{"other": 42}]}`))
+		})
+
+		It("errors if the contents are not json", func() {
+			body := &readerCloser{reader: strings.NewReader(`you-thought-this-was-json`)}
+			requestor.CurlReturns(api.RequestServiceCurlOutput{Body: body, StatusCode: http.StatusOK}, nil)
+
+			actual, err := service.Certificates(context.Background())
+			Expect(actual).To(BeNil())
+			Expect(err).To(MatchError(ContainSubstring(
+				fmt.Sprintf(InvalidResponseErrorFormat, CertificatesPath),
+			)))
+		})
+	})
 })
 
 //go:generate counterfeiter . reader
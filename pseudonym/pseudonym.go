@@ -0,0 +1,179 @@
+package pseudonym
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	MinimumSaltLength = 16
+
+	ShortSaltFormat           = "The pseudonymization salt must be at least %d characters"
+	WriteMappingFailureFormat = "Could not write pseudonym mapping %s"
+)
+
+var (
+	uuidPattern      = `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`
+	productGUIDRegex = regexp.MustCompile(`^([a-z][a-z0-9_-]*)-[0-9a-f]{20}$`)
+
+	// identifierRegex finds the identifiers within text, in order of
+	// preference: UUIDs, Ops Manager product GUIDs, IPv4 addresses, IPv6
+	// address candidates and host names.
+	identifierRegex = regexp.MustCompile(strings.Join([]string{
+		`\b` + uuidPattern + `\b`,
+		`\b[a-z][a-z0-9_-]*-[0-9a-f]{20}\b`,
+		`\b(?:[0-9]{1,3}\.){3}[0-9]{1,3}\b`,
+		`[0-9a-fA-F]{0,4}(?::[0-9a-fA-F]{0,4}){2,7}`,
+		`\b(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?\.)+[a-zA-Z]{2,}\b`,
+	}, "|"))
+	uuidRegex         = regexp.MustCompile(`^` + uuidPattern + `$`)
+	ipv4Regex         = regexp.MustCompile(`^(?:[0-9]{1,3}\.){3}[0-9]{1,3}$`)
+	alphanumericRegex = regexp.MustCompile(`[0-9a-zA-Z]`)
+)
+
+// Pseudonymizer replaces identifiers with an HMAC-SHA256 of them keyed by a
+// secret salt, so the same identifier gets the same pseudonym wherever it
+// appears while the salt is unchanged. It remembers each pseudonym it hands
+// out so they can be looked up again by whoever holds the mapping.
+type Pseudonymizer struct {
+	salt    []byte
+	mapping map[string]string
+}
+
+func NewPseudonymizer(salt string) (*Pseudonymizer, error) {
+	if len(salt) < MinimumSaltLength {
+		return nil, errors.Errorf(ShortSaltFormat, MinimumSaltLength)
+	}
+	return &Pseudonymizer{salt: []byte(salt), mapping: map[string]string{}}, nil
+}
+
+// Value pseudonymizes the whole of a value known to be an identifier. UUIDs
+// and Ops Manager product GUIDs keep their format.
+func (p *Pseudonymizer) Value(value string) string {
+	if value == "" {
+		return value
+	}
+	digest := p.digest(value)
+
+	var pseudonym string
+	switch {
+	case uuidRegex.MatchString(value):
+		pseudonym = strings.Join([]string{digest[0:8], digest[8:12], digest[12:16], digest[16:20], digest[20:32]}, "-")
+	case productGUIDRegex.MatchString(value):
+		pseudonym = productGUIDRegex.FindStringSubmatch(value)[1] + "-" + digest[:20]
+	case net.ParseIP(value) != nil:
+		pseudonym = "ip-" + digest[:16]
+	case strings.Contains(value, "."):
+		pseudonym = "host-" + digest[:16]
+	default:
+		pseudonym = "id-" + digest[:16]
+	}
+	p.mapping[pseudonym] = value
+	return pseudonym
+}
+
+// Text pseudonymizes the UUIDs, product GUIDs, IP addresses and host names
+// within text, such as URLs and error messages.
+func (p *Pseudonymizer) Text(text string) string {
+	var result strings.Builder
+	last := 0
+	for _, match := range identifierRegex.FindAllStringIndex(text, -1) {
+		start, end := match[0], match[1]
+		if !isIdentifier(text[start:end], text[:start], text[end:]) {
+			continue
+		}
+		result.WriteString(text[last:start])
+		result.WriteString(p.Value(text[start:end]))
+		last = end
+	}
+	result.WriteString(text[last:])
+	return result.String()
+}
+
+// isIdentifier rejects matches that only look like IP addresses, such as
+// times and out of range octets, or that are part of a longer word.
+func isIdentifier(match, before, after string) bool {
+	if !strings.Contains(match, ":") && !ipv4Regex.MatchString(match) {
+		return true
+	}
+	return net.ParseIP(match) != nil && !alphanumericRegex.MatchString(lastByte(before)+firstByte(after))
+}
+
+func lastByte(s string) string {
+	if s == "" {
+		return ""
+	}
+	return s[len(s)-1:]
+}
+
+func firstByte(s string) string {
+	if s == "" {
+		return ""
+	}
+	return s[:1]
+}
+
+// JSON pseudonymizes the string values of a JSON document. Values of guid
+// and *_guid fields are pseudonymized whole, and the identifiers within
+// every other string are pseudonymized as with Text.
+func (p *Pseudonymizer) JSON(contents []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	return json.Marshal(p.walk("", document))
+}
+
+func (p *Pseudonymizer) walk(key string, node interface{}) interface{} {
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		for k, v := range typed {
+			typed[k] = p.walk(k, v)
+		}
+	case []interface{}:
+		for i, v := range typed {
+			typed[i] = p.walk(key, v)
+		}
+	case string:
+		if key == "guid" || strings.HasSuffix(key, "_guid") {
+			return p.Value(typed)
+		}
+		return p.Text(typed)
+	}
+	return node
+}
+
+// WriteMapping writes each pseudonym handed out and what it replaced to a
+// new file only the current user can read.
+func (p *Pseudonymizer) WriteMapping(mappingPath string) error {
+	contents, err := json.MarshalIndent(p.mapping, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, WriteMappingFailureFormat, mappingPath)
+	}
+	file, err := os.OpenFile(mappingPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return errors.Wrapf(err, WriteMappingFailureFormat, mappingPath)
+	}
+	defer file.Close()
+	if _, err := file.Write(contents); err != nil {
+		return errors.Wrapf(err, WriteMappingFailureFormat, mappingPath)
+	}
+	return errors.Wrapf(file.Close(), WriteMappingFailureFormat, mappingPath)
+}
+
+func (p *Pseudonymizer) digest(value string) string {
+	mac := hmac.New(sha256.New, p.salt)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
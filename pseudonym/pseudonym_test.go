@@ -0,0 +1,146 @@
+package pseudonym_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/pivotal-cf/aqueduct-courier/pseudonym"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pseudonymizer", func() {
+	const salt = "some-salt-that-is-long-enough"
+
+	var pseudonymizer *Pseudonymizer
+
+	BeforeEach(func() {
+		var err error
+		pseudonymizer, err = NewPseudonymizer(salt)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("requires a salt that is long enough", func() {
+		_, err := NewPseudonymizer("short")
+		Expect(err).To(MatchError(fmt.Sprintf(ShortSaltFormat, MinimumSaltLength)))
+	})
+
+	Describe("Value", func() {
+		table.DescribeTable("gives a pseudonym in the format of the identifier",
+			func(value, pattern string) {
+				pseudonym := pseudonymizer.Value(value)
+				Expect(pseudonym).To(MatchRegexp(pattern))
+				Expect(pseudonym).NotTo(Equal(value))
+			},
+			table.Entry("a UUID", "cf736154-6fd5-47f4-8ca9-1b4a6fe451ad", `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`),
+			table.Entry("a product GUID", "cf-0123456789abcdef0123", `^cf-[0-9a-f]{20}$`),
+			table.Entry("an IPv4 address", "10.0.0.1", `^ip-[0-9a-f]{16}$`),
+			table.Entry("an IPv6 address", "fe80::1", `^ip-[0-9a-f]{16}$`),
+			table.Entry("a host name", "opsman.example.com", `^host-[0-9a-f]{16}$`),
+			table.Entry("anything else", "some-name", `^id-[0-9a-f]{16}$`),
+		)
+
+		It("gives the same identifier the same pseudonym with the same salt", func() {
+			other, err := NewPseudonymizer(salt)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pseudonymizer.Value("10.0.0.1")).To(Equal(other.Value("10.0.0.1")))
+			Expect(pseudonymizer.Value("10.0.0.1")).NotTo(Equal(pseudonymizer.Value("10.0.0.2")))
+
+			differentlySalted, err := NewPseudonymizer("some-other-salt-that-is-long-enough")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pseudonymizer.Value("10.0.0.1")).NotTo(Equal(differentlySalted.Value("10.0.0.1")))
+		})
+	})
+
+	Describe("Text", func() {
+		It("pseudonymizes the identifiers within the text", func() {
+			text := pseudonymizer.Text("Get https://opsman.example.com:443/api/v0/staged/products/cf-0123456789abcdef0123/resources from 10.0.0.1 and [fe80::1]")
+			Expect(text).To(Equal(fmt.Sprintf(
+				"Get https://%s:443/api/v0/staged/products/%s/resources from %s and [%s]",
+				pseudonymizer.Value("opsman.example.com"),
+				pseudonymizer.Value("cf-0123456789abcdef0123"),
+				pseudonymizer.Value("10.0.0.1"),
+				pseudonymizer.Value("fe80::1"),
+			)))
+		})
+
+		table.DescribeTable("leaves values that only look like identifiers",
+			func(text string) {
+				Expect(pseudonymizer.Text(text)).To(Equal(text))
+			},
+			table.Entry("a time", "collected at 12:30:45"),
+			table.Entry("out of range octets", "999.1.2.3"),
+			table.Entry("a C++ scope", "std::vector"),
+			table.Entry("a version", "2.10.3"),
+			table.Entry("a property name", ".properties.enable_tls"),
+		)
+	})
+
+	Describe("JSON", func() {
+		It("pseudonymizes guid fields whole and the identifiers within other strings", func() {
+			contents, err := pseudonymizer.JSON([]byte(`{
+				"guid": "some-guid",
+				"products": [{"product_guid": "another-guid", "address": "10.0.0.1", "count": 12345678901234567890}],
+				"name": "some-name"
+			}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(MatchJSON(fmt.Sprintf(`{
+				"guid": %q,
+				"products": [{"product_guid": %q, "address": %q, "count": 12345678901234567890}],
+				"name": "some-name"
+			}`, pseudonymizer.Value("some-guid"), pseudonymizer.Value("another-guid"), pseudonymizer.Value("10.0.0.1"))))
+			Expect(string(contents)).To(ContainSubstring("12345678901234567890"))
+		})
+
+		It("errors when the contents are not json", func() {
+			_, err := pseudonymizer.JSON([]byte("not json"))
+			Expect(err).To(MatchError(ContainSubstring("invalid character")))
+		})
+	})
+
+	Describe("WriteMapping", func() {
+		var tempDir string
+
+		BeforeEach(func() {
+			var err error
+			tempDir, err = ioutil.TempDir("", "pseudonym")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(tempDir)).To(Succeed())
+		})
+
+		It("writes what each pseudonym replaced to a file only the user can read", func() {
+			ipPseudonym := pseudonymizer.Value("10.0.0.1")
+			hostPseudonym := pseudonymizer.Value("opsman.example.com")
+
+			mappingPath := filepath.Join(tempDir, "mapping.json")
+			Expect(pseudonymizer.WriteMapping(mappingPath)).To(Succeed())
+
+			info, err := os.Stat(mappingPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+			contents, err := ioutil.ReadFile(mappingPath)
+			Expect(err).NotTo(HaveOccurred())
+			var mapping map[string]string
+			Expect(json.Unmarshal(contents, &mapping)).To(Succeed())
+			Expect(mapping).To(Equal(map[string]string{ipPseudonym: "10.0.0.1", hostPseudonym: "opsman.example.com"}))
+		})
+
+		It("does not overwrite an existing file", func() {
+			mappingPath := filepath.Join(tempDir, "mapping.json")
+			Expect(ioutil.WriteFile(mappingPath, []byte("existing"), 0600)).To(Succeed())
+
+			err := pseudonymizer.WriteMapping(mappingPath)
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(WriteMappingFailureFormat, mappingPath))))
+			Expect(ioutil.ReadFile(mappingPath)).To(Equal([]byte("existing")))
+		})
+	})
+})
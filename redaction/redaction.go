@@ -0,0 +1,308 @@
+package redaction
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	Drop = "drop"
+	Mask = "mask"
+	Hash = "hash"
+
+	// AllDataTypes applies a rule to every data type.
+	AllDataTypes = "*"
+
+	MaskedValue = "REDACTED"
+
+	ReadPolicyFailureFormat = "Could not read redaction policy %s"
+	InvalidPathFormat       = "Invalid redaction path %s"
+	InvalidActionFormat     = "Invalid redaction action %s for %s, must be one of drop, mask or hash"
+	MissingDataTypeFormat   = "Redaction rule for %s has no data_type"
+	InvalidConditionFormat  = "Redaction rule for %s needs a field for keep_if"
+)
+
+// Policy is an ordered list of rules, each applied to the data of its type.
+type Policy struct {
+	Rules []Rule `yaml:"rules" json:"rules"`
+}
+
+// Rule applies its action to every value its path matches. Path is a subset
+// of JSONPath: $ followed by .name, ['name'], .*, [*], [index] and ..name.
+type Rule struct {
+	DataType string     `yaml:"data_type" json:"data_type"`
+	Path     string     `yaml:"path" json:"path"`
+	Action   string     `yaml:"action" json:"action"`
+	KeepIf   *Condition `yaml:"keep_if,omitempty" json:"keep_if,omitempty"`
+}
+
+// Condition leaves a matched object alone when its Field is one of In.
+type Condition struct {
+	Field string   `yaml:"field" json:"field"`
+	In    []string `yaml:"in" json:"in"`
+}
+
+// ReadPolicy reads a YAML (or JSON) policy file, rejecting unknown keys and
+// invalid rules.
+func ReadPolicy(policyPath string) (Policy, error) {
+	var policy Policy
+	contents, err := ioutil.ReadFile(policyPath)
+	if err != nil {
+		return Policy{}, errors.Wrapf(err, ReadPolicyFailureFormat, policyPath)
+	}
+	if err := yaml.UnmarshalStrict(contents, &policy); err != nil {
+		return Policy{}, errors.Wrapf(err, ReadPolicyFailureFormat, policyPath)
+	}
+	if err := policy.Validate(); err != nil {
+		return Policy{}, errors.Wrapf(err, ReadPolicyFailureFormat, policyPath)
+	}
+	return policy, nil
+}
+
+func (p Policy) Validate() error {
+	for _, rule := range p.Rules {
+		if rule.DataType == "" {
+			return errors.Errorf(MissingDataTypeFormat, rule.Path)
+		}
+		if _, err := parsePath(rule.Path); err != nil {
+			return err
+		}
+		switch rule.Action {
+		case Drop, Mask, Hash:
+		default:
+			return errors.Errorf(InvalidActionFormat, rule.Action, rule.Path)
+		}
+		if rule.KeepIf != nil && rule.KeepIf.Field == "" {
+			return errors.Errorf(InvalidConditionFormat, rule.Path)
+		}
+	}
+	return nil
+}
+
+// Extend returns a policy with the rules of other applied after those of p.
+func (p Policy) Extend(other Policy) Policy {
+	rules := make([]Rule, 0, len(p.Rules)+len(other.Rules))
+	rules = append(rules, p.Rules...)
+	rules = append(rules, other.Rules...)
+	return Policy{Rules: rules}
+}
+
+// Hash identifies the rules of the policy, however its file was formatted.
+func (p Policy) Hash() string {
+	contents, _ := json.Marshal(p.Rules)
+	sum := sha256.Sum256(contents)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Apply redacts the JSON contents of a data type. Contents are returned
+// untouched when no rule applies to the data type.
+func (p Policy) Apply(dataType string, contents []byte) ([]byte, error) {
+	var rules []Rule
+	for _, rule := range p.Rules {
+		if rule.DataType == dataType || rule.DataType == AllDataTypes {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return contents, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range rules {
+		segments, err := parsePath(rule.Path)
+		if err != nil {
+			return nil, err
+		}
+		document, _ = rule.apply(document, segments)
+	}
+	return json.Marshal(document)
+}
+
+// apply returns node with the rule applied beneath it, and whether node
+// itself is to be dropped.
+func (r Rule) apply(node interface{}, segments []segment) (interface{}, bool) {
+	if len(segments) == 0 {
+		return r.redact(node)
+	}
+	current, rest := segments[0], segments[1:]
+
+	switch current.kind {
+	case child:
+		if object, ok := node.(map[string]interface{}); ok {
+			if value, exists := object[current.name]; exists {
+				applyToKey(object, current.name, value, func(v interface{}) (interface{}, bool) { return r.apply(v, rest) })
+			}
+		}
+	case index:
+		if array, ok := node.([]interface{}); ok && current.index < len(array) {
+			return applyToElements(array, func(i int, v interface{}) (interface{}, bool) {
+				if i != current.index {
+					return v, false
+				}
+				return r.apply(v, rest)
+			}), false
+		}
+	case wildcard:
+		switch typed := node.(type) {
+		case map[string]interface{}:
+			for key, value := range typed {
+				applyToKey(typed, key, value, func(v interface{}) (interface{}, bool) { return r.apply(v, rest) })
+			}
+		case []interface{}:
+			return applyToElements(typed, func(_ int, v interface{}) (interface{}, bool) { return r.apply(v, rest) }), false
+		}
+	case descendant:
+		// A descendant matches at any depth, including within another match.
+		switch typed := node.(type) {
+		case map[string]interface{}:
+			for key, value := range typed {
+				applyToKey(typed, key, value, func(v interface{}) (interface{}, bool) {
+					v, _ = r.apply(v, segments)
+					if key == current.name {
+						return r.apply(v, rest)
+					}
+					return v, false
+				})
+			}
+		case []interface{}:
+			return applyToElements(typed, func(_ int, v interface{}) (interface{}, bool) { return r.apply(v, segments) }), false
+		}
+	}
+	return node, false
+}
+
+func (r Rule) redact(node interface{}) (interface{}, bool) {
+	if r.KeepIf != nil {
+		if object, ok := node.(map[string]interface{}); ok {
+			value, _ := object[r.KeepIf.Field].(string)
+			for _, kept := range r.KeepIf.In {
+				if value == kept {
+					return node, false
+				}
+			}
+		}
+	}
+
+	switch r.Action {
+	case Drop:
+		return nil, true
+	case Mask:
+		return MaskedValue, false
+	case Hash:
+		contents, _ := json.Marshal(node)
+		sum := sha256.Sum256(contents)
+		return "sha256:" + hex.EncodeToString(sum[:]), false
+	}
+	return node, false
+}
+
+func applyToKey(object map[string]interface{}, key string, value interface{}, f func(interface{}) (interface{}, bool)) {
+	redacted, dropped := f(value)
+	if dropped {
+		delete(object, key)
+	} else {
+		object[key] = redacted
+	}
+}
+
+func applyToElements(array []interface{}, f func(int, interface{}) (interface{}, bool)) []interface{} {
+	kept := make([]interface{}, 0, len(array))
+	for i, value := range array {
+		if redacted, dropped := f(i, value); !dropped {
+			kept = append(kept, redacted)
+		}
+	}
+	return kept
+}
+
+type segmentKind int
+
+const (
+	child segmentKind = iota
+	index
+	wildcard
+	descendant
+)
+
+type segment struct {
+	kind  segmentKind
+	name  string
+	index int
+}
+
+func parsePath(path string) ([]segment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, errors.Errorf(InvalidPathFormat, path)
+	}
+
+	var segments []segment
+	rest := path[1:]
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			name, remaining := readName(rest[2:])
+			if name == "" || name == "*" {
+				return nil, errors.Errorf(InvalidPathFormat, path)
+			}
+			segments = append(segments, segment{kind: descendant, name: name})
+			rest = remaining
+		case strings.HasPrefix(rest, "."):
+			name, remaining := readName(rest[1:])
+			if name == "" {
+				return nil, errors.Errorf(InvalidPathFormat, path)
+			}
+			if name == "*" {
+				segments = append(segments, segment{kind: wildcard})
+			} else {
+				segments = append(segments, segment{kind: child, name: name})
+			}
+			rest = remaining
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, errors.Errorf(InvalidPathFormat, path)
+			}
+			selector := rest[1:end]
+			rest = rest[end+1:]
+			if selector == "*" {
+				segments = append(segments, segment{kind: wildcard})
+				continue
+			}
+			if len(selector) >= 2 && strings.HasPrefix(selector, "'") && strings.HasSuffix(selector, "'") {
+				segments = append(segments, segment{kind: child, name: selector[1 : len(selector)-1]})
+				continue
+			}
+			i, err := strconv.Atoi(selector)
+			if err != nil || i < 0 {
+				return nil, errors.Errorf(InvalidPathFormat, path)
+			}
+			segments = append(segments, segment{kind: index, index: i})
+		default:
+			return nil, errors.Errorf(InvalidPathFormat, path)
+		}
+	}
+	return segments, nil
+}
+
+// readName reads a name up to the next . or [.
+func readName(path string) (string, string) {
+	end := strings.IndexAny(path, ".[")
+	if end < 0 {
+		return path, ""
+	}
+	return path[:end], path[end:]
+}
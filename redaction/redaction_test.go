@@ -0,0 +1,212 @@
+package redaction_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/pivotal-cf/aqueduct-courier/redaction"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Redaction", func() {
+	const document = `{
+		"name": "some-name",
+		"count": 12345678901234567890,
+		"networks": [
+			{"name": "first", "cidr": "10.0.0.0/24"},
+			{"name": "second", "cidr": "10.0.1.0/24", "subnets": [{"cidr": "10.0.1.0/28"}]}
+		],
+		"properties": {
+			".properties.kept": {"type": "integer", "value": 1},
+			".properties.dropped": {"type": "secret", "value": "shh"}
+		}
+	}`
+
+	apply := func(rules ...Rule) string {
+		redacted, err := Policy{Rules: rules}.Apply("some-type", []byte(document))
+		Expect(err).NotTo(HaveOccurred())
+		return string(redacted)
+	}
+
+	table.DescribeTable("applies the action to the values matching the path",
+		func(rule Rule, expected string) {
+			rule.DataType = "some-type"
+			Expect(apply(rule)).To(MatchJSON(expected))
+		},
+		table.Entry("drops a child", Rule{Path: "$.name", Action: Drop}, `{
+			"count": 12345678901234567890,
+			"networks": [
+				{"name": "first", "cidr": "10.0.0.0/24"},
+				{"name": "second", "cidr": "10.0.1.0/24", "subnets": [{"cidr": "10.0.1.0/28"}]}
+			],
+			"properties": {
+				".properties.kept": {"type": "integer", "value": 1},
+				".properties.dropped": {"type": "secret", "value": "shh"}
+			}
+		}`),
+		table.Entry("masks a field of every array element", Rule{Path: "$.networks[*].name", Action: Mask}, `{
+			"name": "some-name",
+			"count": 12345678901234567890,
+			"networks": [
+				{"name": "REDACTED", "cidr": "10.0.0.0/24"},
+				{"name": "REDACTED", "cidr": "10.0.1.0/24", "subnets": [{"cidr": "10.0.1.0/28"}]}
+			],
+			"properties": {
+				".properties.kept": {"type": "integer", "value": 1},
+				".properties.dropped": {"type": "secret", "value": "shh"}
+			}
+		}`),
+		table.Entry("drops an array element by index", Rule{Path: "$.networks[0]", Action: Drop}, `{
+			"name": "some-name",
+			"count": 12345678901234567890,
+			"networks": [
+				{"name": "second", "cidr": "10.0.1.0/24", "subnets": [{"cidr": "10.0.1.0/28"}]}
+			],
+			"properties": {
+				".properties.kept": {"type": "integer", "value": 1},
+				".properties.dropped": {"type": "secret", "value": "shh"}
+			}
+		}`),
+		table.Entry("masks a descendant at any depth", Rule{Path: "$..cidr", Action: Mask}, `{
+			"name": "some-name",
+			"count": 12345678901234567890,
+			"networks": [
+				{"name": "first", "cidr": "REDACTED"},
+				{"name": "second", "cidr": "REDACTED", "subnets": [{"cidr": "REDACTED"}]}
+			],
+			"properties": {
+				".properties.kept": {"type": "integer", "value": 1},
+				".properties.dropped": {"type": "secret", "value": "shh"}
+			}
+		}`),
+		table.Entry("masks a quoted child", Rule{Path: "$.properties['.properties.dropped'].value", Action: Mask}, `{
+			"name": "some-name",
+			"count": 12345678901234567890,
+			"networks": [
+				{"name": "first", "cidr": "10.0.0.0/24"},
+				{"name": "second", "cidr": "10.0.1.0/24", "subnets": [{"cidr": "10.0.1.0/28"}]}
+			],
+			"properties": {
+				".properties.kept": {"type": "integer", "value": 1},
+				".properties.dropped": {"type": "secret", "value": "REDACTED"}
+			}
+		}`),
+		table.Entry("keeps matches that meet the condition", Rule{Path: "$.properties.*", Action: Drop, KeepIf: &Condition{Field: "type", In: []string{"integer"}}}, `{
+			"name": "some-name",
+			"count": 12345678901234567890,
+			"networks": [
+				{"name": "first", "cidr": "10.0.0.0/24"},
+				{"name": "second", "cidr": "10.0.1.0/24", "subnets": [{"cidr": "10.0.1.0/28"}]}
+			],
+			"properties": {
+				".properties.kept": {"type": "integer", "value": 1}
+			}
+		}`),
+		table.Entry("ignores paths that match nothing", Rule{Path: "$.networks[5].name.missing", Action: Drop}, document),
+	)
+
+	It("hashes the JSON encoding of matched values", func() {
+		sum := sha256.Sum256([]byte(`"some-name"`))
+		redacted := apply(Rule{DataType: "some-type", Path: "$.name", Action: Hash})
+		Expect(redacted).To(ContainSubstring(fmt.Sprintf(`"name":"sha256:%s"`, hex.EncodeToString(sum[:]))))
+	})
+
+	It("applies rules for all data types", func() {
+		redacted := apply(Rule{DataType: AllDataTypes, Path: "$.name", Action: Drop})
+		Expect(redacted).NotTo(ContainSubstring("some-name"))
+		Expect(redacted).To(ContainSubstring("12345678901234567890"))
+	})
+
+	It("returns the contents untouched when no rule applies to the data type", func() {
+		policy := Policy{Rules: []Rule{{DataType: "other-type", Path: "$.name", Action: Drop}}}
+		redacted, err := policy.Apply("some-type", []byte("not json"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(redacted)).To(Equal("not json"))
+	})
+
+	It("errors when the contents are not json", func() {
+		policy := Policy{Rules: []Rule{{DataType: "some-type", Path: "$.name", Action: Drop}}}
+		_, err := policy.Apply("some-type", []byte("not json"))
+		Expect(err).To(MatchError(ContainSubstring("invalid character")))
+	})
+
+	Describe("Hash", func() {
+		It("changes with the rules", func() {
+			policy := Policy{Rules: []Rule{{DataType: "some-type", Path: "$.name", Action: Drop}}}
+			extended := policy.Extend(Policy{Rules: []Rule{{DataType: "some-type", Path: "$.count", Action: Mask}}})
+			Expect(policy.Hash()).To(HavePrefix("sha256:"))
+			Expect(policy.Hash()).To(Equal(Policy{Rules: []Rule{{DataType: "some-type", Path: "$.name", Action: Drop}}}.Hash()))
+			Expect(extended.Hash()).NotTo(Equal(policy.Hash()))
+			Expect(extended.Rules).To(HaveLen(2))
+			Expect(policy.Rules).To(HaveLen(1))
+		})
+	})
+
+	Describe("ReadPolicy", func() {
+		var tempDir string
+
+		BeforeEach(func() {
+			var err error
+			tempDir, err = ioutil.TempDir("", "redaction")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(tempDir)).To(Succeed())
+		})
+
+		writePolicy := func(contents string) string {
+			path := filepath.Join(tempDir, "policy.yml")
+			Expect(ioutil.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+			return path
+		}
+
+		It("reads the rules", func() {
+			policy, err := ReadPolicy(writePolicy(`
+rules:
+- data_type: properties
+  path: $.properties.*
+  action: drop
+  keep_if:
+    field: type
+    in: [integer]
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy).To(Equal(Policy{Rules: []Rule{{
+				DataType: "properties",
+				Path:     "$.properties.*",
+				Action:   Drop,
+				KeepIf:   &Condition{Field: "type", In: []string{"integer"}},
+			}}}))
+		})
+
+		It("errors when the file cannot be read", func() {
+			path := filepath.Join(tempDir, "missing.yml")
+			_, err := ReadPolicy(path)
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(ReadPolicyFailureFormat, path))))
+		})
+
+		table.DescribeTable("errors when the policy is invalid",
+			func(contents, expectedError string) {
+				path := writePolicy(contents)
+				_, err := ReadPolicy(path)
+				Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(ReadPolicyFailureFormat, path))))
+				Expect(err).To(MatchError(ContainSubstring(expectedError)))
+			},
+			table.Entry("unknown keys", "rules:\n- data_type: a\n  path: $.a\n  action: drop\n  other: b\n", "field other not found"),
+			table.Entry("no data type", "rules:\n- path: $.a\n  action: drop\n", fmt.Sprintf(MissingDataTypeFormat, "$.a")),
+			table.Entry("a path without a root", "rules:\n- data_type: a\n  path: a.b\n  action: drop\n", fmt.Sprintf(InvalidPathFormat, "a.b")),
+			table.Entry("an unclosed bracket", "rules:\n- data_type: a\n  path: $.a[0\n  action: drop\n", fmt.Sprintf(InvalidPathFormat, "$.a[0")),
+			table.Entry("an invalid index", "rules:\n- data_type: a\n  path: $.a[x]\n  action: drop\n", fmt.Sprintf(InvalidPathFormat, "$.a[x]")),
+			table.Entry("an unknown action", "rules:\n- data_type: a\n  path: $.a\n  action: shred\n", fmt.Sprintf(InvalidActionFormat, "shred", "$.a")),
+			table.Entry("a condition without a field", "rules:\n- data_type: a\n  path: $.a\n  action: drop\n  keep_if:\n    in: [b]\n", fmt.Sprintf(InvalidConditionFormat, "$.a")),
+		)
+	})
+})
@@ -0,0 +1,145 @@
+package report
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+const (
+	// ExpiringWithinDays is how soon a certificate must expire to be flagged
+	// in a report.
+	ExpiringWithinDays = 30
+
+	StatusExpired  = "expired"
+	StatusExpiring = "expiring"
+	StatusValid    = "valid"
+	StatusUnknown  = "unknown"
+
+	SourceOpsManager = "Ops Manager"
+	SourceCredHub    = "CredHub"
+)
+
+// Certificate is a certificate or CA from Ops Manager or CredHub. DaysLeft
+// and Status are relative to when it was read, and ExpiresAt is zero when
+// its expiry could not be read.
+type Certificate struct {
+	Source    string    `json:"source"`
+	Name      string    `json:"name"`
+	Issuer    string    `json:"issuer"`
+	ExpiresAt time.Time `json:"expires_at"`
+	DaysLeft  int       `json:"days_left"`
+	Status    string    `json:"status"`
+}
+
+// ParseOpsManagerCertificates reads the Ops Manager deployed certificates,
+// naming each by its product and property or variable.
+func ParseOpsManagerCertificates(contents []byte, now time.Time) ([]Certificate, error) {
+	var certificates struct {
+		Certificates []struct {
+			ProductGUID       string `json:"product_guid"`
+			PropertyReference string `json:"property_reference"`
+			VariablePath      string `json:"variable_path"`
+			Issuer            string `json:"issuer"`
+			ValidUntil        string `json:"valid_until"`
+		} `json:"certificates"`
+	}
+	if err := json.Unmarshal(contents, &certificates); err != nil {
+		return nil, err
+	}
+
+	var result []Certificate
+	for _, certificate := range certificates.Certificates {
+		name := certificate.PropertyReference
+		if name == "" {
+			name = certificate.VariablePath
+		}
+		if certificate.ProductGUID != "" {
+			name = certificate.ProductGUID + " " + name
+		}
+		result = append(result, newCertificate(SourceOpsManager, name, certificate.Issuer, certificate.ValidUntil, now))
+	}
+	return result, nil
+}
+
+// ParseCertificateAuthorities reads the Ops Manager certificate authorities,
+// naming each by its GUID.
+func ParseCertificateAuthorities(contents []byte, now time.Time) ([]Certificate, error) {
+	var certificateAuthorities struct {
+		CertificateAuthorities []struct {
+			GUID      string `json:"guid"`
+			Issuer    string `json:"issuer"`
+			ExpiresOn string `json:"expires_on"`
+			Active    bool   `json:"active"`
+		} `json:"certificate_authorities"`
+	}
+	if err := json.Unmarshal(contents, &certificateAuthorities); err != nil {
+		return nil, err
+	}
+
+	var result []Certificate
+	for _, ca := range certificateAuthorities.CertificateAuthorities {
+		source := SourceOpsManager
+		if ca.Active {
+			source += " (active)"
+		}
+		result = append(result, newCertificate(source, ca.GUID, ca.Issuer, ca.ExpiresOn, now))
+	}
+	return result, nil
+}
+
+// ParseCredHubCertificates reads the CredHub certificates.
+func ParseCredHubCertificates(contents []byte, now time.Time) ([]Certificate, error) {
+	var certificates struct {
+		Certificates []struct {
+			Name     string `json:"name"`
+			Issuer   string `json:"issuer"`
+			NotAfter string `json:"not_after"`
+		} `json:"credhub_certificates"`
+	}
+	if err := json.Unmarshal(contents, &certificates); err != nil {
+		return nil, err
+	}
+
+	var result []Certificate
+	for _, certificate := range certificates.Certificates {
+		result = append(result, newCertificate(SourceCredHub, certificate.Name, certificate.Issuer, certificate.NotAfter, now))
+	}
+	return result, nil
+}
+
+func newCertificate(source, name, issuer, expiresAt string, now time.Time) Certificate {
+	certificate := Certificate{Source: source, Name: name, Issuer: issuer, ExpiresAt: parseTime(expiresAt)}
+	if !certificate.ExpiresAt.IsZero() {
+		certificate.DaysLeft = int(certificate.ExpiresAt.Sub(now).Hours() / 24)
+	}
+	certificate.Status = certificate.StatusWithin(now, ExpiringWithinDays*24*time.Hour)
+	return certificate
+}
+
+// StatusWithin is the status of the certificate at now, counting it as
+// expiring when it expires within the window.
+func (c Certificate) StatusWithin(now time.Time, within time.Duration) string {
+	switch {
+	case c.ExpiresAt.IsZero():
+		return StatusUnknown
+	case !c.ExpiresAt.After(now):
+		return StatusExpired
+	case !c.ExpiresAt.After(now.Add(within)):
+		return StatusExpiring
+	default:
+		return StatusValid
+	}
+}
+
+// SortByExpiry orders certificates soonest to expire first, with those of
+// unknown expiry last.
+func SortByExpiry(certificates []Certificate) {
+	sort.SliceStable(certificates, func(i, j int) bool {
+		a, b := certificates[i], certificates[j]
+		if a.ExpiresAt.IsZero() || b.ExpiresAt.IsZero() {
+			return !a.ExpiresAt.IsZero() && b.ExpiresAt.IsZero()
+		}
+		return a.ExpiresAt.Before(b.ExpiresAt)
+	})
+}
@@ -0,0 +1,67 @@
+package report_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/aqueduct-courier/report"
+)
+
+var _ = Describe("Certificates", func() {
+	var now time.Time
+
+	BeforeEach(func() {
+		now = time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	})
+
+	It("names Ops Manager certificates by product and property or variable", func() {
+		certificates, err := ParseOpsManagerCertificates([]byte(`{"certificates": [
+			{"product_guid": "cf-guid", "property_reference": ".properties.router", "issuer": "some-ca", "valid_until": "2018-06-11T00:00:00Z"},
+			{"variable_path": "/p-bosh/some-variable", "valid_until": "2019-06-01T00:00:00Z"}
+		]}`), now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certificates).To(Equal([]Certificate{
+			{Source: SourceOpsManager, Name: "cf-guid .properties.router", Issuer: "some-ca", ExpiresAt: time.Date(2018, 6, 11, 0, 0, 0, 0, time.UTC), DaysLeft: 10, Status: StatusExpiring},
+			{Source: SourceOpsManager, Name: "/p-bosh/some-variable", ExpiresAt: time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC), DaysLeft: 365, Status: StatusValid},
+		}))
+	})
+
+	It("reads the CredHub certificates", func() {
+		certificates, err := ParseCredHubCertificates([]byte(`{"credhub_certificates": [
+			{"name": "/some-cert", "issuer": "some-ca", "not_after": "2018-05-01T00:00:00Z"},
+			{"name": "/unreadable", "not_after": "soon"}
+		]}`), now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certificates).To(HaveLen(2))
+		Expect(certificates[0].Source).To(Equal(SourceCredHub))
+		Expect(certificates[0].Status).To(Equal(StatusExpired))
+		Expect(certificates[1].ExpiresAt.IsZero()).To(BeTrue())
+		Expect(certificates[1].Status).To(Equal(StatusUnknown))
+	})
+
+	It("fails on certificates that are not in the expected format", func() {
+		_, err := ParseCertificateAuthorities([]byte(`[]`), now)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("counts certificates expiring within the window as expiring", func() {
+		certificate := Certificate{ExpiresAt: now.Add(60 * 24 * time.Hour)}
+		Expect(certificate.StatusWithin(now, 30*24*time.Hour)).To(Equal(StatusValid))
+		Expect(certificate.StatusWithin(now, 90*24*time.Hour)).To(Equal(StatusExpiring))
+		Expect(Certificate{ExpiresAt: now}.StatusWithin(now, 90*24*time.Hour)).To(Equal(StatusExpired))
+		Expect(Certificate{}.StatusWithin(now, 90*24*time.Hour)).To(Equal(StatusUnknown))
+	})
+
+	It("sorts soonest to expire first, with unknown expiry last", func() {
+		certificates := []Certificate{
+			{Name: "unknown"},
+			{Name: "later", ExpiresAt: now.Add(48 * time.Hour)},
+			{Name: "sooner", ExpiresAt: now.Add(time.Hour)},
+		}
+		SortByExpiry(certificates)
+		Expect(certificates[0].Name).To(Equal("sooner"))
+		Expect(certificates[1].Name).To(Equal("later"))
+		Expect(certificates[2].Name).To(Equal("unknown"))
+	})
+})
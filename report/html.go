@@ -0,0 +1,112 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const RenderFailureMessage = "Unable to render the report"
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02 15:04 MST")
+	},
+	"duration": func(d time.Duration) string {
+		if d == 0 {
+			return ""
+		}
+		return d.Round(time.Second).String()
+	},
+}).Parse(htmlSource))
+
+// WriteHTML writes the report as a single HTML page that needs nothing else
+// to be viewed.
+func (r Report) WriteHTML(w io.Writer) error {
+	return errors.Wrap(htmlTemplate.Execute(w, r), RenderFailureMessage)
+}
+
+const htmlSource = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Telemetry collection report{{with .Metadata.FoundationId}} for {{.}}{{end}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #f0f0f0; }
+.expired { background: #f8d0d0; }
+.expiring { background: #fcecc0; }
+.failed { color: #a00; }
+.chart rect { fill: #3a78b4; }
+.chart text { font-size: 11px; text-anchor: middle; }
+</style>
+</head>
+<body>
+<h1>Telemetry collection report</h1>
+<table>
+<tr><th>Foundation</th><td>{{.Metadata.FoundationId}}</td></tr>
+<tr><th>Environment type</th><td>{{.Metadata.EnvType}}</td></tr>
+<tr><th>Collection</th><td>{{.Metadata.CollectionId}}</td></tr>
+<tr><th>Collected at</th><td>{{.Metadata.CollectedAt}}</td></tr>
+<tr><th>Collector version</th><td>{{.Metadata.CollectorVersion}}</td></tr>
+{{with .Metadata.RedactionPolicyHash}}<tr><th>Redaction policy</th><td>{{.}}</td></tr>
+{{end}}{{if .Metadata.Pseudonymized}}<tr><th>Pseudonymized</th><td>true</td></tr>
+{{end}}<tr><th>Report generated at</th><td>{{date .GeneratedAt}}</td></tr>
+</table>
+{{if or .Failures .Problems}}
+<h2>Not included</h2>
+<ul>
+{{range .Failures}}<li class="failed">{{.ProductType}} {{.DataType}}: {{.Error}}</li>
+{{end}}{{range .Problems}}<li class="failed">{{.}}</li>
+{{end}}</ul>
+{{end}}
+<h2>Deployed products</h2>
+{{if .Products}}<table>
+<tr><th>Type</th><th>Version</th><th>Installation name</th><th>GUID</th></tr>
+{{range .Products}}<tr><td>{{.Type}}</td><td>{{.Version}}</td><td>{{.Name}}</td><td>{{.GUID}}</td></tr>
+{{end}}</table>
+{{else}}<p>No deployed products were collected.</p>
+{{end}}
+<h2>VM types</h2>
+{{if .VmTypes}}<table>
+<tr><th>Name</th><th>CPUs</th><th>RAM (MB)</th><th>Ephemeral disk (MB)</th><th>Built in</th></tr>
+{{range .VmTypes}}<tr><td>{{.Name}}</td><td>{{.CPU}}</td><td>{{.RAM}}</td><td>{{.EphemeralDisk}}</td><td>{{.Builtin}}</td></tr>
+{{end}}</table>
+{{else}}<p>No VM types were collected.</p>
+{{end}}
+<h2>Installation history</h2>
+{{if .Installations}}<table>
+<tr><th>ID</th><th>Started</th><th>Finished</th><th>Duration</th><th>Status</th></tr>
+{{range .Installations}}<tr{{if ne .Status "succeeded"}} class="failed"{{end}}><td>{{.ID}}</td><td>{{date .StartedAt}}</td><td>{{date .FinishedAt}}</td><td>{{duration .Duration}}</td><td>{{.Status}}</td></tr>
+{{end}}</table>
+{{else}}<p>No installations were collected.</p>
+{{end}}
+<h2>Certificates</h2>
+{{if .Certificates}}{{template "certificates" .Certificates}}{{else}}<p>No certificates were collected.</p>
+{{end}}
+<h2>Certificate authorities</h2>
+{{if .CertificateAuthorities}}{{template "certificates" .CertificateAuthorities}}{{else}}<p>No certificate authorities were collected.</p>
+{{end}}
+<h2>Monthly usage</h2>
+{{range .Usage}}<h3>{{.Title}}</h3>
+{{if .Bars}}<svg class="chart" width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}" role="img" aria-label="{{.Title}}">
+{{$labelY := .LabelY}}{{$unit := .Unit}}{{range .Bars}}<rect x="{{.X}}" y="{{.Y}}" width="{{.Width}}" height="{{.Height}}"><title>{{.Label}}: {{.FormatValue}} {{$unit}}</title></rect>
+{{if .ShowLabel}}<text x="{{.LabelX}}" y="{{$labelY}}">{{.Label}}</text>
+{{end}}{{end}}</svg>
+{{else}}<p>No monthly usage was reported.</p>
+{{end}}{{else}}<p>No usage was collected.</p>
+{{end}}
+</body>
+</html>
+{{define "certificates"}}<table>
+<tr><th>Expires</th><th>Days left</th><th>Status</th><th>Source</th><th>Name</th><th>Issuer</th></tr>
+{{range .}}<tr class="{{.Status}}"><td>{{date .ExpiresAt}}</td><td>{{if not .ExpiresAt.IsZero}}{{.DaysLeft}}{{end}}</td><td>{{.Status}}</td><td>{{.Source}}</td><td>{{.Name}}</td><td>{{.Issuer}}</td></tr>
+{{end}}</table>
+{{end}}`
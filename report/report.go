@@ -0,0 +1,215 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+	"github.com/pkg/errors"
+)
+
+const (
+	ListFilesFailureMessage = "Unable to list the files in the archive"
+	ReadFileFailureFormat   = "Unable to read %s"
+	NoOpsManagerDataMessage = "Archive has no Ops Manager data set"
+	UnexpectedFormatFormat  = "%s is not in the expected format, so it is left out of the report"
+)
+
+// timeLayouts are the formats Ops Manager, CredHub and the Usage Service
+// write times in.
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05 MST", "2006-01-02T15:04:05.000Z", "2006-01-02"}
+
+type tarReader interface {
+	ReadFile(fileName string) ([]byte, error)
+	FileMd5s() (map[string]string, error)
+}
+
+// Report is what a collection holds, arranged for reading. Sections whose
+// data was not collected are left empty.
+type Report struct {
+	GeneratedAt            time.Time
+	Metadata               manifest.Metadata
+	Products               []Product
+	VmTypes                []VmType
+	Installations          []Installation
+	Certificates           []Certificate
+	CertificateAuthorities []Certificate
+	Usage                  []Chart
+	Failures               []manifest.Failure
+	Problems               []string
+}
+
+type Product struct {
+	Name    string `json:"installation_name"`
+	GUID    string `json:"guid"`
+	Type    string `json:"type"`
+	Version string `json:"product_version"`
+}
+
+type VmType struct {
+	Name          string  `json:"name"`
+	CPU           float64 `json:"cpu"`
+	RAM           float64 `json:"ram"`
+	EphemeralDisk float64 `json:"ephemeral_disk"`
+	Builtin       bool    `json:"builtin"`
+}
+
+type Installation struct {
+	ID         int64
+	Status     string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Duration is zero for an installation that has not finished.
+func (i Installation) Duration() time.Duration {
+	if i.StartedAt.IsZero() || i.FinishedAt.IsZero() {
+		return 0
+	}
+	return i.FinishedAt.Sub(i.StartedAt)
+}
+
+// Build reads the Ops Manager, CredHub and Usage Service data in the archive.
+// Data that is missing or not in the expected format is left out, noting
+// the latter in Problems.
+func Build(archive tarReader, now time.Time) (Report, error) {
+	fileMd5s, err := archive.FileMd5s()
+	if err != nil {
+		return Report{}, errors.Wrap(err, ListFilesFailureMessage)
+	}
+	b := builder{archive: archive, files: fileMd5s, report: Report{GeneratedAt: now}}
+
+	omMetadataPath := filepath.Join(collector_tar.OpsManagerCollectorDataSetId, collector_tar.MetadataFileName)
+	if _, exists := fileMd5s[omMetadataPath]; !exists {
+		return Report{}, errors.New(NoOpsManagerDataMessage)
+	}
+	b.report.Metadata, err = manifest.Read(archive, fileMd5s, collector_tar.OpsManagerCollectorDataSetId)
+	if err != nil {
+		return Report{}, err
+	}
+	b.report.Failures = append(b.report.Failures, b.report.Metadata.Failures...)
+
+	steps := []func() error{b.products, b.vmTypes, b.installations, b.certificates, b.certificateAuthorities, b.credhubCertificates, b.usage}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return Report{}, err
+		}
+	}
+
+	SortByExpiry(b.report.Certificates)
+	SortByExpiry(b.report.CertificateAuthorities)
+	return b.report, nil
+}
+
+type builder struct {
+	archive tarReader
+	files   map[string]string
+	report  Report
+}
+
+// read unmarshals a file from the archive into v. Only a file that cannot
+// be read is an error.
+func (b *builder) read(fileName string, v interface{}) error {
+	contents, err := b.archive.ReadFile(fileName)
+	if err != nil {
+		return errors.Wrapf(err, ReadFileFailureFormat, fileName)
+	}
+	if err := json.Unmarshal(contents, v); err != nil {
+		b.report.Problems = append(b.report.Problems, fmt.Sprintf(UnexpectedFormatFormat, filepath.ToSlash(fileName)))
+	}
+	return nil
+}
+
+// readIfPresent returns whether the file is in the archive.
+func (b *builder) readIfPresent(fileName string, v interface{}) (bool, error) {
+	if _, exists := b.files[fileName]; !exists {
+		return false, nil
+	}
+	return true, b.read(fileName, v)
+}
+
+func opsManagerFile(productType, dataType string) string {
+	return filepath.Join(collector_tar.OpsManagerCollectorDataSetId, productType+"_"+dataType)
+}
+
+func (b *builder) products() error {
+	_, err := b.readIfPresent(opsManagerFile(collector_tar.OpsManagerProductType, collector_tar.DeployedProductsDataType), &b.report.Products)
+	sort.SliceStable(b.report.Products, func(i, j int) bool { return b.report.Products[i].Type < b.report.Products[j].Type })
+	return err
+}
+
+func (b *builder) vmTypes() error {
+	var vmTypes struct {
+		VmTypes []VmType `json:"vm_types"`
+	}
+	_, err := b.readIfPresent(opsManagerFile(collector_tar.OpsManagerProductType, collector_tar.VmTypesDataType), &vmTypes)
+	b.report.VmTypes = vmTypes.VmTypes
+	return err
+}
+
+func (b *builder) installations() error {
+	var installations struct {
+		Installations []struct {
+			ID         int64  `json:"id"`
+			Status     string `json:"status"`
+			StartedAt  string `json:"started_at"`
+			FinishedAt string `json:"finished_at"`
+		} `json:"installations"`
+	}
+	if _, err := b.readIfPresent(opsManagerFile(collector_tar.OpsManagerProductType, collector_tar.InstallationsDataType), &installations); err != nil {
+		return err
+	}
+	for _, installation := range installations.Installations {
+		b.report.Installations = append(b.report.Installations, Installation{
+			ID:         installation.ID,
+			Status:     installation.Status,
+			StartedAt:  parseTime(installation.StartedAt),
+			FinishedAt: parseTime(installation.FinishedAt),
+		})
+	}
+	sort.SliceStable(b.report.Installations, func(i, j int) bool {
+		return b.report.Installations[i].StartedAt.Before(b.report.Installations[j].StartedAt)
+	})
+	return nil
+}
+
+func (b *builder) certificates() error {
+	return b.readCertificates(opsManagerFile(collector_tar.OpsManagerProductType, collector_tar.CertificatesDataType), ParseOpsManagerCertificates, &b.report.Certificates)
+}
+
+func (b *builder) certificateAuthorities() error {
+	return b.readCertificates(opsManagerFile(collector_tar.OpsManagerProductType, collector_tar.CertificateAuthoritiesDataType), ParseCertificateAuthorities, &b.report.CertificateAuthorities)
+}
+
+func (b *builder) credhubCertificates() error {
+	return b.readCertificates(opsManagerFile(collector_tar.DirectorProductType, collector_tar.CertificatesDataType), ParseCredHubCertificates, &b.report.Certificates)
+}
+
+func (b *builder) readCertificates(fileName string, parse func([]byte, time.Time) ([]Certificate, error), certificates *[]Certificate) error {
+	if _, exists := b.files[fileName]; !exists {
+		return nil
+	}
+	contents, err := b.archive.ReadFile(fileName)
+	if err != nil {
+		return errors.Wrapf(err, ReadFileFailureFormat, fileName)
+	}
+	parsed, err := parse(contents, b.report.GeneratedAt)
+	if err != nil {
+		b.report.Problems = append(b.report.Problems, fmt.Sprintf(UnexpectedFormatFormat, filepath.ToSlash(fileName)))
+	}
+	*certificates = append(*certificates, parsed...)
+	return nil
+}
+
+func parseTime(value string) time.Time {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
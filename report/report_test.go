@@ -0,0 +1,185 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
+	. "github.com/pivotal-cf/aqueduct-courier/report"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+	"github.com/pivotal-cf/telemetry-utils/tar"
+)
+
+var _ = Describe("Report", func() {
+	var (
+		files map[string]string
+		now   time.Time
+	)
+
+	omFile := func(productType, dataType string) string {
+		return filepath.Join(collector_tar.OpsManagerCollectorDataSetId, productType+"_"+dataType)
+	}
+
+	build := func() (Report, error) {
+		var archive bytes.Buffer
+		writer := tar.NewTarWriter(&archive)
+		for name, contents := range files {
+			Expect(writer.AddFile([]byte(contents), name)).To(Succeed())
+		}
+		Expect(writer.Close()).To(Succeed())
+		return Build(tar.NewTarReader(bytes.NewReader(archive.Bytes())), now)
+	}
+
+	BeforeEach(func() {
+		now = time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+		metadata, err := json.Marshal(manifest.Metadata{
+			EnvType:      "production",
+			FoundationId: "p-bosh-guid",
+			Failures:     []manifest.Failure{{ProductType: "cf", DataType: collector_tar.PropertiesDataType, Error: "some-error"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		files = map[string]string{
+			filepath.Join(collector_tar.OpsManagerCollectorDataSetId, collector_tar.MetadataFileName): string(metadata),
+			omFile(collector_tar.OpsManagerProductType, collector_tar.DeployedProductsDataType): `[
+				{"installation_name": "cf-1234", "guid": "cf-1234", "type": "cf", "product_version": "2.6.3"},
+				{"installation_name": "p-bosh", "guid": "p-bosh-guid", "type": "p-bosh", "product_version": "2.6.1"}
+			]`,
+			omFile(collector_tar.OpsManagerProductType, collector_tar.VmTypesDataType): `{"vm_types": [{"name": "micro", "cpu": 1, "ram": 1024, "ephemeral_disk": 8192, "builtin": true}]}`,
+			omFile(collector_tar.OpsManagerProductType, collector_tar.InstallationsDataType): `{"installations": [
+				{"id": 2, "status": "failed", "started_at": "2020-05-02T10:00:00.000Z", "finished_at": "2020-05-02T10:30:00.000Z"},
+				{"id": 1, "status": "succeeded", "started_at": "2020-05-01T10:00:00.000Z", "finished_at": "2020-05-01T11:00:00.000Z"}
+			]}`,
+			omFile(collector_tar.OpsManagerProductType, collector_tar.CertificatesDataType): `{"certificates": [
+				{"product_guid": "cf-1234", "property_reference": ".properties.networking_poe_ssl_certs", "issuer": "some-issuer", "valid_until": "2021-06-01T00:00:00Z"},
+				{"product_guid": "cf-1234", "property_reference": ".uaa.service_provider_key_credentials", "issuer": "some-issuer", "valid_until": "2020-06-11T00:00:00Z"}
+			]}`,
+			omFile(collector_tar.OpsManagerProductType, collector_tar.CertificateAuthoritiesDataType): `{"certificate_authorities": [
+				{"guid": "some-ca-guid", "issuer": "Pivotal", "expires_on": "2020-05-01", "active": true}
+			]}`,
+			omFile(collector_tar.DirectorProductType, collector_tar.CertificatesDataType): `{"credhub_certificates": [
+				{"name": "/some/cert", "issuer": "CN=some-ca", "not_after": "not-a-time"}
+			]}`,
+			filepath.Join(collector_tar.UsageServiceCollectorDataSetId, collector_tar.AppUsageDataType): `{"monthly_reports": [
+				{"year": 2020, "month": 2, "app_instance_hours": 20},
+				{"year": 2019, "month": 12, "app_instance_hours": 10}
+			]}`,
+			filepath.Join(collector_tar.UsageServiceCollectorDataSetId, collector_tar.ServiceUsageDataType): `{"monthly_service_reports": [
+				{"usages": [{"year": 2020, "month": 1, "duration_in_hours": 5}]},
+				{"usages": [{"year": 2020, "month": 1, "duration_in_hours": 7}]}
+			]}`,
+		}
+	})
+
+	It("reads the metadata, products and VM types", func() {
+		r, err := build()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.GeneratedAt).To(Equal(now))
+		Expect(r.Metadata.FoundationId).To(Equal("p-bosh-guid"))
+		Expect(r.Failures).To(HaveLen(1))
+		Expect(r.Products).To(Equal([]Product{
+			{Name: "cf-1234", GUID: "cf-1234", Type: "cf", Version: "2.6.3"},
+			{Name: "p-bosh", GUID: "p-bosh-guid", Type: "p-bosh", Version: "2.6.1"},
+		}))
+		Expect(r.VmTypes).To(Equal([]VmType{{Name: "micro", CPU: 1, RAM: 1024, EphemeralDisk: 8192, Builtin: true}}))
+		Expect(r.Problems).To(BeEmpty())
+	})
+
+	It("orders the installations by when they started", func() {
+		r, err := build()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Installations).To(HaveLen(2))
+		Expect(r.Installations[0].ID).To(Equal(int64(1)))
+		Expect(r.Installations[0].Duration()).To(Equal(time.Hour))
+		Expect(r.Installations[1].Status).To(Equal("failed"))
+	})
+
+	It("orders the certificates by expiry and flags those expired or expiring soon", func() {
+		r, err := build()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Certificates).To(HaveLen(3))
+		Expect(r.Certificates[0].Name).To(Equal("cf-1234 .uaa.service_provider_key_credentials"))
+		Expect(r.Certificates[0].DaysLeft).To(Equal(10))
+		Expect(r.Certificates[0].Status).To(Equal(StatusExpiring))
+		Expect(r.Certificates[1].Status).To(Equal(StatusValid))
+		Expect(r.Certificates[2].Source).To(Equal("CredHub"))
+		Expect(r.Certificates[2].Status).To(Equal(StatusUnknown))
+
+		Expect(r.CertificateAuthorities).To(HaveLen(1))
+		Expect(r.CertificateAuthorities[0].Status).To(Equal(StatusExpired))
+	})
+
+	It("charts the monthly usage oldest first", func() {
+		r, err := build()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Usage).To(HaveLen(2))
+
+		Expect(r.Usage[0].Title).To(Equal("App instance hours"))
+		Expect(r.Usage[0].Bars).To(HaveLen(2))
+		Expect(r.Usage[0].Bars[0].Label).To(Equal("2019-12"))
+		Expect(r.Usage[0].Bars[0].Value).To(Equal(10.0))
+		Expect(r.Usage[0].Bars[1].Height).To(Equal(2 * r.Usage[0].Bars[0].Height))
+
+		Expect(r.Usage[1].Title).To(Equal("Service instance hours"))
+		Expect(r.Usage[1].Bars).To(HaveLen(1))
+		Expect(r.Usage[1].Bars[0].Value).To(Equal(12.0))
+	})
+
+	It("charts usage collected as a report per month", func() {
+		delete(files, filepath.Join(collector_tar.UsageServiceCollectorDataSetId, collector_tar.AppUsageDataType))
+		delete(files, filepath.Join(collector_tar.UsageServiceCollectorDataSetId, collector_tar.ServiceUsageDataType))
+		usageMetadata, err := json.Marshal(manifest.Metadata{
+			FileDigests: []manifest.FileDigest{
+				{Name: "task_usage_2020-01", DataType: collector_tar.TaskUsageDataType, Start: "2020-01-01", End: "2020-01-31"},
+				{Name: "task_usage_2020-02", DataType: collector_tar.TaskUsageDataType, Start: "2020-02-01", End: "2020-02-29"},
+			},
+			Failures: []manifest.Failure{{DataType: collector_tar.TaskUsageDataType, Start: "2020-03-01", End: "2020-03-31", Error: "some-error"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		files[filepath.Join(collector_tar.UsageServiceCollectorDataSetId, collector_tar.MetadataFileName)] = string(usageMetadata)
+		files[filepath.Join(collector_tar.UsageServiceCollectorDataSetId, "task_usage_2020-01")] = `{"monthly_reports": [{"year": 2020, "month": 1, "task_hours": 3}]}`
+		files[filepath.Join(collector_tar.UsageServiceCollectorDataSetId, "task_usage_2020-02")] = `{"monthly_reports": [{"year": 2020, "month": 2, "task_hours": 4}]}`
+
+		r, err := build()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Usage).To(HaveLen(1))
+		Expect(r.Usage[0].Title).To(Equal("Task hours"))
+		Expect(r.Usage[0].Bars).To(HaveLen(2))
+		Expect(r.Usage[0].Bars[0].Value).To(Equal(3.0))
+		Expect(r.Usage[0].Bars[1].Value).To(Equal(4.0))
+		Expect(r.Failures).To(HaveLen(2))
+	})
+
+	It("leaves out data that is not in the expected format and notes it", func() {
+		files[omFile(collector_tar.OpsManagerProductType, collector_tar.VmTypesDataType)] = `[]`
+		r, err := build()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.VmTypes).To(BeEmpty())
+		Expect(r.Problems).To(ConsistOf(ContainSubstring("ops_manager_vm_types")))
+	})
+
+	It("fails without Ops Manager data", func() {
+		files = map[string]string{"some-data-set/metadata": "{}"}
+		_, err := build()
+		Expect(err).To(MatchError(NoOpsManagerDataMessage))
+	})
+
+	It("writes the report as HTML", func() {
+		r, err := build()
+		Expect(err).NotTo(HaveOccurred())
+		var output bytes.Buffer
+		Expect(r.WriteHTML(&output)).To(Succeed())
+
+		html := output.String()
+		Expect(html).To(ContainSubstring("<td>cf</td><td>2.6.3</td>"))
+		Expect(html).To(ContainSubstring("<td>micro</td>"))
+		Expect(html).To(ContainSubstring(`<tr class="expiring">`))
+		Expect(html).To(ContainSubstring(`<tr class="expired">`))
+		Expect(html).To(ContainSubstring("<h3>App instance hours</h3>"))
+		Expect(html).To(ContainSubstring("<title>2019-12: 10.0 hours</title>"))
+		Expect(html).To(ContainSubstring("cf properties: some-error"))
+	})
+})
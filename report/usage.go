@@ -0,0 +1,208 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+)
+
+const (
+	chartWidth     = 720
+	chartHeight    = 200
+	chartBarGap    = 4
+	chartLabelSize = 20
+	// chartLabelWidth is the room a month's label needs, so that only every
+	// few bars are labelled when there are many months.
+	chartLabelWidth = 56
+)
+
+// Chart is a monthly series from the Usage Service.
+type Chart struct {
+	Title string
+	Unit  string
+	Bars  []Bar
+}
+
+// Bar is a month's value, with its place in the chart's SVG.
+type Bar struct {
+	Label     string
+	ShowLabel bool
+	Value     float64
+	X         float64
+	Y         float64
+	Width     float64
+	Height    float64
+}
+
+func (c Chart) Width() int        { return chartWidth }
+func (c Chart) Height() int       { return chartHeight + chartLabelSize }
+func (c Chart) LabelY() int       { return chartHeight + chartLabelSize - 6 }
+func (b Bar) LabelX() float64     { return b.X + b.Width/2 }
+func (b Bar) FormatValue() string { return fmt.Sprintf("%.1f", b.Value) }
+
+type month struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+}
+
+func (m month) String() string {
+	return fmt.Sprintf("%04d-%02d", m.Year, m.Month)
+}
+
+func (b *builder) usage() error {
+	var usageMetadata manifest.Metadata
+	if _, present := b.files[filepath.Join(collector_tar.UsageServiceCollectorDataSetId, collector_tar.MetadataFileName)]; present {
+		var err error
+		usageMetadata, err = manifest.Read(b.archive, b.files, collector_tar.UsageServiceCollectorDataSetId)
+		if err != nil {
+			return err
+		}
+		b.report.Failures = append(b.report.Failures, usageMetadata.Failures...)
+	}
+
+	values, present, err := b.usageValues(usageMetadata, collector_tar.AppUsageDataType, func(fileName string) (map[month]float64, error) {
+		var appUsage struct {
+			MonthlyReports []struct {
+				month
+				AppInstanceHours float64 `json:"app_instance_hours"`
+			} `json:"monthly_reports"`
+		}
+		values := map[month]float64{}
+		err := b.read(fileName, &appUsage)
+		for _, report := range appUsage.MonthlyReports {
+			values[report.month] += report.AppInstanceHours
+		}
+		return values, err
+	})
+	if err != nil {
+		return err
+	} else if present {
+		b.report.Usage = append(b.report.Usage, newChart("App instance hours", "hours", values))
+	}
+
+	values, present, err = b.usageValues(usageMetadata, collector_tar.ServiceUsageDataType, func(fileName string) (map[month]float64, error) {
+		var serviceUsage struct {
+			MonthlyServiceReports []struct {
+				Usages []struct {
+					month
+					DurationInHours float64 `json:"duration_in_hours"`
+				} `json:"usages"`
+			} `json:"monthly_service_reports"`
+		}
+		values := map[month]float64{}
+		err := b.read(fileName, &serviceUsage)
+		for _, service := range serviceUsage.MonthlyServiceReports {
+			for _, usage := range service.Usages {
+				values[usage.month] += usage.DurationInHours
+			}
+		}
+		return values, err
+	})
+	if err != nil {
+		return err
+	} else if present {
+		b.report.Usage = append(b.report.Usage, newChart("Service instance hours", "hours", values))
+	}
+
+	values, present, err = b.usageValues(usageMetadata, collector_tar.TaskUsageDataType, func(fileName string) (map[month]float64, error) {
+		var taskUsage struct {
+			MonthlyReports []struct {
+				month
+				TaskHours float64 `json:"task_hours"`
+			} `json:"monthly_reports"`
+		}
+		values := map[month]float64{}
+		err := b.read(fileName, &taskUsage)
+		for _, report := range taskUsage.MonthlyReports {
+			values[report.month] += report.TaskHours
+		}
+		return values, err
+	})
+	if err != nil {
+		return err
+	} else if present {
+		b.report.Usage = append(b.report.Usage, newChart("Task hours", "hours", values))
+	}
+	return nil
+}
+
+// usageValues reads the monthly values from each report of the data type,
+// one per month when a range of months was collected. A month in more than
+// one report takes its value from the last of them.
+func (b *builder) usageValues(metadata manifest.Metadata, dataType string, readReport func(fileName string) (map[month]float64, error)) (map[month]float64, bool, error) {
+	fileNames := []string{usageFile(dataType)}
+	if len(metadata.FileDigests) > 0 {
+		fileNames = nil
+		for _, digest := range metadata.FileDigests {
+			if digest.DataType == dataType {
+				fileNames = append(fileNames, usageFile(digest.Name))
+			}
+		}
+	}
+
+	values := map[month]float64{}
+	present := false
+	for _, fileName := range fileNames {
+		if _, exists := b.files[fileName]; !exists {
+			continue
+		}
+		present = true
+		reportValues, err := readReport(fileName)
+		if err != nil {
+			return nil, false, err
+		}
+		for m, value := range reportValues {
+			values[m] = value
+		}
+	}
+	return values, present, nil
+}
+
+func usageFile(name string) string {
+	return filepath.Join(collector_tar.UsageServiceCollectorDataSetId, name)
+}
+
+// newChart lays out a bar per month, oldest first, scaled to the largest.
+func newChart(title, unit string, values map[month]float64) Chart {
+	var months []month
+	maximum := 0.0
+	for m, value := range values {
+		months = append(months, m)
+		if value > maximum {
+			maximum = value
+		}
+	}
+	sort.Slice(months, func(i, j int) bool {
+		if months[i].Year != months[j].Year {
+			return months[i].Year < months[j].Year
+		}
+		return months[i].Month < months[j].Month
+	})
+
+	chart := Chart{Title: title, Unit: unit}
+	if len(months) == 0 {
+		return chart
+	}
+	slot := float64(chartWidth) / float64(len(months))
+	labelEvery := int(math.Ceil(chartLabelWidth / slot))
+	for i, m := range months {
+		height := 0.0
+		if maximum > 0 {
+			height = values[m] / maximum * chartHeight
+		}
+		chart.Bars = append(chart.Bars, Bar{
+			Label:     m.String(),
+			ShowLabel: i%labelEvery == 0,
+			Value:     values[m],
+			X:         float64(i)*slot + chartBarGap/2,
+			Y:         chartHeight - height,
+			Width:     slot - chartBarGap,
+			Height:    height,
+		})
+	}
+	return chart
+}
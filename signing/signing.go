@@ -0,0 +1,282 @@
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+	"github.com/pkg/errors"
+)
+
+const (
+	SignatureFileExtension = ".sig"
+
+	ReadKeyFailureFormat        = "Could not read key %s"
+	InvalidPrivateKeyFormat     = "%s does not contain an Ed25519 private key"
+	InvalidPublicKeyFormat      = "%s does not contain an Ed25519 public key"
+	ReadSignatureFailureFormat  = "Could not read signature %s"
+	WriteSignatureFailureFormat = "Could not write signature %s"
+	WrongSigningKeyFormat       = "Signed with key %s, not %s"
+	SignatureMismatchMessage    = "Signature is not valid for the signed metadata"
+	UnsignedMetadataFormat      = "%s is not covered by the signature"
+	MissingMetadataFormat       = "%s is signed but missing from the archive"
+	ModifiedMetadataFormat      = "%s does not match the signed digest"
+	MissingSHA256DigestFormat   = "%s lists files without a SHA-256 digest"
+	ModifiedFileFormat          = "%s does not match its signed SHA-256 digest"
+	UnsignedFileFormat          = "%s is not listed in any signed metadata"
+	ListMetadataFailureMessage  = "Unable to list the metadata in the archive"
+)
+
+// signedMessagePrefix versions what is signed, which is this prefix followed
+// by a line per metadata file of its name and SHA-256, sorted by name.
+const signedMessagePrefix = "aqueduct-courier metadata signature v1\n"
+
+// Signature is a detached signature over the SHA-256 digests of each data
+// set's metadata file and metadata extensions. The extensions list a SHA-256
+// digest of every other file, and verification rejects any file they do not
+// list, so the signature covers the whole archive.
+type Signature struct {
+	KeyID           string            `json:"key_id"`
+	MetadataDigests map[string]string `json:"metadata_digests"`
+	Signature       []byte            `json:"signature"`
+}
+
+type tarReader interface {
+	ReadFile(fileName string) ([]byte, error)
+	FileMd5s() (map[string]string, error)
+}
+
+// ReadPrivateKey reads a PEM encoded PKCS#8 Ed25519 private key.
+func ReadPrivateKey(keyPath string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type != "PRIVATE KEY" {
+		return nil, errors.Errorf(InvalidPrivateKeyFormat, keyPath)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if err != nil || !ok {
+		return nil, errors.Errorf(InvalidPrivateKeyFormat, keyPath)
+	}
+	return privateKey, nil
+}
+
+// ReadPublicKey reads a PEM encoded PKIX Ed25519 public key.
+func ReadPublicKey(keyPath string) (ed25519.PublicKey, error) {
+	block, err := readPEM(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, errors.Errorf(InvalidPublicKeyFormat, keyPath)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	publicKey, ok := key.(ed25519.PublicKey)
+	if err != nil || !ok {
+		return nil, errors.Errorf(InvalidPublicKeyFormat, keyPath)
+	}
+	return publicKey, nil
+}
+
+func readPEM(keyPath string) (*pem.Block, error) {
+	contents, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, ReadKeyFailureFormat, keyPath)
+	}
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, errors.Errorf(ReadKeyFailureFormat, keyPath)
+	}
+	return block, nil
+}
+
+// KeyID identifies a public key by the SHA-256 of its PKIX encoding.
+func KeyID(key ed25519.PublicKey) string {
+	der, _ := x509.MarshalPKIXPublicKey(key)
+	sum := sha256.Sum256(der)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// Sign signs the metadata files, keyed by their path in the archive.
+func Sign(key ed25519.PrivateKey, metadataFiles map[string][]byte) Signature {
+	digests := map[string]string{}
+	for name, contents := range metadataFiles {
+		digests[filepath.ToSlash(name)] = manifest.SHA256Checksum(contents)
+	}
+	return Signature{
+		KeyID:           KeyID(key.Public().(ed25519.PublicKey)),
+		MetadataDigests: digests,
+		Signature:       ed25519.Sign(key, signedMessage(digests)),
+	}
+}
+
+func (s Signature) WriteFile(signaturePath string) error {
+	contents, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, WriteSignatureFailureFormat, signaturePath)
+	}
+	return errors.Wrapf(ioutil.WriteFile(signaturePath, contents, 0644), WriteSignatureFailureFormat, signaturePath)
+}
+
+func ReadSignature(signaturePath string) (Signature, error) {
+	var signature Signature
+	contents, err := ioutil.ReadFile(signaturePath)
+	if err != nil {
+		return signature, errors.Wrapf(err, ReadSignatureFailureFormat, signaturePath)
+	}
+	if err := json.Unmarshal(contents, &signature); err != nil {
+		return signature, errors.Wrapf(err, ReadSignatureFailureFormat, signaturePath)
+	}
+	return signature, nil
+}
+
+// VerifyFile verifies the signature at signaturePath against the archive.
+func VerifyFile(signaturePath string, key ed25519.PublicKey, archive tarReader) error {
+	signature, err := ReadSignature(signaturePath)
+	if err != nil {
+		return err
+	}
+	return signature.Verify(key, archive)
+}
+
+// Verify checks that the signature was made with key, that the archive
+// has exactly the signed metadata files, that every file they describe
+// matches the SHA-256 digest they list for it, and that the archive has no
+// other files.
+func (s Signature) Verify(key ed25519.PublicKey, archive tarReader) error {
+	if s.KeyID != KeyID(key) {
+		return errors.Errorf(WrongSigningKeyFormat, s.KeyID, KeyID(key))
+	}
+	if !ed25519.Verify(key, signedMessage(s.MetadataDigests), s.Signature) {
+		return errors.New(SignatureMismatchMessage)
+	}
+
+	fileMd5s, err := archive.FileMd5s()
+	if err != nil {
+		return errors.Wrap(err, ListMetadataFailureMessage)
+	}
+	var metadataNames []string
+	for name := range fileMd5s {
+		if isMetadataFile(name) {
+			metadataNames = append(metadataNames, name)
+		}
+	}
+	sort.Strings(metadataNames)
+
+	covered := map[string]bool{}
+	for _, name := range metadataNames {
+		covered[name] = true
+		signedDigest, signed := s.MetadataDigests[filepath.ToSlash(name)]
+		if !signed {
+			return errors.Errorf(UnsignedMetadataFormat, name)
+		}
+		contents, err := archive.ReadFile(name)
+		if err != nil {
+			return errors.Wrap(err, ListMetadataFailureMessage)
+		}
+		if manifest.SHA256Checksum(contents) != signedDigest {
+			return errors.Errorf(ModifiedMetadataFormat, name)
+		}
+	}
+
+	for _, name := range metadataNames {
+		slashName := filepath.ToSlash(name)
+		dataSet := path.Dir(slashName)
+		if path.Base(slashName) != collector_tar.MetadataFileName || dataSet == manifest.ExtensionsDataSetId {
+			continue
+		}
+		metadata, err := manifest.Read(archive, fileMd5s, filepath.FromSlash(dataSet))
+		if err != nil {
+			return errors.Wrapf(err, ModifiedMetadataFormat, name)
+		}
+		for _, fileDigest := range metadata.FileDigests {
+			if fileDigest.SHA256Checksum == "" {
+				return errors.Errorf(MissingSHA256DigestFormat, name)
+			}
+			fileName := filepath.Join(filepath.FromSlash(dataSet), fileDigest.Name)
+			fileContents, err := archive.ReadFile(fileName)
+			if err != nil || manifest.SHA256Checksum(fileContents) != fileDigest.SHA256Checksum {
+				return errors.Errorf(ModifiedFileFormat, fileName)
+			}
+			covered[fileName] = true
+		}
+	}
+
+	var uncoveredNames []string
+	for name := range fileMd5s {
+		if !covered[name] {
+			uncoveredNames = append(uncoveredNames, name)
+		}
+	}
+	if len(uncoveredNames) > 0 {
+		sort.Strings(uncoveredNames)
+		return errors.Errorf(UnsignedFileFormat, uncoveredNames[0])
+	}
+
+	if len(metadataNames) != len(s.MetadataDigests) {
+		for name := range s.MetadataDigests {
+			if _, exists := fileMd5s[filepath.FromSlash(name)]; !exists {
+				return errors.Errorf(MissingMetadataFormat, name)
+			}
+		}
+	}
+	return nil
+}
+
+func signedMessage(digests map[string]string) []byte {
+	var names []string
+	for name := range digests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var message bytes.Buffer
+	message.WriteString(signedMessagePrefix)
+	for _, name := range names {
+		message.WriteString(name + " " + digests[name] + "\n")
+	}
+	return message.Bytes()
+}
+
+// MetadataRecorder passes files through to a tar writer, keeping the
+// metadata files so they can be signed once the archive is written.
+type MetadataRecorder struct {
+	tarWriter
+	MetadataFiles map[string][]byte
+}
+
+type tarWriter interface {
+	AddFile(contents []byte, fileName string) error
+	Close() error
+}
+
+func NewMetadataRecorder(writer tarWriter) *MetadataRecorder {
+	return &MetadataRecorder{tarWriter: writer, MetadataFiles: map[string][]byte{}}
+}
+
+func (r *MetadataRecorder) AddFile(contents []byte, fileName string) error {
+	if isMetadataFile(fileName) {
+		r.MetadataFiles[fileName] = contents
+	}
+	return r.tarWriter.AddFile(contents, fileName)
+}
+
+// isMetadataFile is whether a file is signed: a data set's metadata, or its
+// metadata extensions.
+func isMetadataFile(fileName string) bool {
+	slashName := filepath.ToSlash(fileName)
+	return (path.Base(slashName) == collector_tar.MetadataFileName && path.Dir(slashName) != ".") ||
+		path.Dir(slashName) == manifest.ExtensionsDataSetId
+}
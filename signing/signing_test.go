@@ -0,0 +1,221 @@
+package signing_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pivotal-cf/aqueduct-courier/manifest"
+	. "github.com/pivotal-cf/aqueduct-courier/signing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/telemetry-utils/collector_tar"
+)
+
+type archive map[string][]byte
+
+func (a archive) ReadFile(fileName string) ([]byte, error) {
+	contents, exists := a[fileName]
+	if !exists {
+		return nil, errors.New("no such file")
+	}
+	return contents, nil
+}
+
+func (a archive) FileMd5s() (map[string]string, error) {
+	fileMd5s := map[string]string{}
+	for name := range a {
+		fileMd5s[name] = "some-md5"
+	}
+	return fileMd5s, nil
+}
+
+var _ = Describe("Signing", func() {
+	var (
+		tempDir    string
+		publicKey  ed25519.PublicKey
+		privateKey ed25519.PrivateKey
+		files      archive
+	)
+
+	metadata := func(digests ...manifest.FileDigest) []byte {
+		contents, err := json.Marshal(manifest.Metadata{FileDigests: digests})
+		Expect(err).NotTo(HaveOccurred())
+		return contents
+	}
+
+	metadataFiles := func() map[string][]byte {
+		recorded := map[string][]byte{}
+		for name, contents := range files {
+			if filepath.Base(name) == collector_tar.MetadataFileName {
+				recorded[name] = contents
+			}
+		}
+		return recorded
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "signing")
+		Expect(err).NotTo(HaveOccurred())
+		publicKey, privateKey, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+
+		files = archive{
+			filepath.Join("opsmanager", "d1"): []byte("d1-content"),
+			filepath.Join("opsmanager", collector_tar.MetadataFileName): metadata(
+				manifest.FileDigest{Name: "d1", SHA256Checksum: manifest.SHA256Checksum([]byte("d1-content"))},
+			),
+			filepath.Join("usage_service", collector_tar.MetadataFileName): metadata(),
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("verifies a signature over the metadata of the archive", func() {
+		signature := Sign(privateKey, metadataFiles())
+		Expect(signature.KeyID).To(Equal(KeyID(publicKey)))
+		Expect(signature.MetadataDigests).To(HaveLen(2))
+		Expect(signature.Verify(publicKey, files)).To(Succeed())
+	})
+
+	It("writes a signature that can be read and verified", func() {
+		signaturePath := filepath.Join(tempDir, "archive.tar"+SignatureFileExtension)
+		Expect(Sign(privateKey, metadataFiles()).WriteFile(signaturePath)).To(Succeed())
+		Expect(VerifyFile(signaturePath, publicKey, files)).To(Succeed())
+	})
+
+	It("fails when the signature cannot be read", func() {
+		signaturePath := filepath.Join(tempDir, "missing"+SignatureFileExtension)
+		err := VerifyFile(signaturePath, publicKey, files)
+		Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(ReadSignatureFailureFormat, signaturePath))))
+	})
+
+	It("fails for a key other than the signing key", func() {
+		otherPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		err = Sign(privateKey, metadataFiles()).Verify(otherPublicKey, files)
+		Expect(err).To(MatchError(fmt.Sprintf(WrongSigningKeyFormat, KeyID(publicKey), KeyID(otherPublicKey))))
+	})
+
+	It("fails when the signed digests have been changed", func() {
+		signature := Sign(privateKey, metadataFiles())
+		signature.MetadataDigests[filepath.ToSlash(filepath.Join("opsmanager", collector_tar.MetadataFileName))] = manifest.SHA256Checksum([]byte("other"))
+		Expect(signature.Verify(publicKey, files)).To(MatchError(SignatureMismatchMessage))
+	})
+
+	It("fails when a metadata file has been modified", func() {
+		signature := Sign(privateKey, metadataFiles())
+		name := filepath.Join("opsmanager", collector_tar.MetadataFileName)
+		files[name] = metadata(manifest.FileDigest{Name: "d1", SHA256Checksum: manifest.SHA256Checksum([]byte("tampered"))})
+		Expect(signature.Verify(publicKey, files)).To(MatchError(fmt.Sprintf(ModifiedMetadataFormat, name)))
+	})
+
+	It("fails when a data file has been modified or removed", func() {
+		signature := Sign(privateKey, metadataFiles())
+		name := filepath.Join("opsmanager", "d1")
+		files[name] = []byte("tampered")
+		Expect(signature.Verify(publicKey, files)).To(MatchError(fmt.Sprintf(ModifiedFileFormat, name)))
+
+		delete(files, name)
+		Expect(signature.Verify(publicKey, files)).To(MatchError(fmt.Sprintf(ModifiedFileFormat, name)))
+	})
+
+	It("fails when a file no signed metadata lists has been added", func() {
+		signature := Sign(privateKey, metadataFiles())
+		added := filepath.Join("opsmanager", "d2")
+		files[added] = []byte("d2-content")
+		Expect(signature.Verify(publicKey, files)).To(MatchError(fmt.Sprintf(UnsignedFileFormat, added)))
+
+		delete(files, added)
+		added = "extra"
+		files[added] = []byte("extra-content")
+		Expect(signature.Verify(publicKey, files)).To(MatchError(fmt.Sprintf(UnsignedFileFormat, added)))
+	})
+
+	It("fails when a data set has been added or removed", func() {
+		signature := Sign(privateKey, metadataFiles())
+		added := filepath.Join("extra", collector_tar.MetadataFileName)
+		files[added] = metadata()
+		Expect(signature.Verify(publicKey, files)).To(MatchError(fmt.Sprintf(UnsignedMetadataFormat, added)))
+
+		delete(files, added)
+		delete(files, filepath.Join("usage_service", collector_tar.MetadataFileName))
+		Expect(signature.Verify(publicKey, files)).To(MatchError(fmt.Sprintf(MissingMetadataFormat, "usage_service/"+collector_tar.MetadataFileName)))
+	})
+
+	It("fails when the metadata does not record SHA-256 digests", func() {
+		name := filepath.Join("opsmanager", collector_tar.MetadataFileName)
+		files[name] = metadata(manifest.FileDigest{Name: "d1", MD5Checksum: "some-md5"})
+		Expect(Sign(privateKey, metadataFiles()).Verify(publicKey, files)).To(MatchError(fmt.Sprintf(MissingSHA256DigestFormat, name)))
+	})
+
+	It("records the metadata files written through it", func() {
+		var written []string
+		recorder := NewMetadataRecorder(&fakeTarWriter{written: &written})
+		Expect(recorder.AddFile([]byte("d1-content"), filepath.Join("opsmanager", "d1"))).To(Succeed())
+		Expect(recorder.AddFile([]byte("{}"), filepath.Join("opsmanager", collector_tar.MetadataFileName))).To(Succeed())
+
+		Expect(written).To(Equal([]string{filepath.Join("opsmanager", "d1"), filepath.Join("opsmanager", collector_tar.MetadataFileName)}))
+		Expect(recorder.MetadataFiles).To(Equal(map[string][]byte{filepath.Join("opsmanager", collector_tar.MetadataFileName): []byte("{}")}))
+	})
+
+	Describe("reading keys", func() {
+		writePEM := func(name, blockType string, der []byte) string {
+			path := filepath.Join(tempDir, name)
+			Expect(ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600)).To(Succeed())
+			return path
+		}
+
+		It("reads PKCS#8 private keys and PKIX public keys", func() {
+			der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ReadPrivateKey(writePEM("private.pem", "PRIVATE KEY", der))).To(Equal(privateKey))
+
+			der, err = x509.MarshalPKIXPublicKey(publicKey)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ReadPublicKey(writePEM("public.pem", "PUBLIC KEY", der))).To(Equal(publicKey))
+		})
+
+		It("rejects keys of the wrong kind", func() {
+			der, err := x509.MarshalPKIXPublicKey(publicKey)
+			Expect(err).NotTo(HaveOccurred())
+			path := writePEM("public.pem", "PUBLIC KEY", der)
+			_, err = ReadPrivateKey(path)
+			Expect(err).To(MatchError(fmt.Sprintf(InvalidPrivateKeyFormat, path)))
+
+			path = writePEM("garbage.pem", "PUBLIC KEY", []byte("garbage"))
+			_, err = ReadPublicKey(path)
+			Expect(err).To(MatchError(fmt.Sprintf(InvalidPublicKeyFormat, path)))
+		})
+
+		It("errors when the key cannot be read", func() {
+			path := filepath.Join(tempDir, "missing.pem")
+			_, err := ReadPublicKey(path)
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(ReadKeyFailureFormat, path))))
+		})
+	})
+})
+
+type fakeTarWriter struct {
+	written *[]string
+}
+
+func (w *fakeTarWriter) AddFile(contents []byte, fileName string) error {
+	*w.written = append(*w.written, fileName)
+	return nil
+}
+
+func (w *fakeTarWriter) Close() error {
+	return nil
+}